@@ -0,0 +1,201 @@
+package config
+
+// Config is every setting the site reads. Load fills it from, in increasing priority, the
+// defaults below, a YAML or TOML file, the environment and command-line flags.
+//
+// Each field's tags say where it comes from:
+//
+//	key      name in the config file and flags, under its section: twitch.client_id
+//	env      environment variable; a trailing * reads every variable with that prefix into a map
+//	default  value when nothing else sets it
+//	secret   redacted wherever the config is logged or shown
+//	reload   applied on a reload without a restart
+type Config struct {
+	Env        string           `key:"env" env:"ENV" default:"development"`
+	Server     ServerConfig     `key:"server"`
+	Database   DatabaseConfig   `key:"database"`
+	Turso      TursoConfig      `key:"turso"`
+	Cache      CacheConfig      `key:"cache"`
+	Google     GoogleConfig     `key:"google"`
+	AI         AIConfig         `key:"ai"`
+	Spotify    SpotifyConfig    `key:"spotify"`
+	Twitch     TwitchConfig     `key:"twitch"`
+	Supabase   SupabaseConfig   `key:"supabase"`
+	Football   FootballConfig   `key:"football"`
+	Stats      StatsConfig      `key:"stats"`
+	Profiles   ProfilesConfig   `key:"profiles"`
+	Stripe     StripeConfig     `key:"stripe"`
+	Speech     SpeechConfig     `key:"speech"`
+	Stream     StreamConfig     `key:"stream"`
+	Infracost  InfracostConfig  `key:"infracost"`
+	GraphQL    GraphQLConfig    `key:"graphql"`
+	Chat       ChatConfig       `key:"chat"`
+	ChatBridge ChatBridgeConfig `key:"chat_bridge"`
+	Tokens     TokensConfig     `key:"tokens"`
+	Alerts     AlertsConfig     `key:"alerts"`
+}
+
+type ServerConfig struct {
+	Port string `key:"port" env:"PORT" default:"8080"`
+	// WSAllowedOrigins may open chat websockets; empty allows only the site's own host
+	WSAllowedOrigins []string `key:"ws_allowed_origins" env:"WS_ALLOWED_ORIGINS" reload:"true"`
+	AdminEmails      []string `key:"admin_emails" env:"ADMIN_EMAILS" reload:"true"`
+	DebugAuth        bool     `key:"debug_auth" env:"DEBUG_AUTH" reload:"true"`
+}
+
+type DatabaseConfig struct {
+	URL string `key:"url" env:"DATABASE_URL" secret:"true"`
+}
+
+// TursoConfig is the libSQL database the scenario endpoints read
+type TursoConfig struct {
+	DatabaseURL string `key:"database_url" env:"TURSO_DATABASE_URL"`
+	Token       string `key:"token" env:"TURSO_TOKEN" secret:"true"`
+}
+
+type CacheConfig struct {
+	RedisURL     string `key:"redis_url" env:"REDIS_URL" secret:"true"`
+	UpstashURL   string `key:"upstash_url" env:"UPSTASH_REDIS_REST_URL"`
+	UpstashToken string `key:"upstash_token" env:"UPSTASH_REDIS_REST_TOKEN" secret:"true"`
+	MemorySize   int    `key:"memory_size" env:"MEMORY_CACHE_SIZE" default:"10000"` // keys held without Redis
+	LocalSize    int    `key:"local_size" env:"LOCAL_CACHE_SIZE" default:"1000"`    // keys each replica keeps in front of Redis
+}
+
+type GoogleConfig struct {
+	APIKey        string `key:"api_key" env:"GCP_API_KEY" secret:"true"` // geocoding, speech and billing
+	YouTubeAPIKey string `key:"youtube_api_key" env:"YT_API_KEY" secret:"true"`
+}
+
+type AIConfig struct {
+	AnthropicAPIKey string `key:"anthropic_api_key" env:"ANTHROPIC_API_KEY" secret:"true"`
+	GeminiAPIKey    string `key:"gemini_api_key" env:"GEMINI_API_KEY" secret:"true"` // also used for embeddings
+	OpenAIAPIKey    string `key:"openai_api_key" env:"OPENAI_API_KEY" secret:"true"`
+	GroqAPIKey      string `key:"groq_api_key" env:"GROQ_API_KEY" secret:"true"`
+}
+
+type SpotifyConfig struct {
+	ClientID     string `key:"client_id" env:"SPOTIFY_CLIENT_ID"`
+	ClientSecret string `key:"client_secret" env:"SPOTIFY_CLIENT_SECRET" secret:"true"`
+	RedirectURI  string `key:"redirect_uri" env:"SPOTIFY_REDIRECT_URI"`
+	// RedirectURL is what the docs page shows; it's usually the same as RedirectURI
+	RedirectURL string `key:"redirect_url" env:"SPOTIFY_REDIRECT_URL"`
+}
+
+type TwitchConfig struct {
+	ClientID     string `key:"client_id" env:"TWITCH_CLIENT_ID"`
+	ClientSecret string `key:"client_secret" env:"TWITCH_CLIENT_SECRET" secret:"true"`
+	AccessToken  string `key:"access_token" env:"TWITCH_ACCESS_TOKEN" secret:"true"` // for user lookups
+	BotUsername  string `key:"bot_username" env:"TWITCH_BOT_USERNAME"`               // chat login, defaults to the channel
+	IRCAddress   string `key:"irc_address" env:"TWITCH_IRC_ADDRESS"`                 // host:port, empty for Twitch's own servers
+	IRCTLS       bool   `key:"irc_tls" env:"TWITCH_IRC_TLS" default:"true"`
+
+	EventSubURL         string `key:"eventsub_url" env:"TWITCH_EVENTSUB_URL" default:"wss://eventsub.wss.twitch.tv/ws"`
+	EventSubTransport   string `key:"eventsub_transport" env:"TWITCH_EVENTSUB_TRANSPORT" default:"websocket"` // websocket or webhook
+	EventSubCallbackURL string `key:"eventsub_callback_url" env:"TWITCH_EVENTSUB_CALLBACK_URL"`
+	EventSubSecret      string `key:"eventsub_secret" env:"TWITCH_EVENTSUB_SECRET" secret:"true"`
+}
+
+type SupabaseConfig struct {
+	URL     string `key:"url" env:"SUPABASE_URL"`
+	AnonKey string `key:"anon_key" env:"SUPABASE_ANON_KEY" secret:"true"`
+}
+
+// FootballConfig is the football-data.org token the league schedules use
+type FootballConfig struct {
+	Token string `key:"token" env:"EPL_TOKEN" secret:"true"`
+}
+
+type StatsConfig struct {
+	GitHubToken      string `key:"github_token" env:"GITHUB_TOKEN" secret:"true"`
+	StackExchangeKey string `key:"stackexchange_key" env:"STACKEXCHANGE_KEY" secret:"true"`
+	// Intervals overrides how often the collector polls a provider: a duration of at least
+	// 1m, or "off"
+	Intervals map[string]string `key:"intervals" env:"STATS_INTERVAL_*"`
+	// Accounts are the default profile's accounts on providers without a field of their own
+	Accounts map[string]string `key:"accounts" env:"STATS_ACCOUNT_*"`
+}
+
+// ProfilesConfig lists the creators the site serves. Without Inline or File there's one
+// profile built from the remaining fields.
+type ProfilesConfig struct {
+	Inline           string `key:"inline" env:"PROFILES"` // JSON array of profiles
+	File             string `key:"file" env:"PROFILES_FILE"`
+	Name             string `key:"name" env:"PROFILE_NAME" default:"Majestic Coding"`
+	TwitchChannel    string `key:"twitch_channel" env:"TWITCH_CHANNEL" default:"majesticcodingtwitch" reload:"true"` // only the chat feed follows a reload
+	GitHubUser       string `key:"github_user" env:"GITHUB_USERNAME" default:"mattmajestic"`
+	LeetCodeUser     string `key:"leetcode_user" env:"LEETCODE_USERNAME" default:"mattmajestic"`
+	YouTubeChannelID string `key:"youtube_channel_id" env:"YT_CHANNEL_ID"`
+}
+
+type StripeConfig struct {
+	PublishableKey string `key:"publishable_key" env:"STRIPE_PUB_KEY"`
+	BuyButtonID    string `key:"buy_button_id" env:"STRIPE_BUY_BTN"`
+}
+
+// SpeechConfig points at a local whisper.cpp, used before Google speech-to-text
+type SpeechConfig struct {
+	WhisperCPPPath   string `key:"whisper_cpp_path" env:"WHISPER_CPP_PATH"`
+	WhisperModelPath string `key:"whisper_model_path" env:"WHISPER_MODEL_PATH"`
+}
+
+type StreamConfig struct {
+	AWSStreamingURL string `key:"aws_streaming_url" env:"AWS_STREAMING_URL"` // IVS playlist checked for the live status
+}
+
+type InfracostConfig struct {
+	APIKey string `key:"api_key" env:"ICS_API_KEY" secret:"true"`
+}
+
+type GraphQLConfig struct {
+	MaxDepth int `key:"max_depth" env:"GRAPHQL_MAX_DEPTH" default:"10" reload:"true"`
+	MaxCost  int `key:"max_cost" env:"GRAPHQL_MAX_COST" default:"1000" reload:"true"`
+}
+
+// ChatConfig limits site chat
+type ChatConfig struct {
+	MaxMessageLength  int     `key:"max_message_length" env:"CHAT_MAX_MESSAGE_LENGTH" default:"500"`
+	ConnRate          float64 `key:"conn_rate" env:"CHAT_CONN_RATE" default:"1"` // messages per second per connection
+	ConnBurst         int     `key:"conn_burst" env:"CHAT_CONN_BURST" default:"5"`
+	IdentityRate      float64 `key:"identity_rate" env:"CHAT_IDENTITY_RATE" default:"1.5"` // per user or IP, across connections
+	IdentityBurst     int     `key:"identity_burst" env:"CHAT_IDENTITY_BURST" default:"8"`
+	MaxDuplicates     int     `key:"max_duplicates" env:"CHAT_MAX_DUPLICATES" default:"2"`
+	MaxLinks          int     `key:"max_links" env:"CHAT_MAX_LINKS" default:"2"`
+	StrikesBeforeMute int     `key:"strikes_before_mute" env:"CHAT_STRIKES_BEFORE_MUTE" default:"3"`
+	MuteSeconds       int     `key:"mute_seconds" env:"CHAT_MUTE_SECONDS" default:"300"`
+}
+
+// ChatBridgeConfig relays messages between site chat and Twitch chat
+type ChatBridgeConfig struct {
+	Enabled       bool     `key:"enabled" env:"CHAT_BRIDGE_ENABLED"`
+	Direction     string   `key:"direction" env:"CHAT_BRIDGE_DIRECTION" default:"both"` // both, site-to-twitch or twitch-to-site
+	IgnoreUsers   []string `key:"ignore_users" env:"CHAT_BRIDGE_IGNORE_USERS"`          // on top of the usual bots
+	AuthOnly      bool     `key:"auth_only" env:"CHAT_BRIDGE_AUTH_ONLY" default:"true"` // only relay signed-in site users
+	RelayCommands bool     `key:"relay_commands" env:"CHAT_BRIDGE_RELAY_COMMANDS"`
+}
+
+type TokensConfig struct {
+	// EncryptionKey encrypts stored OAuth tokens: base64 of 32 bytes, or any passphrase
+	EncryptionKey string `key:"encryption_key" env:"TOKEN_ENCRYPTION_KEY" secret:"true"`
+}
+
+type AlertsConfig struct {
+	// OverlaySecret lets the OBS overlay connect to /ws/alerts as ?key=<secret>; without it
+	// only signed-in admins can
+	OverlaySecret string `key:"overlay_secret" env:"ALERT_OVERLAY_SECRET" secret:"true" reload:"true"`
+}
+
+var defaults = mustDefaults()
+
+// Defaults is the config with nothing set, for code that runs before Load or in tests
+func Defaults() *Config {
+	c := *defaults
+	return &c
+}
+
+func mustDefaults() *Config {
+	c := &Config{}
+	if err := applyDefaults(c); err != nil {
+		panic(err)
+	}
+	return c
+}
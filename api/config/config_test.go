@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := "server:\n  port: \"9000\"\n  admin_emails: [a@example.com, b@example.com]\ngraphql:\n  max_depth: 4\n  max_cost: 50\n"
+	if err := os.WriteFile(file, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	environ := []string{"CONFIG_FILE=" + file, "GRAPHQL_MAX_DEPTH=6", "STATS_INTERVAL_GITHUB=1h"}
+	c, src, err := load([]string{"-graphql.max_depth=8"}, environ)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Server.Port != "9000" || c.GraphQL.MaxCost != 50 {
+		t.Errorf("file values not applied: port=%s max_cost=%d", c.Server.Port, c.GraphQL.MaxCost)
+	}
+	if c.GraphQL.MaxDepth != 8 {
+		t.Errorf("max_depth = %d, want the flag's 8 over env and file", c.GraphQL.MaxDepth)
+	}
+	if len(c.Server.AdminEmails) != 2 || c.Server.AdminEmails[1] != "b@example.com" {
+		t.Errorf("admin_emails = %v", c.Server.AdminEmails)
+	}
+	if c.Stats.Intervals["github"] != "1h" {
+		t.Errorf("intervals = %v", c.Stats.Intervals)
+	}
+	if c.Profiles.TwitchChannel != "majesticcodingtwitch" {
+		t.Errorf("default not applied: %q", c.Profiles.TwitchChannel)
+	}
+	if src.File != file || len(src.Flags) != 1 {
+		t.Errorf("source = %+v", src)
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	_, _, err := load(nil, []string{
+		"TWITCH_EVENTSUB_TRANSPORT=webhook",
+		"UPSTASH_REDIS_REST_URL=https://example.upstash.io",
+		"GRAPHQL_MAX_COST=0",
+	})
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"TWITCH_EVENTSUB_CALLBACK_URL", "UPSTASH_REDIS_REST_TOKEN", "GRAPHQL_MAX_COST"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error doesn't mention %s: %v", want, err)
+		}
+	}
+
+	if _, _, err := load(nil, []string{"CHAT_MAX_LINKS=lots"}); err == nil {
+		t.Error("expected an error for a non-numeric int")
+	}
+}
+
+func TestSettingsRedactSecrets(t *testing.T) {
+	c := Defaults()
+	c.Twitch.ClientID = "public-id"
+	c.Twitch.ClientSecret = "hunter2"
+	for _, kv := range c.Settings() {
+		if strings.Contains(kv[1], "hunter2") {
+			t.Errorf("%s leaks its secret", kv[0])
+		}
+		if kv[0] == "twitch.client_id" && kv[1] != "public-id" {
+			t.Errorf("twitch.client_id = %q", kv[1])
+		}
+	}
+}
+
+func TestMergeReloadable(t *testing.T) {
+	current := Defaults()
+	next := Defaults()
+	next.GraphQL.MaxDepth = 3
+	next.Server.Port = "9999"
+
+	merged, changed := current.mergeReloadable(next)
+	if !changed || merged.GraphQL.MaxDepth != 3 {
+		t.Errorf("reloadable max_depth not applied: %d", merged.GraphQL.MaxDepth)
+	}
+	if merged.Server.Port != current.Server.Port {
+		t.Errorf("port changed on reload to %s", merged.Server.Port)
+	}
+	if current.GraphQL.MaxDepth == 3 {
+		t.Error("merge modified the current config")
+	}
+}
+
+func TestFeaturesWithUnavailable(t *testing.T) {
+	c := Defaults()
+	c.Database.URL = "postgres://localhost/site"
+	c.Twitch.ClientID, c.Twitch.ClientSecret = "id", "secret"
+
+	byName := map[string]Feature{}
+	for _, f := range c.FeaturesWith(map[string]string{"database": "could not connect"}) {
+		byName[f.Name] = f
+	}
+	if f := byName["database"]; f.Enabled || f.Reason != "could not connect" {
+		t.Errorf("database = %+v", f)
+	}
+	if f := byName["twitch_eventsub"]; f.Enabled || f.Reason != "needs database" {
+		t.Errorf("twitch_eventsub = %+v", f)
+	}
+	if !byName["twitch"].Enabled {
+		t.Error("twitch should stay on")
+	}
+	if f := byName["spotify"]; f.Enabled || !strings.HasPrefix(f.Reason, "set SPOTIFY_CLIENT_ID") {
+		t.Errorf("spotify = %+v", f)
+	}
+}
+
+func TestTwitchChatFeatureNeedsOnlyAChannel(t *testing.T) {
+	c := Defaults()
+	byName := map[string]Feature{}
+	for _, f := range c.Features() {
+		byName[f.Name] = f
+	}
+	if byName["twitch"].Enabled {
+		t.Error("twitch should be off without client credentials")
+	}
+	if f := byName["twitch_chat"]; !f.Enabled {
+		t.Errorf("twitch_chat = %+v, want on with the default channel", f)
+	}
+
+	c.Profiles.TwitchChannel = ""
+	for _, f := range c.Features() {
+		if f.Name == "twitch_chat" && (f.Enabled || !strings.HasPrefix(f.Reason, "set TWITCH_CHANNEL")) {
+			t.Errorf("twitch_chat = %+v, want off without a channel", f)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// setting is one leaf field of Config, found by walking its tags
+type setting struct {
+	path   string // e.g. twitch.client_id
+	env    string // variable name, or a prefix for maps
+	def    string
+	secret bool
+	reload bool
+	value  reflect.Value
+}
+
+// Source says where settings came from, for the startup report
+type Source struct {
+	File  string   // the config file read, if any
+	Flags []string // settings given as flags
+}
+
+// Load reads the config file named by -config or CONFIG_FILE, the environment and the flags
+// in args, then validates the result
+func Load(args []string) (*Config, *Source, error) {
+	return load(args, os.Environ())
+}
+
+func load(args []string, environ []string) (*Config, *Source, error) {
+	env := map[string]string{}
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	c := &Config{}
+	settings := settingsOf(c)
+	src := &Source{}
+	var errs []error
+
+	if err := applyDefaults(c); err != nil {
+		return nil, nil, err
+	}
+
+	// Flags are parsed first to find -config, but applied last so they win
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	configFile := fs.String("config", env["CONFIG_FILE"], "YAML or TOML config file")
+	flagValues := map[string]*string{}
+	for _, s := range settings {
+		if s.value.Kind() != reflect.Map {
+			flagValues[s.path] = fs.String(s.path, "", "sets "+s.env)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configFile != "" {
+		values, err := readConfigFile(*configFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		src.File = *configFile
+		for path, raw := range values {
+			s, key, ok := findSetting(settings, path)
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown setting %s", *configFile, path))
+				continue
+			}
+			if err := s.set(key, raw); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if prefix, isMap := strings.CutSuffix(s.env, "*"); isMap {
+			for k, v := range env {
+				if name, ok := strings.CutPrefix(k, prefix); ok && strings.TrimSpace(v) != "" {
+					s.set(strings.ToLower(name), v)
+				}
+			}
+			continue
+		}
+		if v, ok := env[s.env]; ok && strings.TrimSpace(v) != "" {
+			if err := s.set("", v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		if v, ok := flagValues[f.Name]; ok {
+			s, _, _ := findSetting(settings, f.Name)
+			if err := s.set("", *v); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", f.Name, err))
+			}
+			src.Flags = append(src.Flags, f.Name)
+		}
+	})
+
+	if len(errs) == 0 {
+		errs = c.validate()
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	return c, src, nil
+}
+
+// readDotEnv returns the .env file's variables over the process environment, so a reload sees
+// edits to it. Outside production only, like LoadEnv.
+func readDotEnv(environ []string) []string {
+	if os.Getenv("ENV") == "production" {
+		return environ
+	}
+	vars, err := godotenv.Read()
+	if err != nil {
+		return environ
+	}
+	out := append([]string{}, environ...)
+	for k, v := range vars {
+		out = append(out, k+"="+v)
+	}
+	return out
+}
+
+// readConfigFile flattens a YAML or TOML file into dotted paths: {twitch: {client_id: x}}
+// becomes twitch.client_id=x. Lists become comma-separated.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	out := map[string]string{}
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, child := range v {
+				walk(strings.TrimPrefix(prefix+"."+k, "."), child)
+			}
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			out[prefix] = strings.Join(items, ",")
+		case nil:
+		default:
+			out[prefix] = fmt.Sprint(v)
+		}
+	}
+	walk("", tree)
+	return out, nil
+}
+
+// settingsOf lists every leaf field of c with its tags
+func settingsOf(c *Config) []*setting {
+	var out []*setting
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			path := strings.TrimPrefix(prefix+"."+f.Tag.Get("key"), ".")
+			if f.Type.Kind() == reflect.Struct {
+				walk(path, v.Field(i))
+				continue
+			}
+			out = append(out, &setting{
+				path:   path,
+				env:    f.Tag.Get("env"),
+				def:    f.Tag.Get("default"),
+				secret: f.Tag.Get("secret") == "true",
+				reload: f.Tag.Get("reload") == "true",
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk("", reflect.ValueOf(c).Elem())
+	return out
+}
+
+// findSetting resolves a dotted path; for map settings the last part is the map key
+func findSetting(settings []*setting, path string) (*setting, string, bool) {
+	for _, s := range settings {
+		if s.path == path {
+			return s, "", true
+		}
+		if key, ok := strings.CutPrefix(path, s.path+"."); ok && s.value.Kind() == reflect.Map {
+			return s, key, true
+		}
+	}
+	return nil, "", false
+}
+
+func applyDefaults(c *Config) error {
+	for _, s := range settingsOf(c) {
+		if s.def == "" {
+			continue
+		}
+		if err := s.set("", s.def); err != nil {
+			return fmt.Errorf("default for %s: %w", s.path, err)
+		}
+	}
+	return nil
+}
+
+// set parses raw into the field. key names the entry of a map field.
+func (s *setting) set(key, raw string) error {
+	raw = strings.TrimSpace(raw)
+	v := s.value
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		v.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(raw))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// display is the setting's value for logs: secrets show only whether they're set
+func (s *setting) display() string {
+	v := s.value
+	switch v.Kind() {
+	case reflect.Slice:
+		return strings.Join(v.Interface().([]string), ",")
+	case reflect.Map:
+		m := v.Interface().(map[string]string)
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = k + "=" + m[k]
+		}
+		return strings.Join(parts, ",")
+	}
+	if s.secret && !v.IsZero() {
+		return "[redacted]"
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+// reloadPollInterval is how often the config file is checked for changes
+const reloadPollInterval = 30 * time.Second
+
+// Watch reloads the config on SIGHUP and whenever the config file changes. Settings tagged
+// reload are applied by calling apply with current plus the new values; changes to the others
+// are logged as needing a restart. args are the flags the process started with.
+func Watch(current *Config, src *Source, args []string, apply func(*Config)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var modTime time.Time
+	if src != nil && src.File != "" {
+		if info, err := os.Stat(src.File); err == nil {
+			modTime = info.ModTime()
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(reloadPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-hup:
+				log.Printf("🔄 SIGHUP received, reloading config")
+			case <-ticker.C:
+				if src == nil || src.File == "" {
+					continue
+				}
+				info, err := os.Stat(src.File)
+				if err != nil || !info.ModTime().After(modTime) {
+					continue
+				}
+				modTime = info.ModTime()
+				log.Printf("🔄 %s changed, reloading config", src.File)
+			}
+
+			next, _, err := load(args, readDotEnv(os.Environ()))
+			if err != nil {
+				log.Printf("⚠️ Config reload failed, keeping the current config: %v", err)
+				continue
+			}
+			if merged, changed := current.mergeReloadable(next); changed {
+				current = merged
+				apply(merged)
+			}
+		}
+	}()
+}
+
+// mergeReloadable returns a copy of c with next's reloadable settings, logging what changed,
+// and whether anything reloadable did
+func (c *Config) mergeReloadable(next *Config) (*Config, bool) {
+	merged := *c
+	changed := false
+	nextSettings := settingsOf(next)
+	for i, s := range settingsOf(&merged) {
+		n := nextSettings[i]
+		if reflect.DeepEqual(s.value.Interface(), n.value.Interface()) {
+			continue
+		}
+		if !s.reload {
+			log.Printf("⚠️ %s changed; restart to apply it", s.path)
+			continue
+		}
+		s.value.Set(n.value)
+		log.Printf("🔄 %s = %s", s.path, s.display())
+		changed = true
+	}
+	return &merged, changed
+}
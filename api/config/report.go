@@ -0,0 +1,174 @@
+package config
+
+import (
+	"log"
+	"strings"
+)
+
+// Feature is a part of the site that only works with certain settings
+type Feature struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Enabled     bool     `json:"enabled"`
+	Reason      string   `json:"reason,omitempty"`   // why it's off
+	Missing     []string `json:"missing,omitempty"`  // variables to set to enable it
+	Requires    []string `json:"requires,omitempty"` // features it needs
+}
+
+// requirement is a setting a feature needs, by its variable name, or another feature
+type requirement struct {
+	env     string
+	set     bool
+	feature string
+}
+
+func feature(name, description string, needs ...requirement) Feature {
+	f := Feature{Name: name, Description: description, Enabled: true}
+	for _, r := range needs {
+		switch {
+		case r.feature != "":
+			f.Requires = append(f.Requires, r.feature)
+		case !r.set:
+			f.Enabled = false
+			f.Missing = append(f.Missing, r.env)
+		}
+	}
+	if len(f.Missing) > 0 {
+		f.Reason = "set " + strings.Join(f.Missing, ", ")
+	}
+	return f
+}
+
+// anyOf is satisfied by one of several settings, e.g. any AI provider's key
+func anyOf(names string, set ...bool) requirement {
+	for _, s := range set {
+		if s {
+			return requirement{env: names, set: true}
+		}
+	}
+	return requirement{env: names}
+}
+
+func need(env, value string) requirement {
+	return requirement{env: env, set: value != ""}
+}
+
+// requires makes a feature depend on an earlier one: it's off whenever that one is
+func requires(name string) requirement {
+	return requirement{feature: name}
+}
+
+// Features lists every optional feature and whether this config turns it on
+func (c *Config) Features() []Feature {
+	return c.FeaturesWith(nil)
+}
+
+// FeaturesWith is Features with some features known to be unavailable at runtime, by name
+// with the reason, such as a database that's configured but can't be reached. Features that
+// require them are off too.
+func (c *Config) FeaturesWith(unavailable map[string]string) []Feature {
+	features := c.features()
+	off := map[string]bool{}
+	for i := range features {
+		f := &features[i]
+		var reasons []string
+		if f.Reason != "" {
+			reasons = append(reasons, f.Reason)
+		}
+		if reason, ok := unavailable[f.Name]; ok && f.Enabled {
+			f.Enabled = false
+			reasons = append(reasons, reason)
+		}
+		for _, dep := range f.Requires {
+			if off[dep] {
+				f.Enabled = false
+				reasons = append(reasons, "needs "+dep)
+			}
+		}
+		f.Reason = strings.Join(reasons, "; ")
+		off[f.Name] = !f.Enabled
+	}
+	return features
+}
+
+// features declares every feature and what it needs. A feature may only require ones above it.
+func (c *Config) features() []Feature {
+	return []Feature{
+		feature("database", "Postgres storage for history, checkins, chat and tokens",
+			need("DATABASE_URL", c.Database.URL)),
+		feature("shared_cache", "Redis shared by every replica (otherwise each keeps its own in memory)",
+			anyOf("REDIS_URL or UPSTASH_REDIS_REST_URL", c.Cache.RedisURL != "", c.Cache.UpstashURL != "")),
+		feature("twitch_chat", "Twitch chat feed over the anonymous IRC connection",
+			anyOf("TWITCH_CHANNEL, PROFILES or PROFILES_FILE",
+				c.Profiles.TwitchChannel != "", c.Profiles.Inline != "", c.Profiles.File != "")),
+		feature("twitch", "Twitch stats, bot and OAuth",
+			need("TWITCH_CLIENT_ID", c.Twitch.ClientID), need("TWITCH_CLIENT_SECRET", c.Twitch.ClientSecret)),
+		feature("twitch_eventsub", "Twitch follows, subs, raids and stream events",
+			requires("twitch"), requires("database")),
+		feature("chat_bridge", "Relaying between site chat and Twitch chat",
+			requirement{env: "CHAT_BRIDGE_ENABLED", set: c.ChatBridge.Enabled}, requires("twitch")),
+		feature("spotify", "Now playing",
+			need("SPOTIFY_CLIENT_ID", c.Spotify.ClientID), need("SPOTIFY_CLIENT_SECRET", c.Spotify.ClientSecret),
+			need("SPOTIFY_REDIRECT_URI", c.Spotify.RedirectURI)),
+		feature("auth", "Supabase sign-in and admin pages",
+			need("SUPABASE_URL", c.Supabase.URL), need("SUPABASE_ANON_KEY", c.Supabase.AnonKey)),
+		feature("geo", "Geocoding and the globe map", need("GCP_API_KEY", c.Google.APIKey)),
+		feature("speech", "Speech to text",
+			anyOf("GCP_API_KEY or WHISPER_CPP_PATH and WHISPER_MODEL_PATH",
+				c.Google.APIKey != "", c.Speech.WhisperCPPPath != "" && c.Speech.WhisperModelPath != "")),
+		feature("llm", "AI chat",
+			anyOf("ANTHROPIC_API_KEY, GEMINI_API_KEY, OPENAI_API_KEY or GROQ_API_KEY",
+				c.AI.AnthropicAPIKey != "", c.AI.GeminiAPIKey != "", c.AI.OpenAIAPIKey != "", c.AI.GroqAPIKey != "")),
+		feature("embeddings", "Semantic search", need("GEMINI_API_KEY", c.AI.GeminiAPIKey)),
+		feature("football", "Premier League and La Liga schedules", need("EPL_TOKEN", c.Football.Token)),
+		feature("youtube_stats", "YouTube channel stats", need("YT_API_KEY", c.Google.YouTubeAPIKey)),
+		feature("stream_status", "IVS live status", need("AWS_STREAMING_URL", c.Stream.AWSStreamingURL)),
+		feature("scenarios", "Scenario data from Turso",
+			need("TURSO_DATABASE_URL", c.Turso.DatabaseURL), need("TURSO_TOKEN", c.Turso.Token)),
+		feature("cost", "Google Cloud Run pricing", need("GCP_API_KEY", c.Google.APIKey)),
+		feature("infracost", "Infrastructure cost estimates", need("ICS_API_KEY", c.Infracost.APIKey)),
+		feature("token_encryption", "Encrypting stored OAuth tokens",
+			need("TOKEN_ENCRYPTION_KEY", c.Tokens.EncryptionKey)),
+	}
+}
+
+// Settings returns every setting by path with secrets redacted, in declaration order
+func (c *Config) Settings() [][2]string {
+	settings := settingsOf(c)
+	out := make([][2]string, 0, len(settings))
+	for _, s := range settings {
+		out = append(out, [2]string{s.path, s.display()})
+	}
+	return out
+}
+
+// LogSummary logs where the config came from, the settings that differ from the defaults
+// (secrets redacted) and which features are on
+func (c *Config) LogSummary(src *Source) {
+	from := []string{"defaults", "environment"}
+	if src != nil && src.File != "" {
+		from = append(from, src.File)
+	}
+	if src != nil && len(src.Flags) > 0 {
+		from = append(from, "flags ("+strings.Join(src.Flags, ", ")+")")
+	}
+	log.Printf("⚙️ Config loaded from %s", strings.Join(from, ", "))
+
+	base := map[string]string{}
+	for _, kv := range Defaults().Settings() {
+		base[kv[0]] = kv[1]
+	}
+	for _, kv := range c.Settings() {
+		if kv[1] != base[kv[0]] {
+			log.Printf("⚙️   %s = %s", kv[0], kv[1])
+		}
+	}
+
+	for _, f := range c.Features() {
+		if f.Enabled {
+			log.Printf("✅ %s", f.Name)
+		} else {
+			log.Printf("⛔ %s disabled (%s)", f.Name, f.Reason)
+		}
+	}
+}
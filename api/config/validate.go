@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// validate reports settings that are set but unusable. Missing credentials aren't errors:
+// the feature needing them is just off (see Features).
+func (c *Config) validate() []error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch c.Twitch.EventSubTransport {
+	case "websocket":
+	case "webhook":
+		if u, err := url.Parse(c.Twitch.EventSubCallbackURL); err != nil || u.Scheme != "https" {
+			fail("TWITCH_EVENTSUB_CALLBACK_URL must be an https URL for the webhook transport")
+		}
+		if n := len(c.Twitch.EventSubSecret); n < 10 || n > 100 {
+			fail("TWITCH_EVENTSUB_SECRET must be 10-100 characters for the webhook transport")
+		}
+	default:
+		fail("TWITCH_EVENTSUB_TRANSPORT must be websocket or webhook, not %q", c.Twitch.EventSubTransport)
+	}
+
+	switch c.ChatBridge.Direction {
+	case "both", "site-to-twitch", "twitch-to-site":
+	default:
+		fail("CHAT_BRIDGE_DIRECTION must be both, site-to-twitch or twitch-to-site, not %q", c.ChatBridge.Direction)
+	}
+
+	if c.Cache.RedisURL != "" {
+		if u, err := url.Parse(c.Cache.RedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") {
+			fail("REDIS_URL must start with redis:// or rediss://")
+		}
+	}
+	if (c.Cache.UpstashURL == "") != (c.Cache.UpstashToken == "") {
+		fail("UPSTASH_REDIS_REST_URL and UPSTASH_REDIS_REST_TOKEN must be set together")
+	}
+
+	for name, n := range map[string]int{
+		"GRAPHQL_MAX_DEPTH":       c.GraphQL.MaxDepth,
+		"GRAPHQL_MAX_COST":        c.GraphQL.MaxCost,
+		"MEMORY_CACHE_SIZE":       c.Cache.MemorySize,
+		"LOCAL_CACHE_SIZE":        c.Cache.LocalSize,
+		"CHAT_MAX_MESSAGE_LENGTH": c.Chat.MaxMessageLength,
+		"CHAT_CONN_BURST":         c.Chat.ConnBurst,
+		"CHAT_IDENTITY_BURST":     c.Chat.IdentityBurst,
+	} {
+		if n <= 0 {
+			fail("%s must be positive", name)
+		}
+	}
+	if c.Chat.ConnRate <= 0 || c.Chat.IdentityRate <= 0 {
+		fail("CHAT_CONN_RATE and CHAT_IDENTITY_RATE must be positive")
+	}
+
+	for provider, raw := range c.Stats.Intervals {
+		if raw == "off" || raw == "0" {
+			continue
+		}
+		if d, err := time.ParseDuration(raw); err != nil || d < time.Minute {
+			fail("STATS_INTERVAL_%s must be a duration of at least 1m or off, not %q", strings.ToUpper(provider), raw)
+		}
+	}
+	return errs
+}
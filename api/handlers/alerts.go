@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/api/services"
+)
+
+var (
+	alertOverlays   = make(map[*websocket.Conn]bool)
+	alertOverlaysMu sync.Mutex
+)
+
+// AlertOverlayWebSocket connects a stream overlay to the alert queue. The overlay
+// receives "play" and "skip" frames and replies with {"type":"ack","id":...,"status":"started"|"done"}.
+// Acks end alerts early, so only authorized overlays get past the upgrade.
+func AlertOverlayWebSocket(c *gin.Context) {
+	if !alertOverlayAuthorized(c.Request) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "overlay key or admin sign-in required"})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("❌ Alert overlay WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	alertOverlaysMu.Lock()
+	alertOverlays[conn] = true
+	alertOverlaysMu.Unlock()
+	log.Printf("🖥️ Alert overlay connected")
+
+	engine := services.GetAlertEngine()
+	if engine != nil {
+		engine.Wake()
+	}
+
+	for {
+		var frame struct {
+			Type   string `json:"type"`
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		}
+		if err := conn.ReadJSON(&frame); err != nil {
+			break
+		}
+		if frame.Type == "ack" && engine != nil {
+			engine.Ack(frame.ID, frame.Status)
+		}
+	}
+
+	alertOverlaysMu.Lock()
+	delete(alertOverlays, conn)
+	alertOverlaysMu.Unlock()
+	conn.Close()
+}
+
+// alertOverlayAuthorized reports whether a request may connect an overlay: it carries
+// ?key=ALERT_OVERLAY_SECRET, as the OBS browser source URL does, or an admin's Supabase token
+func alertOverlayAuthorized(r *http.Request) bool {
+	if secret := conf().Alerts.OverlaySecret; secret != "" {
+		key := r.URL.Query().Get("key")
+		if subtle.ConstantTimeCompare([]byte(key), []byte(secret)) == 1 {
+			return true
+		}
+	}
+
+	token := getSupabaseTokenFromRequest(r)
+	if token == "" {
+		return false
+	}
+	user, err := verifySupabaseToken(token)
+	if err != nil {
+		return false
+	}
+	return isAdminEmail(extractUserEmail(user))
+}
+
+// DeliverAlertFrame sends a frame to every connected overlay and returns how many received it
+func DeliverAlertFrame(frame models.AlertFrame) int {
+	alertOverlaysMu.Lock()
+	defer alertOverlaysMu.Unlock()
+
+	delivered := 0
+	for conn := range alertOverlays {
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.WriteJSON(frame); err != nil {
+			log.Printf("Alert overlay write error: %v", err)
+			conn.Close()
+			delete(alertOverlays, conn)
+			continue
+		}
+		delivered++
+	}
+	return delivered
+}
+
+func alertOverlayCount() int {
+	alertOverlaysMu.Lock()
+	defer alertOverlaysMu.Unlock()
+	return len(alertOverlays)
+}
+
+// GET /api/alerts
+func GetAlertQueue(c *gin.Context) {
+	engine := services.GetAlertEngine()
+	if engine == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Alert engine not running"})
+		return
+	}
+
+	state := engine.State()
+	state.Overlays = alertOverlayCount()
+	c.JSON(http.StatusOK, state)
+}
+
+// POST /api/alerts/test
+func TestFireAlert(c *gin.Context) {
+	var req struct {
+		Type     string `json:"type"`
+		Username string `json:"username"`
+		Amount   int    `json:"amount"`
+		Message  string `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	engine := services.GetAlertEngine()
+	if engine == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Alert engine not running"})
+		return
+	}
+	if _, ok := services.DefaultAlertTemplates[req.Type]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown alert type"})
+		return
+	}
+	if req.Username == "" {
+		req.Username = "TestUser"
+	}
+
+	alert, ok := engine.Enqueue(models.StreamAlert{
+		Type:     req.Type,
+		Username: req.Username,
+		Amount:   req.Amount,
+		Message:  req.Message,
+		Test:     true,
+	})
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "alert type is disabled"})
+		return
+	}
+	c.JSON(http.StatusAccepted, alert)
+}
+
+// POST /api/alerts/skip
+func SkipAlert(c *gin.Context) {
+	engine := services.GetAlertEngine()
+	if engine == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Alert engine not running"})
+		return
+	}
+	if !engine.Skip() {
+		c.JSON(http.StatusConflict, gin.H{"error": "no alert is playing"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"skipped": true})
+}
+
+// POST /api/alerts/replay/:id
+func ReplayAlert(c *gin.Context) {
+	engine := services.GetAlertEngine()
+	if engine == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Alert engine not running"})
+		return
+	}
+	alert, err := engine.Replay(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, alert)
+}
+
+// GET /api/alerts/templates
+func GetAlertTemplates(c *gin.Context) {
+	engine := services.GetAlertEngine()
+	if engine == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Alert engine not running"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": engine.Templates()})
+}
+
+// PUT /api/alerts/templates/:type
+func SaveAlertTemplate(c *gin.Context) {
+	var tmpl models.AlertTemplate
+	if err := c.ShouldBindJSON(&tmpl); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	tmpl.Type = c.Param("type")
+	if _, ok := services.DefaultAlertTemplates[tmpl.Type]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown alert type"})
+		return
+	}
+	if tmpl.DurationSeconds <= 0 || tmpl.DurationSeconds > 60 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration_seconds must be 1-60"})
+		return
+	}
+
+	engine := services.GetAlertEngine()
+	if engine == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Alert engine not running"})
+		return
+	}
+	if err := engine.SetTemplate(tmpl); err != nil {
+		log.Printf("❌ Failed to save %s alert template: %v", tmpl.Type, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tmpl)
+}
@@ -2,17 +2,30 @@ package handlers
 
 import (
 	"log"
+
+	"majesticcoding.com/api/models"
 )
 
 func StartBroadcaster() {
 	go func() {
-		for msg := range Broadcast {
+		for event := range Broadcast {
 			Mu.Lock()
-			for client := range Clients {
-				if err := client.WriteJSON(msg); err != nil {
+			for conn, client := range Clients {
+				if conn == event.Exclude {
+					continue
+				}
+
+				var err error
+				if client.Protocol >= models.ChatProtocolVersion {
+					err = conn.WriteJSON(event.Envelope)
+				} else if event.Legacy != nil {
+					err = conn.WriteJSON(event.Legacy)
+				}
+
+				if err != nil {
 					log.Println("Broadcast error:", err)
-					client.Close()
-					delete(Clients, client)
+					conn.Close()
+					delete(Clients, conn)
 				}
 			}
 			Mu.Unlock()
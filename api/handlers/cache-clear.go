@@ -9,30 +9,31 @@ import (
 	"majesticcoding.com/api/services"
 )
 
-// ClearStatsCache clears all stats cache entries
+// ClearStatsCache drops every cached stats, football, checkin, geocode and GraphQL entry
 func ClearStatsCache(c *gin.Context) {
-	patterns := []string{
-		"github:stats:*",
-		"youtube:stats:*",
-		"twitch:stats:*",
-		"leetcode:stats:*",
-		"epl:schedule:*",
-		"laliga:schedule:*",
-		"checkins:recent:*",
-		"geocode:*",
-	}
+	tags := []string{"stats", "football", "checkins", "geocode", "spotify"}
 
-	cleared := 0
-	for _, pattern := range patterns {
-		if err := services.RedisClearPattern(pattern); err != nil {
-			log.Printf("Failed to clear pattern %s: %v", pattern, err)
-		} else {
-			cleared++
-		}
+	deleted, err := services.InvalidateCacheTags(tags...)
+	if err != nil {
+		log.Printf("Failed to clear cache: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "deleted": deleted})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":  fmt.Sprintf("Cleared cache for %d patterns", cleared),
-		"patterns": patterns,
+		"message": fmt.Sprintf("Cleared %d cache entries", deleted),
+		"tags":    tags,
+		"deleted": deleted,
 	})
 }
+
+// CacheMetricsHandler reports the cache tiers and hits, misses and loads for every cache
+// @Summary Cache metrics
+// @Description The shared backend, this replica's local tier (size, evictions, invalidations) and per-cache hit, miss, load and tier counters since startup
+// @Tags Cache
+// @Produce json
+// @Success 200 {object} models.CacheReport
+// @Router /api/cache/metrics [get]
+func CacheMetricsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, services.CacheReport())
+}
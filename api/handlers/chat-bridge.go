@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	goaway "github.com/TwiN/go-away"
+	"github.com/google/uuid"
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/api/services"
+)
+
+// RelayTwitchToSiteChat shows a bridged Twitch message in site chat with platform badges,
+// censored like messages sent from the site
+func RelayTwitchToSiteChat(tm models.TwitchMessage) {
+	name := tm.DisplayName
+	if name == "" {
+		name = tm.Username
+	}
+
+	timestamp := tm.Time
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	msg := models.Message{
+		ID:          uuid.NewString(),
+		Content:     goaway.Censor(strings.TrimSpace(tm.Message)),
+		Username:    name,
+		Timestamp:   timestamp,
+		DisplayTime: timestamp.Format("15:04:05"),
+		Platform:    "twitch",
+		Badges:      twitchBadgeLabels(tm),
+	}
+
+	Mu.Lock()
+	Messages = append(Messages, msg)
+	Mu.Unlock()
+
+	// Legacy clients have no badge support, so mark the platform in the name
+	legacy := msg
+	legacy.Username = "🟣 " + name
+
+	Broadcast <- ChatEvent{
+		Envelope: models.NewChatEnvelope(models.ChatEventMessage, msg.ID, msg),
+		Legacy:   &legacy,
+	}
+	services.PublishEvent(services.TopicSiteChatMessage, msg)
+}
+
+func twitchBadgeLabels(tm models.TwitchMessage) []string {
+	badges := []string{"twitch"}
+	switch {
+	case tm.IsBroadcaster:
+		badges = append(badges, "broadcaster")
+	case tm.IsMod:
+		badges = append(badges, "moderator")
+	case tm.IsVip:
+		badges = append(badges, "vip")
+	}
+	if _, ok := tm.Badges["subscriber"]; ok {
+		badges = append(badges, "subscriber")
+	}
+	return badges
+}
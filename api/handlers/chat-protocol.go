@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	goaway "github.com/TwiN/go-away"
+	"github.com/gorilla/websocket"
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/api/services"
+)
+
+const (
+	maxAckedIDsPerIdentity = 200
+	typingThrottle         = 2 * time.Second
+	maxReactionRunes       = 16
+)
+
+// ackCache remembers which client frame IDs were already handled so retries
+// are acknowledged again without being re-broadcast
+type ackCache struct {
+	ids   map[string]string // client id -> message id
+	order []string
+}
+
+var (
+	chatAcks   = make(map[string]*ackCache) // keyed by client identity
+	chatAcksMu sync.Mutex
+)
+
+// chatProtocolFromRequest picks the protocol version from ?v= or the chat.v2 subprotocol
+func chatProtocolFromRequest(r *http.Request) int {
+	if v, err := strconv.Atoi(strings.TrimPrefix(r.URL.Query().Get("v"), "v")); err == nil && v >= models.ChatProtocolVersion {
+		return models.ChatProtocolVersion
+	}
+	for _, p := range strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",") {
+		if strings.TrimSpace(p) == "chat.v2" {
+			return models.ChatProtocolVersion
+		}
+	}
+	return 1
+}
+
+// sendChatHistory writes the message backlog to a new client. Caller must hold Mu.
+func sendChatHistory(conn *websocket.Conn, client *ChatClient) {
+	if client.Protocol >= models.ChatProtocolVersion {
+		history := Messages
+		if history == nil {
+			history = []models.Message{}
+		}
+		if err := conn.WriteJSON(models.NewChatEnvelope(models.ChatEventHistory, "", history)); err != nil {
+			log.Println("Send error:", err)
+		}
+		return
+	}
+
+	for _, msg := range Messages {
+		if err := conn.WriteJSON(msg); err != nil {
+			log.Println("Send error:", err)
+		}
+	}
+}
+
+func broadcastPresence(eventType, username string, count int) {
+	Broadcast <- ChatEvent{
+		Envelope: models.NewChatEnvelope(eventType, "", models.ChatPresencePayload{
+			Username:  username,
+			UserCount: count,
+		}),
+	}
+}
+
+// BroadcastChatNotice sends a server notice to everyone in site chat
+func BroadcastChatNotice(level, text string) {
+	now := time.Now()
+	Broadcast <- ChatEvent{
+		Envelope: models.NewChatEnvelope(models.ChatEventNotice, "", models.ChatNoticePayload{Level: level, Text: text}),
+		Legacy: &models.Message{
+			Content:     text,
+			Username:    "📢 System",
+			Timestamp:   now,
+			DisplayTime: now.Format("15:04:05"),
+		},
+	}
+}
+
+// readChatEnvelopes handles v2 clients until the connection closes
+func readChatEnvelopes(conn *websocket.Conn, client *ChatClient) {
+	for {
+		var env models.ChatEnvelope
+		if err := conn.ReadJSON(&env); err != nil {
+			log.Println("Read error:", err)
+			return
+		}
+
+		if env.ID != "" {
+			if messageID, seen := lookupAck(client.Identity, env.ID); seen {
+				sendChatFrame(conn, models.NewChatEnvelope(models.ChatEventAck, env.ID, models.ChatAckPayload{
+					ClientID:  env.ID,
+					MessageID: messageID,
+					Status:    "duplicate",
+				}))
+				continue
+			}
+		}
+
+		messageID, violation := handleChatEnvelope(conn, client, env)
+		if violation != nil {
+			sendChatError(conn, client, violation, env.ID)
+			continue
+		}
+
+		if env.ID != "" {
+			rememberAck(client.Identity, env.ID, messageID)
+			sendChatFrame(conn, models.NewChatEnvelope(models.ChatEventAck, env.ID, models.ChatAckPayload{
+				ClientID:  env.ID,
+				MessageID: messageID,
+				Status:    "ok",
+			}))
+		}
+	}
+}
+
+func handleChatEnvelope(conn *websocket.Conn, client *ChatClient, env models.ChatEnvelope) (string, *services.ChatViolation) {
+	switch env.Type {
+	case models.ChatEventMessage:
+		var payload models.ChatSendPayload
+		if err := json.Unmarshal(env.Payload, &payload); err != nil {
+			return "", invalidPayload()
+		}
+		msg, violation := publishChatMessage(client, payload.Content)
+		if violation != nil {
+			return "", violation
+		}
+		return msg.ID, nil
+
+	case models.ChatEventTyping:
+		var payload models.ChatTypingPayload
+		if err := json.Unmarshal(env.Payload, &payload); err != nil {
+			return "", invalidPayload()
+		}
+		// Every typing frame is broadcast to every client, so both states count against the
+		// sender's rate limits. Only typing:true is throttled; dropping a typing:false would
+		// leave the indicator stuck for everyone else.
+		now := time.Now()
+		if payload.Typing && now.Sub(client.lastTyping) < typingThrottle {
+			return "", nil
+		}
+		if violation := services.GetChatModerator().Allow(client.limiter, client.Identity); violation != nil {
+			return "", violation
+		}
+		if payload.Typing {
+			client.lastTyping = now
+		} else {
+			client.lastTyping = time.Time{}
+		}
+		Broadcast <- ChatEvent{
+			Envelope: models.NewChatEnvelope(models.ChatEventTyping, "", models.ChatTypingPayload{
+				Username: client.Username,
+				Typing:   payload.Typing,
+			}),
+			Exclude: conn,
+		}
+		return "", nil
+
+	case models.ChatEventEdit:
+		var payload models.ChatEditPayload
+		if err := json.Unmarshal(env.Payload, &payload); err != nil || payload.MessageID == "" {
+			return "", invalidPayload()
+		}
+		return payload.MessageID, editChatMessage(client, payload)
+
+	case models.ChatEventReaction:
+		var payload models.ChatReactionPayload
+		if err := json.Unmarshal(env.Payload, &payload); err != nil || payload.MessageID == "" {
+			return "", invalidPayload()
+		}
+		return payload.MessageID, reactToChatMessage(client, payload)
+
+	default:
+		return "", &services.ChatViolation{Code: "unsupported_type", Message: "Unsupported event type: " + env.Type}
+	}
+}
+
+func editChatMessage(client *ChatClient, payload models.ChatEditPayload) *services.ChatViolation {
+	if violation := services.GetChatModerator().Check(client.limiter, client.Identity, payload.Content); violation != nil {
+		return violation
+	}
+
+	content := goaway.Censor(strings.TrimSpace(payload.Content))
+	now := time.Now()
+
+	Mu.Lock()
+	msg := findChatMessage(payload.MessageID)
+	if msg == nil {
+		Mu.Unlock()
+		return &services.ChatViolation{Code: "not_found", Message: "Message not found"}
+	}
+	if msg.Username != client.Username {
+		Mu.Unlock()
+		return &services.ChatViolation{Code: "forbidden", Message: "You can only edit your own messages"}
+	}
+	msg.Content = content
+	msg.EditedAt = &now
+	Mu.Unlock()
+
+	Broadcast <- ChatEvent{
+		Envelope: models.NewChatEnvelope(models.ChatEventEdit, payload.MessageID, models.ChatEditPayload{
+			MessageID: payload.MessageID,
+			Content:   content,
+			EditedAt:  &now,
+		}),
+	}
+	return nil
+}
+
+func reactToChatMessage(client *ChatClient, payload models.ChatReactionPayload) *services.ChatViolation {
+	emoji := strings.TrimSpace(payload.Emoji)
+	if emoji == "" || len([]rune(emoji)) > maxReactionRunes {
+		return invalidPayload()
+	}
+	if violation := services.GetChatModerator().Allow(client.limiter, client.Identity); violation != nil {
+		return violation
+	}
+
+	Mu.Lock()
+	msg := findChatMessage(payload.MessageID)
+	if msg == nil {
+		Mu.Unlock()
+		return &services.ChatViolation{Code: "not_found", Message: "Message not found"}
+	}
+	if msg.Reactions == nil {
+		msg.Reactions = make(map[string][]string)
+	}
+	users := msg.Reactions[emoji]
+	filtered := users[:0]
+	for _, u := range users {
+		if u != client.Username {
+			filtered = append(filtered, u)
+		}
+	}
+	if !payload.Remove {
+		filtered = append(filtered, client.Username)
+	}
+	if len(filtered) == 0 {
+		delete(msg.Reactions, emoji)
+	} else {
+		msg.Reactions[emoji] = filtered
+	}
+	count := len(filtered)
+	Mu.Unlock()
+
+	Broadcast <- ChatEvent{
+		Envelope: models.NewChatEnvelope(models.ChatEventReaction, payload.MessageID, models.ChatReactionPayload{
+			MessageID: payload.MessageID,
+			Emoji:     emoji,
+			Remove:    payload.Remove,
+			Username:  client.Username,
+			Count:     count,
+		}),
+	}
+	return nil
+}
+
+// findChatMessage returns the in-memory message with id. Caller must hold Mu.
+func findChatMessage(id string) *models.Message {
+	for i := range Messages {
+		if Messages[i].ID == id {
+			return &Messages[i]
+		}
+	}
+	return nil
+}
+
+func sendChatFrame(conn *websocket.Conn, env models.ChatEnvelope) {
+	Mu.Lock()
+	defer Mu.Unlock()
+	if err := conn.WriteJSON(env); err != nil {
+		log.Println("Send error:", err)
+	}
+}
+
+func invalidPayload() *services.ChatViolation {
+	return &services.ChatViolation{Code: "invalid_payload", Message: "Malformed event payload"}
+}
+
+func lookupAck(identity, clientID string) (string, bool) {
+	chatAcksMu.Lock()
+	defer chatAcksMu.Unlock()
+
+	cache, ok := chatAcks[identity]
+	if !ok {
+		return "", false
+	}
+	messageID, seen := cache.ids[clientID]
+	return messageID, seen
+}
+
+func rememberAck(identity, clientID, messageID string) {
+	chatAcksMu.Lock()
+	defer chatAcksMu.Unlock()
+
+	cache, ok := chatAcks[identity]
+	if !ok {
+		cache = &ackCache{ids: make(map[string]string)}
+		chatAcks[identity] = cache
+	}
+	cache.ids[clientID] = messageID
+	cache.order = append(cache.order, clientID)
+	if len(cache.order) > maxAckedIDsPerIdentity {
+		delete(cache.ids, cache.order[0])
+		cache.order = cache.order[1:]
+	}
+}
+
+// pruneChatAcks drops ack caches for identities with no connected clients
+func pruneChatAcks() {
+	Mu.Lock()
+	active := make(map[string]bool, len(Clients))
+	for _, client := range Clients {
+		active[client.Identity] = true
+	}
+	Mu.Unlock()
+
+	chatAcksMu.Lock()
+	defer chatAcksMu.Unlock()
+	for identity := range chatAcks {
+		if !active[identity] {
+			delete(chatAcks, identity)
+		}
+	}
+}
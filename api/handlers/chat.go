@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"majesticcoding.com/api/models"
+	"majesticcoding.com/api/services"
 )
 
 func GetMessages(c *gin.Context) {
@@ -30,6 +31,9 @@ func StartMessageCleanup() {
 			}
 			Messages = filtered
 			Mu.Unlock()
+
+			services.GetChatModerator().Prune(60 * time.Minute)
+			pruneChatAcks()
 		}
 	}()
 }
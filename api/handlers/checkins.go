@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"majesticcoding.com/api/models"
@@ -11,44 +13,52 @@ import (
 )
 
 // POST /api/checkin
+// Accepts either coordinates (lat/lon) or a place to geocode (query, or city/country).
+// The checkin is attributed to the signed-in user, if any; a username in the body is ignored
+// so nobody can check in as someone else.
 func PostCheckinHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var checkin models.Checkin
-		if err := c.ShouldBindJSON(&checkin); err != nil {
+		var req struct {
+			Query   string   `json:"query"`
+			Lat     *float64 `json:"lat"`
+			Lon     *float64 `json:"lon"`
+			City    string   `json:"city"`
+			Country string   `json:"country"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
 			return
 		}
 
-		database := db.GetDB()
-		if database == nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "database not available"})
-			return
+		checkinReq := services.CheckinRequest{
+			Query:   req.Query,
+			Lat:     req.Lat,
+			Lon:     req.Lon,
+			City:    req.City,
+			Country: req.Country,
+		}
+		if username := getUsernameFromAuth(c.Request); strings.HasPrefix(username, "✓ ") {
+			checkinReq.Username = strings.TrimPrefix(username, "✓ ")
+			checkinReq.Platform = "site"
 		}
 
-		err := db.InsertCheckin(database, checkin.Lat, checkin.Lon, checkin.City, checkin.Country)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save checkin"})
+		result, err := services.CreateCheckin(c.Request.Context(), checkinReq)
+		if errors.Is(err, services.ErrCheckinLocation) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-
-		// Get fresh checkins from database and update Redis cache
-		checkins, err := db.GetRecentCheckins(database, 8) // Last 8 hours
 		if err != nil {
-			log.Printf("⚠️ Failed to get recent checkins after insert: %v", err)
-			// Fallback: just delete cache to force refresh
-			if err := services.RedisDelete("checkins:recent:8h"); err != nil {
-				log.Printf("⚠️ Failed to invalidate checkins cache: %v", err)
-			}
-		} else {
-			// Update the cache with fresh data (5 minutes TTL = 300 seconds)
-			if err := services.RedisSetJSON("checkins:recent:8h", checkins, 300); err != nil {
-				log.Printf("⚠️ Failed to update checkins cache: %v", err)
-			} else {
-				log.Printf("✅ Updated checkins:recent:8h cache with new %s checkin", checkin.City)
-			}
+			log.Printf("❌ Failed to save checkin: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save checkin"})
+			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "ok",
+			"checkin":   result.Checkin,
+			"place":     result.Place,
+			"duplicate": result.Duplicate,
+		})
 	}
 }
 
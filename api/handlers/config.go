@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"sync/atomic"
+
+	"majesticcoding.com/api/config"
+)
+
+// settings is the config main loaded; until UseConfig runs (and in tests) it's the defaults
+var settings atomic.Pointer[config.Config]
+
+// UseConfig sets the config handlers read. It's safe to call again on a reload: code reading
+// reloadable settings sees the new values on its next call.
+func UseConfig(c *config.Config) {
+	settings.Store(c)
+}
+
+func conf() *config.Config {
+	if c := settings.Load(); c != nil {
+		return c
+	}
+	return config.Defaults()
+}
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"net/http"
-	"os"
 
 	"github.com/gin-gonic/gin"
 	"majesticcoding.com/api/models"
@@ -10,11 +9,7 @@ import (
 
 // GET /api/cost/cloudrun?region=us-central1&currency=USD
 func CloudRunCostHandler(c *gin.Context) {
-	apiKey := os.Getenv("GCP_API_KEY")
-	if apiKey == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "GCP_API_KEY not set"})
-		return
-	}
+	apiKey := conf().Google.APIKey
 	region := c.DefaultQuery("region", "us-central1")
 	currency := c.DefaultQuery("currency", "USD")
 
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"majesticcoding.com/api/services"
+)
+
+// FeaturesHandler godoc
+// @Summary Features
+// @Description Lists the site's optional features, whether each is enabled and, if not, why
+// @Tags Features
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /features [get]
+func FeaturesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"features": services.Features()})
+}
+
+// requireFeature answers 503 with the reason when any of the named features is off, so every
+// route of an unconfigured integration fails the same way
+func requireFeature(names ...string) gin.HandlerFunc {
+	for _, name := range names {
+		if _, ok := services.FeatureStatus(name); !ok {
+			panic("unknown feature " + name)
+		}
+	}
+
+	return func(c *gin.Context) {
+		for _, name := range names {
+			f, _ := services.FeatureStatus(name)
+			if !f.Enabled {
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"error":   f.Name + " is not available",
+					"feature": f.Name,
+					"reason":  f.Reason,
+				})
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -1,9 +1,7 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 
@@ -14,14 +12,7 @@ import (
 )
 
 func MapHandler() gin.HandlerFunc {
-	apiKey := strings.TrimSpace(os.Getenv("GCP_API_KEY"))
-
 	return func(c *gin.Context) {
-		if apiKey == "" {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "missing GCP_API_KEY"})
-			return
-		}
-
 		q := strings.TrimSpace(c.Query("q"))
 		if q == "" {
 			q = strings.TrimSpace(c.Query("city"))
@@ -68,7 +59,7 @@ func MapHandler() gin.HandlerFunc {
 			Lat:    lat,
 			Lng:    lng,
 			Label:  label,
-			APIKey: apiKey,
+			APIKey: conf().Google.APIKey,
 			Zoom:   zoom,
 		}
 		c.HTML(http.StatusOK, "globe.tmpl", data)
@@ -77,37 +68,12 @@ func MapHandler() gin.HandlerFunc {
 
 func RecentCheckinsHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		cacheKey := "checkins:recent:8h"
-
-		// Try to get from Redis cache first (5 minutes TTL = 300 seconds)
-		cachedJSON, err := services.RedisGetRawJSON(cacheKey)
-		if err == nil && cachedJSON != "" {
-			log.Printf("✅ Recent checkins cache HIT")
-			c.Header("Content-Type", "application/json")
-			c.String(http.StatusOK, cachedJSON)
-			return
-		}
-		log.Printf("🔍 Recent checkins cache MISS, fetching from DB")
-
-		database := db.GetDB()
-		if database == nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "database not available"})
-			return
-		}
-
-		checkins, err := db.GetRecentCheckins(database, 8) // Last 8 hours
+		checkins, err := services.RecentCheckins(c.Request.Context())
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		// Cache the checkins for 5 minutes (300 seconds)
-		if err := services.RedisSetJSON(cacheKey, checkins, 300); err != nil {
-			log.Printf("⚠️ Failed to cache recent checkins: %v", err)
-		} else {
-			log.Printf("💾 Cached recent checkins for 5 minutes")
-		}
-
 		c.JSON(http.StatusOK, checkins)
 	}
 }
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/api/services"
+)
+
+// graphql-transport-ws message types (https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md)
+const (
+	gqlWSConnectionInit = "connection_init"
+	gqlWSConnectionAck  = "connection_ack"
+	gqlWSPing           = "ping"
+	gqlWSPong           = "pong"
+	gqlWSSubscribe      = "subscribe"
+	gqlWSNext           = "next"
+	gqlWSError          = "error"
+	gqlWSComplete       = "complete"
+)
+
+// graphql-transport-ws close codes
+const (
+	gqlWSCloseBadRequest       = 4400
+	gqlWSCloseUnauthorized     = 4401
+	gqlWSCloseInitTimeout      = 4408
+	gqlWSCloseSubscriberExists = 4409
+	gqlWSCloseTooManyInits     = 4429
+)
+
+const gqlWSInitTimeout = 10 * time.Second
+
+var graphQLUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return isAllowedWSOrigin(r)
+	},
+	Subprotocols: []string{"graphql-transport-ws"},
+}
+
+type gqlWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// gqlWSConn is one graphql-transport-ws connection and its running operations
+type gqlWSConn struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	mu     sync.Mutex
+	acked  bool
+	closed bool
+	ops    map[string]context.CancelFunc
+}
+
+// GraphQLWebSocket serves GraphQL subscriptions over the graphql-transport-ws protocol
+// @Summary GraphQL subscriptions
+// @Description WebSocket endpoint speaking graphql-transport-ws. Subscriptions stream chat and Twitch messages, follows, raids, subs, bits, checkins, Spotify now-playing and stream status.
+// @Tags GraphQL
+// @Success 101 {string} string "Switching Protocols"
+// @Router /ws/graphql [get]
+func GraphQLWebSocket(c *gin.Context) {
+	conn, err := graphQLUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	if conn.Subprotocol() != "graphql-transport-ws" {
+		closeGraphQLWS(conn, websocket.CloseProtocolError, "Subprotocol not acceptable")
+		return
+	}
+
+	ws := &gqlWSConn{conn: conn, ops: make(map[string]context.CancelFunc)}
+	defer ws.cancelAll()
+
+	initTimer := time.AfterFunc(gqlWSInitTimeout, func() {
+		if !ws.isAcked() {
+			ws.close(gqlWSCloseInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	for {
+		var msg gqlWSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if _, ok := err.(*websocket.CloseError); !ok && !ws.isClosed() {
+				ws.close(gqlWSCloseBadRequest, "Invalid message received")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case gqlWSConnectionInit:
+			ws.mu.Lock()
+			already := ws.acked
+			ws.acked = true
+			ws.mu.Unlock()
+			if already {
+				ws.close(gqlWSCloseTooManyInits, "Too many initialisation requests")
+				return
+			}
+			ws.send(gqlWSMessage{Type: gqlWSConnectionAck})
+
+		case gqlWSPing:
+			ws.send(gqlWSMessage{Type: gqlWSPong})
+
+		case gqlWSPong:
+
+		case gqlWSSubscribe:
+			if !ws.isAcked() {
+				ws.close(gqlWSCloseUnauthorized, "Unauthorized")
+				return
+			}
+			var request models.GraphQLRequest
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &request) != nil || (request.Query == "" && request.Extensions == nil) {
+				ws.close(gqlWSCloseBadRequest, "Invalid subscribe message")
+				return
+			}
+			if !ws.start(msg.ID, request) {
+				ws.close(gqlWSCloseSubscriberExists, "Subscriber for "+msg.ID+" already exists")
+				return
+			}
+
+		case gqlWSComplete:
+			ws.finish(msg.ID)
+
+		default:
+			ws.close(gqlWSCloseBadRequest, "Invalid message received")
+			return
+		}
+	}
+}
+
+// start runs an operation under id, streaming its responses until it finishes or is stopped.
+// It returns false if id is already in use.
+func (ws *gqlWSConn) start(id string, request models.GraphQLRequest) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ws.mu.Lock()
+	if _, exists := ws.ops[id]; exists {
+		ws.mu.Unlock()
+		cancel()
+		return false
+	}
+	ws.ops[id] = cancel
+	ws.mu.Unlock()
+
+	query, pqErr := services.ResolvePersistedQuery(request)
+	if pqErr != nil {
+		ws.finish(id)
+		ws.sendJSON(id, gqlWSError, []models.GraphQLError{*pqErr})
+		return true
+	}
+
+	responses, err := services.SubscribeGraphQL(ctx, query, request.OperationName, request.Variables)
+	if err != nil {
+		ws.finish(id)
+		var reqErr *services.GraphQLRequestError
+		if errors.As(err, &reqErr) {
+			ws.sendJSON(id, gqlWSError, reqErr.Errors)
+		} else {
+			log.Printf("❌ GraphQL subscription %s failed: %v", id, err)
+			ws.sendJSON(id, gqlWSError, []models.GraphQLError{{Message: err.Error()}})
+		}
+		return true
+	}
+
+	go func() {
+		for response := range responses {
+			if ctx.Err() == nil {
+				ws.sendJSON(id, gqlWSNext, response)
+			}
+		}
+		// Operations the client completed don't get a complete back
+		if ws.finish(id) {
+			ws.send(gqlWSMessage{ID: id, Type: gqlWSComplete})
+		}
+	}()
+	return true
+}
+
+// finish cancels and forgets an operation, reporting whether it was still running
+func (ws *gqlWSConn) finish(id string) bool {
+	ws.mu.Lock()
+	cancel, ok := ws.ops[id]
+	delete(ws.ops, id)
+	ws.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+func (ws *gqlWSConn) cancelAll() {
+	ws.mu.Lock()
+	ws.closed = true
+	for id, cancel := range ws.ops {
+		cancel()
+		delete(ws.ops, id)
+	}
+	ws.mu.Unlock()
+}
+
+func (ws *gqlWSConn) isAcked() bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.acked
+}
+
+func (ws *gqlWSConn) isClosed() bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.closed
+}
+
+func (ws *gqlWSConn) sendJSON(id, msgType string, payload interface{}) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("❌ Failed to encode GraphQL %s message: %v", msgType, err)
+		return
+	}
+	ws.send(gqlWSMessage{ID: id, Type: msgType, Payload: raw})
+}
+
+func (ws *gqlWSConn) send(msg gqlWSMessage) {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	if err := ws.conn.WriteJSON(msg); err != nil {
+		ws.conn.Close()
+	}
+}
+
+// close ends the connection with a graphql-transport-ws close code
+func (ws *gqlWSConn) close(code int, reason string) {
+	ws.mu.Lock()
+	ws.closed = true
+	ws.mu.Unlock()
+
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	closeGraphQLWS(ws.conn, code, reason)
+}
+
+func closeGraphQLWS(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	conn.Close()
+}
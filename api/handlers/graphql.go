@@ -1,19 +1,25 @@
 package handlers
 
 import (
-	"crypto/md5"
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"majesticcoding.com/api/models"
 	"majesticcoding.com/api/services"
 )
 
+// graphQLTimeout bounds a request, including every upstream call its resolvers make
+const graphQLTimeout = 15 * time.Second
+
 // GraphQLHandler handles GraphQL queries
 // @Summary Execute GraphQL query
-// @Description Execute a GraphQL query for unified stats
+// @Description Execute a GraphQL query against the site schema (stats, history, checkins, Twitch, football, Spotify).
+// @Description Queries are limited in depth and cost (GRAPHQL_MAX_DEPTH, GRAPHQL_MAX_COST); the cost is reported in extensions.cost.
+// @Description Automatic persisted queries are supported: send extensions.persistedQuery {version: 1, sha256Hash} with or without the query.
 // @Tags GraphQL
 // @Accept json
 // @Produce json
@@ -25,42 +31,57 @@ import (
 func GraphQLHandler(c *gin.Context) {
 	var request models.GraphQLRequest
 
-	if err := c.ShouldBindJSON(&request); err != nil {
+	if c.Request.Method == http.MethodGet {
+		if err := bindGraphQLQueryParams(c, &request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 		return
 	}
 
-	// Create cache key based on query hash
-	queryHash := fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s%v", request.Query, request.Variables))))
-	cacheKey := fmt.Sprintf("graphql:query:%s", queryHash)
-
-	// Try to get from Redis cache first (10 minutes TTL = 600 seconds)
-	var cachedResponse models.GraphQLResponse
-	err := services.RedisGetJSON(cacheKey, &cachedResponse)
-	if err == nil {
-		log.Printf("✅ GraphQL query cache HIT for hash: %s", queryHash[:8])
-		c.JSON(http.StatusOK, cachedResponse)
+	query, pqErr := services.ResolvePersistedQuery(request)
+	if pqErr != nil {
+		c.JSON(http.StatusOK, models.GraphQLResponse{Errors: []models.GraphQLError{*pqErr}})
 		return
 	}
-	log.Printf("🔍 GraphQL query cache MISS for hash: %s", queryHash[:8])
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing query"})
+		return
+	}
+
+	// Responses aren't cached as a whole: the schema has live fields, and the resolvers for
+	// upstream data go through the stats, football and Spotify caches already
+	ctx, cancel := context.WithTimeout(c.Request.Context(), graphQLTimeout)
+	defer cancel()
 
 	// Execute the GraphQL query
-	response, err := services.ExecuteGraphQLQuery(c.Request.Context(), request.Query, request.Variables)
+	response, err := services.ExecuteGraphQLQuery(ctx, query, request.OperationName, request.Variables)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Cache successful responses for 10 minutes (600 seconds)
-	if response != nil && len(response.Errors) == 0 {
-		if err := services.RedisSetJSON(cacheKey, response, 600); err != nil {
-			log.Printf("⚠️ Failed to cache GraphQL response: %v", err)
-		} else {
-			log.Printf("💾 Cached GraphQL response for 10 minutes (hash: %s)", queryHash[:8])
+	c.JSON(http.StatusOK, response)
+}
+
+// bindGraphQLQueryParams reads a GET request, where variables and extensions are JSON strings.
+// Persisted queries are usually sent this way so CDNs can cache them.
+func bindGraphQLQueryParams(c *gin.Context, request *models.GraphQLRequest) error {
+	request.Query = c.Query("query")
+	request.OperationName = c.Query("operationName")
+	if raw := c.Query("variables"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &request.Variables); err != nil {
+			return fmt.Errorf("variables must be a JSON object")
 		}
 	}
-
-	c.JSON(http.StatusOK, response)
+	if raw := c.Query("extensions"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &request.Extensions); err != nil {
+			return fmt.Errorf("extensions must be a JSON object")
+		}
+	}
+	return nil
 }
 
 // GraphQLPlaygroundHandler serves the GraphQL playground template
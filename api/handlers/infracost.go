@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"net/http"
-	"os"
 
 	"github.com/gin-gonic/gin"
 	"majesticcoding.com/api/models"
@@ -10,11 +9,7 @@ import (
 
 // GET /api/cost/infracost?vendor=gcp&service=Cloud%20Run&region=us-central1&purchaseOption=on_demand
 func InfracostHandler(c *gin.Context) {
-	apiKey := os.Getenv("ICS_API_KEY")
-	if apiKey == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ICS_API_KEY not set"})
-		return
-	}
+	apiKey := conf().Infracost.APIKey
 
 	req := models.InfracostRequest{
 		Vendor:         c.DefaultQuery("vendor", "gcp"),
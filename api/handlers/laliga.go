@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -16,19 +15,7 @@ import (
 // @Failure 500 {object} map[string]string
 // @Router /laliga/schedule [get]
 func GetLaLigaSchedule(c *gin.Context) {
-	cacheKey := "laliga:schedule:weekly"
-
-	// Try to get from Redis cache first (6 hours TTL = 21600 seconds)
-	cachedJSON, err := services.RedisGetRawJSON(cacheKey)
-	if err == nil && cachedJSON != "" {
-		log.Printf("✅ La Liga schedule cache HIT")
-		c.Header("Content-Type", "application/json")
-		c.String(http.StatusOK, cachedJSON)
-		return
-	}
-	log.Printf("🔍 La Liga schedule cache MISS, fetching from API")
-
-	matches, err := services.FetchLaLigaSchedule()
+	matches, err := services.LaLigaSchedule(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -38,14 +25,6 @@ func GetLaLigaSchedule(c *gin.Context) {
 		"matches": matches,
 		"count":   len(matches),
 	}
-
-	// Cache the schedule for 6 hours (21600 seconds)
-	if err := services.RedisSetJSON(cacheKey, response, 21600); err != nil {
-		log.Printf("⚠️ Failed to cache La Liga schedule: %v", err)
-	} else {
-		log.Printf("💾 Cached La Liga schedule for 6 hours")
-	}
-
 	c.JSON(http.StatusOK, response)
 }
 
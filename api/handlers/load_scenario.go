@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
-	"os"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/tursodatabase/libsql-client-go/libsql"
@@ -12,7 +11,7 @@ import (
 )
 
 func LoadScenarios(c *gin.Context) {
-	db, err := sql.Open("libsql", os.Getenv("TURSO_DATABASE_URL")+"?authToken="+os.Getenv("TURSO_TOKEN"))
+	db, err := sql.Open("libsql", conf().Turso.DatabaseURL+"?authToken="+conf().Turso.Token)
 	if err != nil {
 		fmt.Println("DB connection error:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB connection failed"})
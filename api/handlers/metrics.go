@@ -1,8 +1,9 @@
 package handlers
 
 import (
-	"log"
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shirou/gopsutil/host"
@@ -12,19 +13,18 @@ import (
 	"majesticcoding.com/api/services"
 )
 
-func MetricsHandler(c *gin.Context) {
-	cacheKey := "metrics:system:2m"
+// metricsCache holds the host metrics so a busy dashboard doesn't sample the CPU per request
+var metricsCache = services.NewCached[models.Metrics]("metrics.system", services.CachePolicy{
+	Fresh: time.Minute,
+	Stale: time.Minute,
+})
 
-	// Try to get from Redis cache first (2 minutes TTL = 120 seconds)
-	var metrics models.Metrics
-	err := services.RedisGetJSON(cacheKey, &metrics)
-	if err == nil {
-		log.Printf("✅ System metrics cache HIT")
-		c.JSON(http.StatusOK, metrics)
-		return
-	}
-	log.Printf("🔍 System metrics cache MISS, collecting fresh data")
+func MetricsHandler(c *gin.Context) {
+	metrics, _ := metricsCache.Key("host").Get(c.Request.Context(), collectSystemMetrics)
+	c.JSON(http.StatusOK, metrics)
+}
 
+func collectSystemMetrics(ctx context.Context) (models.Metrics, error) {
 	cpuPercent, _ := cpu.Percent(0, false)
 	vmStat, _ := mem.VirtualMemory()
 	swapStat, _ := mem.SwapMemory()
@@ -33,7 +33,7 @@ func MetricsHandler(c *gin.Context) {
 	uptimeSeconds, _ := host.Uptime()
 	uptimeHours := float64(uptimeSeconds) / 3600
 
-	metrics = models.Metrics{
+	return models.Metrics{
 		CPUPercent:      cpuPercent,
 		MemTotal:        vmStat.Total,
 		MemUsed:         vmStat.Used,
@@ -42,14 +42,5 @@ func MetricsHandler(c *gin.Context) {
 		MemFreePercent:  memFreePercent,
 		SwapUsedPercent: swapStat.UsedPercent,
 		UptimeHours:     uptimeHours,
-	}
-
-	// Cache the metrics for 2 minutes (120 seconds)
-	if err := services.RedisSetJSON(cacheKey, metrics, 120); err != nil {
-		log.Printf("⚠️ Failed to cache system metrics: %v", err)
-	} else {
-		log.Printf("💾 Cached system metrics for 2 minutes")
-	}
-
-	c.JSON(http.StatusOK, metrics)
+	}, nil
 }
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/api/services"
+)
+
+// ListProfiles returns the creators this deployment tracks
+// @Summary List profiles
+// @Description Returns every configured creator profile. The default profile is used when a route doesn't name one.
+// @Tags Profiles
+// @Produce json
+// @Success 200 {array} models.Profile
+// @Router /api/profiles [get]
+func ListProfiles(c *gin.Context) {
+	c.JSON(http.StatusOK, services.Profiles())
+}
+
+// requestProfile resolves the profile from the :profile path param or ?profile= query,
+// falling back to the default profile. It writes a 404 and returns false for unknown ids.
+func requestProfile(c *gin.Context) (models.Profile, bool) {
+	id := c.Param("profile")
+	if id == "" {
+		id = c.Query("profile")
+	}
+
+	profile, err := services.GetProfile(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return profile, false
+	}
+	return profile, true
+}
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"net/http"
-	"os"
 
 	"github.com/gin-gonic/gin"
 	"majesticcoding.com/api/models"
@@ -19,9 +18,9 @@ func RenderTemplate(tmpl string) gin.HandlerFunc {
 func RenderSpotify(tmpl string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.HTML(http.StatusOK, tmpl, gin.H{
-			"SPOTIFY_ID":           os.Getenv("SPOTIFY_CLIENT_ID"),
-			"SPOTIFY_SECRET":       os.Getenv("SPOTIFY_CLIENT_SECRET"),
-			"SPOTIFY_REDIRECT_URL": os.Getenv("SPOTIFY_REDIRECT_URL"),
+			"SPOTIFY_ID":           conf().Spotify.ClientID,
+			"SPOTIFY_SECRET":       conf().Spotify.ClientSecret,
+			"SPOTIFY_REDIRECT_URL": conf().Spotify.RedirectURL,
 		})
 	}
 }
@@ -29,8 +28,8 @@ func RenderSpotify(tmpl string) gin.HandlerFunc {
 func RenderStripe(tmpl string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.HTML(http.StatusOK, tmpl, gin.H{
-			"STRIPE_PUBLISHABLE_KEY": os.Getenv("STRIPE_PUB_KEY"),
-			"STRIPE_BUY_BUTTON_ID":   os.Getenv("STRIPE_BUY_BTN"),
+			"STRIPE_PUBLISHABLE_KEY": conf().Stripe.PublishableKey,
+			"STRIPE_BUY_BUTTON_ID":   conf().Stripe.BuyButtonID,
 		})
 	}
 }
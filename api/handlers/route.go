@@ -53,6 +53,7 @@ func SetupRoutes(router *gin.Engine) {
 
 	/// Streaming Widgets
 	router.GET("/widget/chat", RenderTemplate("chat-widget.tmpl"))
+	router.GET("/widget/alerts", RenderTemplate("alerts-widget.tmpl"))
 	router.GET("/widget/twitch", RenderTemplate("twitch.tmpl"))
 	router.GET("/widget/lavalamp", RenderTemplate("lavalamp.tmpl"))
 	router.GET("/widget/globe", GlobeWidgetHandler())
@@ -63,8 +64,8 @@ func SetupRoutes(router *gin.Engine) {
 
 	// API routes
 	/// Scenarios
-	router.POST("/api/scenario", SaveScenario)
-	router.GET("/api/scenarios", LoadScenarios)
+	router.POST("/api/scenario", requireFeature("scenarios"), SaveScenario)
+	router.GET("/api/scenarios", requireFeature("scenarios"), LoadScenarios)
 
 	/// Session Info
 	router.GET("/api/user/status", AuthStatus)
@@ -77,54 +78,104 @@ func SetupRoutes(router *gin.Engine) {
 	router.POST("/api/user/sync", SyncUserHandler)
 	router.GET("/api/user/info", GetUserHandler)
 
+	/// Creator Profiles
+	router.GET("/api/profiles", ListProfiles)
+	router.GET("/api/profiles/:profile/stats/:provider", StatsRouter)
+	router.GET("/api/profiles/:profile/stats/:provider/history", StatsHistoryHandler)
+
 	/// 3rd Party APIs (YouTube, Github, Twitch, Leetcode)
 	router.GET("/api/stats/:provider", StatsRouter)
+	router.GET("/api/stats/:provider/history", StatsHistoryHandler)
+	router.GET("/api/stats/collector/status", StatsCollectorStatusHandler)
 	router.DELETE("/api/cache/stats", ClearStatsCache)
+	router.GET("/api/cache/metrics", CacheMetricsHandler)
 	router.GET("/api/git/hash", GitHashHandler)
+	router.GET("/api/features", FeaturesHandler)
 
 	/// Football Leagues
-	router.GET("/api/epl/schedule", GetPremierLeagueSchedule)
-	router.GET("/api/laliga/schedule", GetLaLigaSchedule)
+	router.GET("/api/epl/schedule", requireFeature("football"), GetPremierLeagueSchedule)
+	router.GET("/api/laliga/schedule", requireFeature("football"), GetLaLigaSchedule)
 
 	/// Geocoding and Globe
-	router.GET("/api/geocode", GeocodeHandler())
-	router.POST("/api/checkin", PostCheckinHandler())
+	router.GET("/api/geocode", requireFeature("geo"), GeocodeHandler())
+	router.POST("/api/checkin", requireFeature("database"), PostCheckinHandler())
 	router.GET("/api/checkins", GetCheckinsHandler())
 	router.GET("/api/checkins/recent", RecentCheckinsHandler())
-	router.GET("/api/globe", MapHandler())
+	router.GET("/api/globe", requireFeature("geo"), MapHandler())
 
 	// Spotify
-	router.GET("/api/spotify/login", SpotifyLogin)
-	router.GET("/api/spotify/callback", SpotifyCallback)
-	router.GET("/api/spotify/status", SpotifyStatus)
-	router.GET("/api/spotify/current", SpotifyCurrent)
+	spotifyGroup := router.Group("/api/spotify")
+	spotifyGroup.Use(requireFeature("spotify"))
+	{
+		spotifyGroup.GET("/login", SpotifyLogin)
+		spotifyGroup.GET("/callback", SpotifyCallback)
+		spotifyGroup.GET("/status", SpotifyStatus)
+		spotifyGroup.GET("/current", SpotifyCurrent)
+	}
 
 	/// Chat with Websockets
 	router.GET("/api/chat", GetMessages)
 	router.GET("/api/chat/users", ChatUserCount)
 	router.GET("/ws/chat", ChatWebSocket)
-	router.GET("/ws/twitch", TwitchMessagesHandler)
-	router.GET("/ws/speech", SpeechWebSocket)
+	router.GET("/ws/twitch", requireFeature("twitch_chat"), TwitchMessagesHandler)
+	router.GET("/ws/speech", requireFeature("speech"), SpeechWebSocket)
+	router.GET("/ws/alerts", AlertOverlayWebSocket)
+	router.GET("/ws/graphql", GraphQLWebSocket)
 
 	/// Twitch Activities
-	router.GET("/api/twitch/followers", TwitchFollowersHandler)
-	router.GET("/api/twitch/raids", TwitchRaidsHandler)
-	router.GET("/api/twitch/subs", TwitchSubsHandler)
-	router.GET("/api/twitch/bits", TwitchBitsHandler)
-	router.GET("/api/twitch/lookup", TwitchUserLookupHandler)
+	router.GET("/api/twitch/followers", requireFeature("database"), TwitchFollowersHandler)
+	router.GET("/api/twitch/raids", requireFeature("database"), TwitchRaidsHandler)
+	router.GET("/api/twitch/subs", requireFeature("database"), TwitchSubsHandler)
+	router.GET("/api/twitch/bits", requireFeature("database"), TwitchBitsHandler)
+	router.GET("/api/twitch/lookup", requireFeature("twitch"), TwitchUserLookupHandler)
+
+	/// Twitch Chat Analytics (per profile channel)
+	analyticsGroup := router.Group("/api/twitch/analytics")
+	analyticsGroup.Use(requireFeature("database"))
+	{
+		analyticsGroup.GET("/leaderboard", ChatLeaderboardHandler)
+		analyticsGroup.GET("/users/:username", ChatterStatsHandler)
+		analyticsGroup.GET("/words", ChatWordsHandler)
+		analyticsGroup.GET("/emotes", ChatEmotesHandler)
+		analyticsGroup.GET("/heatmap", ChatHeatmapHandler)
+	}
 
 	/// Twitch OAuth for EventSub
-	router.GET("/api/twitch/oauth/start", TwitchOAuthHandler)
-	router.GET("/api/twitch/oauth/callback", TwitchOAuthCallbackHandler)
-	router.GET("/api/twitch/status", TwitchStatusHandler)
+	router.GET("/api/twitch/oauth/start", requireFeature("twitch"), TwitchOAuthHandler)
+	router.GET("/api/twitch/oauth/callback", requireFeature("twitch"), TwitchOAuthCallbackHandler)
+	router.GET("/api/twitch/status", requireFeature("twitch"), TwitchStatusHandler)
+	router.POST("/api/twitch/eventsub", requireFeature("twitch_eventsub"), TwitchEventSubWebhookHandler)
+
+	/// Twitch Chat Bot
+	router.GET("/api/twitch/bot/commands", requireFeature("twitch"), ListTwitchBotCommands)
+	botGroup := router.Group("/api/twitch/bot")
+	botGroup.Use(requireFeature("twitch"), SupabaseAuthMiddleware(), AdminOnlyMiddleware())
+	{
+		botGroup.POST("/commands", SaveTwitchBotCommand)
+		botGroup.DELETE("/commands/:name", DeleteTwitchBotCommand)
+	}
+
+	/// Stream Alerts
+	router.GET("/api/alerts", GetAlertQueue)
+	router.GET("/api/alerts/templates", GetAlertTemplates)
+	alertGroup := router.Group("/api/alerts")
+	alertGroup.Use(SupabaseAuthMiddleware(), AdminOnlyMiddleware())
+	{
+		alertGroup.POST("/test", TestFireAlert)
+		alertGroup.POST("/skip", SkipAlert)
+		alertGroup.POST("/replay/:id", ReplayAlert)
+		alertGroup.PUT("/templates/:type", SaveAlertTemplate)
+	}
 
 	/// App Metrics (Stream)
-	router.GET("/api/stream/status", StreamStatusHandler)
+	router.GET("/api/stream/status", requireFeature("stream_status"), StreamStatusHandler)
+	router.GET("/api/streams", requireFeature("database"), StreamSessionsHandler)
+	router.GET("/api/streams/:id/report", requireFeature("database"), StreamReportHandler)
 	router.GET("/api/metrics", MetricsHandler)
 
 	/// LLM API (Protected)
 	llmGroup := router.Group("/api/llm")
-	llmGroup.Use(SupabaseAuthMiddleware())
+	llmGroup.Use(requireFeature("llm", "auth"), SupabaseAuthMiddleware())
 	{
 		llmGroup.POST("/", PostLLM)
 		llmGroup.GET("/providers", GetProviders)
@@ -132,20 +183,21 @@ func SetupRoutes(router *gin.Engine) {
 
 	/// Speech API (Protected)
 	speechGroup := router.Group("/api/speech")
-	speechGroup.Use(SupabaseAuthMiddleware())
+	speechGroup.Use(requireFeature("speech", "auth"), SupabaseAuthMiddleware())
 	{
 		speechGroup.POST("/transcribe", PostSpeechTranscribe)
 	}
 
 	/// GraphQL API
 	router.POST("/api/graphql", GraphQLHandler)
+	router.GET("/api/graphql", GraphQLHandler)
 	router.GET("/api/graphql/playground", GraphQLPlaygroundHandler)
 	/// Deploy IAC
 	router.GET("/api/deploy/:provider", DeployIACHandler)
 
 	/// Cost Estimation
-	router.GET("/api/cost/cloudrun", CloudRunCostHandler)
-	router.GET("/api/cost/infracost", InfracostHandler)
+	router.GET("/api/cost/cloudrun", requireFeature("cost"), CloudRunCostHandler)
+	router.GET("/api/cost/infracost", requireFeature("infracost"), InfracostHandler)
 
 	// Dev (uncomment for development)
 	// router.POST("/api/chat", PostMessage)
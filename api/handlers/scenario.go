@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
-	"os"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/tursodatabase/libsql-client-go/libsql"
@@ -35,7 +34,7 @@ func SaveScenario(c *gin.Context) {
 
 	scenario := models.NewScenario(input.UserID, input.ProjectName, input.CloudProvider)
 
-	db, err := sql.Open("libsql", os.Getenv("TURSO_DATABASE_URL")+"?authToken="+os.Getenv("TURSO_TOKEN"))
+	db, err := sql.Open("libsql", conf().Turso.DatabaseURL+"?authToken="+conf().Turso.Token)
 	if err != nil {
 		fmt.Println("DB connection error:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB connection failed"})
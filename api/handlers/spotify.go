@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
@@ -18,23 +19,10 @@ func SpotifyCurrent(c *gin.Context) {
 		return
 	}
 
-	cacheKey := "spotify:current:30s"
-
-	// Try to get from Redis cache first (30 seconds TTL)
-	var cachedTrack models.CurrentTrack
-	err := services.RedisGetJSON(cacheKey, &cachedTrack)
-	if err == nil {
-		log.Printf("✅ Spotify current track cache HIT")
-		c.JSON(http.StatusOK, cachedTrack)
-		return
-	}
-	log.Printf("🔍 Spotify current track cache MISS, fetching from API")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
-	log.Println("Fetching currently playing track from Spotify...")
-	cp, err := spClient.PlayerCurrentlyPlaying(ctx)
+	track, err := CachedSpotifyTrack(ctx)
 	if err != nil {
 		log.Printf("Spotify API error: %v", err)
 		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
@@ -42,25 +30,48 @@ func SpotifyCurrent(c *gin.Context) {
 	}
 
 	// Nothing playing or no device
-	if cp == nil || cp.Item == nil {
-		log.Println("No track currently playing or no active device")
-		notPlayingResp := gin.H{
+	if track == nil {
+		c.JSON(http.StatusOK, gin.H{
 			"is_playing": false,
 			"message":    "No track currently playing or no active device",
-		}
+		})
+		return
+	}
+	c.JSON(http.StatusOK, track)
+}
 
-		// Cache "not playing" status for shorter time (10 seconds)
-		if err := services.RedisSetJSON("spotify:notplaying:10s", notPlayingResp, 10); err != nil {
-			log.Printf("⚠️ Failed to cache Spotify not-playing status: %v", err)
-		} else {
-			log.Printf("💾 Cached Spotify not-playing status for 10 seconds")
-		}
+// spotifyCurrentCache holds what's playing, including nothing (a nil track). Playback changes
+// quickly, so entries are short-lived.
+var spotifyCurrentCache = services.NewCached[*models.CurrentTrack]("spotify.current", services.CachePolicy{
+	Fresh:       10 * time.Second,
+	Stale:       20 * time.Second,
+	NegativeTTL: 5 * time.Second,
+	Tags:        []string{"spotify"},
+})
+
+// CachedSpotifyTrack is CurrentSpotifyTrack through the cache, for callers that can live with
+// a track up to half a minute old
+func CachedSpotifyTrack(ctx context.Context) (*models.CurrentTrack, error) {
+	if spClient == nil {
+		return nil, fmt.Errorf("not connected to Spotify")
+	}
+	return spotifyCurrentCache.Key("now").Get(ctx, CurrentSpotifyTrack)
+}
 
-		c.JSON(http.StatusOK, notPlayingResp)
-		return
+// CurrentSpotifyTrack returns the track playing on Spotify, or nil when nothing is playing
+func CurrentSpotifyTrack(ctx context.Context) (*models.CurrentTrack, error) {
+	if spClient == nil {
+		return nil, fmt.Errorf("not connected to Spotify")
+	}
+
+	cp, err := spClient.PlayerCurrentlyPlaying(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cp == nil || cp.Item == nil {
+		return nil, nil
 	}
 
-	// replace your album handling + numeric fields with this:
 	item := cp.Item
 
 	artists := make([]string, 0, len(item.Artists))
@@ -69,7 +80,6 @@ func SpotifyCurrent(c *gin.Context) {
 	}
 
 	// Album is a value type in v2, so no nil check
-	albumName := item.Album.Name
 	albumImg := ""
 	if len(item.Album.Images) > 0 {
 		albumImg = item.Album.Images[0].URL
@@ -82,23 +92,14 @@ func SpotifyCurrent(c *gin.Context) {
 		}
 	}
 
-	resp := models.CurrentTrack{
+	return &models.CurrentTrack{
 		Title:      item.Name,
 		Artists:    artists,
-		Album:      albumName,
+		Album:      item.Album.Name,
 		AlbumImage: albumImg,
 		URL:        url,
 		IsPlaying:  cp.Playing,
 		ProgressMS: int(cp.Progress),   // cast from spotify.Numeric
 		DurationMS: int(item.Duration), // cast from spotify.Numeric
-	}
-
-	// Cache the current track for 30 seconds
-	if err := services.RedisSetJSON(cacheKey, resp, 30); err != nil {
-		log.Printf("⚠️ Failed to cache Spotify current track: %v", err)
-	} else {
-		log.Printf("💾 Cached Spotify track '%s' for 30 seconds", resp.Title)
-	}
-
-	c.JSON(http.StatusOK, resp)
+	}, nil
 }
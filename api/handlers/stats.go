@@ -5,232 +5,111 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"majesticcoding.com/api/services"
 	"majesticcoding.com/db"
 )
 
-// dummy provider handlers map
-var statsHandlers = map[string]func(c *gin.Context){
-	"youtube":  getYouTubeStats,
-	"github":   getGithubStats,
-	"twitch":   getTwitchStats,
-	"leetcode": getLeetCodeStats,
-}
-
 // StatsRouter godoc
 // @Summary Get stats from a provider
-// @Description Returns stats for the given provider (youtube, github, twitch, leetcode)
+// @Description Returns a snapshot of the given provider's stats (youtube, github, twitch, leetcode, bluesky,
+// @Description mastodon, devto, stackoverflow, npm): headline metrics plus provider-specific details. Stats
+// @Description are normally served from the cache the background collector keeps warm.
 // @Tags Stats
 // @Param provider path string true "Stats Provider"
-// @Success 200 {object} map[string]interface{}
+// @Param profile query string false "Profile id (default profile if omitted)"
+// @Success 200 {object} models.StatsSnapshot
 // @Failure 404 {object} map[string]string
 // @Router /stats/{provider} [get]
 func StatsRouter(c *gin.Context) {
 	provider := c.Param("provider")
 
-	handler, exists := statsHandlers[provider]
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "provider not supported"})
+	profile, ok := requestProfile(c)
+	if !ok {
 		return
 	}
-
-	handler(c)
-}
-
-// YouTube handler for API Data
-func getYouTubeStats(c *gin.Context) {
-	cacheKey := "youtube:stats:channel"
-
-	// Try to get from Redis cache first (30 minutes TTL)
-	cachedJSON, err := services.RedisGetRawJSON(cacheKey)
-	if err == nil && cachedJSON != "" {
-		log.Printf("✅ YouTube stats cache HIT")
-		c.Header("Content-Type", "application/json")
-		c.String(http.StatusOK, cachedJSON)
+	account, supported := services.StatsAccount(provider, profile)
+	if !supported {
+		c.JSON(http.StatusNotFound, gin.H{"error": "provider not supported"})
 		return
 	}
-	log.Printf("🔍 YouTube stats cache MISS, fetching from API")
-
-	stats, err := services.FetchYouTubeStats()
-	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	if account == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("profile has no %s account", provider)})
 		return
 	}
 
-	// Cache the stats for 30 minutes (1800 seconds)
-	if err := services.RedisSetJSON(cacheKey, stats, 1800); err != nil {
-		log.Printf("⚠️ Failed to cache YouTube stats: %v", err)
-	} else {
-		log.Printf("💾 Cached YouTube stats")
-	}
-
-	// Store stats in database
-	database := db.GetDB()
-	if database != nil {
-		channelTitle := ""
-		subscribers := 0
-		videos := 0
-		views := int64(0)
-
-		if val, ok := stats["channelTitle"].(string); ok {
-			channelTitle = val
-		}
-		if val, ok := stats["subscribers"].(string); ok {
-			fmt.Sscanf(val, "%d", &subscribers)
-		}
-		if val, ok := stats["videos"].(string); ok {
-			fmt.Sscanf(val, "%d", &videos)
-		}
-		if val, ok := stats["views"].(string); ok {
-			fmt.Sscanf(val, "%d", &views)
-		}
-
-		if err := db.InsertYouTubeStats(database, channelTitle, subscribers, videos, views); err != nil {
-			log.Printf("❌ Failed to save YouTube stats: %v", err)
+	stats, err := services.ProviderStats(c.Request.Context(), provider, profile)
+	if err != nil {
+		// LeetCode especially rate limits often; the last recorded snapshot is better than an error
+		if errors.Is(err, services.ErrRateLimited) {
+			if database := db.GetDB(); database != nil {
+				if recorded, dbErr := services.RecordedStats(database, provider, account); dbErr == nil {
+					c.JSON(http.StatusOK, recorded)
+					return
+				}
+			}
 		}
-	}
-
-	c.JSON(http.StatusOK, stats)
-}
-
-// GitHub Handler for API Data
-func getGithubStats(c *gin.Context) {
-	username := "mattmajestic"
-	cacheKey := fmt.Sprintf("github:stats:%s", username)
 
-	// Try to get from Redis cache first (30 minutes TTL)
-	cachedJSON, err := services.RedisGetRawJSON(cacheKey)
-	if err == nil && cachedJSON != "" {
-		log.Printf("✅ GitHub stats cache HIT")
-		c.Header("Content-Type", "application/json")
-		c.String(http.StatusOK, cachedJSON)
-		return
-	}
-	log.Printf("🔍 GitHub stats cache MISS, fetching from API")
-
-	stats, err := services.FetchGitHubStats(username)
-	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Cache the stats for 30 minutes (1800 seconds)
-	if err := services.RedisSetJSON(cacheKey, stats, 1800); err != nil {
-		log.Printf("⚠️ Failed to cache GitHub stats: %v", err)
-	} else {
-		log.Printf("💾 Cached GitHub stats")
-	}
-
-	// Store stats in database
-	database := db.GetDB()
-	if database != nil {
-		if err := db.InsertGitHubStats(database, username, stats.PublicRepos, stats.Followers, 0, stats.StarsReceived); err != nil {
-			log.Printf("❌ Failed to save GitHub stats: %v", err)
-		}
-	}
-
 	c.JSON(http.StatusOK, stats)
 }
 
-// Twitch Handler for API Data
-func getTwitchStats(c *gin.Context) {
-	username := "MajesticCodingTwitch"
-	cacheKey := fmt.Sprintf("twitch:stats:%s", username)
-
-	// Try to get from Redis cache first (30 minutes TTL)
-	cachedJSON, err := services.RedisGetRawJSON(cacheKey)
-	if err == nil && cachedJSON != "" {
-		log.Printf("✅ Twitch stats cache HIT")
-		c.Header("Content-Type", "application/json")
-		c.String(http.StatusOK, cachedJSON)
-		return
-	}
-	log.Printf("🔍 Twitch stats cache MISS, fetching from API")
-
-	stats, err := services.FetchTwitchStats(username)
-	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+// StatsHistoryHandler godoc
+// @Summary Get a provider's stats history
+// @Description Returns bucketed series (min, max, first, last and delta per bucket), growth over the range
+// @Description and milestones crossed for each of the provider's metrics.
+// @Tags Stats
+// @Produce json
+// @Param provider path string true "Stats Provider"
+// @Param profile query string false "Profile id (default profile if omitted)"
+// @Param from query string false "Start, RFC3339 or YYYY-MM-DD (default: 30 days before to)"
+// @Param to query string false "End, RFC3339 or YYYY-MM-DD (default: now)"
+// @Param interval query string false "Bucket size, e.g. 1h, 6h, 1d, 1w (default: picked from the range)"
+// @Param metric query string false "Comma-separated metrics (default: all)"
+// @Success 200 {object} models.StatsHistory
+// @Failure 400 {object} map[string]string
+// @Router /api/stats/{provider}/history [get]
+func StatsHistoryHandler(c *gin.Context) {
+	profile, ok := requestProfile(c)
+	if !ok {
 		return
 	}
 
-	// Cache the stats for 30 minutes (1800 seconds)
-	if err := services.RedisSetJSON(cacheKey, stats, 1800); err != nil {
-		log.Printf("⚠️ Failed to cache Twitch stats: %v", err)
-	} else {
-		log.Printf("💾 Cached Twitch stats")
+	q := services.StatsHistoryQuery{
+		Provider: c.Param("provider"),
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+		Interval: c.Query("interval"),
 	}
-
-	// Store stats in database
-	database := db.GetDB()
-	if database != nil {
-		if err := db.InsertTwitchStats(database, username, stats.Followers, 0, false); err != nil {
-			log.Printf("❌ Failed to save Twitch stats: %v", err)
-		}
+	if metric := c.Query("metric"); metric != "" {
+		q.Metrics = strings.Split(metric, ",")
 	}
 
-	c.JSON(http.StatusOK, stats)
-}
-
-// Dummy Leetcode handler
-// func getLeetCodeStats(c *gin.Context) {
-// 	dummy := models.LeetCodeStats{
-// 		Username:     "mattmajestic",
-// 		MainLanguage: "Python & Go",
-// 		SolvedCount:  355,
-// 		Ranking:      285162,
-// 	}
-// 	c.JSON(http.StatusOK, dummy)
-// }
-
-func getLeetCodeStats(c *gin.Context) {
-	username := "mattmajestic"
-	cacheKey := fmt.Sprintf("leetcode:stats:%s", username)
-
-	// Try to get from Redis cache first (30 minutes TTL)
-	cachedJSON, err := services.RedisGetRawJSON(cacheKey)
-	if err == nil && cachedJSON != "" {
-		log.Printf("✅ LeetCode stats cache HIT")
-		c.Header("Content-Type", "application/json")
-		c.String(http.StatusOK, cachedJSON)
+	history, err := services.GetStatsHistory(profile, q)
+	if errors.Is(err, services.ErrStatsHistoryQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	log.Printf("🔍 LeetCode stats cache MISS, fetching from API")
-
-	stats, err := services.FetchLeetCodeStats(username)
 	if err != nil {
-		// Check if it's a rate limit error
-		if errors.Is(err, services.ErrRateLimited) {
-			// Try to get latest stats from database
-			database := db.GetDB()
-			if database != nil {
-				dbStats, dbErr := db.GetLatestLeetCodeStats(database, username)
-				if dbErr == nil {
-					c.JSON(http.StatusOK, dbStats)
-					return
-				}
-			}
-		}
-
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		log.Printf("❌ Stats history query failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load stats history"})
 		return
 	}
+	c.JSON(http.StatusOK, history)
+}
 
-	// Cache the stats for 30 minutes (1800 seconds)
-	if err := services.RedisSetJSON(cacheKey, stats, 1800); err != nil {
-		log.Printf("⚠️ Failed to cache LeetCode stats: %v", err)
-	} else {
-		log.Printf("💾 Cached LeetCode stats")
-	}
-
-	// Store stats in database
-	database := db.GetDB()
-	if database != nil {
-		if err := db.InsertLeetCodeStats(database, username, stats.SolvedCount, stats.Ranking, stats.Languages); err != nil {
-			log.Printf("❌ Failed to save LeetCode stats: %v", err)
-		}
-	}
-
-	c.JSON(http.StatusOK, stats)
+// StatsCollectorStatusHandler reports the background stats collector's jobs
+// @Summary Stats collector status
+// @Description Returns the last run, last error and next run of every provider/profile poll
+// @Tags Stats
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/stats/collector/status [get]
+func StatsCollectorStatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"jobs": services.StatsCollectorStatus()})
 }
@@ -3,14 +3,33 @@ package handlers
 
 import (
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"majesticcoding.com/api/models"
+	"majesticcoding.com/api/services"
 )
 
+// ChatClient is a connected site chat WebSocket
+type ChatClient struct {
+	Username   string
+	Identity   string
+	Protocol   int
+	limiter    *services.ChatConnectionLimiter
+	lastTyping time.Time
+}
+
+// ChatEvent is queued on Broadcast. Legacy is what v1 clients receive, nil
+// if the event has no legacy form.
+type ChatEvent struct {
+	Envelope models.ChatEnvelope
+	Legacy   *models.Message
+	Exclude  *websocket.Conn
+}
+
 var (
 	Messages  []models.Message
-	Clients   = make(map[*websocket.Conn]bool)
-	Broadcast = make(chan models.Message)
+	Clients   = make(map[*websocket.Conn]*ChatClient)
+	Broadcast = make(chan ChatEvent)
 	Mu        sync.Mutex
 )
@@ -1,17 +1,20 @@
 package handlers
 
 import (
+	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"majesticcoding.com/api/models"
 	"majesticcoding.com/api/services"
+	"majesticcoding.com/db"
 )
 
 func StreamHandler(c *gin.Context) {
-	awsURL := os.Getenv("AWS_STREAMING_URL")
+	awsURL := conf().Stream.AWSStreamingURL
 	stream := models.NewStream("", awsURL)
 	c.HTML(http.StatusOK, "live.tmpl", gin.H{
 		"IsStreaming": stream.IsActive,
@@ -21,38 +24,106 @@ func StreamHandler(c *gin.Context) {
 
 // StreamStatusHandler godoc
 // @Summary Stream Status
-// @Description Returns whether the stream is currently active
+// @Description Returns whether the IVS stream was live at the last check (polled every minute)
 // @Tags Stream
 // @Success 200 {string} string "true or false"
 // @Router /stream/status [get]
 func StreamStatusHandler(c *gin.Context) {
-	cacheKey := "stream:status:3m"
+	c.String(http.StatusOK, strconv.FormatBool(services.IVSStreamLive()))
+}
+
+// StreamSessionsHandler lists recent stream sessions
+// @Summary List stream sessions
+// @Description Returns recent broadcasts detected from EventSub and the IVS status check
+// @Tags Stream
+// @Produce json
+// @Param limit query int false "Number of sessions to return (default: 20)"
+// @Param profile query string false "Profile id (default profile if omitted)"
+// @Success 200 {array} models.StreamSession
+// @Router /api/streams [get]
+func StreamSessionsHandler(c *gin.Context) {
+	limit := 20
+	if parsed, err := strconv.Atoi(c.Query("limit")); err == nil && parsed > 0 && parsed <= 200 {
+		limit = parsed
+	}
 
-	// Try to get from Redis cache first (3 minutes TTL = 180 seconds)
-	cachedStatus, err := services.RedisGet(cacheKey)
-	if err == nil && cachedStatus != "" {
-		log.Printf("✅ Stream status cache HIT: %s", cachedStatus)
-		c.String(http.StatusOK, cachedStatus)
+	profile, ok := requestProfile(c)
+	if !ok {
 		return
 	}
-	log.Printf("🔍 Stream status cache MISS, checking AWS IVS")
 
-	awsURL := os.Getenv("AWS_STREAMING_URL")
-	stream := models.NewStream("", awsURL)
+	database := db.GetDB()
+	if database == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database not available"})
+		return
+	}
 
-	var status string
-	if stream.IsActive {
-		status = "true"
-	} else {
-		status = "false"
+	sessions, err := db.GetStreamSessions(database, profile.TwitchChannel, limit)
+	if err != nil {
+		log.Printf("❌ Failed to fetch stream sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stream sessions"})
+		return
 	}
+	c.JSON(http.StatusOK, sessions)
+}
 
-	// Cache the status for 3 minutes (180 seconds)
-	if err := services.RedisSet(cacheKey, status, 180); err != nil {
-		log.Printf("⚠️ Failed to cache stream status: %v", err)
+// StreamReportHandler returns analytics for one stream session
+// @Summary Stream session report
+// @Description Chat, follower, raid, sub, bit and checkin analytics for a session. Use "current" for the live session.
+// @Tags Stream
+// @Produce json,text/csv
+// @Param id path string true "Session id or current"
+// @Param format query string false "json (default) or csv"
+// @Param profile query string false "Profile whose live session \"current\" means (default profile if omitted)"
+// @Success 200 {object} models.StreamReport
+// @Router /api/streams/{id}/report [get]
+func StreamReportHandler(c *gin.Context) {
+	database := db.GetDB()
+	if database == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database not available"})
+		return
+	}
+
+	var session models.StreamSession
+	var err error
+	if c.Param("id") == "current" {
+		profile, ok := requestProfile(c)
+		if !ok {
+			return
+		}
+		session, err = services.CurrentStreamSession(profile.TwitchChannel)
 	} else {
-		log.Printf("💾 Cached stream status '%s' for 3 minutes", status)
+		id, convErr := strconv.Atoi(c.Param("id"))
+		if convErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+			return
+		}
+		session, err = db.GetStreamSession(database, id)
+	}
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "stream session not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to load stream session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load stream session"})
+		return
 	}
 
-	c.String(http.StatusOK, status)
+	report, err := services.BuildStreamReport(session)
+	if err != nil {
+		log.Printf("❌ Failed to build report for stream session %d: %v", session.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build stream report"})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="stream-%d-report.csv"`, session.ID))
+		c.Header("Content-Type", "text/csv")
+		if err := services.WriteStreamReportCSV(c.Writer, report); err != nil {
+			log.Printf("❌ Failed to write stream report CSV: %v", err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, report)
 }
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -77,6 +76,33 @@ func SupabaseAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// isAdminEmail reports whether email is listed in ADMIN_EMAILS
+func isAdminEmail(email string) bool {
+	for _, admin := range conf().Server.AdminEmails {
+		if email != "" && strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminOnlyMiddleware allows only users whose email is listed in ADMIN_EMAILS.
+// It must run after SupabaseAuthMiddleware.
+func AdminOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email, _ := c.Get("user_email")
+		emailStr, _ := email.(string)
+
+		if isAdminEmail(emailStr) {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+		c.Abort()
+	}
+}
+
 func extractUserID(user map[string]interface{}) string {
 	if value, ok := user["id"].(string); ok && value != "" {
 		return value
@@ -128,8 +154,8 @@ func verifySupabaseToken(tokenString string) (map[string]interface{}, error) {
 		log.Printf("🔍 Auth cache MISS for token, fetching from Supabase")
 	}
 
-	supabaseURL := os.Getenv("SUPABASE_URL")
-	supabaseKey := os.Getenv("SUPABASE_ANON_KEY")
+	supabaseURL := conf().Supabase.URL
+	supabaseKey := conf().Supabase.AnonKey
 
 	if supabaseURL == "" || supabaseKey == "" {
 		return nil, fmt.Errorf("Supabase configuration missing")
@@ -230,8 +256,8 @@ func ProtectedProfileHandler(c *gin.Context) {
 
 // SupabaseConfigHandler returns Supabase configuration for client
 func SupabaseConfigHandler(c *gin.Context) {
-	supabaseURL := os.Getenv("SUPABASE_URL")
-	supabaseKey := os.Getenv("SUPABASE_ANON_KEY")
+	supabaseURL := conf().Supabase.URL
+	supabaseKey := conf().Supabase.AnonKey
 
 	if supabaseURL == "" || supabaseKey == "" {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -379,7 +405,7 @@ func AutoSyncHandler(c *gin.Context) {
 
 // DebugSyncHandler - simple debug endpoint
 func DebugSyncHandler(c *gin.Context) {
-	if os.Getenv("DEBUG_AUTH") != "true" {
+	if !conf().Server.DebugAuth {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
@@ -14,6 +14,7 @@ import (
 // @Tags twitch
 // @Produce json
 // @Param limit query int false "Number of followers to return (default: 10)"
+// @Param profile query string false "Profile id (default profile if omitted)"
 // @Success 200 {array} models.TwitchFollower
 // @Router /api/twitch/followers [get]
 func TwitchFollowersHandler(c *gin.Context) {
@@ -24,13 +25,18 @@ func TwitchFollowersHandler(c *gin.Context) {
 		}
 	}
 
+	profile, ok := requestProfile(c)
+	if !ok {
+		return
+	}
+
 	database := db.GetDB()
 	if database == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database not available"})
 		return
 	}
 
-	followers, err := db.GetRecentTwitchFollowers(database, limit)
+	followers, err := db.GetRecentTwitchFollowers(database, profile.TwitchChannel, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch followers"})
 		return
@@ -45,6 +51,7 @@ func TwitchFollowersHandler(c *gin.Context) {
 // @Tags twitch
 // @Produce json
 // @Param limit query int false "Number of raids to return (default: 10)"
+// @Param profile query string false "Profile id (default profile if omitted)"
 // @Success 200 {array} models.TwitchRaid
 // @Router /api/twitch/raids [get]
 func TwitchRaidsHandler(c *gin.Context) {
@@ -55,13 +62,18 @@ func TwitchRaidsHandler(c *gin.Context) {
 		}
 	}
 
+	profile, ok := requestProfile(c)
+	if !ok {
+		return
+	}
+
 	database := db.GetDB()
 	if database == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database not available"})
 		return
 	}
 
-	raids, err := db.GetRecentTwitchRaids(database, limit)
+	raids, err := db.GetRecentTwitchRaids(database, profile.TwitchChannel, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch raids"})
 		return
@@ -76,6 +88,7 @@ func TwitchRaidsHandler(c *gin.Context) {
 // @Tags twitch
 // @Produce json
 // @Param limit query int false "Number of subscriptions to return (default: 10)"
+// @Param profile query string false "Profile id (default profile if omitted)"
 // @Success 200 {array} models.TwitchSub
 // @Router /api/twitch/subs [get]
 func TwitchSubsHandler(c *gin.Context) {
@@ -86,13 +99,18 @@ func TwitchSubsHandler(c *gin.Context) {
 		}
 	}
 
+	profile, ok := requestProfile(c)
+	if !ok {
+		return
+	}
+
 	database := db.GetDB()
 	if database == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database not available"})
 		return
 	}
 
-	subs, err := db.GetRecentTwitchSubs(database, limit)
+	subs, err := db.GetRecentTwitchSubs(database, profile.TwitchChannel, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscriptions"})
 		return
@@ -107,6 +125,7 @@ func TwitchSubsHandler(c *gin.Context) {
 // @Tags twitch
 // @Produce json
 // @Param limit query int false "Number of bits to return (default: 10)"
+// @Param profile query string false "Profile id (default profile if omitted)"
 // @Success 200 {array} models.TwitchBits
 // @Router /api/twitch/bits [get]
 func TwitchBitsHandler(c *gin.Context) {
@@ -117,13 +136,18 @@ func TwitchBitsHandler(c *gin.Context) {
 		}
 	}
 
+	profile, ok := requestProfile(c)
+	if !ok {
+		return
+	}
+
 	database := db.GetDB()
 	if database == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database not available"})
 		return
 	}
 
-	bits, err := db.GetRecentTwitchBits(database, limit)
+	bits, err := db.GetRecentTwitchBits(database, profile.TwitchChannel, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bits"})
 		return
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"majesticcoding.com/api/services"
+)
+
+// analyticsLimit reads ?limit=, defaulting to def and capped at 100
+func analyticsLimit(c *gin.Context, def int) int {
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		if n > 100 {
+			return 100
+		}
+		return n
+	}
+	return def
+}
+
+// analyticsChannel resolves the requested profile's Twitch channel, writing a 404 if there isn't one
+func analyticsChannel(c *gin.Context) (string, bool) {
+	profile, ok := requestProfile(c)
+	if !ok {
+		return "", false
+	}
+	if profile.TwitchChannel == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "profile has no Twitch channel"})
+		return "", false
+	}
+	return profile.TwitchChannel, true
+}
+
+func analyticsError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrAnalyticsQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("❌ Chat analytics query failed: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load chat analytics"})
+}
+
+// ChatLeaderboardHandler ranks Twitch chatters by messages sent
+// @Summary Twitch chat leaderboard
+// @Tags twitch
+// @Produce json
+// @Param profile query string false "Profile id (default profile if omitted)"
+// @Param window query string false "today, all, <n>d or <n>w (default: 7d)"
+// @Param limit query int false "Number of chatters (default: 10, max: 100)"
+// @Success 200 {array} models.LeaderboardEntry
+// @Router /api/twitch/analytics/leaderboard [get]
+func ChatLeaderboardHandler(c *gin.Context) {
+	channel, ok := analyticsChannel(c)
+	if !ok {
+		return
+	}
+	entries, err := services.ChatLeaderboard(channel, c.Query("window"), analyticsLimit(c, 10))
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// ChatterStatsHandler returns one chatter's message count, first and last seen, streaks and recent days
+// @Summary Twitch chatter stats
+// @Tags twitch
+// @Produce json
+// @Param username path string true "Twitch login"
+// @Param profile query string false "Profile id (default profile if omitted)"
+// @Success 200 {object} models.ChatterStats
+// @Router /api/twitch/analytics/users/{username} [get]
+func ChatterStatsHandler(c *gin.Context) {
+	channel, ok := analyticsChannel(c)
+	if !ok {
+		return
+	}
+	stats, err := services.ChatterProfile(channel, c.Param("username"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "chatter not found"})
+		return
+	}
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// ChatWordsHandler returns the most used words in chat
+// @Summary Twitch chat word frequency
+// @Tags twitch
+// @Produce json
+// @Param profile query string false "Profile id (default profile if omitted)"
+// @Param window query string false "today, all, <n>d or <n>w (default: 7d)"
+// @Param limit query int false "Number of words (default: 25, max: 100)"
+// @Success 200 {array} models.TermCount
+// @Router /api/twitch/analytics/words [get]
+func ChatWordsHandler(c *gin.Context) {
+	channel, ok := analyticsChannel(c)
+	if !ok {
+		return
+	}
+	words, err := services.TopChatWords(channel, c.Query("window"), analyticsLimit(c, 25))
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, words)
+}
+
+// ChatEmotesHandler returns the most used Twitch emotes in chat
+// @Summary Twitch chat emote frequency
+// @Tags twitch
+// @Produce json
+// @Param profile query string false "Profile id (default profile if omitted)"
+// @Param window query string false "today, all, <n>d or <n>w (default: 7d)"
+// @Param limit query int false "Number of emotes (default: 25, max: 100)"
+// @Success 200 {array} models.TermCount
+// @Router /api/twitch/analytics/emotes [get]
+func ChatEmotesHandler(c *gin.Context) {
+	channel, ok := analyticsChannel(c)
+	if !ok {
+		return
+	}
+	emotes, err := services.TopChatEmotes(channel, c.Query("window"), analyticsLimit(c, 25))
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, emotes)
+}
+
+// ChatHeatmapHandler counts chat messages by weekday and hour
+// @Summary Twitch chat time-of-day heatmap
+// @Tags twitch
+// @Produce json
+// @Param profile query string false "Profile id (default profile if omitted)"
+// @Param window query string false "today, all, <n>d or <n>w (default: 7d)"
+// @Param tz query string false "IANA time zone (default: UTC)"
+// @Success 200 {object} models.ChatHeatmap
+// @Router /api/twitch/analytics/heatmap [get]
+func ChatHeatmapHandler(c *gin.Context) {
+	channel, ok := analyticsChannel(c)
+	if !ok {
+		return
+	}
+	heatmap, err := services.ChatActivityHeatmap(channel, c.Query("window"), c.Query("tz"))
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, heatmap)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/api/services"
+	"majesticcoding.com/db"
+)
+
+var botCommandName = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// GET /api/twitch/bot/commands
+func ListTwitchBotCommands(c *gin.Context) {
+	bot := services.GetTwitchBot()
+	if bot == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Twitch bot not running"})
+		return
+	}
+
+	resp := gin.H{"commands": bot.Registry().List()}
+
+	// Include disabled custom commands so they can be managed
+	if database := db.GetDB(); database != nil {
+		if custom, err := db.GetTwitchBotCommands(database); err == nil {
+			resp["custom"] = custom
+		} else {
+			log.Printf("⚠️ Failed to load custom bot commands: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// POST /api/twitch/bot/commands
+func SaveTwitchBotCommand(c *gin.Context) {
+	var req struct {
+		Name            string   `json:"name"`
+		Response        string   `json:"response"`
+		Aliases         []string `json:"aliases"`
+		Permission      string   `json:"permission"`
+		CooldownSeconds *int     `json:"cooldown_seconds"`
+		Enabled         *bool    `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	bot := services.GetTwitchBot()
+	database := db.GetDB()
+	if bot == nil || database == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Twitch bot or database not available"})
+		return
+	}
+
+	cmd := models.TwitchBotCommand{
+		Name:            strings.ToLower(strings.TrimPrefix(strings.TrimSpace(req.Name), "!")),
+		Response:        strings.TrimSpace(req.Response),
+		CooldownSeconds: 5,
+		Enabled:         true,
+	}
+	if req.CooldownSeconds != nil {
+		cmd.CooldownSeconds = *req.CooldownSeconds
+	}
+	if req.Enabled != nil {
+		cmd.Enabled = *req.Enabled
+	}
+
+	if !botCommandName.MatchString(cmd.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1-32 letters, digits or underscores"})
+		return
+	}
+	if cmd.Response == "" || len([]rune(cmd.Response)) > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "response must be 1-500 characters"})
+		return
+	}
+	if cmd.CooldownSeconds < 0 || cmd.CooldownSeconds > 3600 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cooldown_seconds must be between 0 and 3600"})
+		return
+	}
+
+	permission, err := services.ParseTwitchPermission(req.Permission)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cmd.Permission = permission.String()
+
+	registry := bot.Registry()
+	for _, alias := range append([]string{cmd.Name}, req.Aliases...) {
+		alias = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(alias), "!"))
+		if !botCommandName.MatchString(alias) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid alias: " + alias})
+			return
+		}
+		if registry.IsBuiltin(alias) {
+			c.JSON(http.StatusConflict, gin.H{"error": "!" + alias + " is a built-in command"})
+			return
+		}
+		if alias != cmd.Name {
+			cmd.Aliases = append(cmd.Aliases, alias)
+		}
+	}
+
+	if err := db.UpsertTwitchBotCommand(database, cmd); err != nil {
+		log.Printf("❌ Failed to save bot command !%s: %v", cmd.Name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save command"})
+		return
+	}
+	if err := bot.ReloadCustomCommands(); err != nil {
+		log.Printf("⚠️ Failed to reload bot commands: %v", err)
+	}
+
+	log.Printf("🤖 Saved custom bot command !%s", cmd.Name)
+	c.JSON(http.StatusOK, cmd)
+}
+
+// DELETE /api/twitch/bot/commands/:name
+func DeleteTwitchBotCommand(c *gin.Context) {
+	name := strings.ToLower(strings.TrimPrefix(c.Param("name"), "!"))
+
+	bot := services.GetTwitchBot()
+	database := db.GetDB()
+	if bot == nil || database == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Twitch bot or database not available"})
+		return
+	}
+
+	deleted, err := db.DeleteTwitchBotCommand(database, name)
+	if err != nil {
+		log.Printf("❌ Failed to delete bot command !%s: %v", name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete command"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "command not found"})
+		return
+	}
+	if err := bot.ReloadCustomCommands(); err != nil {
+		log.Printf("⚠️ Failed to reload bot commands: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": name})
+}
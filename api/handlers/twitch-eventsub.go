@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"majesticcoding.com/api/services"
+)
+
+// POST /api/twitch/eventsub
+// Callback for the EventSub webhook transport. The raw body is needed to verify the signature.
+func TwitchEventSubWebhookHandler(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.String(http.StatusBadRequest, "failed to read body")
+		return
+	}
+
+	status, reply := services.HandleEventSubWebhook(c.Request.Header, body)
+	if status == http.StatusNoContent {
+		c.Status(status)
+		return
+	}
+	c.String(status, reply)
+}
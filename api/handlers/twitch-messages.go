@@ -1,12 +1,203 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"majesticcoding.com/api/models"
 	"majesticcoding.com/api/services"
 )
 
+// twitchChatFilter limits which chat messages a /ws/twitch client receives.
+// Moderation events are always delivered so overlays can hide removed messages.
+type twitchChatFilter struct {
+	channel       string
+	minRole       services.TwitchPermission
+	badges        map[string]bool // require at least one of these
+	excludeBadges map[string]bool
+	users         map[string]bool
+}
+
+// twitchClientBuffer is how many events a /ws/twitch client may fall behind before it's dropped
+const twitchClientBuffer = 64
+
+// twitchChatClient is a /ws/twitch connection. Events are queued on send and written by the
+// client's own goroutine, so a slow client never holds up the IRC reader.
+type twitchChatClient struct {
+	conn   *websocket.Conn
+	filter *twitchChatFilter
+	send   chan models.TwitchChatEvent
+}
+
+var (
+	twitchClients   = make(map[*twitchChatClient]bool)
+	twitchClientsMu sync.Mutex
+)
+
+// TwitchMessagesHandler streams Twitch chat over a WebSocket. Plain HTTP
+// requests still get the recent message snapshot as JSON.
+//
+// Query filters: profile=id (default profile's channel if omitted),
+// min_role=subscriber|vip|moderator|broadcaster, badges=a,b (any of), exclude_badges=a,b, users=a,b
 func TwitchMessagesHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, services.GetRecentMessages())
+	profile, ok := requestProfile(c)
+	if !ok {
+		return
+	}
+	if profile.TwitchChannel == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "profile has no Twitch channel"})
+		return
+	}
+
+	filter, err := parseTwitchChatFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.channel = profile.TwitchChannel
+
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		c.JSON(http.StatusOK, filter.apply(services.GetRecentMessages(filter.channel)))
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("❌ Twitch WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	client := &twitchChatClient{
+		conn:   conn,
+		filter: filter,
+		send:   make(chan models.TwitchChatEvent, twitchClientBuffer),
+	}
+
+	// Queue the history as the client registers so no message falls between the two
+	twitchClientsMu.Lock()
+	client.send <- models.TwitchChatEvent{
+		Type:     models.TwitchChatHistory,
+		Channel:  filter.channel,
+		Messages: filter.apply(services.GetRecentMessages(filter.channel)),
+	}
+	twitchClients[client] = true
+	twitchClientsMu.Unlock()
+	go client.writeEvents()
+
+	// Clients don't send anything; read until the connection closes
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	removeTwitchClient(client)
+}
+
+// BroadcastTwitchChatEvent queues a live chat event for every /ws/twitch client watching its
+// channel. It never blocks: a client whose queue is full is disconnected.
+func BroadcastTwitchChatEvent(event models.TwitchChatEvent) {
+	twitchClientsMu.Lock()
+	defer twitchClientsMu.Unlock()
+
+	for client := range twitchClients {
+		if event.Channel != client.filter.channel {
+			continue
+		}
+		if event.Message != nil && !client.filter.matches(*event.Message) {
+			continue
+		}
+		select {
+		case client.send <- event:
+		default:
+			log.Printf("⚠️ Dropping slow /ws/twitch client for #%s", client.filter.channel)
+			delete(twitchClients, client)
+			close(client.send)
+		}
+	}
+}
+
+// writeEvents writes queued events until the queue is closed or a write fails, then closes
+// the connection, which ends the handler's read loop
+func (c *twitchChatClient) writeEvents() {
+	defer c.conn.Close()
+	for event := range c.send {
+		c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if err := c.conn.WriteJSON(event); err != nil {
+			log.Printf("Twitch broadcast error: %v", err)
+			return
+		}
+	}
+}
+
+// removeTwitchClient unregisters a client and stops its writer, if that hasn't happened yet
+func removeTwitchClient(client *twitchChatClient) {
+	twitchClientsMu.Lock()
+	if twitchClients[client] {
+		delete(twitchClients, client)
+		close(client.send)
+	}
+	twitchClientsMu.Unlock()
+	client.conn.Close()
+}
+
+func parseTwitchChatFilter(c *gin.Context) (*twitchChatFilter, error) {
+	minRole, err := services.ParseTwitchPermission(c.Query("min_role"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &twitchChatFilter{
+		minRole:       minRole,
+		badges:        commaSet(c.Query("badges")),
+		excludeBadges: commaSet(c.Query("exclude_badges")),
+		users:         commaSet(c.Query("users")),
+	}, nil
+}
+
+func (f *twitchChatFilter) matches(m models.TwitchMessage) bool {
+	if services.TwitchUserPermission(m) < f.minRole {
+		return false
+	}
+	if len(f.users) > 0 && !f.users[strings.ToLower(m.Username)] && !f.users[strings.ToLower(m.DisplayName)] {
+		return false
+	}
+	for badge := range m.Badges {
+		if f.excludeBadges[badge] {
+			return false
+		}
+	}
+	if len(f.badges) > 0 {
+		for badge := range m.Badges {
+			if f.badges[badge] {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (f *twitchChatFilter) apply(msgs []models.TwitchMessage) []models.TwitchMessage {
+	filtered := make([]models.TwitchMessage, 0, len(msgs))
+	for _, m := range msgs {
+		if f.matches(m) {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+func commaSet(value string) map[string]bool {
+	set := make(map[string]bool)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			set[v] = true
+		}
+	}
+	return set
 }
@@ -3,17 +3,19 @@ package handlers
 import (
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 
 	goaway "github.com/TwiN/go-away"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/moby/moby/pkg/namesgenerator"
 	"majesticcoding.com/api/models"
+	"majesticcoding.com/api/services"
 	"majesticcoding.com/db"
 )
 
@@ -21,7 +23,7 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return isAllowedWSOrigin(r)
 	},
-	Subprotocols: []string{"supabase-auth"},
+	Subprotocols: []string{"supabase-auth", "chat.v2"},
 }
 
 func generateAnonUsername() string {
@@ -34,10 +36,9 @@ func isAllowedWSOrigin(r *http.Request) bool {
 		return true
 	}
 
-	allowedOrigins := strings.Split(os.Getenv("WS_ALLOWED_ORIGINS"), ",")
-	if len(allowedOrigins) > 0 && strings.TrimSpace(allowedOrigins[0]) != "" {
+	if allowedOrigins := conf().Server.WSAllowedOrigins; len(allowedOrigins) > 0 {
 		for _, allowed := range allowedOrigins {
-			if strings.TrimSpace(allowed) == origin {
+			if allowed == origin {
 				return true
 			}
 		}
@@ -99,52 +100,118 @@ func ChatWebSocket(c *gin.Context) {
 
 	username := getUsernameFromAuth(c.Request)
 
+	// Authenticated users are limited by username, anonymous users by IP
+	identity := "ip:" + c.ClientIP()
+	if strings.HasPrefix(username, "✓ ") {
+		identity = "user:" + username
+	}
+
+	client := &ChatClient{
+		Username: username,
+		Identity: identity,
+		Protocol: chatProtocolFromRequest(c.Request),
+		limiter:  services.GetChatModerator().NewConnection(),
+	}
+
 	Mu.Lock()
-	Clients[conn] = true
+	Clients[conn] = client
+	count := len(Clients)
+	// Send existing messages to the new client
+	sendChatHistory(conn, client)
 	Mu.Unlock()
 
-	log.Printf("✅ User %s connected to chat", username)
+	log.Printf("✅ User %s connected to chat (protocol v%d)", username, client.Protocol)
+	broadcastPresence(models.ChatEventJoin, username, count)
 
-	// Send existing messages to the new client
-	Mu.Lock()
-	for _, msg := range Messages {
-		if err := conn.WriteJSON(msg); err != nil {
-			log.Println("Send error:", err)
-		}
+	if client.Protocol >= models.ChatProtocolVersion {
+		readChatEnvelopes(conn, client)
+	} else {
+		readLegacyChatMessages(conn, client)
 	}
+
+	Mu.Lock()
+	delete(Clients, conn)
+	count = len(Clients)
 	Mu.Unlock()
 
+	broadcastPresence(models.ChatEventLeave, username, count)
+}
+
+// readLegacyChatMessages handles v1 clients that send bare models.Message JSON
+func readLegacyChatMessages(conn *websocket.Conn, client *ChatClient) {
 	for {
 		var msg models.Message
 		if err := conn.ReadJSON(&msg); err != nil {
 			log.Println("Read error:", err)
-			Mu.Lock()
-			delete(Clients, conn)
-			Mu.Unlock()
-			break
+			return
 		}
 
-		msg.Content = goaway.Censor(msg.Content)
+		if _, violation := publishChatMessage(client, msg.Content); violation != nil {
+			sendChatError(conn, client, violation, "")
+		}
+	}
+}
 
-		msg.Username = username
-		msg.Timestamp = time.Now()
-		msg.DisplayTime = msg.Timestamp.Format("15:04:05")
+// publishChatMessage moderates, stores and broadcasts a new chat message
+func publishChatMessage(client *ChatClient, content string) (*models.Message, *services.ChatViolation) {
+	if violation := services.GetChatModerator().Check(client.limiter, client.Identity, content); violation != nil {
+		log.Printf("🚫 Rejected chat message from %s: %v", client.Username, violation)
+		return nil, violation
+	}
 
-		// Store message in database
-		database := db.GetDB()
-		if database != nil {
-			if err := db.InsertChatMessage(database, username, msg.Content); err != nil {
-				log.Printf("❌ Failed to save chat message to database: %v", err)
-			} else {
-				log.Printf("💬 Saved chat message from %s: %s", username, msg.Content)
-			}
+	msg := models.Message{
+		ID:        uuid.NewString(),
+		Content:   goaway.Censor(strings.TrimSpace(content)),
+		Username:  client.Username,
+		Timestamp: time.Now(),
+		Platform:  "site",
+	}
+	msg.DisplayTime = msg.Timestamp.Format("15:04:05")
+
+	// Store message in database
+	database := db.GetDB()
+	if database != nil {
+		if err := db.InsertChatMessage(database, msg.Username, msg.Content); err != nil {
+			log.Printf("❌ Failed to save chat message to database: %v", err)
+		} else {
+			log.Printf("💬 Saved chat message from %s: %s", msg.Username, msg.Content)
 		}
+	}
+
+	Mu.Lock()
+	Messages = append(Messages, msg)
+	Mu.Unlock()
+
+	Broadcast <- ChatEvent{
+		Envelope: models.NewChatEnvelope(models.ChatEventMessage, msg.ID, msg),
+		Legacy:   &msg,
+	}
+	services.PublishEvent(services.TopicSiteChatMessage, msg)
+
+	services.RelaySiteChatToTwitch(msg.Username, msg.Content, strings.HasPrefix(msg.Username, "✓ "))
+
+	return &msg, nil
+}
 
-		Mu.Lock()
-		Messages = append(Messages, msg)
-		Mu.Unlock()
+// sendChatError reports a rejected frame back to the client
+func sendChatError(conn *websocket.Conn, client *ChatClient, violation *services.ChatViolation, clientID string) {
+	frame := models.ChatErrorFrame{
+		Type:       models.ChatEventError,
+		Code:       violation.Code,
+		Message:    violation.Message,
+		RetryAfter: int(math.Ceil(violation.RetryAfter.Seconds())),
+		ClientID:   clientID,
+	}
+
+	var payload interface{} = frame
+	if client.Protocol >= models.ChatProtocolVersion {
+		payload = models.NewChatEnvelope(models.ChatEventError, clientID, frame)
+	}
 
-		Broadcast <- msg
+	Mu.Lock()
+	defer Mu.Unlock()
+	if err := conn.WriteJSON(payload); err != nil {
+		log.Println("Send error:", err)
 	}
 }
 
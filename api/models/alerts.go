@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Stream alert types
+const (
+	AlertFollow     = "follow"
+	AlertRaid       = "raid"
+	AlertSub        = "sub"
+	AlertResub      = "resub"
+	AlertGiftSub    = "gift_sub"
+	AlertBits       = "bits"
+	AlertRedemption = "redemption"
+	AlertHypeTrain  = "hype_train"
+)
+
+// Alert playback states
+const (
+	AlertQueued   = "queued"
+	AlertPlaying  = "playing"
+	AlertDone     = "done"
+	AlertSkipped  = "skipped"
+	AlertTimedOut = "timed_out" // the overlay never acknowledged it
+)
+
+// StreamAlert is one alert shown on the overlay
+type StreamAlert struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Username   string     `json:"username"`
+	Amount     int        `json:"amount,omitempty"` // viewers, bits, months or gifted subs
+	Message    string     `json:"message,omitempty"`
+	Text       string     `json:"text"` // rendered template
+	ImageURL   string     `json:"image_url,omitempty"`
+	SoundURL   string     `json:"sound_url,omitempty"`
+	Priority   int        `json:"priority"`
+	Duration   int        `json:"duration_ms"`
+	SourceID   string     `json:"source_id,omitempty"` // EventSub message id
+	Test       bool       `json:"test,omitempty"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// AlertTemplate controls how an alert type is rendered and whether it fires.
+// Text supports {user}, {amount} and {message}.
+type AlertTemplate struct {
+	Type            string `json:"type"`
+	Enabled         bool   `json:"enabled"`
+	Text            string `json:"text"`
+	DurationSeconds int    `json:"duration_seconds"`
+	Priority        int    `json:"priority"`
+	MinAmount       int    `json:"min_amount"` // e.g. minimum bits
+	ImageURL        string `json:"image_url,omitempty"`
+	SoundURL        string `json:"sound_url,omitempty"`
+}
+
+// AlertFrame is sent to and received from the alert overlay WebSocket.
+// Server frames: play, skip. Client frames: ack (status started or done).
+type AlertFrame struct {
+	Type   string       `json:"type"`
+	ID     string       `json:"id,omitempty"`
+	Status string       `json:"status,omitempty"`
+	Alert  *StreamAlert `json:"alert,omitempty"`
+}
+
+// AlertQueueState is returned by the alerts API
+type AlertQueueState struct {
+	Current  *StreamAlert  `json:"current,omitempty"`
+	Queue    []StreamAlert `json:"queue"`
+	History  []StreamAlert `json:"history"`
+	Overlays int           `json:"overlays"`
+}
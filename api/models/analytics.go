@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// ChatterStats is the lifetime chat activity of one Twitch user
+type ChatterStats struct {
+	Username      string       `json:"username"`
+	DisplayName   string       `json:"display_name"`
+	Messages      int          `json:"messages"`
+	FirstSeen     time.Time    `json:"first_seen"`
+	LastSeen      time.Time    `json:"last_seen"`
+	CurrentStreak int          `json:"current_streak"` // consecutive days with a message, ending on last_streak_day
+	LongestStreak int          `json:"longest_streak"`
+	LastStreakDay string       `json:"last_streak_day"` // YYYY-MM-DD (UTC)
+	Daily         []DailyCount `json:"daily,omitempty"`
+}
+
+// DailyCount is a message count for one UTC day
+type DailyCount struct {
+	Day      string `json:"day"`
+	Messages int    `json:"messages"`
+}
+
+// LeaderboardEntry ranks a chatter within a window
+type LeaderboardEntry struct {
+	Rank        int    `json:"rank"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Messages    int    `json:"messages"`
+	ActiveDays  int    `json:"active_days"`
+}
+
+// TermCount is how often a word or emote was used within a window
+type TermCount struct {
+	Term     string `json:"term"`
+	EmoteID  string `json:"emote_id,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+	Count    int    `json:"count"`
+}
+
+// ChatHeatmap counts messages by weekday (0 = Sunday) and hour in the requested time zone
+type ChatHeatmap struct {
+	TimeZone string     `json:"time_zone"`
+	Since    *time.Time `json:"since,omitempty"`
+	Hours    [7][24]int `json:"hours"`
+}
+
+// ChatRollup is one incremental batch of chat messages aggregated for the rollup tables
+type ChatRollup struct {
+	LastMessageID int
+	Chatters      map[string]*ChatterRollup // by lowercase username
+	Hourly        map[time.Time]int
+	Words         map[DayTerm]int
+	Emotes        map[DayTerm]int // Term is the emote id
+	EmoteNames    map[string]string
+}
+
+// ChatterRollup is one user's activity within a ChatRollup
+type ChatterRollup struct {
+	DisplayName string
+	Messages    int
+	FirstSeen   time.Time
+	LastSeen    time.Time
+	Days        map[string]int // YYYY-MM-DD -> messages
+}
+
+// DayTerm keys a word or emote count by UTC day
+type DayTerm struct {
+	Day  string
+	Term string
+}
@@ -0,0 +1,33 @@
+package models
+
+// CacheReport is the state of the cache tiers and every cache using them
+type CacheReport struct {
+	Backend string            `json:"backend"` // the shared tier: redis, upstash or memory
+	Local   LocalCacheMetrics `json:"local"`
+	Caches  []CacheMetrics    `json:"caches"`
+}
+
+// LocalCacheMetrics describes this replica's in-process tier
+type LocalCacheMetrics struct {
+	Enabled               bool  `json:"enabled"` // off when the shared tier is itself in memory
+	Entries               int   `json:"entries"`
+	Capacity              int   `json:"capacity"`
+	Evictions             int64 `json:"evictions"`
+	InvalidationsSent     int64 `json:"invalidations_sent"`
+	InvalidationsReceived int64 `json:"invalidations_received"`
+}
+
+// CacheMetrics counts one cache's lookups and loads since startup
+type CacheMetrics struct {
+	Name         string  `json:"name"`
+	Hits         int64   `json:"hits"`
+	StaleHits    int64   `json:"stale_hits"` // served while refreshing in the background
+	Misses       int64   `json:"misses"`
+	NegativeHits int64   `json:"negative_hits"` // remembered failures
+	Loads        int64   `json:"loads"`
+	LoadErrors   int64   `json:"load_errors"`
+	Refreshes    int64   `json:"refreshes"`    // background loads started by stale hits
+	LocalHits    int64   `json:"local_hits"`   // lookups answered by the local tier
+	SharedReads  int64   `json:"shared_reads"` // lookups that went to the shared tier
+	HitRatio     float64 `json:"hit_ratio"`
+}
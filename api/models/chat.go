@@ -1,11 +1,111 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Message struct {
+	ID          string `json:",omitempty"`
 	Content     string
 	Username    string
 	Timestamp   time.Time
 	DisplayTime string
 	IsAI        bool
+	Platform    string              `json:",omitempty"` // "site" or "twitch"
+	Badges      []string            `json:",omitempty"`
+	EditedAt    *time.Time          `json:",omitempty"`
+	Reactions   map[string][]string `json:",omitempty"` // emoji -> usernames
+}
+
+// ChatErrorFrame is sent to a chat client when its message is rejected
+type ChatErrorFrame struct {
+	Type       string `json:"type"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	RetryAfter int    `json:"retry_after,omitempty"` // seconds
+	ClientID   string `json:"client_id,omitempty"`
+}
+
+// ChatProtocolVersion is the current chat envelope version. Clients that do
+// not ask for it get the legacy bare Message JSON.
+const ChatProtocolVersion = 2
+
+// Chat event types carried in ChatEnvelope.Type
+const (
+	ChatEventMessage  = "message"
+	ChatEventHistory  = "history"
+	ChatEventTyping   = "typing"
+	ChatEventJoin     = "join"
+	ChatEventLeave    = "leave"
+	ChatEventEdit     = "edit"
+	ChatEventReaction = "reaction"
+	ChatEventNotice   = "notice"
+	ChatEventAck      = "ack"
+	ChatEventError    = "error"
+)
+
+// ChatEnvelope wraps every frame of the versioned chat protocol
+type ChatEnvelope struct {
+	V       int             `json:"v"`
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// NewChatEnvelope builds an envelope with payload marshaled to JSON
+func NewChatEnvelope(eventType, id string, payload interface{}) ChatEnvelope {
+	env := ChatEnvelope{V: ChatProtocolVersion, Type: eventType, ID: id}
+	if payload != nil {
+		if raw, err := json.Marshal(payload); err == nil {
+			env.Payload = raw
+		}
+	}
+	return env
+}
+
+// ChatSendPayload is sent by clients for message events
+type ChatSendPayload struct {
+	Content string `json:"content"`
+}
+
+// ChatTypingPayload is a typing indicator
+type ChatTypingPayload struct {
+	Username string `json:"username,omitempty"`
+	Typing   bool   `json:"typing"`
+}
+
+// ChatPresencePayload is sent on join and leave
+type ChatPresencePayload struct {
+	Username  string `json:"username"`
+	UserCount int    `json:"user_count"`
+}
+
+// ChatEditPayload edits a previously sent message
+type ChatEditPayload struct {
+	MessageID string     `json:"message_id"`
+	Content   string     `json:"content"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+}
+
+// ChatReactionPayload adds or removes a reaction on a message
+type ChatReactionPayload struct {
+	MessageID string `json:"message_id"`
+	Emoji     string `json:"emoji"`
+	Remove    bool   `json:"remove,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Count     int    `json:"count"`
+}
+
+// ChatNoticePayload is a server notice shown to everyone in chat
+type ChatNoticePayload struct {
+	Level string `json:"level"`
+	Text  string `json:"text"`
+}
+
+// ChatAckPayload confirms a client frame. Status is "ok" or "duplicate".
+type ChatAckPayload struct {
+	ClientID  string `json:"client_id"`
+	MessageID string `json:"message_id,omitempty"`
+	Status    string `json:"status"`
 }
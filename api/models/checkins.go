@@ -9,5 +9,7 @@ type Checkin struct {
 	Lon         float64   `json:"lon"`
 	City        string    `json:"city,omitempty"`
 	Country     string    `json:"country,omitempty"`
+	Platform    string    `json:"platform,omitempty"` // "twitch" or "site"
+	StreamKey   string    `json:"-"`                  // identifies the stream a checkin belongs to, for dedupe
 	CheckinTime time.Time `json:"checkin_time"`
 }
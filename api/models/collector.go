@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// StatsCollectorJob is the state of one provider/profile poll in the background stats collector
+type StatsCollectorJob struct {
+	Provider            string     `json:"provider"`
+	Profile             string     `json:"profile"`
+	Account             string     `json:"account"`
+	Interval            string     `json:"interval"`
+	Runs                int        `json:"runs"`
+	LastRun             *time.Time `json:"last_run,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	RateLimitedUntil    *time.Time `json:"rate_limited_until,omitempty"`
+	NextRun             time.Time  `json:"next_run"`
+}
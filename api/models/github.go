@@ -6,3 +6,25 @@ type GitHubStats struct {
 	Followers     int    `json:"followers"`
 	StarsReceived int    `json:"stars_received"`
 }
+
+// GitHubDetails is the GitHub provider's snapshot details
+type GitHubDetails struct {
+	Languages     []LanguageCount      `json:"languages"`               // across owned, non-fork repos, most used first
+	Contributions *GitHubContributions `json:"contributions,omitempty"` // nil without GITHUB_TOKEN
+	ReposCounted  int                  `json:"reposCounted"`
+}
+
+// LanguageCount is how many repos use a language as their main one
+type LanguageCount struct {
+	Name  string `json:"name"`
+	Repos int    `json:"repos"`
+}
+
+// GitHubContributions are a user's contributions over the last year
+type GitHubContributions struct {
+	Total        int `json:"total"`
+	Commits      int `json:"commits"`
+	PullRequests int `json:"pullRequests"`
+	Issues       int `json:"issues"`
+	Reviews      int `json:"reviews"`
+}
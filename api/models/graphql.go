@@ -11,19 +11,22 @@ type UnifiedStats struct {
 
 // GraphQL-specific stats models (cleaner field names for GraphQL)
 type YouTubeStatsGQL struct {
-	ChannelName string `json:"channelName"`
-	Subscribers int    `json:"subscribers"`
-	Views       int    `json:"views"`
-	Videos      int    `json:"videos"`
-	Error       string `json:"error,omitempty"`
+	ChannelName   string         `json:"channelName"`
+	Subscribers   int            `json:"subscribers"`
+	Views         int            `json:"views"`
+	Videos        int            `json:"videos"`
+	RecentUploads []YouTubeVideo `json:"recentUploads"`
+	Error         string         `json:"error,omitempty"`
 }
 
 type GitHubStatsGQL struct {
-	Username      string `json:"username"`
-	PublicRepos   int    `json:"publicRepos"`
-	Followers     int    `json:"followers"`
-	StarsReceived int    `json:"starsReceived"`
-	Error         string `json:"error,omitempty"`
+	Username      string               `json:"username"`
+	PublicRepos   int                  `json:"publicRepos"`
+	Followers     int                  `json:"followers"`
+	StarsReceived int                  `json:"starsReceived"`
+	Languages     []LanguageCount      `json:"languages"`
+	Contributions *GitHubContributions `json:"contributions,omitempty"`
+	Error         string               `json:"error,omitempty"`
 }
 
 type TwitchStatsGQL struct {
@@ -35,25 +38,38 @@ type TwitchStatsGQL struct {
 }
 
 type LeetCodeStatsGQL struct {
-	Username    string `json:"username"`
-	Languages   string `json:"languages"`
-	SolvedCount int    `json:"solvedCount"`
-	Ranking     int    `json:"ranking"`
-	Error       string `json:"error,omitempty"`
+	Username    string             `json:"username"`
+	Languages   string             `json:"languages"`
+	SolvedCount int                `json:"solvedCount"`
+	Ranking     int                `json:"ranking"`
+	Easy        LeetCodeDifficulty `json:"easy"`
+	Medium      LeetCodeDifficulty `json:"medium"`
+	Hard        LeetCodeDifficulty `json:"hard"`
+	Error       string             `json:"error,omitempty"`
 }
 
 // GraphQL request/response models
 type GraphQLRequest struct {
-	Query     string                 `json:"query"`
-	Variables map[string]interface{} `json:"variables,omitempty"`
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"` // e.g. persistedQuery
 }
 
 type GraphQLResponse struct {
-	Data   interface{}    `json:"data"`
-	Errors []GraphQLError `json:"errors,omitempty"`
+	Data       interface{}            `json:"data,omitempty"` // absent when the request was rejected before execution
+	Errors     []GraphQLError         `json:"errors,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
 }
 
 type GraphQLError struct {
-	Message string        `json:"message"`
-	Path    []interface{} `json:"path,omitempty"`
+	Message    string                 `json:"message"`
+	Locations  []GraphQLLocation      `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
 }
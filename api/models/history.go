@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// StatsHistory is a provider's recorded stats for one profile over a time range
+type StatsHistory struct {
+	Provider string              `json:"provider"`
+	Profile  string              `json:"profile"`
+	Account  string              `json:"account"`
+	From     time.Time           `json:"from"`
+	To       time.Time           `json:"to"`
+	Interval string              `json:"interval"`
+	Metrics  []StatsMetricSeries `json:"metrics"`
+}
+
+// StatsMetricSeries is one metric's buckets, growth over the range and the milestones reached in it
+type StatsMetricSeries struct {
+	Metric     string           `json:"metric"`
+	Buckets    []StatsBucket    `json:"buckets"`
+	Growth     *StatsGrowth     `json:"growth,omitempty"` // nil when there are no samples
+	Milestones []StatsMilestone `json:"milestones"`
+}
+
+// StatsBucket summarises the samples recorded in one interval
+type StatsBucket struct {
+	Start   time.Time `json:"start"`
+	Min     int64     `json:"min"`
+	Max     int64     `json:"max"`
+	First   int64     `json:"first"`
+	Last    int64     `json:"last"`
+	Delta   int64     `json:"delta"` // last minus the previous bucket's last (or this bucket's first)
+	Samples int       `json:"samples"`
+
+	FirstAt time.Time `json:"-"`
+	LastAt  time.Time `json:"-"`
+}
+
+// StatsGrowth compares the first and last samples in the range
+type StatsGrowth struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Start   int64     `json:"start"`
+	End     int64     `json:"end"`
+	Change  int64     `json:"change"`
+	Percent *float64  `json:"percent,omitempty"` // nil when the start was 0
+	PerDay  float64   `json:"per_day"`
+}
+
+// StatsMilestone is a round number a metric crossed
+type StatsMilestone struct {
+	Value     int64     `json:"value"`
+	ReachedAt time.Time `json:"reached_at"`
+	Label     string    `json:"label"` // e.g. "crossed 1,000 followers"
+}
+
+// StatsChange is a sample whose value differs from the one before it
+type StatsChange struct {
+	At       time.Time
+	Previous *int64 // nil for the first sample ever recorded
+	Value    int64
+}
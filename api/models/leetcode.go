@@ -6,3 +6,18 @@ type LeetCodeStats struct {
 	SolvedCount int    `json:"totalSolved"`
 	Ranking     int    `json:"ranking"`
 }
+
+// LeetCodeDetails is the LeetCode provider's snapshot details
+type LeetCodeDetails struct {
+	Easy           LeetCodeDifficulty `json:"easy"`
+	Medium         LeetCodeDifficulty `json:"medium"`
+	Hard           LeetCodeDifficulty `json:"hard"`
+	TotalQuestions int                `json:"totalQuestions"`
+	Languages      string             `json:"mainLanguages"`
+}
+
+// LeetCodeDifficulty is how many problems of one difficulty were solved, out of how many
+type LeetCodeDifficulty struct {
+	Solved int `json:"solved"`
+	Total  int `json:"total"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// NpmPackageDownloads is one package's downloads over the last week
+type NpmPackageDownloads struct {
+	Name      string `json:"name"`
+	Downloads int64  `json:"downloads"`
+}
+
+// NpmDetails lists a maintainer's packages, most downloaded first
+type NpmDetails struct {
+	Packages []NpmPackageDownloads `json:"packages"`
+}
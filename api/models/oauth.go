@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// OAuth providers managed by the token store
+const (
+	ProviderTwitch  = "twitch"
+	ProviderSpotify = "spotify"
+)
+
+// Token refresh states
+const (
+	TokenOK             = "ok"
+	TokenRefreshFailed  = "refresh_failed"  // will be retried
+	TokenReauthRequired = "reauth_required" // the refresh token was rejected; log in again
+)
+
+// OAuthToken is a decrypted user token
+type OAuthToken struct {
+	Provider     string    `json:"provider"`
+	Account      string    `json:"account"` // login or user id on the provider
+	AccessToken  string    `json:"-"`
+	RefreshToken string    `json:"-"`
+	TokenType    string    `json:"token_type"`
+	Scopes       string    `json:"scopes"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// StoredOAuthToken is a row of bronze.oauth_tokens with the tokens still encrypted
+type StoredOAuthToken struct {
+	OAuthToken
+	AccessTokenEnc  string
+	RefreshTokenEnc string
+	Status          string
+	LastError       string
+	LastRefreshAt   *time.Time
+	Failures        int
+}
+
+// TokenStatus is reported by the provider status endpoints
+type TokenStatus struct {
+	Provider      string     `json:"provider"`
+	Account       string     `json:"account,omitempty"`
+	Connected     bool       `json:"connected"`
+	Status        string     `json:"status"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	Scopes        string     `json:"scopes,omitempty"`
+	LastRefreshAt *time.Time `json:"last_refresh_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	Failures      int        `json:"refresh_failures"`
+}
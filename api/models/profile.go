@@ -0,0 +1,18 @@
+package models
+
+// DefaultProfileID is the profile used by routes that don't name one
+const DefaultProfileID = "default"
+
+// Profile is one creator tracked by the deployment
+type Profile struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	TwitchChannel    string `json:"twitch_channel,omitempty"` // login, lowercase
+	GitHubUser       string `json:"github_user,omitempty"`
+	LeetCodeUser     string `json:"leetcode_user,omitempty"`
+	YouTubeChannelID string `json:"youtube_channel_id,omitempty"`
+	// Accounts on stats providers without a field of their own, keyed by provider name
+	// (e.g. "bluesky": "name.bsky.social", "mastodon": "name@mastodon.social")
+	Accounts map[string]string `json:"accounts,omitempty"`
+	Default  bool              `json:"default"`
+}
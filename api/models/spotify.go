@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type CurrentTrack struct {
 	Title      string   `json:"title"`
 	Artists    []string `json:"artists"`
@@ -10,3 +12,9 @@ type CurrentTrack struct {
 	ProgressMS int      `json:"progress_ms"`
 	DurationMS int      `json:"duration_ms"`
 }
+
+// NowPlayingChange is published when the Spotify track changes or playback starts or stops
+type NowPlayingChange struct {
+	Track     *CurrentTrack `json:"track"` // nil when nothing is playing
+	ChangedAt time.Time     `json:"changed_at"`
+}
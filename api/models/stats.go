@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// StatsSnapshot is what one stats provider reported for an account. Metrics holds the provider's
+// headline counts (the ones the collector charts); Details holds its own typed extras.
+type StatsSnapshot struct {
+	Provider    string           `json:"provider"`
+	Account     string           `json:"account"`
+	DisplayName string           `json:"displayName,omitempty"`
+	URL         string           `json:"url,omitempty"`
+	Metrics     map[string]int64 `json:"metrics"`
+	Details     interface{}      `json:"details,omitempty"`
+	FetchedAt   time.Time        `json:"fetchedAt"`
+}
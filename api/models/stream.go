@@ -3,7 +3,6 @@ package models
 import (
 	"io"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 )
@@ -16,7 +15,7 @@ type Stream struct {
 }
 
 func NewStream(basePath, baseURL string) *Stream {
-	url := os.Getenv("AWS_STREAMING_URL")
+	url := baseURL
 
 	client := http.Client{Timeout: 3 * time.Second}
 
@@ -37,3 +36,76 @@ func NewStream(basePath, baseURL string) *Stream {
 		IsActive: isActive,
 	}
 }
+
+// Stream session sources
+const (
+	StreamSourceEventSub = "eventsub"
+	StreamSourceIVS      = "ivs"
+)
+
+// StreamSession is one broadcast, from going live to going offline
+type StreamSession struct {
+	ID        int        `json:"id"`
+	Channel   string     `json:"channel"`
+	Source    string     `json:"source"`                     // what detected the start: eventsub or ivs
+	TwitchID  string     `json:"twitch_stream_id,omitempty"` // Twitch's stream id when known
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"` // nil while live
+}
+
+// StreamStatusChange is published when a channel's stream session starts or ends
+type StreamStatusChange struct {
+	Channel string        `json:"channel"`
+	Live    bool          `json:"live"`
+	Session StreamSession `json:"session"`
+}
+
+// StreamReport summarises the activity during a stream session
+type StreamReport struct {
+	Session          StreamSession       `json:"session"`
+	DurationMinutes  int                 `json:"duration_minutes"`
+	Messages         int                 `json:"messages"`
+	UniqueChatters   int                 `json:"unique_chatters"`
+	MessageRate      []StreamRateBucket  `json:"message_rate"` // messages per bucket
+	TopChatters      []StreamCount       `json:"top_chatters"`
+	NewFollowers     []string            `json:"new_followers"`
+	Raids            []StreamRaidSource  `json:"raids"`
+	Subs             StreamSubSummary    `json:"subs"`
+	Bits             int                 `json:"bits"`
+	Revenue          StreamRevenueReport `json:"revenue_estimate"`
+	CheckinCountries []StreamCount       `json:"checkin_countries"`
+}
+
+// StreamRateBucket counts chat messages in one time bucket
+type StreamRateBucket struct {
+	Start    time.Time `json:"start"`
+	Messages int       `json:"messages"`
+}
+
+// StreamCount is a name with how often it appeared
+type StreamCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// StreamRaidSource is a channel that raided during the session
+type StreamRaidSource struct {
+	Channel string    `json:"channel"`
+	Viewers int       `json:"viewers"`
+	At      time.Time `json:"at"`
+}
+
+// StreamSubSummary counts new, gifted and renewed subscriptions by tier ("1000", "2000", "3000")
+type StreamSubSummary struct {
+	New    int            `json:"new"`
+	Gifted int            `json:"gifted"`
+	Resubs int            `json:"resubs"`
+	ByTier map[string]int `json:"by_tier"`
+}
+
+// StreamRevenueReport is a rough estimate in USD of the streamer's share
+type StreamRevenueReport struct {
+	Subs  float64 `json:"subs"`
+	Bits  float64 `json:"bits"`
+	Total float64 `json:"total"`
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type TwitchStats struct {
 	DisplayName     string `json:"display_name"`
@@ -9,8 +12,18 @@ type TwitchStats struct {
 	Followers       int    `json:"followers"`
 }
 
+// TwitchDetails is the Twitch provider's snapshot details
+type TwitchDetails struct {
+	Description     string `json:"description"`
+	BroadcasterType string `json:"broadcasterType"` // "partner", "affiliate" or ""
+	ProfileImageURL string `json:"profileImageUrl,omitempty"`
+}
+
 type TwitchMessage struct {
 	ID            int            `json:"id"`
+	MessageID     string         `json:"message_id,omitempty"` // Twitch's message id, used for deletions
+	UserID        string         `json:"user_id,omitempty"`
+	Channel       string         `json:"channel,omitempty"` // login of the channel it was sent in
 	Username      string         `json:"username"`
 	DisplayName   string         `json:"display_name"`
 	Message       string         `json:"message"`
@@ -19,12 +32,21 @@ type TwitchMessage struct {
 	IsMod         bool           `json:"is_mod"`
 	IsVip         bool           `json:"is_vip"`
 	IsBroadcaster bool           `json:"is_broadcaster"`
+	Emotes        []TwitchEmote  `json:"emotes,omitempty"` // from the IRC emotes tag
 	Time          time.Time      `json:"time"`
 	CreatedAt     time.Time      `json:"created_at"`
 }
 
+// TwitchEmote is an emote used in a chat message
+type TwitchEmote struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
 type TwitchFollower struct {
 	ID         int       `json:"id"`
+	Channel    string    `json:"channel,omitempty"`
 	UserID     string    `json:"user_id"`
 	UserLogin  string    `json:"user_login"`
 	UserName   string    `json:"user_name"`
@@ -73,3 +95,63 @@ type TwitchBits struct {
 	Bits                 int       `json:"bits"`
 	CreatedAt            time.Time `json:"created_at"`
 }
+
+// TwitchBotCommand is a custom text command managed through the API
+type TwitchBotCommand struct {
+	ID              int       `json:"id"`
+	Name            string    `json:"name"`
+	Response        string    `json:"response"` // supports {user}, {args} and {channel}
+	Aliases         []string  `json:"aliases,omitempty"`
+	Permission      string    `json:"permission"` // everyone, subscriber, vip, moderator, broadcaster
+	CooldownSeconds int       `json:"cooldown_seconds"`
+	Enabled         bool      `json:"enabled"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TwitchBotCommandInfo describes a registered bot command, built-in or custom
+type TwitchBotCommandInfo struct {
+	Name            string   `json:"name"`
+	Aliases         []string `json:"aliases,omitempty"`
+	Description     string   `json:"description,omitempty"`
+	Permission      string   `json:"permission"`
+	CooldownSeconds int      `json:"cooldown_seconds"`
+	Builtin         bool     `json:"builtin"`
+}
+
+// Twitch chat stream event types sent over /ws/twitch
+const (
+	TwitchChatHistory = "history"
+	TwitchChatMessage = "message"
+	TwitchChatDelete  = "delete"  // a single message was removed (CLEARMSG)
+	TwitchChatTimeout = "timeout" // a user was timed out (CLEARCHAT with duration)
+	TwitchChatBan     = "ban"     // a user was banned (CLEARCHAT without duration)
+	TwitchChatClear   = "clear"   // the whole chat was cleared
+)
+
+// TwitchChatEvent is one frame of the /ws/twitch stream
+type TwitchChatEvent struct {
+	Type      string          `json:"type"`
+	Channel   string          `json:"channel,omitempty"`
+	Message   *TwitchMessage  `json:"message,omitempty"`
+	Messages  []TwitchMessage `json:"messages,omitempty"`
+	MessageID string          `json:"message_id,omitempty"`
+	Username  string          `json:"username,omitempty"`
+	UserID    string          `json:"user_id,omitempty"`
+	Duration  int             `json:"duration,omitempty"` // timeout length in seconds
+}
+
+// TwitchEvent is an EventSub notification as stored in bronze.twitch_events
+type TwitchEvent struct {
+	ID                int             `json:"id"`
+	MessageID         string          `json:"message_id"`
+	Type              string          `json:"type"`
+	Version           string          `json:"version"`
+	Channel           string          `json:"channel,omitempty"` // login of the profile's channel it was received for
+	BroadcasterUserID string          `json:"broadcaster_user_id,omitempty"`
+	UserID            string          `json:"user_id,omitempty"`
+	UserLogin         string          `json:"user_login,omitempty"`
+	UserName          string          `json:"user_name,omitempty"`
+	Event             json.RawMessage `json:"event"`
+	OccurredAt        time.Time       `json:"occurred_at"`
+}
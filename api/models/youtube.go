@@ -1,8 +1,27 @@
 package models
 
+import "time"
+
 type YouTubeStats struct {
 	ChannelName string `json:"channel_name"`
 	Subscribers int    `json:"subscribers"`
 	Views       int    `json:"views"`
 	Videos      int    `json:"videos"`
 }
+
+// YouTubeDetails is the YouTube provider's snapshot details
+type YouTubeDetails struct {
+	RecentUploads []YouTubeVideo `json:"recentUploads"` // newest first
+}
+
+// YouTubeVideo is an upload and its current statistics
+type YouTubeVideo struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Thumbnail   string    `json:"thumbnail,omitempty"`
+	PublishedAt time.Time `json:"publishedAt"`
+	Views       int64     `json:"views"`
+	Likes       int64     `json:"likes"` // 0 when the channel hides them
+	Comments    int64     `json:"comments"`
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
 )
 
@@ -113,7 +112,7 @@ func GenerateAIResponse(req AIRequest) (*AIResponse, error) {
 }
 
 func callAnthropic(req AIRequest) (*AIResponse, error) {
-	apiKey := os.Getenv("ANTHROPIC_API_KEY")
+	apiKey := conf().AI.AnthropicAPIKey
 	if apiKey == "" {
 		return nil, fmt.Errorf("ANTHROPIC_API_KEY not set")
 	}
@@ -166,7 +165,7 @@ func callAnthropic(req AIRequest) (*AIResponse, error) {
 }
 
 func callGemini(req AIRequest) (*AIResponse, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
+	apiKey := conf().AI.GeminiAPIKey
 	if apiKey == "" {
 		return nil, fmt.Errorf("GEMINI_API_KEY not set")
 	}
@@ -220,7 +219,7 @@ func callGemini(req AIRequest) (*AIResponse, error) {
 }
 
 func callOpenAI(req AIRequest) (*AIResponse, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
+	apiKey := conf().AI.OpenAIAPIKey
 	if apiKey == "" {
 		return nil, fmt.Errorf("OPENAI_API_KEY not set")
 	}
@@ -271,7 +270,7 @@ func callOpenAI(req AIRequest) (*AIResponse, error) {
 }
 
 func callGroq(req AIRequest) (*AIResponse, error) {
-	apiKey := os.Getenv("GROQ_API_KEY")
+	apiKey := conf().AI.GroqAPIKey
 	if apiKey == "" {
 		return nil, fmt.Errorf("GROQ_API_KEY not set")
 	}
@@ -325,16 +324,16 @@ func callGroq(req AIRequest) (*AIResponse, error) {
 func GetAvailableProviders() []string {
 	var providers []string
 
-	if os.Getenv("ANTHROPIC_API_KEY") != "" {
+	if conf().AI.AnthropicAPIKey != "" {
 		providers = append(providers, string(ProviderAnthropic))
 	}
-	if os.Getenv("GEMINI_API_KEY") != "" {
+	if conf().AI.GeminiAPIKey != "" {
 		providers = append(providers, string(ProviderGemini))
 	}
-	if os.Getenv("OPENAI_API_KEY") != "" {
+	if conf().AI.OpenAIAPIKey != "" {
 		providers = append(providers, string(ProviderOpenAI))
 	}
-	if os.Getenv("GROQ_API_KEY") != "" {
+	if conf().AI.GroqAPIKey != "" {
 		providers = append(providers, string(ProviderGroq))
 	}
 
@@ -0,0 +1,440 @@
+package services
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/db"
+)
+
+const (
+	alertAckGrace      = 3 * time.Second        // extra time for the overlay to report done
+	alertGap           = 500 * time.Millisecond // pause between alerts
+	alertHistorySize   = 50
+	alertFollowDedupe  = 24 * time.Hour // refollows don't re-alert
+	alertDefaultDedupe = time.Hour
+)
+
+// DefaultAlertTemplates are used for any type without a stored override
+var DefaultAlertTemplates = map[string]models.AlertTemplate{
+	models.AlertFollow:     {Type: models.AlertFollow, Enabled: true, Text: "{user} just followed!", DurationSeconds: 5, Priority: 0},
+	models.AlertRaid:       {Type: models.AlertRaid, Enabled: true, Text: "{user} is raiding with {amount} viewers!", DurationSeconds: 8, Priority: 50},
+	models.AlertSub:        {Type: models.AlertSub, Enabled: true, Text: "{user} just subscribed!", DurationSeconds: 6, Priority: 30},
+	models.AlertResub:      {Type: models.AlertResub, Enabled: true, Text: "{user} resubscribed for {amount} months! {message}", DurationSeconds: 7, Priority: 30},
+	models.AlertGiftSub:    {Type: models.AlertGiftSub, Enabled: true, Text: "{user} gifted {amount} subs!", DurationSeconds: 7, Priority: 40},
+	models.AlertBits:       {Type: models.AlertBits, Enabled: true, Text: "{user} cheered {amount} bits! {message}", DurationSeconds: 6, Priority: 20, MinAmount: 1},
+	models.AlertRedemption: {Type: models.AlertRedemption, Enabled: true, Text: "{user} redeemed {message}", DurationSeconds: 5, Priority: 10},
+	models.AlertHypeTrain:  {Type: models.AlertHypeTrain, Enabled: true, Text: "🚂 Hype train! Level {amount}", DurationSeconds: 8, Priority: 45},
+}
+
+// alertHeap orders alerts by priority, then by arrival
+type alertHeap []*queuedAlert
+
+type queuedAlert struct {
+	alert *models.StreamAlert
+	seq   int64
+}
+
+func (h alertHeap) Len() int { return len(h) }
+func (h alertHeap) Less(i, j int) bool {
+	if h[i].alert.Priority != h[j].alert.Priority {
+		return h[i].alert.Priority > h[j].alert.Priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h alertHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *alertHeap) Push(x interface{}) { *h = append(*h, x.(*queuedAlert)) }
+func (h *alertHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// AlertEngine queues stream alerts and plays them on the overlays one at a time
+type AlertEngine struct {
+	mu        sync.Mutex
+	queue     alertHeap
+	seq       int64
+	current   *models.StreamAlert
+	finished  chan string // status for the current alert
+	history   []models.StreamAlert
+	seen      map[string]time.Time
+	templates map[string]models.AlertTemplate
+	wake      chan struct{}
+
+	// deliver sends a frame to every overlay and returns how many received it
+	deliver func(models.AlertFrame) int
+}
+
+var alertEngine *AlertEngine
+
+// NewAlertEngine creates an engine that plays alerts through deliver
+func NewAlertEngine(deliver func(models.AlertFrame) int) *AlertEngine {
+	templates := make(map[string]models.AlertTemplate, len(DefaultAlertTemplates))
+	for k, t := range DefaultAlertTemplates {
+		templates[k] = t
+	}
+
+	return &AlertEngine{
+		seen:      make(map[string]time.Time),
+		templates: templates,
+		wake:      make(chan struct{}, 1),
+		deliver:   deliver,
+	}
+}
+
+// StartAlertEngine loads templates, subscribes to EventSub notifications and starts playback
+func StartAlertEngine(deliver func(models.AlertFrame) int) *AlertEngine {
+	e := NewAlertEngine(deliver)
+	if database := db.GetDB(); database != nil {
+		if stored, err := db.GetAlertTemplates(database); err != nil {
+			log.Printf("⚠️ Failed to load alert templates: %v", err)
+		} else {
+			for _, t := range stored {
+				e.templates[t.Type] = t
+			}
+		}
+	}
+
+	// The overlay belongs to the default profile's stream
+	AddTwitchEventListener(func(event models.TwitchEvent) {
+		if event.Channel == "" || event.Channel == DefaultProfile().TwitchChannel {
+			e.HandleTwitchEvent(event)
+		}
+	})
+	go e.run()
+
+	alertEngine = e
+	return e
+}
+
+// GetAlertEngine returns the running engine, or nil if it was never started
+func GetAlertEngine() *AlertEngine {
+	return alertEngine
+}
+
+// Wake nudges playback, e.g. after an overlay connects
+func (e *AlertEngine) Wake() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue renders an alert from its template and queues it. It returns false if the
+// alert was filtered by its template or is a duplicate.
+func (e *AlertEngine) Enqueue(alert models.StreamAlert) (*models.StreamAlert, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tmpl, ok := e.templates[alert.Type]
+	if !ok || !tmpl.Enabled {
+		return nil, false
+	}
+	if alert.Amount < tmpl.MinAmount && !alert.Test {
+		log.Printf("🔕 %s alert from %s below threshold (%d < %d)", alert.Type, alert.Username, alert.Amount, tmpl.MinAmount)
+		return nil, false
+	}
+
+	if !alert.Test {
+		key, window := alertDedupeKey(alert)
+		now := time.Now()
+		if at, ok := e.seen[key]; ok && now.Sub(at) < window {
+			return nil, false
+		}
+		e.seen[key] = now
+		e.pruneSeenLocked(now)
+	}
+
+	alert.ID = uuid.New().String()
+	alert.Text = renderAlertText(tmpl.Text, alert)
+	alert.ImageURL = tmpl.ImageURL
+	alert.SoundURL = tmpl.SoundURL
+	alert.Priority = tmpl.Priority
+	alert.Duration = tmpl.DurationSeconds * 1000
+	alert.Status = models.AlertQueued
+	alert.CreatedAt = time.Now()
+
+	e.pushLocked(&alert)
+	log.Printf("🚨 Queued %s alert for %s", alert.Type, alert.Username)
+	return &alert, true
+}
+
+func (e *AlertEngine) pushLocked(alert *models.StreamAlert) {
+	e.seq++
+	heap.Push(&e.queue, &queuedAlert{alert: alert, seq: e.seq})
+	e.Wake()
+}
+
+func alertDedupeKey(alert models.StreamAlert) (string, time.Duration) {
+	if alert.Type == models.AlertFollow {
+		return "follow:" + strings.ToLower(alert.Username), alertFollowDedupe
+	}
+	if alert.SourceID != "" {
+		return "source:" + alert.SourceID, alertDefaultDedupe
+	}
+	return fmt.Sprintf("%s:%s:%d:%s", alert.Type, strings.ToLower(alert.Username), alert.Amount, alert.Message), 10 * time.Second
+}
+
+func (e *AlertEngine) pruneSeenLocked(now time.Time) {
+	if len(e.seen) < 1000 {
+		return
+	}
+	for k, at := range e.seen {
+		if now.Sub(at) > alertFollowDedupe {
+			delete(e.seen, k)
+		}
+	}
+}
+
+func renderAlertText(text string, alert models.StreamAlert) string {
+	rendered := strings.NewReplacer(
+		"{user}", alert.Username,
+		"{amount}", fmt.Sprintf("%d", alert.Amount),
+		"{message}", alert.Message,
+	).Replace(text)
+	return strings.TrimSpace(rendered)
+}
+
+// run plays queued alerts one at a time while at least one overlay is connected
+func (e *AlertEngine) run() {
+	for {
+		e.mu.Lock()
+		if e.queue.Len() == 0 {
+			e.mu.Unlock()
+			<-e.wake
+			continue
+		}
+		alert := heap.Pop(&e.queue).(*queuedAlert).alert
+		started := time.Now()
+		alert.Status = models.AlertPlaying
+		alert.StartedAt = &started
+		e.current = alert
+		e.finished = make(chan string, 1)
+		finished := e.finished
+		play := *alert
+		e.mu.Unlock()
+
+		if e.deliver(models.AlertFrame{Type: "play", ID: play.ID, Alert: &play}) == 0 {
+			// Nobody is watching; keep the alert until an overlay connects
+			e.mu.Lock()
+			alert.Status = models.AlertQueued
+			alert.StartedAt = nil
+			e.current = nil
+			e.seq++
+			heap.Push(&e.queue, &queuedAlert{alert: alert, seq: -e.seq}) // back to the front of its priority
+			e.mu.Unlock()
+			<-e.wake
+			continue
+		}
+
+		status := models.AlertTimedOut
+		select {
+		case status = <-finished:
+		case <-time.After(time.Duration(alert.Duration)*time.Millisecond + alertAckGrace):
+		}
+
+		e.mu.Lock()
+		done := time.Now()
+		alert.Status = status
+		alert.FinishedAt = &done
+		e.current = nil
+		e.finished = nil
+		e.history = append(e.history, *alert)
+		if len(e.history) > alertHistorySize {
+			e.history = e.history[len(e.history)-alertHistorySize:]
+		}
+		e.mu.Unlock()
+
+		time.Sleep(alertGap)
+	}
+}
+
+// Ack records an overlay acknowledgement for the playing alert
+func (e *AlertEngine) Ack(id, status string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.current == nil || e.current.ID != id {
+		return
+	}
+	switch status {
+	case "started":
+		now := time.Now()
+		e.current.StartedAt = &now
+	case "done":
+		e.finishLocked(models.AlertDone)
+	}
+}
+
+// Skip ends the playing alert early. It returns false if nothing is playing.
+func (e *AlertEngine) Skip() bool {
+	e.mu.Lock()
+	if e.current == nil {
+		e.mu.Unlock()
+		return false
+	}
+	id := e.current.ID
+	e.finishLocked(models.AlertSkipped)
+	e.mu.Unlock()
+
+	e.deliver(models.AlertFrame{Type: "skip", ID: id})
+	return true
+}
+
+func (e *AlertEngine) finishLocked(status string) {
+	select {
+	case e.finished <- status:
+	default:
+	}
+}
+
+// Replay queues a copy of an alert from history
+func (e *AlertEngine) Replay(id string) (*models.StreamAlert, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := len(e.history) - 1; i >= 0; i-- {
+		if e.history[i].ID != id {
+			continue
+		}
+		replay := e.history[i]
+		replay.ID = uuid.New().String()
+		replay.Status = models.AlertQueued
+		replay.CreatedAt = time.Now()
+		replay.StartedAt, replay.FinishedAt = nil, nil
+		e.pushLocked(&replay)
+		return &replay, nil
+	}
+	return nil, fmt.Errorf("alert %s not found in history", id)
+}
+
+// State returns the playing alert, the queue in play order and recent history
+func (e *AlertEngine) State() models.AlertQueueState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state := models.AlertQueueState{
+		Queue:   make([]models.StreamAlert, 0, e.queue.Len()),
+		History: append([]models.StreamAlert(nil), e.history...),
+	}
+	if e.current != nil {
+		current := *e.current
+		state.Current = &current
+	}
+
+	ordered := append(alertHeap(nil), e.queue...)
+	for ordered.Len() > 0 {
+		state.Queue = append(state.Queue, *heap.Pop(&ordered).(*queuedAlert).alert)
+	}
+	return state
+}
+
+// Templates returns the effective template for every alert type
+func (e *AlertEngine) Templates() []models.AlertTemplate {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	list := make([]models.AlertTemplate, 0, len(e.templates))
+	for _, t := range e.templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Type < list[j].Type })
+	return list
+}
+
+// SetTemplate stores and applies a template
+func (e *AlertEngine) SetTemplate(t models.AlertTemplate) error {
+	if _, ok := DefaultAlertTemplates[t.Type]; !ok {
+		return fmt.Errorf("unknown alert type %q", t.Type)
+	}
+	if database := db.GetDB(); database != nil {
+		if err := db.UpsertAlertTemplate(database, t); err != nil {
+			return err
+		}
+	}
+
+	e.mu.Lock()
+	e.templates[t.Type] = t
+	e.mu.Unlock()
+	return nil
+}
+
+// HandleTwitchEvent turns EventSub notifications into alerts
+func (e *AlertEngine) HandleTwitchEvent(event models.TwitchEvent) {
+	if alert, ok := alertFromTwitchEvent(event); ok {
+		alert.SourceID = event.MessageID
+		e.Enqueue(alert)
+	}
+}
+
+func alertFromTwitchEvent(event models.TwitchEvent) (models.StreamAlert, bool) {
+	var e struct {
+		UserName            string          `json:"user_name"`
+		IsAnonymous         bool            `json:"is_anonymous"`
+		FromBroadcasterName string          `json:"from_broadcaster_user_name"`
+		Viewers             int             `json:"viewers"`
+		IsGift              bool            `json:"is_gift"`
+		CumulativeMonths    int             `json:"cumulative_months"`
+		Total               int             `json:"total"`
+		Bits                int             `json:"bits"`
+		Level               int             `json:"level"`
+		UserInput           string          `json:"user_input"`
+		Message             json.RawMessage `json:"message"`
+		Reward              struct {
+			Title string `json:"title"`
+		} `json:"reward"`
+	}
+	if err := json.Unmarshal(event.Event, &e); err != nil {
+		return models.StreamAlert{}, false
+	}
+
+	user := e.UserName
+	if e.IsAnonymous || user == "" {
+		user = "Anonymous"
+	}
+
+	// message is a string for cheers and an object for resubs
+	var text string
+	if err := json.Unmarshal(e.Message, &text); err != nil {
+		var obj struct {
+			Text string `json:"text"`
+		}
+		json.Unmarshal(e.Message, &obj)
+		text = obj.Text
+	}
+
+	switch event.Type {
+	case "channel.follow":
+		return models.StreamAlert{Type: models.AlertFollow, Username: user}, true
+	case "channel.raid":
+		return models.StreamAlert{Type: models.AlertRaid, Username: e.FromBroadcasterName, Amount: e.Viewers}, true
+	case "channel.subscribe":
+		// Gifted subs are covered by the gift alert
+		return models.StreamAlert{Type: models.AlertSub, Username: user}, !e.IsGift
+	case "channel.subscription.message":
+		return models.StreamAlert{Type: models.AlertResub, Username: user, Amount: e.CumulativeMonths, Message: text}, true
+	case "channel.subscription.gift":
+		return models.StreamAlert{Type: models.AlertGiftSub, Username: user, Amount: e.Total}, true
+	case "channel.cheer":
+		return models.StreamAlert{Type: models.AlertBits, Username: user, Amount: e.Bits, Message: text}, true
+	case "channel.channel_points_custom_reward_redemption.add":
+		msg := e.Reward.Title
+		if e.UserInput != "" {
+			msg += ": " + e.UserInput
+		}
+		return models.StreamAlert{Type: models.AlertRedemption, Username: user, Message: msg}, true
+	case "channel.hype_train.begin":
+		return models.StreamAlert{Type: models.AlertHypeTrain, Amount: e.Level}, true
+	}
+	return models.StreamAlert{}, false
+}
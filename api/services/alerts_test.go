@@ -0,0 +1,137 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"majesticcoding.com/api/models"
+)
+
+func TestAlertEngineEnqueue(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates func(map[string]models.AlertTemplate)
+		alerts    []models.StreamAlert
+		accepted  []bool
+		queue     []string // usernames in play order
+	}{
+		{
+			name: "priority then arrival",
+			alerts: []models.StreamAlert{
+				{Type: models.AlertFollow, Username: "alice"},
+				{Type: models.AlertRaid, Username: "bob", Amount: 10},
+				{Type: models.AlertSub, Username: "carol"},
+				{Type: models.AlertSub, Username: "dave"},
+				{Type: models.AlertRaid, Username: "erin", Amount: 5},
+			},
+			accepted: []bool{true, true, true, true, true},
+			queue:    []string{"bob", "erin", "carol", "dave", "alice"},
+		},
+		{
+			name: "refollows are deduped by user",
+			alerts: []models.StreamAlert{
+				{Type: models.AlertFollow, Username: "alice"},
+				{Type: models.AlertFollow, Username: "ALICE"},
+				{Type: models.AlertFollow, Username: "bob"},
+			},
+			accepted: []bool{true, false, true},
+			queue:    []string{"alice", "bob"},
+		},
+		{
+			name: "redelivered notifications are deduped by source",
+			alerts: []models.StreamAlert{
+				{Type: models.AlertBits, Username: "alice", Amount: 100, SourceID: "msg-1"},
+				{Type: models.AlertBits, Username: "alice", Amount: 100, SourceID: "msg-1"},
+				{Type: models.AlertBits, Username: "alice", Amount: 100, SourceID: "msg-2"},
+			},
+			accepted: []bool{true, false, true},
+			queue:    []string{"alice", "alice"},
+		},
+		{
+			name: "identical alerts without a source",
+			alerts: []models.StreamAlert{
+				{Type: models.AlertResub, Username: "alice", Amount: 3, Message: "hi"},
+				{Type: models.AlertResub, Username: "alice", Amount: 3, Message: "hi"},
+				{Type: models.AlertResub, Username: "alice", Amount: 4, Message: "hi"},
+			},
+			accepted: []bool{true, false, true},
+			queue:    []string{"alice", "alice"},
+		},
+		{
+			name: "below MinAmount",
+			templates: func(t map[string]models.AlertTemplate) {
+				bits := t[models.AlertBits]
+				bits.MinAmount = 100
+				t[models.AlertBits] = bits
+			},
+			alerts: []models.StreamAlert{
+				{Type: models.AlertBits, Username: "alice", Amount: 99},
+				{Type: models.AlertBits, Username: "bob", Amount: 100},
+				{Type: models.AlertBits, Username: "carol", Amount: 1, Test: true},
+			},
+			accepted: []bool{false, true, true},
+			queue:    []string{"bob", "carol"},
+		},
+		{
+			name: "disabled or unknown type",
+			templates: func(t map[string]models.AlertTemplate) {
+				follow := t[models.AlertFollow]
+				follow.Enabled = false
+				t[models.AlertFollow] = follow
+			},
+			alerts: []models.StreamAlert{
+				{Type: models.AlertFollow, Username: "alice"},
+				{Type: models.AlertFollow, Username: "bob", Test: true},
+				{Type: "unknown", Username: "carol"},
+			},
+			accepted: []bool{false, false, false},
+			queue:    []string{},
+		},
+		{
+			name: "test alerts aren't deduped",
+			alerts: []models.StreamAlert{
+				{Type: models.AlertFollow, Username: "alice", Test: true},
+				{Type: models.AlertFollow, Username: "alice", Test: true},
+			},
+			accepted: []bool{true, true},
+			queue:    []string{"alice", "alice"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewAlertEngine(func(models.AlertFrame) int { return 1 })
+			if tt.templates != nil {
+				tt.templates(e.templates)
+			}
+
+			for i, alert := range tt.alerts {
+				if _, ok := e.Enqueue(alert); ok != tt.accepted[i] {
+					t.Errorf("alert %d (%s from %s): accepted = %t, want %t", i, alert.Type, alert.Username, ok, tt.accepted[i])
+				}
+			}
+
+			queue := []string{}
+			for _, alert := range e.State().Queue {
+				queue = append(queue, alert.Username)
+			}
+			if !reflect.DeepEqual(queue, tt.queue) {
+				t.Errorf("queue = %v, want %v", queue, tt.queue)
+			}
+		})
+	}
+}
+
+func TestAlertEngineRendersTemplate(t *testing.T) {
+	e := NewAlertEngine(func(models.AlertFrame) int { return 1 })
+	alert, ok := e.Enqueue(models.StreamAlert{Type: models.AlertBits, Username: "alice", Amount: 250, Message: "gg"})
+	if !ok {
+		t.Fatal("alert was not queued")
+	}
+
+	tmpl := DefaultAlertTemplates[models.AlertBits]
+	if alert.Text != "alice cheered 250 bits! gg" || alert.Priority != tmpl.Priority ||
+		alert.Duration != tmpl.DurationSeconds*1000 || alert.Status != models.AlertQueued {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+type blueskyStatsProvider struct{}
+
+func (blueskyStatsProvider) Name() string                    { return "bluesky" }
+func (blueskyStatsProvider) Account(p models.Profile) string { return profileAccount(p, "bluesky") }
+func (blueskyStatsProvider) DefaultInterval() time.Duration  { return 15 * time.Minute }
+func (blueskyStatsProvider) NewDetails() interface{}         { return nil }
+
+// Fetch reads a handle's (e.g. name.bsky.social) profile counts from the public AppView, which
+// needs no auth
+func (blueskyStatsProvider) Fetch(ctx context.Context, handle string) (*models.StatsSnapshot, error) {
+	handle = strings.TrimPrefix(handle, "@")
+	var profile struct {
+		Handle         string `json:"handle"`
+		DisplayName    string `json:"displayName"`
+		FollowersCount int64  `json:"followersCount"`
+		FollowsCount   int64  `json:"followsCount"`
+		PostsCount     int64  `json:"postsCount"`
+	}
+	endpoint := "https://public.api.bsky.app/xrpc/app.bsky.actor.getProfile?actor=" + url.QueryEscape(handle)
+	if err := getStatsJSON(ctx, "bluesky", endpoint, nil, &profile); err != nil {
+		return nil, err
+	}
+
+	displayName := profile.DisplayName
+	if displayName == "" {
+		displayName = profile.Handle
+	}
+	return &models.StatsSnapshot{
+		DisplayName: displayName,
+		URL:         "https://bsky.app/profile/" + profile.Handle,
+		Metrics: map[string]int64{
+			"followers": profile.FollowersCount,
+			"following": profile.FollowsCount,
+			"posts":     profile.PostsCount,
+		},
+	}, nil
+}
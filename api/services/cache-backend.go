@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Cache is a key-value store with expiring string keys, sets and pub/sub: the part of Redis
+// the site uses. The Redis* helpers and Cached go through whichever backend InitRedis picked.
+type Cache interface {
+	// Name identifies the backend in logs, e.g. "upstash" or "memory"
+	Name() string
+	Ping(ctx context.Context) error
+
+	// Get returns a key's value and whether it exists
+	Get(ctx context.Context, key string) (string, bool, error)
+	// Set stores a value; a ttl of 0 keeps it until it's deleted or evicted
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// Delete removes keys and returns how many existed
+	Delete(ctx context.Context, keys ...string) (int, error)
+	// Scan returns the keys matching a Redis glob pattern
+	Scan(ctx context.Context, pattern string) ([]string, error)
+
+	// SetAdd adds members to a set, resetting its ttl when ttl > 0
+	SetAdd(ctx context.Context, key string, ttl time.Duration, members ...string) error
+	SetMembers(ctx context.Context, key string) ([]string, error)
+	SetCount(ctx context.Context, key string) (int, error)
+
+	Publish(ctx context.Context, channel, message string) error
+	// Subscribe delivers messages published on channel until ctx is done or the connection
+	// drops, then closes the returned channel. Callers that need to keep listening resubscribe.
+	Subscribe(ctx context.Context, channel string) (<-chan string, error)
+
+	Close() error
+}
+
+// ErrCacheUnsupported is returned by backends that can't do an operation
+var ErrCacheUnsupported = errors.New("operation not supported by this cache backend")
+
+// cacheOpTimeout bounds a cache call made without a deadline of its own
+const cacheOpTimeout = 10 * time.Second
+
+var (
+	cacheBackendMu sync.RWMutex
+	// Tests and instances without Redis get the in-memory backend
+	cacheBackend Cache = NewMemoryCache(conf().Cache.MemorySize)
+)
+
+// CacheBackend returns the backend in use
+func CacheBackend() Cache {
+	cacheBackendMu.RLock()
+	defer cacheBackendMu.RUnlock()
+	return cacheBackend
+}
+
+func useCacheBackend(c Cache) {
+	cacheBackendMu.Lock()
+	defer cacheBackendMu.Unlock()
+	cacheBackend = c
+}
+
+// InitRedis picks the cache backend: a Redis server from REDIS_URL, else Upstash from
+// UPSTASH_REDIS_REST_URL and UPSTASH_REDIS_REST_TOKEN. Without either, or if the configured one
+// can't be reached, the in-memory backend is used and the error says why. It sizes the
+// in-memory caches from the config, so it runs at startup before anything is cached.
+func InitRedis() error {
+	cacheConf := conf().Cache
+	useCacheBackend(NewMemoryCache(cacheConf.MemorySize))
+	localCache = NewMemoryCache(cacheConf.LocalSize)
+
+	backend, err := configuredCacheBackend()
+	if err != nil {
+		log.Printf("🧠 Using in-memory cache")
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheOpTimeout)
+	defer cancel()
+	if err := backend.Ping(ctx); err != nil {
+		backend.Close()
+		log.Printf("🧠 Using in-memory cache")
+		return fmt.Errorf("failed to connect to %s: %v", backend.Name(), err)
+	}
+
+	useCacheBackend(backend)
+	log.Printf("✅ Using %s cache", backend.Name())
+	return nil
+}
+
+func configuredCacheBackend() (Cache, error) {
+	cacheConf := conf().Cache
+	if cacheConf.RedisURL != "" {
+		return NewRESPCache(cacheConf.RedisURL)
+	}
+	if cacheConf.UpstashURL != "" && cacheConf.UpstashToken != "" {
+		return NewUpstashCache(cacheConf.UpstashURL, cacheConf.UpstashToken), nil
+	}
+	return nil, fmt.Errorf("Redis credentials not found in config")
+}
+
+// cacheContext gives a call without a deadline the default one
+func cacheContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), cacheOpTimeout)
+}
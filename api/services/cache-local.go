@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+// Cached entries are kept in two tiers: a small LRU in each replica in front of the shared
+// Redis backend. A replica answers from its own copy for a few seconds without a round-trip;
+// when any replica writes or deletes a key it publishes the key on cacheInvalidationChannel and
+// the others drop their copies.
+
+const (
+	// defaultLocalTTL is how long a replica keeps its own copy unless a policy says otherwise
+	defaultLocalTTL = 5 * time.Second
+
+	cacheInvalidationChannel = "cache:invalidate"
+)
+
+var (
+	localCache = NewMemoryCache(conf().Cache.LocalSize)
+
+	// replicaID tells this replica's invalidations apart from the others'
+	replicaID = newReplicaID()
+
+	invalidationsSent, invalidationsReceived int64
+)
+
+// cacheInvalidation is published when keys change
+type cacheInvalidation struct {
+	From string   `json:"from"`
+	Keys []string `json:"keys"`
+}
+
+func newReplicaID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	host, _ := os.Hostname()
+	return host + "-" + hex.EncodeToString(b)
+}
+
+// localTier returns the local cache, or nil when the shared backend is in this process
+// already and a second copy would only cost memory
+func localTier() *MemoryCache {
+	if _, inProcess := CacheBackend().(*MemoryCache); inProcess {
+		return nil
+	}
+	return localCache
+}
+
+// localTTL is how long a replica may keep an entry of this policy: Local if set (negative turns
+// the local tier off), else defaultLocalTTL, and never longer than the entry is fresh
+func (p CachePolicy) localTTL(negative bool) time.Duration {
+	ttl := p.Local
+	if ttl == 0 {
+		ttl = defaultLocalTTL
+	}
+	limit := p.Fresh
+	if negative {
+		limit = p.NegativeTTL
+	}
+	return min(ttl, limit)
+}
+
+// publishInvalidation tells the other replicas to drop their copies of keys
+func publishInvalidation(keys ...string) {
+	if localTier() == nil || len(keys) == 0 {
+		return
+	}
+	msg, _ := json.Marshal(cacheInvalidation{From: replicaID, Keys: keys})
+
+	ctx, cancel := cacheContext()
+	defer cancel()
+	if err := CacheBackend().Publish(ctx, cacheInvalidationChannel, string(msg)); err != nil {
+		log.Printf("⚠️ Failed to publish cache invalidation: %v", err)
+		return
+	}
+	atomic.AddInt64(&invalidationsSent, 1)
+}
+
+// handleInvalidation drops the local copies of keys another replica changed
+func handleInvalidation(msg string) {
+	var inv cacheInvalidation
+	if err := json.Unmarshal([]byte(msg), &inv); err != nil || inv.From == replicaID {
+		return
+	}
+	atomic.AddInt64(&invalidationsReceived, 1)
+	localCache.Delete(context.Background(), inv.Keys...)
+}
+
+// StartCacheInvalidation listens for other replicas' invalidations. Call it after InitRedis;
+// with the in-memory backend there are no other replicas and it does nothing.
+func StartCacheInvalidation() {
+	if localTier() == nil {
+		return
+	}
+
+	go func() {
+		backoff := time.Second
+		for {
+			messages, err := CacheBackend().Subscribe(context.Background(), cacheInvalidationChannel)
+			if err != nil {
+				log.Printf("⚠️ Cache invalidation subscribe failed, retrying in %v: %v", backoff, err)
+				time.Sleep(backoff)
+				backoff = min(backoff*2, time.Minute)
+				continue
+			}
+			log.Printf("📡 Listening for cache invalidations as %s", replicaID)
+			backoff = time.Second
+
+			for msg := range messages {
+				handleInvalidation(msg)
+			}
+
+			// Whatever was published while we were disconnected is lost, so start over
+			localCache.Clear()
+			log.Printf("⚠️ Cache invalidation subscription dropped, cleared the local cache")
+			time.Sleep(backoff)
+		}
+	}()
+}
+
+func localCacheMetrics() models.LocalCacheMetrics {
+	local := localTier()
+	if local == nil {
+		return models.LocalCacheMetrics{}
+	}
+	return models.LocalCacheMetrics{
+		Enabled:               true,
+		Entries:               local.Len(),
+		Capacity:              local.Capacity(),
+		Evictions:             local.Evictions(),
+		InvalidationsSent:     atomic.LoadInt64(&invalidationsSent),
+		InvalidationsReceived: atomic.LoadInt64(&invalidationsReceived),
+	}
+}
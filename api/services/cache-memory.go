@@ -0,0 +1,296 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryCache is an in-process LRU standing in for Redis when none is configured, and in
+// tests. It holds at most size keys, evicting the least recently used, and its pub/sub only
+// reaches subscribers in the same process.
+type MemoryCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List // front is most recently used
+	subs    map[string]map[chan string]struct{}
+
+	evictions int64
+}
+
+type memoryEntry struct {
+	key       string
+	value     string
+	set       map[string]struct{} // nil unless the key is a set
+	expiresAt time.Time           // zero for no expiry
+}
+
+// NewMemoryCache creates an in-memory backend holding at most size keys
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+		subs:    map[string]map[chan string]struct{}{},
+	}
+}
+
+func (m *MemoryCache) Name() string { return "memory" }
+
+func (m *MemoryCache) Ping(ctx context.Context) error { return nil }
+
+func (m *MemoryCache) Close() error { return nil }
+
+func (m *MemoryCache) Get(ctx context.Context, key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.lookup(key)
+	if e == nil || e.set != nil {
+		return "", false, nil
+	}
+	return e.value, true, nil
+}
+
+func (m *MemoryCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.store(key)
+	e.value, e.set = value, nil
+	e.expiresAt = expiryAfter(ttl)
+	return nil
+}
+
+func (m *MemoryCache) Delete(ctx context.Context, keys ...string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deleted := 0
+	for _, key := range keys {
+		if m.lookup(key) != nil {
+			m.remove(m.entries[key])
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *MemoryCache) Scan(ctx context.Context, pattern string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []string
+	for key := range m.entries {
+		if m.lookup(key) != nil && globMatch(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *MemoryCache) SetAdd(ctx context.Context, key string, ttl time.Duration, members ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.lookup(key)
+	if e == nil || e.set == nil {
+		e = m.store(key)
+		e.value, e.set = "", map[string]struct{}{}
+	}
+	for _, member := range members {
+		e.set[member] = struct{}{}
+	}
+	if ttl > 0 {
+		e.expiresAt = expiryAfter(ttl)
+	}
+	return nil
+}
+
+func (m *MemoryCache) SetMembers(ctx context.Context, key string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.lookup(key)
+	if e == nil {
+		return nil, nil
+	}
+	members := make([]string, 0, len(e.set))
+	for member := range e.set {
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func (m *MemoryCache) SetCount(ctx context.Context, key string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.lookup(key)
+	if e == nil {
+		return 0, nil
+	}
+	return len(e.set), nil
+}
+
+// Publish delivers to this process's subscribers, dropping the message for any that are full
+func (m *MemoryCache) Publish(ctx context.Context, channel, message string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for sub := range m.subs[channel] {
+		select {
+		case sub <- message:
+		default:
+		}
+	}
+	return nil
+}
+
+func (m *MemoryCache) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	sub := make(chan string, 16)
+	m.mu.Lock()
+	if m.subs[channel] == nil {
+		m.subs[channel] = map[chan string]struct{}{}
+	}
+	m.subs[channel][sub] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		delete(m.subs[channel], sub)
+		m.mu.Unlock()
+		close(sub)
+	}()
+	return sub, nil
+}
+
+// Clear drops every key
+func (m *MemoryCache) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = map[string]*list.Element{}
+	m.lru.Init()
+}
+
+// Len is how many keys are held, including expired ones not yet dropped
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len()
+}
+
+// Capacity is the most keys the cache holds
+func (m *MemoryCache) Capacity() int { return m.size }
+
+// Evictions counts keys dropped to make room
+func (m *MemoryCache) Evictions() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.evictions
+}
+
+// lookup returns a live entry and marks it used, dropping it if it has expired
+func (m *MemoryCache) lookup(key string) *memoryEntry {
+	el, ok := m.entries[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*memoryEntry)
+	if !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+		m.remove(el)
+		return nil
+	}
+	m.lru.MoveToFront(el)
+	return e
+}
+
+// store returns the entry for key, creating it and evicting the least recently used entry if
+// the cache is full
+func (m *MemoryCache) store(key string) *memoryEntry {
+	if el, ok := m.entries[key]; ok {
+		m.lru.MoveToFront(el)
+		return el.Value.(*memoryEntry)
+	}
+	for m.lru.Len() >= m.size && m.lru.Len() > 0 {
+		m.remove(m.lru.Back())
+		m.evictions++
+	}
+	e := &memoryEntry{key: key}
+	m.entries[key] = m.lru.PushFront(e)
+	return e
+}
+
+func (m *MemoryCache) remove(el *list.Element) {
+	m.lru.Remove(el)
+	delete(m.entries, el.Value.(*memoryEntry).key)
+}
+
+func expiryAfter(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// globMatch matches a key against a Redis glob pattern: * and ? wildcards, [abc] and [a-z]
+// classes (^ negates) and \ escapes
+func globMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if globMatch(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		case '[':
+			if s == "" {
+				return false
+			}
+			end := 1
+			for end < len(pattern) && pattern[end] != ']' {
+				end++
+			}
+			if end == len(pattern) {
+				return false // unterminated class
+			}
+			class := pattern[1:end]
+			negate := len(class) > 0 && class[0] == '^'
+			if negate {
+				class = class[1:]
+			}
+			matched := false
+			for i := 0; i < len(class); i++ {
+				if i+2 < len(class) && class[i+1] == '-' {
+					if class[i] <= s[0] && s[0] <= class[i+2] {
+						matched = true
+					}
+					i += 2
+				} else if class[i] == s[0] {
+					matched = true
+				}
+			}
+			if matched == negate {
+				return false
+			}
+			pattern, s = pattern[end+1:], s[1:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if s == "" || pattern[0] != s[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return s == ""
+}
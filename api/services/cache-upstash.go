@@ -0,0 +1,261 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UpstashRedisClient talks to Upstash over its REST API, one HTTPS request per command or
+// pipeline
+type UpstashRedisClient struct {
+	BaseURL string
+	Token   string
+	Client  *http.Client
+}
+
+type RedisResponse struct {
+	Result interface{} `json:"result"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// NewUpstashCache creates the Upstash REST backend
+func NewUpstashCache(baseURL, token string) *UpstashRedisClient {
+	return &UpstashRedisClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		Client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (c *UpstashRedisClient) Name() string { return "upstash" }
+
+func (c *UpstashRedisClient) Close() error { return nil }
+
+func (c *UpstashRedisClient) Ping(ctx context.Context) error {
+	_, err := c.executeCommand(ctx, []interface{}{"PING"})
+	return err
+}
+
+func (c *UpstashRedisClient) Get(ctx context.Context, key string) (string, bool, error) {
+	result, err := c.executeCommand(ctx, []interface{}{"GET", key})
+	if err != nil || result == nil {
+		return "", false, err
+	}
+	return fmt.Sprintf("%v", result), true, nil
+}
+
+func (c *UpstashRedisClient) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	command := []interface{}{"SET", key, value}
+	if ttl > 0 {
+		command = append(command, "PX", ttl.Milliseconds())
+	}
+	_, err := c.executeCommand(ctx, command)
+	return err
+}
+
+func (c *UpstashRedisClient) Delete(ctx context.Context, keys ...string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	command := []interface{}{"DEL"}
+	for _, key := range keys {
+		command = append(command, key)
+	}
+	result, err := c.executeCommand(ctx, command)
+	if err != nil {
+		return 0, err
+	}
+	return upstashInt(result), nil
+}
+
+// Scan walks the keyspace with SCAN rather than KEYS, which blocks the server
+func (c *UpstashRedisClient) Scan(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		result, err := c.executeCommand(ctx, []interface{}{"SCAN", cursor, "MATCH", pattern, "COUNT", 500})
+		if err != nil {
+			return nil, err
+		}
+		page, ok := result.([]interface{})
+		if !ok || len(page) != 2 {
+			return nil, fmt.Errorf("unexpected SCAN reply %v", result)
+		}
+		keys = append(keys, upstashStrings(page[1])...)
+		cursor = fmt.Sprintf("%v", page[0])
+		if cursor == "0" {
+			return keys, nil
+		}
+	}
+}
+
+func (c *UpstashRedisClient) SetAdd(ctx context.Context, key string, ttl time.Duration, members ...string) error {
+	sadd := []interface{}{"SADD", key}
+	for _, m := range members {
+		sadd = append(sadd, m)
+	}
+	commands := [][]interface{}{sadd}
+	if ttl > 0 {
+		commands = append(commands, []interface{}{"PEXPIRE", key, ttl.Milliseconds()})
+	}
+	_, err := c.pipeline(ctx, commands)
+	return err
+}
+
+func (c *UpstashRedisClient) SetMembers(ctx context.Context, key string) ([]string, error) {
+	result, err := c.executeCommand(ctx, []interface{}{"SMEMBERS", key})
+	if err != nil {
+		return nil, err
+	}
+	return upstashStrings(result), nil
+}
+
+func (c *UpstashRedisClient) SetCount(ctx context.Context, key string) (int, error) {
+	result, err := c.executeCommand(ctx, []interface{}{"SCARD", key})
+	if err != nil {
+		return 0, err
+	}
+	return upstashInt(result), nil
+}
+
+func (c *UpstashRedisClient) Publish(ctx context.Context, channel, message string) error {
+	_, err := c.executeCommand(ctx, []interface{}{"PUBLISH", channel, message})
+	return err
+}
+
+// Subscribe holds open Upstash's server-sent events endpoint, which streams lines like
+// "data: message,<channel>,<payload>"
+func (c *UpstashRedisClient) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/subscribe/"+url.PathEscape(channel), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Accept", "text/event-stream")
+
+	// The stream is long-lived, so it can't use the client's request timeout
+	resp, err := (&http.Client{Transport: c.Client.Transport}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("subscribe returned status %d", resp.StatusCode)
+	}
+
+	messages := make(chan string, 16)
+	go func() {
+		defer close(messages)
+		defer resp.Body.Close()
+		prefix := "data: message," + channel + ","
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			payload, ok := strings.CutPrefix(scanner.Text(), prefix)
+			if !ok {
+				continue // subscribe confirmations and keep-alives
+			}
+			select {
+			case messages <- payload:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return messages, nil
+}
+
+// executeCommand executes a Redis command via REST API
+func (c *UpstashRedisClient) executeCommand(ctx context.Context, command []interface{}) (interface{}, error) {
+	var redisResp RedisResponse
+	if err := c.post(ctx, c.BaseURL, command, &redisResp); err != nil {
+		return nil, err
+	}
+	if redisResp.Error != "" {
+		return nil, fmt.Errorf("Redis error: %s", redisResp.Error)
+	}
+	return redisResp.Result, nil
+}
+
+// pipeline sends several commands in one request. It fails if any of them did.
+func (c *UpstashRedisClient) pipeline(ctx context.Context, commands [][]interface{}) ([]interface{}, error) {
+	var replies []RedisResponse
+	if err := c.post(ctx, c.BaseURL+"/pipeline", commands, &replies); err != nil {
+		return nil, err
+	}
+	results := make([]interface{}, len(replies))
+	for i, r := range replies {
+		if r.Error != "" {
+			return nil, fmt.Errorf("Redis error in %v: %s", commands[i][0], r.Error)
+		}
+		results[i] = r.Result
+	}
+	return results, nil
+}
+
+func (c *UpstashRedisClient) post(ctx context.Context, endpoint string, body, out interface{}) error {
+	// Convert command to JSON
+	commandJSON, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal command: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(commandJSON))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		// 400 carries a Redis error in the body; anything else is the REST layer failing
+		return fmt.Errorf("Upstash returned status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse response: %v", err)
+	}
+	return nil
+}
+
+// upstashInt reads an integer reply, which arrives as a JSON number
+func upstashInt(v interface{}) int {
+	switch n := v.(type) {
+	case float64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(n)
+		return i
+	}
+	return 0
+}
+
+func upstashStrings(v interface{}) []string {
+	list, _ := v.([]interface{})
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"majesticcoding.com/api/models"
+)
+
+// CachePolicy says how long a Cached keeps its entries.
+//
+// An entry is served as-is for Fresh. For Stale after that it is still served, while one caller
+// refreshes it in the background. A failed load is remembered for NegativeTTL (0 doesn't
+// remember failures), so a broken upstream isn't hit by every request.
+//
+// Each replica also keeps its own copy of an entry for Local (defaultLocalTTL if 0, never past
+// Fresh; negative keeps no local copy), answering without asking Redis.
+type CachePolicy struct {
+	Fresh       time.Duration
+	Stale       time.Duration
+	NegativeTTL time.Duration
+	Local       time.Duration
+	Tags        []string // every entry can be dropped with InvalidateCacheTags
+}
+
+// Cached is a stale-while-revalidate cache of T values in Redis, with single-flight loads.
+// Create one per kind of value with NewCached and build its keys with Key.
+type Cached[T any] struct {
+	name   string
+	policy CachePolicy
+	decode func(raw []byte) (T, error)
+	flight singleflight.Group
+	stats  *cacheCounters
+}
+
+// CacheKey is one entry of a Cached[T]. Keys only come from the cache's Key method, so an
+// entry is always read back as the type it was written as.
+type CacheKey[T any] struct {
+	cache *Cached[T]
+	key   string
+}
+
+// CachedError is a failed load remembered by negative caching
+type CachedError struct {
+	Message     string
+	RateLimited bool
+}
+
+func (e *CachedError) Error() string { return e.Message }
+
+// Is lets errors.Is match a remembered rate limit against ErrRateLimited
+func (e *CachedError) Is(target error) bool {
+	return e.RateLimited && target == ErrRateLimited
+}
+
+// cacheEntry is what's stored in Redis for each key
+type cacheEntry struct {
+	Value       json.RawMessage `json:"v,omitempty"`
+	Error       string          `json:"err,omitempty"` // set for negative entries
+	RateLimited bool            `json:"rl,omitempty"`
+	StoredAt    time.Time       `json:"at"`
+}
+
+type cacheCounters struct {
+	hits, staleHits, misses, negativeHits, loads, loadErrors, refreshes int64
+	localHits, sharedReads                                              int64
+}
+
+var (
+	cacheRegistryMu sync.Mutex
+	cacheRegistry   = map[string]*cacheCounters{}
+)
+
+const cacheTagTTL = 7 * 24 * time.Hour
+
+// NewCached creates a cache whose keys are prefixed with name. The name is also the metrics label.
+func NewCached[T any](name string, policy CachePolicy) *Cached[T] {
+	c := &Cached[T]{name: name, policy: policy, stats: &cacheCounters{}}
+	c.decode = func(raw []byte) (T, error) {
+		var v T
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	}
+
+	cacheRegistryMu.Lock()
+	defer cacheRegistryMu.Unlock()
+	if _, dup := cacheRegistry[name]; dup {
+		panic(fmt.Sprintf("cache %q registered twice", name))
+	}
+	cacheRegistry[name] = c.stats
+	return c
+}
+
+// WithDecoder replaces JSON decoding of cached values, for types that need help (e.g. ones
+// holding interfaces). It returns the cache so it can be chained onto NewCached.
+func (c *Cached[T]) WithDecoder(decode func(raw []byte) (T, error)) *Cached[T] {
+	c.decode = decode
+	return c
+}
+
+// Key builds the key for the entry identified by parts, e.g. Key("github", "octocat")
+func (c *Cached[T]) Key(parts ...string) CacheKey[T] {
+	return CacheKey[T]{cache: c, key: "cache:" + c.name + ":" + strings.Join(parts, ":")}
+}
+
+// String is the Redis key
+func (k CacheKey[T]) String() string {
+	return k.key
+}
+
+// Get returns the cached value, loading it on a miss. Concurrent misses share one load, and the
+// load outlives a caller that gives up so the others still get it and it still gets cached.
+// Stale values are returned immediately while a background load refreshes them.
+func (k CacheKey[T]) Get(ctx context.Context, load func(ctx context.Context) (T, error)) (T, error) {
+	c := k.cache
+	entry, ok := k.read()
+	if ok {
+		age := time.Since(entry.StoredAt)
+		switch {
+		case entry.Error != "":
+			atomic.AddInt64(&c.stats.negativeHits, 1)
+			log.Printf("🚫 %s cache NEGATIVE HIT", c.name)
+			var zero T
+			return zero, &CachedError{Message: entry.Error, RateLimited: entry.RateLimited}
+		case age < c.policy.Fresh:
+			if v, err := c.decode(entry.Value); err == nil {
+				atomic.AddInt64(&c.stats.hits, 1)
+				log.Printf("✅ %s cache HIT", c.name)
+				return v, nil
+			}
+		default:
+			if v, err := c.decode(entry.Value); err == nil {
+				atomic.AddInt64(&c.stats.staleHits, 1)
+				log.Printf("♻️ %s cache STALE, refreshing in the background", c.name)
+				k.refreshInBackground(ctx, load)
+				return v, nil
+			}
+		}
+		log.Printf("⚠️ Ignoring undecodable %s cache entry", k.key)
+	}
+
+	atomic.AddInt64(&c.stats.misses, 1)
+	log.Printf("🔍 %s cache MISS, loading", c.name)
+	return k.Refresh(ctx, load)
+}
+
+// Refresh loads and stores the value now, sharing a load already in flight. Use it to warm the
+// cache (the stats collector) or to reload it after a write.
+func (k CacheKey[T]) Refresh(ctx context.Context, load func(ctx context.Context) (T, error)) (T, error) {
+	results := k.cache.flight.DoChan(k.key, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheLoadTimeout)
+		defer cancel()
+		return k.load(loadCtx, load, false)
+	})
+	select {
+	case r := <-results:
+		v, _ := r.Val.(T)
+		return v, r.Err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Set stores a value directly, e.g. one a handler computed itself
+func (k CacheKey[T]) Set(v T) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("⚠️ Failed to encode %s cache entry: %v", k.cache.name, err)
+		return
+	}
+	k.write(cacheEntry{Value: raw, StoredAt: time.Now()}, k.cache.policy.Fresh+k.cache.policy.Stale)
+}
+
+// Lookup returns a fresh cached value without loading. Stale and negative entries are misses.
+func (k CacheKey[T]) Lookup() (T, bool) {
+	c := k.cache
+	var zero T
+	entry, ok := k.read()
+	if !ok || entry.Error != "" || time.Since(entry.StoredAt) >= c.policy.Fresh {
+		atomic.AddInt64(&c.stats.misses, 1)
+		return zero, false
+	}
+	v, err := c.decode(entry.Value)
+	if err != nil {
+		atomic.AddInt64(&c.stats.misses, 1)
+		return zero, false
+	}
+	atomic.AddInt64(&c.stats.hits, 1)
+	return v, true
+}
+
+// Delete drops the entry from every replica
+func (k CacheKey[T]) Delete() {
+	if local := localTier(); local != nil {
+		local.Delete(context.Background(), k.key)
+	}
+	if err := RedisDelete(k.key); err != nil {
+		log.Printf("⚠️ Failed to delete %s: %v", k.key, err)
+		return
+	}
+	publishInvalidation(k.key)
+}
+
+// cacheLoadTimeout bounds a load that no caller is waiting on any more
+const cacheLoadTimeout = 30 * time.Second
+
+func (k CacheKey[T]) refreshInBackground(ctx context.Context, load func(ctx context.Context) (T, error)) {
+	c := k.cache
+	// DoChan joins a refresh already running instead of starting another
+	c.flight.DoChan(k.key, func() (interface{}, error) {
+		atomic.AddInt64(&c.stats.refreshes, 1)
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheLoadTimeout)
+		defer cancel()
+		return k.load(loadCtx, load, true)
+	})
+}
+
+// load runs the loader and stores its result. A failed background refresh keeps the stale
+// value rather than replacing it with the error.
+func (k CacheKey[T]) load(ctx context.Context, load func(ctx context.Context) (T, error), haveStale bool) (T, error) {
+	c := k.cache
+	atomic.AddInt64(&c.stats.loads, 1)
+	v, err := load(ctx)
+	if err != nil {
+		atomic.AddInt64(&c.stats.loadErrors, 1)
+		if c.policy.NegativeTTL > 0 && !haveStale {
+			k.write(cacheEntry{
+				Error:       err.Error(),
+				RateLimited: errors.Is(err, ErrRateLimited),
+				StoredAt:    time.Now(),
+			}, c.policy.NegativeTTL)
+		}
+		return v, err
+	}
+	k.Set(v)
+	return v, nil
+}
+
+// read returns the entry from the local tier, or from Redis and keeps a local copy
+func (k CacheKey[T]) read() (cacheEntry, bool) {
+	c := k.cache
+	var entry cacheEntry
+	local := localTier()
+
+	raw, ok := "", false
+	if local != nil {
+		raw, ok, _ = local.Get(context.Background(), k.key)
+	}
+	if ok {
+		atomic.AddInt64(&c.stats.localHits, 1)
+	} else {
+		atomic.AddInt64(&c.stats.sharedReads, 1)
+		var err error
+		if raw, err = RedisGet(k.key); err != nil || raw == "" {
+			return entry, false
+		}
+	}
+
+	if err := json.Unmarshal([]byte(raw), &entry); err != nil || entry.StoredAt.IsZero() {
+		return entry, false
+	}
+	if !ok {
+		k.keepLocal(entry, raw)
+	}
+	return entry, true
+}
+
+// keepLocal stores a local copy of an entry for as long as the policy allows
+func (k CacheKey[T]) keepLocal(entry cacheEntry, raw string) {
+	local := localTier()
+	ttl := k.cache.policy.localTTL(entry.Error != "") - time.Since(entry.StoredAt)
+	if local == nil || ttl <= 0 {
+		return
+	}
+	local.Set(context.Background(), k.key, raw, ttl)
+}
+
+func (k CacheKey[T]) write(entry cacheEntry, ttl time.Duration) {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	seconds := int(ttl.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	if err := RedisSet(k.key, string(raw), seconds); err != nil {
+		log.Printf("⚠️ Failed to cache %s: %v", k.cache.name, err)
+		return
+	}
+	k.keepLocal(entry, string(raw))
+	publishInvalidation(k.key)
+	for _, tag := range k.cache.policy.Tags {
+		if err := RedisSetAdd(cacheTagKey(tag), k.key, int(cacheTagTTL.Seconds())); err != nil {
+			log.Printf("⚠️ Failed to tag %s with %s: %v", k.key, tag, err)
+		}
+	}
+}
+
+func cacheTagKey(tag string) string {
+	return "cache:tag:" + tag
+}
+
+// InvalidateCacheTags drops every entry cached under any of the tags and returns how many
+// keys were deleted
+func InvalidateCacheTags(tags ...string) (int, error) {
+	deleted := 0
+	var failed []string
+	for _, tag := range tags {
+		keys, err := RedisSetMembers(cacheTagKey(tag))
+		if err != nil {
+			failed = append(failed, tag)
+			continue
+		}
+		for _, key := range keys {
+			if err := RedisDelete(key); err == nil {
+				deleted++
+			}
+		}
+		if local := localTier(); local != nil {
+			local.Delete(context.Background(), keys...)
+		}
+		publishInvalidation(keys...)
+		RedisDelete(cacheTagKey(tag))
+		log.Printf("🧹 Invalidated %d cache entries tagged %s", len(keys), tag)
+	}
+	if len(failed) > 0 {
+		return deleted, fmt.Errorf("failed to invalidate tags: %s", strings.Join(failed, ", "))
+	}
+	return deleted, nil
+}
+
+// CacheReport describes the cache tiers and every cache's counters since startup
+func CacheReport() models.CacheReport {
+	return models.CacheReport{
+		Backend: CacheBackend().Name(),
+		Local:   localCacheMetrics(),
+		Caches:  CacheMetrics(),
+	}
+}
+
+// CacheMetrics reports every cache's counters since startup, ordered by name
+func CacheMetrics() []models.CacheMetrics {
+	cacheRegistryMu.Lock()
+	defer cacheRegistryMu.Unlock()
+
+	out := make([]models.CacheMetrics, 0, len(cacheRegistry))
+	for name, s := range cacheRegistry {
+		m := models.CacheMetrics{
+			Name:         name,
+			Hits:         atomic.LoadInt64(&s.hits),
+			StaleHits:    atomic.LoadInt64(&s.staleHits),
+			Misses:       atomic.LoadInt64(&s.misses),
+			NegativeHits: atomic.LoadInt64(&s.negativeHits),
+			Loads:        atomic.LoadInt64(&s.loads),
+			LoadErrors:   atomic.LoadInt64(&s.loadErrors),
+			Refreshes:    atomic.LoadInt64(&s.refreshes),
+			LocalHits:    atomic.LoadInt64(&s.localHits),
+			SharedReads:  atomic.LoadInt64(&s.sharedReads),
+		}
+		if lookups := m.Hits + m.StaleHits + m.NegativeHits + m.Misses; lookups > 0 {
+			m.HitRatio = float64(m.Hits+m.StaleHits+m.NegativeHits) / float64(lookups)
+		}
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryCache(2)
+	m.Set(ctx, "a", "1", 0)
+	m.Set(ctx, "b", "2", 0)
+	m.Get(ctx, "a") // b is now the least recently used
+	m.Set(ctx, "c", "3", 0)
+
+	if _, ok, _ := m.Get(ctx, "b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := m.Get(ctx, key); !ok {
+			t.Fatalf("expected %s to be kept", key)
+		}
+	}
+}
+
+func TestMemoryCacheExpiresKeysAndSets(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryCache(10)
+	m.Set(ctx, "short", "x", 20*time.Millisecond)
+	m.SetAdd(ctx, "tags", 20*time.Millisecond, "k1", "k2")
+	m.SetAdd(ctx, "tags", 0, "k2")
+
+	if n, _ := m.SetCount(ctx, "tags"); n != 2 {
+		t.Fatalf("expected 2 members, got %d", n)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, ok, _ := m.Get(ctx, "short"); ok {
+		t.Fatal("expected short to expire")
+	}
+	if members, _ := m.SetMembers(ctx, "tags"); len(members) != 0 {
+		t.Fatalf("expected the set to expire, got %v", members)
+	}
+}
+
+func TestMemoryCacheScanMatchesRedisGlobs(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryCache(10)
+	for _, key := range []string{"cache:stats.github:octocat", "cache:stats.npm:a/b", "cache:geocode:1", "other"} {
+		m.Set(ctx, key, "v", 0)
+	}
+
+	for pattern, want := range map[string][]string{
+		"cache:stats.*":        {"cache:stats.github:octocat", "cache:stats.npm:a/b"},
+		"cache:*:?":            {"cache:geocode:1"},
+		"cache:stats.[gn]*:a*": {"cache:stats.npm:a/b"},
+		"cache:stats.[^g]*":    {"cache:stats.npm:a/b"},
+		"*":                    {"cache:geocode:1", "cache:stats.github:octocat", "cache:stats.npm:a/b", "other"},
+	} {
+		got, _ := m.Scan(ctx, pattern)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Scan(%q) = %v, want %v", pattern, got, want)
+		}
+	}
+}
+
+func TestRESPReadsPipelinedReplies(t *testing.T) {
+	replies := "+OK\r\n$5\r\nhello\r\n$-1\r\n:3\r\n*2\r\n$1\r\n0\r\n*1\r\n$3\r\nkey\r\n-ERR wrong type\r\n"
+	rc := &respConn{r: bufio.NewReader(strings.NewReader(replies))}
+
+	want := []interface{}{"OK", "hello", nil, int64(3), []interface{}{"0", []interface{}{"key"}}, respError("ERR wrong type")}
+	for i, w := range want {
+		got, err := rc.read()
+		if err != nil {
+			t.Fatalf("reply %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Fatalf("reply %d = %#v, want %#v", i, got, w)
+		}
+	}
+}
+
+// Caches register by name, so they're created once per test binary
+var (
+	testSWRCache      = NewCached[string]("test.swr", CachePolicy{Fresh: 30 * time.Millisecond, Stale: time.Minute})
+	testNegativeCache = NewCached[int]("test.negative", CachePolicy{Fresh: time.Minute, NegativeTTL: time.Minute, Tags: []string{"test"}})
+)
+
+func TestCachedServesStaleWhileRefreshing(t *testing.T) {
+	useTestCache(t)
+	key := testSWRCache.Key("k")
+
+	var loads int32
+	load := func(ctx context.Context) (string, error) {
+		n := atomic.AddInt32(&loads, 1)
+		return strings.Repeat("v", int(n)), nil
+	}
+
+	if v, _ := key.Get(context.Background(), load); v != "v" {
+		t.Fatalf("expected the first load, got %q", v)
+	}
+	if v, _ := key.Get(context.Background(), load); v != "v" || atomic.LoadInt32(&loads) != 1 {
+		t.Fatalf("expected a hit, got %q after %d loads", v, loads)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if v, _ := key.Get(context.Background(), load); v != "v" {
+		t.Fatalf("expected the stale value, got %q", v)
+	}
+	waitFor(t, func() bool {
+		v, ok := key.Lookup()
+		return ok && v == "vv"
+	})
+}
+
+func TestCachedRemembersFailures(t *testing.T) {
+	useTestCache(t)
+	key := testNegativeCache.Key("k")
+
+	var loads int32
+	failing := func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&loads, 1)
+		return 0, &RateLimitError{Provider: "test"}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := key.Get(context.Background(), failing); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("call %d: expected a rate limit error, got %v", i, err)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("expected the failure to be remembered, got %d loads", loads)
+	}
+
+	// Invalidating the tag forgets the failure
+	if n, err := InvalidateCacheTags("test"); err != nil || n != 1 {
+		t.Fatalf("expected 1 invalidated entry, got %d (%v)", n, err)
+	}
+	v, err := key.Get(context.Background(), func(ctx context.Context) (int, error) { return 7, nil })
+	if err != nil || v != 7 {
+		t.Fatalf("expected a fresh load after invalidation, got %d (%v)", v, err)
+	}
+}
+
+// sharedTestCache is an in-memory backend that doesn't look like one, so Cached puts the
+// local tier in front of it, and counts reads
+type sharedTestCache struct {
+	*MemoryCache
+	reads int32
+}
+
+func (s *sharedTestCache) Get(ctx context.Context, key string) (string, bool, error) {
+	atomic.AddInt32(&s.reads, 1)
+	return s.MemoryCache.Get(ctx, key)
+}
+
+var testLocalCache = NewCached[string]("test.local", CachePolicy{Fresh: time.Minute})
+
+func TestCachedLocalTierAndInvalidation(t *testing.T) {
+	shared := &sharedTestCache{MemoryCache: NewMemoryCache(100)}
+	original := CacheBackend()
+	useCacheBackend(shared)
+	t.Cleanup(func() { useCacheBackend(original) })
+	localCache.Clear()
+
+	messages, err := shared.Subscribe(context.Background(), cacheInvalidationChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := testLocalCache.Key("k")
+	key.Set("v1")
+	if msg := <-messages; !strings.Contains(msg, key.String()) {
+		t.Fatalf("expected the write to publish an invalidation, got %q", msg)
+	}
+
+	for i := 0; i < 3; i++ {
+		if v, ok := key.Lookup(); !ok || v != "v1" {
+			t.Fatalf("expected v1, got %q", v)
+		}
+	}
+	if n := atomic.LoadInt32(&shared.reads); n != 0 {
+		t.Fatalf("expected the local tier to answer, got %d shared reads", n)
+	}
+
+	// Another replica writes the key: this one drops its copy and reads the new value
+	shared.MemoryCache.Set(context.Background(), key.String(), `{"v":"v2","at":"`+time.Now().Format(time.RFC3339Nano)+`"}`, 0)
+	handleInvalidation(`{"from":"other-replica","keys":["` + key.String() + `"]}`)
+	if v, ok := key.Lookup(); !ok || v != "v2" {
+		t.Fatalf("expected v2 after invalidation, got %q", v)
+	}
+	if n := atomic.LoadInt32(&shared.reads); n != 1 {
+		t.Fatalf("expected 1 shared read, got %d", n)
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+	"majesticcoding.com/api/models"
+)
+
+const (
+	bridgeRelayPrefix   = "[site]"
+	twitchMaxMessageLen = 500
+)
+
+// ChatBridgeConfig controls which messages cross between site chat and Twitch chat
+type ChatBridgeConfig struct {
+	Channel           string
+	IRC               TwitchIRCConfig
+	SiteToTwitch      bool
+	TwitchToSite      bool
+	AuthenticatedOnly bool // only relay signed-in site users to Twitch
+	RelayCommands     bool // relay messages starting with "!"
+	IgnoreUsers       map[string]bool
+}
+
+// ChatBridge relays messages between site chat and a Twitch channel
+type ChatBridge struct {
+	cfg     ChatBridgeConfig
+	client  *twitch.Client
+	token   func() (string, error)
+	toSite  func(models.TwitchMessage)
+	done    chan struct{}
+	mu      sync.Mutex // guards limiter
+	limiter *tokenBucket
+}
+
+var chatBridge *ChatBridge
+
+// LoadChatBridgeConfig reads bridge settings from the config. The bridge is off unless
+// CHAT_BRIDGE_ENABLED=true.
+func LoadChatBridgeConfig(channel string) (ChatBridgeConfig, bool) {
+	bridgeConf := conf().ChatBridge
+	direction := strings.ToLower(bridgeConf.Direction)
+
+	ignore := map[string]bool{"nightbot": true, "streamelements": true, "moobot": true}
+	for _, u := range bridgeConf.IgnoreUsers {
+		ignore[strings.ToLower(u)] = true
+	}
+
+	cfg := ChatBridgeConfig{
+		Channel:           strings.ToLower(channel),
+		IRC:               LoadTwitchIRCConfig(channel),
+		SiteToTwitch:      direction == "both" || direction == "site-to-twitch",
+		TwitchToSite:      direction == "both" || direction == "twitch-to-site",
+		AuthenticatedOnly: bridgeConf.AuthOnly,
+		RelayCommands:     bridgeConf.RelayCommands,
+		IgnoreUsers:       ignore,
+	}
+
+	return cfg, bridgeConf.Enabled
+}
+
+// NewChatBridge creates a bridge that logs into Twitch chat with the access token from token,
+// fetched again on every login. toSite is called for every Twitch message that should appear
+// in site chat.
+func NewChatBridge(cfg ChatBridgeConfig, token func() (string, error), toSite func(models.TwitchMessage)) *ChatBridge {
+	// Twitch allows 20 messages per 30 seconds for regular chatters
+	return &ChatBridge{
+		cfg:     cfg,
+		client:  NewAuthenticatedTwitchClient(cfg.IRC, ""),
+		token:   token,
+		toSite:  toSite,
+		done:    make(chan struct{}),
+		limiter: newTokenBucket(20.0/30.0, 10, time.Now()),
+	}
+}
+
+// StartChatBridge starts the bridge for channel using the stored Twitch user token
+func StartChatBridge(channel string, toSite func(models.TwitchMessage)) error {
+	cfg, enabled := LoadChatBridgeConfig(channel)
+	if !enabled {
+		log.Println("ℹ️ Chat bridge disabled (set CHAT_BRIDGE_ENABLED=true to relay site chat and Twitch chat)")
+		return nil
+	}
+
+	chatBridge = NewChatBridge(cfg, getTwitchUserToken, toSite)
+	chatBridge.Start()
+	return nil
+}
+
+// RelaySiteChatToTwitch forwards a site chat message to Twitch when the bridge is running
+func RelaySiteChatToTwitch(username, content string, authenticated bool) {
+	if chatBridge != nil {
+		chatBridge.RelayToTwitch(username, content, authenticated)
+	}
+}
+
+// Start joins the channel and connects in the background
+func (b *ChatBridge) Start() {
+	b.client.OnPrivateMessage(b.handleTwitchMessage)
+	b.client.OnConnect(func() {
+		log.Printf("🌉 Chat bridge connected to #%s as %s", b.cfg.Channel, b.cfg.IRC.Login)
+	})
+	b.client.Join(b.cfg.Channel)
+
+	go connectTwitchIRC(b.client, "Chat bridge", b.token, b.done)
+}
+
+// Stop disconnects from Twitch chat
+func (b *ChatBridge) Stop() {
+	close(b.done)
+	if err := b.client.Disconnect(); err != nil {
+		log.Printf("⚠️ Chat bridge disconnect: %v", err)
+	}
+}
+
+// RelayToTwitch sends a site chat message to Twitch, returning false if it was filtered
+func (b *ChatBridge) RelayToTwitch(username, content string, authenticated bool) bool {
+	if !b.cfg.SiteToTwitch {
+		return false
+	}
+	if b.cfg.AuthenticatedOnly && !authenticated {
+		return false
+	}
+
+	content = strings.TrimSpace(content)
+	if content == "" || (!b.cfg.RelayCommands && strings.HasPrefix(content, "!")) {
+		return false
+	}
+
+	name := strings.TrimSpace(strings.TrimPrefix(username, "✓ "))
+	text := fmt.Sprintf("%s %s: %s", bridgeRelayPrefix, name, content)
+	if runes := []rune(text); len(runes) > twitchMaxMessageLen {
+		text = string(runes[:twitchMaxMessageLen])
+	}
+
+	b.mu.Lock()
+	ok, _ := b.limiter.take(time.Now())
+	b.mu.Unlock()
+	if !ok {
+		log.Printf("⏳ Chat bridge rate limited, dropping message from %s", name)
+		return false
+	}
+
+	b.client.Say(b.cfg.Channel, text)
+	return true
+}
+
+func (b *ChatBridge) handleTwitchMessage(msg twitch.PrivateMessage) {
+	if !b.cfg.TwitchToSite || b.toSite == nil {
+		return
+	}
+
+	if b.cfg.IgnoreUsers[strings.ToLower(msg.User.Name)] {
+		return
+	}
+
+	// Everything the bridge sends starts with the relay prefix, which is enough to stop loops.
+	// Filtering by login would drop the broadcaster, since the bridge usually logs in as them,
+	// and matching on text would drop viewers who happen to say the same thing.
+	content := strings.TrimSpace(msg.Message)
+	if strings.HasPrefix(content, bridgeRelayPrefix) {
+		return
+	}
+	if !b.cfg.RelayCommands && strings.HasPrefix(content, "!") {
+		return
+	}
+
+	b.toSite(toTwitchMessage(msg))
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+// fakeIRCServer speaks just enough of Twitch IRC for a single client
+type fakeIRCServer struct {
+	ln       net.Listener
+	conn     chan net.Conn
+	received chan string
+	joined   chan struct{}
+}
+
+func newFakeIRCServer(t *testing.T) *fakeIRCServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	s := &fakeIRCServer{
+		ln:       ln,
+		conn:     make(chan net.Conn, 1),
+		received: make(chan string, 32),
+		joined:   make(chan struct{}, 1),
+	}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeIRCServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle speaks to one client, rejecting the token "expired" like Twitch rejects a stale one
+func (s *fakeIRCServer) handle(conn net.Conn) {
+	var nick string
+	rejected := false
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case rejected:
+		case line == "PASS oauth:expired":
+			rejected = true
+			fmt.Fprintf(conn, ":tmi.twitch.tv NOTICE * :Login authentication failed\r\n")
+		case strings.HasPrefix(line, "NICK "):
+			s.conn <- conn
+			nick = strings.TrimPrefix(line, "NICK ")
+			fmt.Fprintf(conn, ":tmi.twitch.tv 001 %s :Welcome, GLHF!\r\n", nick)
+		case strings.HasPrefix(line, "JOIN "):
+			channel := strings.TrimPrefix(line, "JOIN ")
+			fmt.Fprintf(conn, ":%s!%s@%s.tmi.twitch.tv JOIN %s\r\n", nick, nick, nick, channel)
+			s.joined <- struct{}{}
+		case strings.HasPrefix(line, "PING"):
+			fmt.Fprintf(conn, "PONG :tmi.twitch.tv\r\n")
+		}
+		s.received <- line
+	}
+}
+
+func (s *fakeIRCServer) sendPrivmsg(conn net.Conn, login, channel, text string) {
+	fmt.Fprintf(conn, "@badges=;color=#FF0000;display-name=%s;id=%d;mod=0;room-id=1;subscriber=0;tmi-sent-ts=%d;user-id=2 :%s!%s@%s.tmi.twitch.tv PRIVMSG #%s :%s\r\n",
+		login, time.Now().UnixNano(), time.Now().UnixMilli(), login, login, login, channel, text)
+}
+
+func (s *fakeIRCServer) waitFor(t *testing.T, prefix string) string {
+	t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case line := <-s.received:
+			if strings.HasPrefix(line, prefix) {
+				return line
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %q", prefix)
+		}
+	}
+}
+
+func TestChatBridgeRelaysBothDirections(t *testing.T) {
+	server := newFakeIRCServer(t)
+
+	cfg := ChatBridgeConfig{
+		Channel:           "majestic",
+		IRC:               TwitchIRCConfig{Login: "bridgebot", Address: server.ln.Addr().String(), TLS: false},
+		SiteToTwitch:      true,
+		TwitchToSite:      true,
+		AuthenticatedOnly: true,
+		IgnoreUsers:       map[string]bool{"nightbot": true},
+	}
+
+	toSite := make(chan models.TwitchMessage, 8)
+	// The token is fetched for every login, so an expired one is replaced on the retry
+	tokens := []string{"expired", "secret"}
+	token := func() (string, error) {
+		t := tokens[0]
+		if len(tokens) > 1 {
+			tokens = tokens[1:]
+		}
+		return t, nil
+	}
+	bridge := NewChatBridge(cfg, token, func(m models.TwitchMessage) { toSite <- m })
+	bridge.Start()
+	defer bridge.Stop()
+
+	if pass := server.waitFor(t, "PASS "); pass != "PASS oauth:expired" {
+		t.Fatalf("unexpected login %q", pass)
+	}
+	if pass := server.waitFor(t, "PASS "); pass != "PASS oauth:secret" {
+		t.Fatalf("expected a login with a fresh token, got %q", pass)
+	}
+	conn := <-server.conn
+	select {
+	case <-server.joined:
+	case <-time.After(3 * time.Second):
+		t.Fatal("bridge never joined the channel")
+	}
+
+	// Site -> Twitch: anonymous users are filtered, signed-in users relayed
+	if bridge.RelayToTwitch("quiet_turing_01", "hi from anon", false) {
+		t.Fatal("anonymous message should not be relayed")
+	}
+	if !bridge.RelayToTwitch("✓ alice", "hello twitch", true) {
+		t.Fatal("authenticated message should be relayed")
+	}
+	if got := server.waitFor(t, "PRIVMSG"); got != "PRIVMSG #majestic :[site] alice: hello twitch" {
+		t.Fatalf("unexpected relay %q", got)
+	}
+
+	// Twitch -> site: the bridge's own echo and ignored bots are dropped, but the broadcaster
+	// the bridge logs in as and a viewer saying the same text as a relayed message are not
+	server.sendPrivmsg(conn, "bridgebot", "majestic", "[site] alice: hello twitch")
+	server.sendPrivmsg(conn, "nightbot", "majestic", "Follow the channel!")
+	server.sendPrivmsg(conn, "bridgebot", "majestic", "welcome in everyone")
+	server.sendPrivmsg(conn, "someone", "majestic", "hello twitch")
+	server.sendPrivmsg(conn, "viewer", "majestic", "hi site chat")
+
+	for _, want := range []models.TwitchMessage{
+		{Username: "bridgebot", Message: "welcome in everyone"},
+		{Username: "someone", Message: "hello twitch"},
+		{Username: "viewer", Message: "hi site chat"},
+	} {
+		select {
+		case m := <-toSite:
+			if m.Username != want.Username || m.Message != want.Message {
+				t.Fatalf("relayed %s: %q, want %s: %q", m.Username, m.Message, want.Username, want.Message)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("%s's message was not relayed to site chat", want.Username)
+		}
+	}
+
+	select {
+	case m := <-toSite:
+		t.Fatalf("unexpected extra message relayed to site: %+v", m)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChatLimitConfig controls rate limiting and spam detection for site chat
+type ChatLimitConfig struct {
+	MaxMessageLength  int
+	ConnRate          float64 // messages per second per connection
+	ConnBurst         int
+	IdentityRate      float64 // messages per second per user/IP across connections
+	IdentityBurst     int
+	DuplicateWindow   time.Duration
+	MaxDuplicates     int
+	MaxLinks          int
+	StrikeWindow      time.Duration
+	StrikesBeforeMute int
+	MuteDuration      time.Duration
+}
+
+// Violation codes reported back to chat clients
+const (
+	ChatViolationEmpty     = "empty_message"
+	ChatViolationTooLong   = "message_too_long"
+	ChatViolationRate      = "rate_limited"
+	ChatViolationDuplicate = "duplicate_message"
+	ChatViolationLinks     = "link_spam"
+	ChatViolationMuted     = "muted"
+)
+
+// ChatViolation describes why a chat message was rejected
+type ChatViolation struct {
+	Code       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (v *ChatViolation) Error() string {
+	return fmt.Sprintf("%s: %s", v.Code, v.Message)
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  float64
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{tokens: float64(burst), last: now, rate: rate, burst: float64(burst)}
+}
+
+// take consumes one token, returning how long to wait when none are available
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	if b.rate <= 0 {
+		return false, time.Minute
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	return false, wait
+}
+
+type recentChatMessage struct {
+	content string
+	at      time.Time
+}
+
+type chatIdentityState struct {
+	bucket     *tokenBucket
+	recent     []recentChatMessage
+	strikes    []time.Time
+	mutedUntil time.Time
+	lastSeen   time.Time
+}
+
+// ChatConnectionLimiter holds the per-connection token bucket
+type ChatConnectionLimiter struct {
+	bucket *tokenBucket
+}
+
+// ChatModerator applies rate limits and spam heuristics to incoming chat messages
+type ChatModerator struct {
+	mu         sync.Mutex
+	cfg        ChatLimitConfig
+	identities map[string]*chatIdentityState
+	now        func() time.Time
+}
+
+var (
+	chatModerator     *ChatModerator
+	chatModeratorOnce sync.Once
+
+	linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+|\b[a-z0-9-]+\.(com|net|org|io|gg|ru|xyz|ly|co)\b`)
+)
+
+// DefaultChatLimitConfig returns the built-in chat limits
+func DefaultChatLimitConfig() ChatLimitConfig {
+	return ChatLimitConfig{
+		MaxMessageLength:  500,
+		ConnRate:          1,
+		ConnBurst:         5,
+		IdentityRate:      1.5,
+		IdentityBurst:     8,
+		DuplicateWindow:   30 * time.Second,
+		MaxDuplicates:     2,
+		MaxLinks:          2,
+		StrikeWindow:      2 * time.Minute,
+		StrikesBeforeMute: 3,
+		MuteDuration:      5 * time.Minute,
+	}
+}
+
+// LoadChatLimitConfig reads chat limits from the config
+func LoadChatLimitConfig() ChatLimitConfig {
+	chat := conf().Chat
+	cfg := DefaultChatLimitConfig()
+	cfg.MaxMessageLength = chat.MaxMessageLength
+	cfg.ConnRate = chat.ConnRate
+	cfg.ConnBurst = chat.ConnBurst
+	cfg.IdentityRate = chat.IdentityRate
+	cfg.IdentityBurst = chat.IdentityBurst
+	cfg.MaxDuplicates = chat.MaxDuplicates
+	cfg.MaxLinks = chat.MaxLinks
+	cfg.StrikesBeforeMute = chat.StrikesBeforeMute
+	cfg.MuteDuration = time.Duration(chat.MuteSeconds) * time.Second
+	return cfg
+}
+
+// NewChatModerator creates a moderator with the given limits
+func NewChatModerator(cfg ChatLimitConfig) *ChatModerator {
+	return &ChatModerator{
+		cfg:        cfg,
+		identities: make(map[string]*chatIdentityState),
+		now:        time.Now,
+	}
+}
+
+// GetChatModerator returns the shared moderator, loading limits from env on first use
+func GetChatModerator() *ChatModerator {
+	chatModeratorOnce.Do(func() {
+		chatModerator = NewChatModerator(LoadChatLimitConfig())
+	})
+	return chatModerator
+}
+
+// Config returns the active limits
+func (m *ChatModerator) Config() ChatLimitConfig {
+	return m.cfg
+}
+
+// NewConnection returns a limiter for a single WebSocket connection
+func (m *ChatModerator) NewConnection() *ChatConnectionLimiter {
+	return &ChatConnectionLimiter{bucket: newTokenBucket(m.cfg.ConnRate, m.cfg.ConnBurst, m.now())}
+}
+
+// Check validates a message from identity on conn. A nil result means the message may be sent.
+func (m *ChatModerator) Check(conn *ChatConnectionLimiter, identity, content string) *ChatViolation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	state := m.identity(identity, now)
+
+	if now.Before(state.mutedUntil) {
+		wait := state.mutedUntil.Sub(now)
+		return &ChatViolation{
+			Code:       ChatViolationMuted,
+			Message:    fmt.Sprintf("You are muted for %s", wait.Round(time.Second)),
+			RetryAfter: wait,
+		}
+	}
+
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return &ChatViolation{Code: ChatViolationEmpty, Message: "Message is empty"}
+	}
+
+	if m.cfg.MaxMessageLength > 0 && len([]rune(trimmed)) > m.cfg.MaxMessageLength {
+		return m.strike(state, now, &ChatViolation{
+			Code:    ChatViolationTooLong,
+			Message: fmt.Sprintf("Messages are limited to %d characters", m.cfg.MaxMessageLength),
+		})
+	}
+
+	if conn != nil {
+		if ok, wait := conn.bucket.take(now); !ok {
+			return m.strike(state, now, &ChatViolation{
+				Code:       ChatViolationRate,
+				Message:    "You are sending messages too quickly",
+				RetryAfter: wait,
+			})
+		}
+	}
+
+	if ok, wait := state.bucket.take(now); !ok {
+		return m.strike(state, now, &ChatViolation{
+			Code:       ChatViolationRate,
+			Message:    "You are sending messages too quickly",
+			RetryAfter: wait,
+		})
+	}
+
+	normalized := strings.ToLower(strings.Join(strings.Fields(trimmed), " "))
+	cutoff := now.Add(-m.cfg.DuplicateWindow)
+	kept := state.recent[:0]
+	duplicates := 0
+	for _, r := range state.recent {
+		if r.at.After(cutoff) {
+			kept = append(kept, r)
+			if r.content == normalized {
+				duplicates++
+			}
+		}
+	}
+	state.recent = kept
+
+	if m.cfg.MaxDuplicates > 0 && duplicates >= m.cfg.MaxDuplicates {
+		return m.strike(state, now, &ChatViolation{
+			Code:       ChatViolationDuplicate,
+			Message:    "Please don't repeat the same message",
+			RetryAfter: m.cfg.DuplicateWindow,
+		})
+	}
+
+	if m.cfg.MaxLinks >= 0 && len(linkPattern.FindAllString(trimmed, -1)) > m.cfg.MaxLinks {
+		return m.strike(state, now, &ChatViolation{
+			Code:    ChatViolationLinks,
+			Message: fmt.Sprintf("Messages may contain at most %d links", m.cfg.MaxLinks),
+		})
+	}
+
+	state.recent = append(state.recent, recentChatMessage{content: normalized, at: now})
+	return nil
+}
+
+// Allow applies only the mute and rate limits, for frames without message content
+// such as reactions
+func (m *ChatModerator) Allow(conn *ChatConnectionLimiter, identity string) *ChatViolation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	state := m.identity(identity, now)
+
+	if now.Before(state.mutedUntil) {
+		wait := state.mutedUntil.Sub(now)
+		return &ChatViolation{
+			Code:       ChatViolationMuted,
+			Message:    fmt.Sprintf("You are muted for %s", wait.Round(time.Second)),
+			RetryAfter: wait,
+		}
+	}
+
+	if conn != nil {
+		if ok, wait := conn.bucket.take(now); !ok {
+			return m.strike(state, now, &ChatViolation{
+				Code:       ChatViolationRate,
+				Message:    "You are sending messages too quickly",
+				RetryAfter: wait,
+			})
+		}
+	}
+
+	return nil
+}
+
+// IsMuted reports whether identity is currently muted
+func (m *ChatModerator) IsMuted(identity string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.identities[identity]
+	return ok && m.now().Before(state.mutedUntil)
+}
+
+// Prune drops identities that have been idle longer than maxIdle
+func (m *ChatModerator) Prune(maxIdle time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	for id, state := range m.identities {
+		if now.Sub(state.lastSeen) > maxIdle && now.After(state.mutedUntil) {
+			delete(m.identities, id)
+		}
+	}
+}
+
+func (m *ChatModerator) identity(identity string, now time.Time) *chatIdentityState {
+	state, ok := m.identities[identity]
+	if !ok {
+		state = &chatIdentityState{bucket: newTokenBucket(m.cfg.IdentityRate, m.cfg.IdentityBurst, now)}
+		m.identities[identity] = state
+	}
+	state.lastSeen = now
+	return state
+}
+
+// strike records a violation and mutes the identity once the threshold trips
+func (m *ChatModerator) strike(state *chatIdentityState, now time.Time, v *ChatViolation) *ChatViolation {
+	cutoff := now.Add(-m.cfg.StrikeWindow)
+	kept := state.strikes[:0]
+	for _, t := range state.strikes {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	state.strikes = append(kept, now)
+
+	if m.cfg.StrikesBeforeMute > 0 && len(state.strikes) >= m.cfg.StrikesBeforeMute {
+		state.mutedUntil = now.Add(m.cfg.MuteDuration)
+		state.strikes = nil
+		return &ChatViolation{
+			Code:       ChatViolationMuted,
+			Message:    fmt.Sprintf("%s. You have been muted for %s", v.Message, m.cfg.MuteDuration),
+			RetryAfter: m.cfg.MuteDuration,
+		}
+	}
+
+	return v
+}
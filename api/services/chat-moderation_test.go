@@ -0,0 +1,146 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChatModeratorCheck(t *testing.T) {
+	type step struct {
+		advance time.Duration // moved on the clock before the message
+		conn    int           // which of the identity's connections sends it
+		content string
+		want    string // violation code, "" when allowed
+	}
+
+	tests := []struct {
+		name  string
+		tweak func(*ChatLimitConfig)
+		steps []step
+	}{
+		{
+			name:  "connection rate limit refills",
+			tweak: func(c *ChatLimitConfig) { c.ConnRate, c.ConnBurst = 1, 2 },
+			steps: []step{
+				{content: "one"},
+				{content: "two"},
+				{content: "three", want: ChatViolationRate},
+				{conn: 1, content: "other tab"},
+				{advance: time.Second, content: "four"},
+			},
+		},
+		{
+			name:  "identity rate limit spans connections",
+			tweak: func(c *ChatLimitConfig) { c.IdentityRate, c.IdentityBurst = 1, 2 },
+			steps: []step{
+				{conn: 0, content: "one"},
+				{conn: 1, content: "two"},
+				{conn: 2, content: "three", want: ChatViolationRate},
+				{advance: time.Second, conn: 2, content: "four"},
+			},
+		},
+		{
+			name: "duplicates within the window",
+			steps: []step{
+				{content: "hello there"},
+				{content: "  HELLO   there "},
+				{content: "hello there", want: ChatViolationDuplicate},
+				{content: "something else"},
+				{advance: 31 * time.Second, content: "hello there"},
+			},
+		},
+		{
+			name:  "link spam",
+			tweak: func(c *ChatLimitConfig) { c.MaxLinks = 1 },
+			steps: []step{
+				{content: "see https://example.com/page"},
+				{content: "example.com and www.test.org", want: ChatViolationLinks},
+				{content: "no links in this one"},
+			},
+		},
+		{
+			name:  "empty and too long",
+			tweak: func(c *ChatLimitConfig) { c.MaxMessageLength = 5 },
+			steps: []step{
+				{content: "   ", want: ChatViolationEmpty},
+				{content: "ééééé"},
+				{content: "toolong", want: ChatViolationTooLong},
+			},
+		},
+		{
+			name:  "strikes mute until the mute expires",
+			tweak: func(c *ChatLimitConfig) { c.MaxLinks = 0 },
+			steps: []step{
+				{content: "a.com", want: ChatViolationLinks},
+				{content: "b.com", want: ChatViolationLinks},
+				{content: "c.com", want: ChatViolationMuted},
+				{content: "hello", want: ChatViolationMuted},
+				{advance: 5 * time.Minute, content: "hello"},
+			},
+		},
+		{
+			name:  "strikes outside the window are forgotten",
+			tweak: func(c *ChatLimitConfig) { c.MaxLinks = 0 },
+			steps: []step{
+				{content: "a.com", want: ChatViolationLinks},
+				{content: "b.com", want: ChatViolationLinks},
+				{advance: 2*time.Minute + time.Second, content: "c.com", want: ChatViolationLinks},
+				{content: "still allowed"},
+			},
+		},
+		{
+			name: "empty messages aren't strikes",
+			steps: []step{
+				{content: "", want: ChatViolationEmpty},
+				{content: "", want: ChatViolationEmpty},
+				{content: "", want: ChatViolationEmpty},
+				{content: "hi"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultChatLimitConfig()
+			cfg.ConnRate, cfg.ConnBurst = 100, 100
+			cfg.IdentityRate, cfg.IdentityBurst = 100, 100
+			if tt.tweak != nil {
+				tt.tweak(&cfg)
+			}
+
+			now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+			m := NewChatModerator(cfg)
+			m.now = func() time.Time { return now }
+			conns := []*ChatConnectionLimiter{m.NewConnection(), m.NewConnection(), m.NewConnection()}
+
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+				got := ""
+				if v := m.Check(conns[s.conn], "user:alice", s.content); v != nil {
+					got = v.Code
+				}
+				if got != s.want {
+					t.Fatalf("step %d (%q): got %q, want %q", i, s.content, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestChatLinkPattern(t *testing.T) {
+	tests := map[string]int{
+		"no links here":                     0,
+		"https://example.com and http://x":  2,
+		"visit www.example.org/page today":  1,
+		"twitch.tv isn't a listed tld":      0,
+		"discord.gg/abc or scam.xyz":        2,
+		"version 1.2.3 and e.g. this":       0,
+		strings.Repeat("a.com ", 3) + "end": 3,
+	}
+	for text, want := range tests {
+		if got := len(linkPattern.FindAllString(text, -1)); got != want {
+			t.Errorf("%q: %d links, want %d", text, got, want)
+		}
+	}
+}
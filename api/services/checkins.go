@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/db"
+)
+
+// ErrCheckinLocation is returned when a checkin has no usable location
+var ErrCheckinLocation = errors.New("could not resolve location")
+
+// CheckinRequest is a request to put a viewer on the globe
+type CheckinRequest struct {
+	Query    string // free-text place, geocoded when Lat/Lon are not set
+	Lat, Lon *float64
+	City     string
+	Country  string
+	Username string
+	Platform string // "twitch" or "site"
+	Channel  string // Twitch channel used to find the current stream, for dedupe
+}
+
+// CheckinResult describes a stored (or previously stored) checkin
+type CheckinResult struct {
+	Checkin   models.Checkin
+	Place     string
+	Duplicate bool // the user already checked in during this stream
+}
+
+// geocodeCache holds geocoding results for a day. Failures are remembered briefly so a bad
+// place name typed repeatedly in chat costs one API call.
+var geocodeCache = NewCached[*models.GeocodeResult]("geocode", CachePolicy{
+	Fresh:       24 * time.Hour,
+	Stale:       7 * 24 * time.Hour,
+	NegativeTTL: time.Minute,
+	Tags:        []string{"geocode"},
+})
+
+// recentCheckinsCache holds the globe's last 8 hours of checkins. New checkins refresh it.
+var recentCheckinsCache = NewCached[[]models.Checkin]("checkins.recent", CachePolicy{
+	Fresh: 5 * time.Minute,
+	Stale: 10 * time.Minute,
+	Tags:  []string{"checkins"},
+})
+
+// GeocodeCached geocodes a query through the geocode cache
+func GeocodeCached(ctx context.Context, q string) (*models.GeocodeResult, error) {
+	// Hash the query to avoid special characters in the key
+	queryHash := fmt.Sprintf("%x", md5.Sum([]byte(strings.ToLower(q))))
+	return geocodeCache.Key(queryHash).Get(ctx, func(ctx context.Context) (*models.GeocodeResult, error) {
+		return Geocode(ctx, q)
+	})
+}
+
+// CreateCheckin resolves the place, dedupes per user per stream and stores the checkin
+func CreateCheckin(ctx context.Context, req CheckinRequest) (*CheckinResult, error) {
+	database := db.GetDB()
+	if database == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+
+	checkin := models.Checkin{
+		City:     strings.TrimSpace(req.City),
+		Country:  strings.TrimSpace(req.Country),
+		Username: strings.TrimSpace(req.Username),
+		Platform: req.Platform,
+	}
+
+	if checkin.Username != "" && checkin.Platform != "" {
+		checkin.StreamKey = currentStreamKey(req.Channel)
+		existing, err := db.GetStreamCheckin(database, checkin.Username, checkin.Platform, checkin.StreamKey)
+		if err == nil {
+			return &CheckinResult{Checkin: existing, Place: checkinPlace(existing), Duplicate: true}, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to look up previous checkin: %w", err)
+		}
+	}
+
+	if req.Lat != nil && req.Lon != nil {
+		checkin.Lat, checkin.Lon = *req.Lat, *req.Lon
+	} else {
+		query := strings.TrimSpace(req.Query)
+		if query == "" {
+			query = strings.TrimSpace(strings.Join([]string{checkin.City, checkin.Country}, " "))
+		}
+		if query == "" {
+			return nil, fmt.Errorf("%w: missing location", ErrCheckinLocation)
+		}
+
+		res, err := GeocodeCached(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCheckinLocation, err)
+		}
+		checkin.Lat, checkin.Lon = res.Location.Lat, res.Location.Lng
+		checkin.City = res.Components.City
+		if checkin.City == "" {
+			checkin.City = res.Formatted
+		}
+		checkin.Country = res.Components.Country
+	}
+
+	stored, err := db.InsertCheckin(database, checkin)
+	if errors.Is(err, sql.ErrNoRows) {
+		// A concurrent checkin for the same stream won the insert
+		existing, err := db.GetStreamCheckin(database, checkin.Username, checkin.Platform, checkin.StreamKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up previous checkin: %w", err)
+		}
+		return &CheckinResult{Checkin: existing, Place: checkinPlace(existing), Duplicate: true}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save checkin: %w", err)
+	}
+	log.Printf("📍 New checkin saved for %s from %s (%s)", stored.City, stored.Username, stored.Platform)
+	PublishEvent(TopicCheckin, stored)
+
+	RefreshRecentCheckinsCache()
+	return &CheckinResult{Checkin: stored, Place: checkinPlace(stored)}, nil
+}
+
+// RecentCheckins returns the last 8 hours of checkins through the cache
+func RecentCheckins(ctx context.Context) ([]models.Checkin, error) {
+	return recentCheckinsCache.Key("8h").Get(ctx, loadRecentCheckins)
+}
+
+// RefreshRecentCheckinsCache reloads the last 8 hours of checkins into the cache
+func RefreshRecentCheckinsCache() {
+	key := recentCheckinsCache.Key("8h")
+	if _, err := key.Refresh(context.Background(), loadRecentCheckins); err != nil {
+		log.Printf("⚠️ Failed to get recent checkins after insert: %v", err)
+		// Drop the entry so the next read reloads rather than serving it stale
+		key.Delete()
+	}
+}
+
+func loadRecentCheckins(ctx context.Context) ([]models.Checkin, error) {
+	database := db.GetDB()
+	if database == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	return db.GetRecentCheckins(database, 8) // Last 8 hours
+}
+
+// currentStreamKey identifies the live stream on channel. When offline, or the
+// stream can't be looked up, checkins are deduped per UTC day instead.
+func currentStreamKey(channel string) string {
+	if channel != "" {
+		startedAt, live, err := FetchTwitchStreamStart(channel)
+		if err != nil {
+			log.Printf("⚠️ Could not look up stream for checkin dedupe: %v", err)
+		} else if live {
+			return "stream:" + startedAt.UTC().Format(time.RFC3339)
+		}
+	}
+	return "day:" + time.Now().UTC().Format("2006-01-02")
+}
+
+func checkinPlace(c models.Checkin) string {
+	if c.Country != "" && c.Country != c.City && !strings.Contains(c.City, c.Country) {
+		return c.City + ", " + c.Country
+	}
+	return c.City
+}
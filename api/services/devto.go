@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+// devToMaxPages caps how many pages of 1000 articles a poll reads
+const devToMaxPages = 5
+
+type devToStatsProvider struct{}
+
+func (devToStatsProvider) Name() string                    { return "devto" }
+func (devToStatsProvider) Account(p models.Profile) string { return profileAccount(p, "devto") }
+func (devToStatsProvider) DefaultInterval() time.Duration  { return 30 * time.Minute }
+func (devToStatsProvider) NewDetails() interface{}         { return nil }
+
+// Fetch totals the reactions and comments on a user's published articles. Dev.to doesn't
+// expose follower counts or page views publicly.
+func (devToStatsProvider) Fetch(ctx context.Context, username string) (*models.StatsSnapshot, error) {
+	var user struct {
+		Name     string `json:"name"`
+		Username string `json:"username"`
+	}
+	if err := getStatsJSON(ctx, "devto", "https://dev.to/api/users/by_username?url="+url.QueryEscape(username), nil, &user); err != nil {
+		return nil, err
+	}
+
+	var articles, reactions, comments int64
+	for page := 1; page <= devToMaxPages; page++ {
+		var batch []struct {
+			PublicReactionsCount int64 `json:"public_reactions_count"`
+			CommentsCount        int64 `json:"comments_count"`
+		}
+		endpoint := fmt.Sprintf("https://dev.to/api/articles?username=%s&per_page=1000&page=%d", url.QueryEscape(username), page)
+		if err := getStatsJSON(ctx, "devto", endpoint, nil, &batch); err != nil {
+			return nil, fmt.Errorf("articles: %w", err)
+		}
+		for _, a := range batch {
+			articles++
+			reactions += a.PublicReactionsCount
+			comments += a.CommentsCount
+		}
+		if len(batch) < 1000 {
+			break
+		}
+	}
+
+	displayName := user.Name
+	if displayName == "" {
+		displayName = user.Username
+	}
+	return &models.StatsSnapshot{
+		DisplayName: displayName,
+		URL:         "https://dev.to/" + user.Username,
+		Metrics: map[string]int64{
+			"articles":  articles,
+			"reactions": reactions,
+			"comments":  comments,
+		},
+	}, nil
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -30,7 +29,7 @@ type GeminiEmbeddingResponse struct {
 
 // GenerateEmbedding creates an embedding for the given text using Gemini
 func GenerateEmbedding(text string) ([]float64, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
+	apiKey := conf().AI.GeminiAPIKey
 	if apiKey == "" {
 		return nil, fmt.Errorf("GEMINI_API_KEY not found")
 	}
@@ -170,8 +169,17 @@ func StoreWebsiteContext(contentType, title, content, sourceURL string, metadata
 	return nil
 }
 
-// StoreSocialStatsContext stores social media stats as website context
-func StoreSocialStatsContext(stats *models.UnifiedStats) error {
+// socialStatsTitle keeps each profile's stats in their own context rows. The default
+// profile keeps the original titles so existing rows are updated in place.
+func socialStatsTitle(profile models.Profile, title string) string {
+	if profile.Default {
+		return title
+	}
+	return fmt.Sprintf("%s (%s)", title, profile.Name)
+}
+
+// StoreSocialStatsContext stores a profile's social media stats as website context
+func StoreSocialStatsContext(profile models.Profile, stats *models.UnifiedStats) error {
 	contexts := ConvertStatsToText(stats)
 
 	for i, contextText := range contexts {
@@ -210,6 +218,7 @@ func StoreSocialStatsContext(stats *models.UnifiedStats) error {
 		}
 
 		// Store with high priority (3) for current stats
+		title = socialStatsTitle(profile, title)
 		if err := StoreWebsiteContext(contentType, title, contextText, "", metadata, 3); err != nil {
 			fmt.Printf("Failed to store %s: %v\n", title, err)
 		}
@@ -218,8 +227,8 @@ func StoreSocialStatsContext(stats *models.UnifiedStats) error {
 	return nil
 }
 
-// StoreLatestSocialStatsContextFromDB fetches latest stats rows and stores them as context.
-func StoreLatestSocialStatsContextFromDB() error {
+// StoreLatestSocialStatsContextFromDB fetches a profile's latest stats rows and stores them as context.
+func StoreLatestSocialStatsContextFromDB(profile models.Profile) error {
 	database := db.GetDB()
 	if database == nil {
 		return fmt.Errorf("database not available")
@@ -227,48 +236,48 @@ func StoreLatestSocialStatsContextFromDB() error {
 
 	var failures []string
 
-	if stats, err := db.GetLatestYouTubeStats(database); err == nil {
+	if stats, err := db.GetLatestYouTubeStats(database, profile.YouTubeChannelID); err == nil {
 		content := fmt.Sprintf(
 			"YouTube Channel: %s has %d subscribers, %d total views across %d videos.",
 			stats.ChannelName, stats.Subscribers, stats.Views, stats.Videos,
 		)
-		if err := StoreWebsiteContext("social_stats", "YouTube Channel Stats", content, "", stats, 3); err != nil {
+		if err := StoreWebsiteContext("social_stats", socialStatsTitle(profile, "YouTube Channel Stats"), content, "", stats, 3); err != nil {
 			failures = append(failures, fmt.Sprintf("youtube: %v", err))
 		}
 	} else {
 		failures = append(failures, fmt.Sprintf("youtube: %v", err))
 	}
 
-	if stats, err := db.GetLatestGitHubStats(database); err == nil {
+	if stats, err := db.GetLatestGitHubStats(database, profile.GitHubUser); err == nil {
 		content := fmt.Sprintf(
 			"GitHub Profile: %s has %d public repositories, %d followers, and %d total stars.",
 			stats.Username, stats.PublicRepos, stats.Followers, stats.StarsReceived,
 		)
-		if err := StoreWebsiteContext("social_stats", "GitHub Profile Stats", content, "", stats, 3); err != nil {
+		if err := StoreWebsiteContext("social_stats", socialStatsTitle(profile, "GitHub Profile Stats"), content, "", stats, 3); err != nil {
 			failures = append(failures, fmt.Sprintf("github: %v", err))
 		}
 	} else {
 		failures = append(failures, fmt.Sprintf("github: %v", err))
 	}
 
-	if stats, err := db.GetLatestTwitchStats(database); err == nil {
+	if stats, err := db.GetLatestTwitchStats(database, profile.TwitchChannel); err == nil {
 		content := fmt.Sprintf(
 			"Twitch Channel: %s has %d followers.",
 			stats.DisplayName, stats.Followers,
 		)
-		if err := StoreWebsiteContext("social_stats", "Twitch Channel Stats", content, "", stats, 3); err != nil {
+		if err := StoreWebsiteContext("social_stats", socialStatsTitle(profile, "Twitch Channel Stats"), content, "", stats, 3); err != nil {
 			failures = append(failures, fmt.Sprintf("twitch: %v", err))
 		}
 	} else {
 		failures = append(failures, fmt.Sprintf("twitch: %v", err))
 	}
 
-	if stats, err := db.GetLatestLeetCodeStats(database, "mattmajestic"); err == nil {
+	if stats, err := db.GetLatestLeetCodeStats(database, profile.LeetCodeUser); err == nil {
 		content := fmt.Sprintf(
 			"LeetCode Profile: %s has solved %d problems and is ranked #%d. Primary languages: %s.",
 			stats.Username, stats.SolvedCount, stats.Ranking, stats.Languages,
 		)
-		if err := StoreWebsiteContext("social_stats", "LeetCode Profile Stats", content, "", stats, 3); err != nil {
+		if err := StoreWebsiteContext("social_stats", socialStatsTitle(profile, "LeetCode Profile Stats"), content, "", stats, 3); err != nil {
 			failures = append(failures, fmt.Sprintf("leetcode: %v", err))
 		}
 	} else {
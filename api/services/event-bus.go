@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Event bus topics. Payload types are noted beside each.
+const (
+	TopicSiteChatMessage   = "chat.message"        // models.Message
+	TopicTwitchChatMessage = "twitch.message"      // models.TwitchMessage
+	TopicTwitchEvent       = "twitch.event"        // models.TwitchEvent (follows, raids, subs, bits, ...)
+	TopicCheckin           = "checkin.created"     // models.Checkin
+	TopicNowPlaying        = "spotify.now_playing" // models.NowPlayingChange
+	TopicStreamStatus      = "stream.status"       // models.StreamStatusChange
+)
+
+// eventBusBuffer is how many events a slow subscriber can fall behind before it misses some
+const eventBusBuffer = 64
+
+type busSubscriber struct {
+	ch     chan interface{}
+	filter func(payload interface{}) bool
+}
+
+var (
+	eventBusMu   sync.RWMutex
+	eventBusSubs = make(map[string]map[*busSubscriber]struct{})
+)
+
+// PublishEvent delivers payload to every subscriber of topic without blocking. Subscribers
+// whose buffer is full miss the event.
+func PublishEvent(topic string, payload interface{}) {
+	eventBusMu.RLock()
+	defer eventBusMu.RUnlock()
+
+	for sub := range eventBusSubs[topic] {
+		if sub.filter != nil && !sub.filter(payload) {
+			continue
+		}
+		select {
+		case sub.ch <- payload:
+		default:
+			log.Printf("⚠️ Event bus subscriber to %s is behind, dropping event", topic)
+		}
+	}
+}
+
+// SubscribeEvents returns a channel of topic's payloads that pass filter (nil keeps everything).
+// The channel is closed once ctx is done.
+func SubscribeEvents(ctx context.Context, topic string, filter func(payload interface{}) bool) chan interface{} {
+	sub := &busSubscriber{ch: make(chan interface{}, eventBusBuffer), filter: filter}
+
+	eventBusMu.Lock()
+	if eventBusSubs[topic] == nil {
+		eventBusSubs[topic] = make(map[*busSubscriber]struct{})
+	}
+	eventBusSubs[topic][sub] = struct{}{}
+	eventBusMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		eventBusMu.Lock()
+		delete(eventBusSubs[topic], sub)
+		eventBusMu.Unlock()
+		close(sub.ch)
+	}()
+	return sub.ch
+}
+
+// HasEventSubscribers reports whether anyone is listening on topic, so producers that poll can
+// stay idle otherwise
+func HasEventSubscribers(topic string) bool {
+	eventBusMu.RLock()
+	defer eventBusMu.RUnlock()
+	return len(eventBusSubs[topic]) > 0
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"log"
+	"sync"
+
+	"majesticcoding.com/api/config"
+)
+
+// Features are on or off by config (see config.Features), but a configured one can still be
+// unavailable, e.g. when its database can't be reached at startup. Those are tracked here.
+var (
+	unavailableMu       sync.RWMutex
+	unavailableFeatures = map[string]string{}
+)
+
+// MarkFeatureUnavailable turns a configured feature off for the rest of the process, along
+// with every feature that requires it
+func MarkFeatureUnavailable(name, reason string) {
+	unavailableMu.Lock()
+	defer unavailableMu.Unlock()
+	unavailableFeatures[name] = reason
+	log.Printf("⛔ %s unavailable: %s", name, reason)
+}
+
+// Features reports every feature and whether it's on right now
+func Features() []config.Feature {
+	unavailableMu.RLock()
+	defer unavailableMu.RUnlock()
+	return conf().FeaturesWith(unavailableFeatures)
+}
+
+// FeatureStatus returns one feature by name, and false if there's no such feature
+func FeatureStatus(name string) (config.Feature, bool) {
+	for _, f := range Features() {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return config.Feature{}, false
+}
+
+// FeatureEnabled reports whether a feature is on. Unknown names are off.
+func FeatureEnabled(name string) bool {
+	f, _ := FeatureStatus(name)
+	return f.Enabled
+}
@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
-	"strings"
 	"time"
 
 	"majesticcoding.com/api/models"
@@ -37,7 +35,7 @@ type gAddrComponent struct {
 }
 
 func Geocode(ctx context.Context, query string) (*models.GeocodeResult, error) {
-	apiKey := strings.TrimSpace(os.Getenv("GCP_API_KEY"))
+	apiKey := conf().Google.APIKey
 	if apiKey == "" {
 		return nil, fmt.Errorf("missing GCP_API_KEY env var")
 	}
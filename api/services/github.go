@@ -1,63 +1,254 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
+	"regexp"
+	"sort"
+	"time"
 
 	"majesticcoding.com/api/models"
+	"majesticcoding.com/db"
 )
 
-func FetchGitHubStats(username string) (models.GitHubStats, error) {
-	client := &http.Client{}
+// gitHubMaxRepoPages caps how many pages of 100 repos a poll reads
+const gitHubMaxRepoPages = 20
 
-	// Fetch basic user info
-	userURL := fmt.Sprintf("https://api.github.com/users/%s", username)
-	reqUser, _ := http.NewRequest("GET", userURL, nil)
-	reqUser.Header.Set("Authorization", "Bearer "+os.Getenv("GITHUB_TOKEN"))
+var gitHubNextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
 
-	respUser, err := client.Do(reqUser)
+type gitHubStatsProvider struct{}
+
+func (gitHubStatsProvider) Name() string                    { return "github" }
+func (gitHubStatsProvider) Account(p models.Profile) string { return p.GitHubUser }
+func (gitHubStatsProvider) DefaultInterval() time.Duration  { return 15 * time.Minute }
+func (gitHubStatsProvider) NewDetails() interface{}         { return &models.GitHubDetails{} }
+func (gitHubStatsProvider) Fetch(ctx context.Context, username string) (*models.StatsSnapshot, error) {
+	return FetchGitHubStats(ctx, username)
+}
+
+func (gitHubStatsProvider) Record(database *sql.DB, s *models.StatsSnapshot) error {
+	return db.InsertGitHubStats(database, s.Account, int(s.Metrics["public_repos"]),
+		int(s.Metrics["followers"]), int(s.Metrics["following"]), int(s.Metrics["stars"]))
+}
+
+func (gitHubStatsProvider) Latest(database *sql.DB, username string) (*models.StatsSnapshot, error) {
+	stats, err := db.GetLatestGitHubStats(database, username)
+	if err != nil {
+		return nil, err
+	}
+	return &models.StatsSnapshot{
+		DisplayName: stats.Username,
+		URL:         "https://github.com/" + stats.Username,
+		Metrics: map[string]int64{
+			"public_repos": int64(stats.PublicRepos),
+			"followers":    int64(stats.Followers),
+			"stars":        int64(stats.StarsReceived),
+		},
+	}, nil
+}
+
+// FetchGitHubStats fetches a user's profile counts, the stars and languages of all their repos
+// and, when GITHUB_TOKEN is set, their contributions over the last year
+func FetchGitHubStats(ctx context.Context, username string) (*models.StatsSnapshot, error) {
+	var user struct {
+		Login       string `json:"login"`
+		Name        string `json:"name"`
+		HTMLURL     string `json:"html_url"`
+		PublicRepos int    `json:"public_repos"`
+		Followers   int    `json:"followers"`
+		Following   int    `json:"following"`
+	}
+	if err := getStatsJSON(ctx, "github", "https://api.github.com/users/"+username, gitHubHeader(), &user); err != nil {
+		return nil, err
+	}
+
+	stars, details, err := gitHubRepoStats(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := map[string]int64{
+		"public_repos": int64(user.PublicRepos),
+		"followers":    int64(user.Followers),
+		"following":    int64(user.Following),
+		"stars":        int64(stars),
+	}
+	if conf().Stats.GitHubToken != "" {
+		contributions, err := gitHubContributions(ctx, username)
+		if err != nil {
+			return nil, fmt.Errorf("contributions: %w", err)
+		}
+		details.Contributions = contributions
+		metrics["contributions"] = int64(contributions.Total)
+	}
+
+	displayName := user.Name
+	if displayName == "" {
+		displayName = user.Login
+	}
+	return &models.StatsSnapshot{
+		DisplayName: displayName,
+		URL:         user.HTMLURL,
+		Metrics:     metrics,
+		Details:     details,
+	}, nil
+}
+
+// gitHubRepoStats pages through a user's repos, totalling stars and counting the main language
+// of each repo they didn't fork
+func gitHubRepoStats(ctx context.Context, username string) (int, *models.GitHubDetails, error) {
+	next := fmt.Sprintf("https://api.github.com/users/%s/repos?type=owner&per_page=100", username)
+	stars := 0
+	languages := map[string]int{}
+	details := &models.GitHubDetails{Languages: []models.LanguageCount{}}
+
+	for page := 0; next != "" && page < gitHubMaxRepoPages; page++ {
+		var repos []struct {
+			StargazersCount int    `json:"stargazers_count"`
+			Language        string `json:"language"`
+			Fork            bool   `json:"fork"`
+		}
+		var err error
+		next, err = gitHubGetPage(ctx, next, &repos)
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, repo := range repos {
+			stars += repo.StargazersCount
+			details.ReposCounted++
+			if !repo.Fork && repo.Language != "" {
+				languages[repo.Language]++
+			}
+		}
+	}
+
+	for name, repos := range languages {
+		details.Languages = append(details.Languages, models.LanguageCount{Name: name, Repos: repos})
+	}
+	sort.Slice(details.Languages, func(i, j int) bool {
+		a, b := details.Languages[i], details.Languages[j]
+		if a.Repos != b.Repos {
+			return a.Repos > b.Repos
+		}
+		return a.Name < b.Name
+	})
+	return stars, details, nil
+}
+
+// gitHubGetPage decodes one page of a list endpoint and returns the next page's URL, or ""
+func gitHubGetPage(ctx context.Context, url string, out interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return models.GitHubStats{}, err
+		return "", err
 	}
-	defer respUser.Body.Close()
+	req.Header = gitHubHeader()
 
-	var userData struct {
-		PublicRepos int `json:"public_repos"`
-		Followers   int `json:"followers"`
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := rateLimitFromResponse("github", resp); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("github repos API returned status %d", resp.StatusCode)
 	}
-	if err := json.NewDecoder(respUser.Body).Decode(&userData); err != nil {
-		return models.GitHubStats{}, err
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return "", err
 	}
 
-	// Fetch repos to calculate stars
-	reposURL := fmt.Sprintf("https://api.github.com/users/%s/repos?per_page=100", username)
-	reqRepos, _ := http.NewRequest("GET", reposURL, nil)
-	reqRepos.Header.Set("Authorization", "Bearer "+os.Getenv("GITHUB_TOKEN"))
+	if m := gitHubNextLink.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+		return m[1], nil
+	}
+	return "", nil
+}
 
-	respRepos, err := client.Do(reqRepos)
+// gitHubContributions reads the contribution counts GitHub shows on a profile. The GraphQL
+// API needs a token.
+func gitHubContributions(ctx context.Context, username string) (*models.GitHubContributions, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"query": `query($login: String!) {
+			user(login: $login) {
+				contributionsCollection {
+					totalCommitContributions
+					totalPullRequestContributions
+					totalIssueContributions
+					totalPullRequestReviewContributions
+					contributionCalendar { totalContributions }
+				}
+			}
+		}`,
+		"variables": map[string]string{"login": username},
+	})
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.github.com/graphql", bytes.NewReader(body))
 	if err != nil {
-		return models.GitHubStats{}, err
+		return nil, err
 	}
-	defer respRepos.Body.Close()
+	req.Header = gitHubHeader()
+	req.Header.Set("Content-Type", "application/json")
 
-	var repos []struct {
-		StargazersCount int `json:"stargazers_count"`
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
-	if err := json.NewDecoder(respRepos.Body).Decode(&repos); err != nil {
-		return models.GitHubStats{}, err
+	defer resp.Body.Close()
+	if err := rateLimitFromResponse("github", resp); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("github GraphQL API returned status %d", resp.StatusCode)
 	}
 
-	totalStars := 0
-	for _, repo := range repos {
-		totalStars += repo.StargazersCount
+	var result struct {
+		Data struct {
+			User *struct {
+				ContributionsCollection struct {
+					TotalCommitContributions            int `json:"totalCommitContributions"`
+					TotalPullRequestContributions       int `json:"totalPullRequestContributions"`
+					TotalIssueContributions             int `json:"totalIssueContributions"`
+					TotalPullRequestReviewContributions int `json:"totalPullRequestReviewContributions"`
+					ContributionCalendar                struct {
+						TotalContributions int `json:"totalContributions"`
+					} `json:"contributionCalendar"`
+				} `json:"contributionsCollection"`
+			} `json:"user"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("github GraphQL: %s", result.Errors[0].Message)
+	}
+	if result.Data.User == nil {
+		return nil, fmt.Errorf("no github user %s", username)
 	}
 
-	return models.GitHubStats{
-		Username:      username,
-		PublicRepos:   userData.PublicRepos,
-		Followers:     userData.Followers,
-		StarsReceived: totalStars,
+	c := result.Data.User.ContributionsCollection
+	return &models.GitHubContributions{
+		Total:        c.ContributionCalendar.TotalContributions,
+		Commits:      c.TotalCommitContributions,
+		PullRequests: c.TotalPullRequestContributions,
+		Issues:       c.TotalIssueContributions,
+		Reviews:      c.TotalPullRequestReviewContributions,
 	}, nil
 }
+
+// gitHubHeader authenticates with GITHUB_TOKEN when set; without it the REST API still works
+// at a lower rate limit
+func gitHubHeader() http.Header {
+	h := http.Header{}
+	h.Set("Accept", "application/vnd.github+json")
+	if token := conf().Stats.GitHubToken; token != "" {
+		h.Set("Authorization", "Bearer "+token)
+	}
+	return h
+}
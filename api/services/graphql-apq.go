@@ -0,0 +1,122 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"majesticcoding.com/api/models"
+)
+
+// Automatic persisted queries (the Apollo protocol): a client sends only the SHA-256 of its
+// query, and on PersistedQueryNotFound retries once with the full text so later requests can
+// use the hash alone.
+const (
+	persistedQueryTTL        = 30 * 24 * 60 * 60 // seconds
+	persistedQueryMemoryKeep = 1000
+	maxPersistedQueryBytes   = 16 << 10
+)
+
+var (
+	persistedQueriesMu sync.RWMutex
+	persistedQueries   = make(map[string]string) // hash -> query, used when Redis is unavailable
+)
+
+// ResolvePersistedQuery returns the query to execute for a request. Requests without a
+// persistedQuery extension are returned unchanged. A hash is only registered for a query that
+// passes validation and the depth and cost budgets, so callers can't fill the store with text
+// that would never run.
+func ResolvePersistedQuery(request models.GraphQLRequest) (string, *models.GraphQLError) {
+	query := request.Query
+	pq, ok := request.Extensions["persistedQuery"].(map[string]interface{})
+	if !ok {
+		return query, nil
+	}
+	if version, _ := pq["version"].(float64); version != 1 {
+		return "", &models.GraphQLError{
+			Message:    "PersistedQueryNotSupported",
+			Extensions: map[string]interface{}{"code": "PERSISTED_QUERY_NOT_SUPPORTED"},
+		}
+	}
+	hash, _ := pq["sha256Hash"].(string)
+	hash = strings.ToLower(hash)
+	if len(hash) != sha256.Size*2 {
+		return "", &models.GraphQLError{
+			Message:    "persistedQuery.sha256Hash must be a hex SHA-256",
+			Extensions: map[string]interface{}{"code": "BAD_REQUEST"},
+		}
+	}
+
+	if query == "" {
+		if stored := lookupPersistedQuery(hash); stored != "" {
+			return stored, nil
+		}
+		return "", &models.GraphQLError{
+			Message:    "PersistedQueryNotFound",
+			Extensions: map[string]interface{}{"code": "PERSISTED_QUERY_NOT_FOUND"},
+		}
+	}
+
+	if len(query) > maxPersistedQueryBytes {
+		return "", &models.GraphQLError{
+			Message:    fmt.Sprintf("persisted queries are limited to %d bytes", maxPersistedQueryBytes),
+			Extensions: map[string]interface{}{"code": "PERSISTED_QUERY_TOO_LARGE"},
+		}
+	}
+	sum := sha256.Sum256([]byte(query))
+	if hex.EncodeToString(sum[:]) != hash {
+		return "", &models.GraphQLError{
+			Message:    "provided sha does not match query",
+			Extensions: map[string]interface{}{"code": "BAD_REQUEST"},
+		}
+	}
+	// Registering is rare, so parsing the query again when it executes is fine. A rejected
+	// query still runs, which is how its errors get back to the client.
+	if schema, err := GraphQLSchema(); err == nil {
+		if _, _, errs := prepareGraphQL(schema, query, request.OperationName, request.Variables); errs == nil {
+			storePersistedQuery(hash, query)
+		}
+	}
+	return query, nil
+}
+
+func persistedQueryKey(hash string) string {
+	return "graphql:apq:" + hash
+}
+
+func lookupPersistedQuery(hash string) string {
+	persistedQueriesMu.RLock()
+	query := persistedQueries[hash]
+	persistedQueriesMu.RUnlock()
+	if query != "" {
+		return query
+	}
+
+	query, err := RedisGet(persistedQueryKey(hash))
+	if err != nil || query == "" {
+		return ""
+	}
+	rememberPersistedQuery(hash, query)
+	return query
+}
+
+func storePersistedQuery(hash, query string) {
+	rememberPersistedQuery(hash, query)
+	// Best effort: without Redis each instance keeps its own copy
+	RedisSet(persistedQueryKey(hash), query, persistedQueryTTL)
+}
+
+func rememberPersistedQuery(hash, query string) {
+	persistedQueriesMu.Lock()
+	if len(persistedQueries) >= persistedQueryMemoryKeep {
+		// Forget an arbitrary entry; Redis still has it, and clients re-register on a miss
+		for k := range persistedQueries {
+			delete(persistedQueries, k)
+			break
+		}
+	}
+	persistedQueries[hash] = query
+	persistedQueriesMu.Unlock()
+}
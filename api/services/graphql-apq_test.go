@@ -0,0 +1,67 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"majesticcoding.com/api/models"
+)
+
+func persistedQueryRequest(query, hash string) models.GraphQLRequest {
+	return models.GraphQLRequest{
+		Query: query,
+		Extensions: map[string]interface{}{
+			"persistedQuery": map[string]interface{}{"version": float64(1), "sha256Hash": hash},
+		},
+	}
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestResolvePersistedQuery(t *testing.T) {
+	useTestCache(t)
+	useTestGraphQLLimits(t, 10, 100)
+	persistedQueriesMu.Lock()
+	persistedQueries = make(map[string]string)
+	persistedQueriesMu.Unlock()
+
+	valid := `{ statsProviders }`
+	invalid := `{ noSuchField }`
+	overBudget := `{ twitchChat(limit: 100) { message } twitchFollowers { userName } }`
+	tooLarge := "{ statsProviders }" + strings.Repeat(" ", maxPersistedQueryBytes)
+
+	tests := []struct {
+		name    string
+		request models.GraphQLRequest
+		want    string
+		code    string
+	}{
+		{"no extension", models.GraphQLRequest{Query: valid}, valid, ""},
+		{"unknown hash", persistedQueryRequest("", sha256Hex(valid)), "", "PERSISTED_QUERY_NOT_FOUND"},
+		{"hash mismatch", persistedQueryRequest(valid, sha256Hex(invalid)), "", "BAD_REQUEST"},
+		{"hash not hex sha-256", persistedQueryRequest(valid, "abc"), "", "BAD_REQUEST"},
+		{"too large", persistedQueryRequest(tooLarge, sha256Hex(tooLarge)), "", "PERSISTED_QUERY_TOO_LARGE"},
+		{"register", persistedQueryRequest(valid, sha256Hex(valid)), valid, ""},
+		{"registered hash", persistedQueryRequest("", sha256Hex(valid)), valid, ""},
+		{"register invalid", persistedQueryRequest(invalid, sha256Hex(invalid)), invalid, ""},
+		{"invalid wasn't registered", persistedQueryRequest("", sha256Hex(invalid)), "", "PERSISTED_QUERY_NOT_FOUND"},
+		{"register over budget", persistedQueryRequest(overBudget, sha256Hex(overBudget)), overBudget, ""},
+		{"over budget wasn't registered", persistedQueryRequest("", sha256Hex(overBudget)), "", "PERSISTED_QUERY_NOT_FOUND"},
+	}
+	// In order: later cases look up hashes earlier ones registered
+	for _, tt := range tests {
+		query, err := ResolvePersistedQuery(tt.request)
+		var code string
+		if err != nil {
+			code, _ = err.Extensions["code"].(string)
+		}
+		if query != tt.want || code != tt.code {
+			t.Errorf("%s: got %q, %q; want %q, %q", tt.name, query, code, tt.want, tt.code)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"majesticcoding.com/api/models"
+)
+
+// graphQLFieldCosts weights fields by what resolving them costs us. Fields backed by an upstream
+// API cost the most; unlisted object fields cost 1 and scalars are free. List fields with a
+// limit argument are charged per item.
+var graphQLFieldCosts = map[string]int{
+	"UnifiedStats.youtube":        25,
+	"UnifiedStats.github":         25, // a page of repos per 100, plus contributions
+	"UnifiedStats.twitch":         25,
+	"UnifiedStats.leetcode":       25,
+	"Query.stats":                 25,
+	"Query.premierLeagueSchedule": 25,
+	"Query.laLigaSchedule":        25,
+	"Query.spotifyCurrentTrack":   10,
+	"Query.statsHistory":          10, // two aggregate queries per metric
+	"Query.checkins":              5,
+	"Query.twitchFollowers":       2,
+	"Query.twitchRaids":           2,
+	"Query.twitchSubs":            2,
+	"Query.twitchBits":            2,
+	"Query.twitchChat":            1,
+}
+
+// graphQLLimits are the depth and cost budgets, from GRAPHQL_MAX_DEPTH and GRAPHQL_MAX_COST.
+// They're read per request, so a config reload changes them.
+func graphQLLimits() (maxDepth, maxCost int) {
+	limits := conf().GraphQL
+	return limits.MaxDepth, limits.MaxCost
+}
+
+// checkGraphQLLimits measures the operation that would run and rejects it if it is deeper or
+// costlier than allowed. It also returns the cost so responses can report it.
+func checkGraphQLLimits(schema graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}) (int, []models.GraphQLError) {
+	a := &graphQLAnalysis{schema: schema, variables: variables, fragments: map[string]*ast.FragmentDefinition{}}
+	var op *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.FragmentDefinition:
+			a.fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if op == nil && (operationName == "" || (d.Name != nil && d.Name.Value == operationName)) {
+				op = d
+			}
+		}
+	}
+	if op == nil {
+		return 0, nil // graphql-go reports the missing operation
+	}
+
+	var root *graphql.Object
+	switch op.Operation {
+	case ast.OperationTypeSubscription:
+		root = schema.SubscriptionType()
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	default:
+		root = schema.QueryType()
+	}
+	if root == nil {
+		return 0, nil
+	}
+
+	depth, cost := a.selectionSet(root, op.SelectionSet, map[string]bool{})
+	maxDepth, maxCost := graphQLLimits()
+
+	var errs []models.GraphQLError
+	if depth > maxDepth {
+		errs = append(errs, models.GraphQLError{
+			Message: fmt.Sprintf("Query depth %d exceeds the maximum of %d. Split it into smaller queries or select fewer nested fields.", depth, maxDepth),
+			Extensions: map[string]interface{}{
+				"code":     "QUERY_TOO_DEEP",
+				"depth":    depth,
+				"maxDepth": maxDepth,
+			},
+		})
+	}
+	if cost > maxCost {
+		errs = append(errs, models.GraphQLError{
+			Message: fmt.Sprintf("Query cost %d exceeds the budget of %d. Lower list limits or select fewer upstream-backed fields (stats providers, football schedules, Spotify).", cost, maxCost),
+			Extensions: map[string]interface{}{
+				"code":    "QUERY_TOO_COMPLEX",
+				"cost":    cost,
+				"maxCost": maxCost,
+			},
+		})
+	}
+	return cost, errs
+}
+
+type graphQLAnalysis struct {
+	schema    graphql.Schema
+	variables map[string]interface{}
+	fragments map[string]*ast.FragmentDefinition
+}
+
+// selectionSet returns the depth and cost of a selection on parent. visiting holds the
+// fragments being expanded, so a cycle that slipped past validation can't recurse forever.
+func (a *graphQLAnalysis) selectionSet(parent graphql.Type, set *ast.SelectionSet, visiting map[string]bool) (depth, cost int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, sel := range set.Selections {
+		var d, c int
+		switch s := sel.(type) {
+		case *ast.Field:
+			d, c = a.field(parent, s, visiting)
+		case *ast.InlineFragment:
+			t := parent
+			if s.TypeCondition != nil {
+				if named := a.schema.Type(s.TypeCondition.Name.Value); named != nil {
+					t = named
+				}
+			}
+			d, c = a.selectionSet(t, s.SelectionSet, visiting)
+		case *ast.FragmentSpread:
+			frag := a.fragments[s.Name.Value]
+			if frag == nil || visiting[s.Name.Value] {
+				continue
+			}
+			visiting[s.Name.Value] = true
+			t := parent
+			if named := a.schema.Type(frag.TypeCondition.Name.Value); named != nil {
+				t = named
+			}
+			d, c = a.selectionSet(t, frag.SelectionSet, visiting)
+			delete(visiting, s.Name.Value)
+		}
+		if d > depth {
+			depth = d
+		}
+		cost += c
+	}
+	return depth, cost
+}
+
+func (a *graphQLAnalysis) field(parent graphql.Type, f *ast.Field, visiting map[string]bool) (depth, cost int) {
+	// Introspection is answered from the schema without touching any resolver
+	if strings.HasPrefix(f.Name.Value, "__") {
+		return 0, 0
+	}
+
+	var fields graphql.FieldDefinitionMap
+	switch p := parent.(type) {
+	case *graphql.Object:
+		fields = p.Fields()
+	case *graphql.Interface:
+		fields = p.Fields()
+	}
+	def := fields[f.Name.Value]
+	if def == nil {
+		return 1, 0 // unknown fields fail validation
+	}
+
+	named, _ := graphql.GetNamed(def.Type).(graphql.Type)
+	childDepth, childCost := a.selectionSet(named, f.SelectionSet, visiting)
+
+	weight, ok := graphQLFieldCosts[parent.Name()+"."+f.Name.Value]
+	if !ok {
+		switch named.(type) {
+		case *graphql.Object, *graphql.Interface, *graphql.Union:
+			weight = 1
+		}
+	}
+
+	cost = weight + childCost
+	if limit, ok := a.limit(def, f); ok && isGraphQLList(def.Type) {
+		cost *= limit
+	}
+	return 1 + childDepth, cost
+}
+
+// limit is the field's limit argument as the resolver will see it, clamped like gqlLimit
+func (a *graphQLAnalysis) limit(def *graphql.FieldDefinition, f *ast.Field) (int, bool) {
+	var argDef *graphql.Argument
+	for _, arg := range def.Args {
+		if arg.Name() == "limit" {
+			argDef = arg
+		}
+	}
+	if argDef == nil {
+		return 0, false
+	}
+
+	var value interface{} = argDef.DefaultValue
+	for _, arg := range f.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			value, _ = strconv.Atoi(v.Value)
+		case *ast.Variable:
+			if val, ok := a.variables[v.Name.Value]; ok {
+				value = val
+			}
+		}
+	}
+
+	n := 1
+	switch v := value.(type) {
+	case int:
+		n = v
+	case float64: // JSON variables
+		n = int(v)
+	}
+	if n < 1 {
+		n = 1
+	}
+	if n > 100 {
+		n = 100
+	}
+	return n, true
+}
+
+func isGraphQLList(t graphql.Type) bool {
+	if nn, ok := t.(*graphql.NonNull); ok {
+		t = nn.OfType
+	}
+	_, ok := t.(*graphql.List)
+	return ok
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+	"majesticcoding.com/api/config"
+)
+
+func useTestGraphQLLimits(t *testing.T, maxDepth, maxCost int) {
+	t.Helper()
+	original := settings.Load()
+	c := config.Defaults()
+	c.GraphQL.MaxDepth, c.GraphQL.MaxCost = maxDepth, maxCost
+	UseConfig(c)
+	t.Cleanup(func() { settings.Store(original) })
+}
+
+func TestCheckGraphQLLimits(t *testing.T) {
+	schema, err := GraphQLSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	useTestGraphQLLimits(t, 3, 100)
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		cost      int
+		code      string // the error expected, if any
+	}{
+		{"scalars are free", `{ statsProviders }`, nil, 0, ""},
+		{"within depth", `{ unifiedStats { profile { id } } }`, nil, 2, ""},
+		{"too deep", `{ unifiedStats { youtube { recentUploads { title } } } }`, nil, 27, "QUERY_TOO_DEEP"},
+		{"upstream fields", `{ unifiedStats { youtube { views } github { followers } } }`, nil, 51, ""},
+		{"over budget", `{ unifiedStats { youtube { views } github { followers } twitch { followers } leetcode { ranking } } }`, nil, 101, "QUERY_TOO_COMPLEX"},
+		{"default limit", `{ twitchChat { message } }`, nil, 50, ""},
+		{"limit argument", `{ twitchFollowers(limit: 20) { userName } }`, nil, 40, ""},
+		{"limit variable", `query($n: Int) { twitchChat(limit: $n) { message } }`, map[string]interface{}{"n": float64(7)}, 7, ""},
+		{"limit is clamped", `{ twitchChat(limit: 5000) { message } twitchRaids(limit: 0) { viewers } }`, nil, 102, "QUERY_TOO_COMPLEX"},
+		{"fragments", `{ unifiedStats { ...yt } } fragment yt on UnifiedStats { youtube { views } }`, nil, 26, ""},
+		{"fragment cycle", `{ unifiedStats { ...a } } fragment a on UnifiedStats { youtube { views } ...b } fragment b on UnifiedStats { ...a }`, nil, 26, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Not validated, so a fragment cycle reaches the analysis
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cost, errs := checkGraphQLLimits(schema, doc, "", tt.variables)
+			if cost != tt.cost {
+				t.Errorf("cost = %d, want %d", cost, tt.cost)
+			}
+			var code string
+			if len(errs) > 0 {
+				code, _ = errs[0].Extensions["code"].(string)
+			}
+			if code != tt.code {
+				t.Errorf("error code = %q, want %q (%v)", code, tt.code, errs)
+			}
+		})
+	}
+}
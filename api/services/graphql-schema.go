@@ -0,0 +1,734 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/db"
+)
+
+// GraphQLDeps are the parts of the schema served from outside the services package
+type GraphQLDeps struct {
+	NowPlaying func(ctx context.Context) (*models.CurrentTrack, error)
+}
+
+var (
+	graphQLDeps       GraphQLDeps
+	graphQLSchema     graphql.Schema
+	graphQLSchemaErr  error
+	graphQLSchemaOnce sync.Once
+)
+
+// SetGraphQLDeps wires resolvers that live outside services. Call it before serving requests.
+func SetGraphQLDeps(deps GraphQLDeps) {
+	graphQLDeps = deps
+}
+
+// GraphQLSchema returns the API schema, building it on first use.
+//
+// Object fields resolve from the Go struct field with the same name (case-insensitively), so
+// twitchFollower.userLogin reads models.TwitchFollower.UserLogin without a resolver of its own.
+func GraphQLSchema() (graphql.Schema, error) {
+	graphQLSchemaOnce.Do(func() {
+		query, subscription := graphQLRootTypes()
+		graphQLSchema, graphQLSchemaErr = graphql.NewSchema(graphql.SchemaConfig{
+			Query:        query,
+			Subscription: subscription,
+		})
+	})
+	return graphQLSchema, graphQLSchemaErr
+}
+
+// ExecuteGraphQLQuery validates, budgets and executes a query against the schema. Rejected
+// requests and resolver errors are returned in the response; only a broken schema is returned
+// as an error.
+func ExecuteGraphQLQuery(ctx context.Context, query, operationName string, variables map[string]interface{}) (*models.GraphQLResponse, error) {
+	schema, err := GraphQLSchema()
+	if err != nil {
+		return nil, fmt.Errorf("graphql schema: %w", err)
+	}
+
+	doc, cost, errs := prepareGraphQL(schema, query, operationName, variables)
+	if errs != nil {
+		return &models.GraphQLResponse{Errors: errs}, nil
+	}
+	if graphQLOperationType(doc, operationName) == ast.OperationTypeSubscription {
+		return &models.GraphQLResponse{Errors: []models.GraphQLError{{
+			Message:    "Subscriptions are served over the graphql-transport-ws protocol at /ws/graphql",
+			Extensions: map[string]interface{}{"code": "BAD_REQUEST"},
+		}}}, nil
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        schema,
+		AST:           doc,
+		OperationName: operationName,
+		Args:          variables,
+		Context:       WithStatsMemo(ctx),
+	})
+	response := graphQLResponse(result)
+	response.Extensions = map[string]interface{}{"cost": cost}
+	return response, nil
+}
+
+// prepareGraphQL parses and validates a request and checks it against the depth and cost
+// budgets, returning the document and its cost or the errors to send back
+func prepareGraphQL(schema graphql.Schema, query, operationName string, variables map[string]interface{}) (*ast.Document, int, []models.GraphQLError) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"})})
+	if err != nil {
+		return nil, 0, graphQLResponse(&graphql.Result{Errors: gqlerrors.FormatErrors(err)}).Errors
+	}
+	if result := graphql.ValidateDocument(&schema, doc, nil); !result.IsValid {
+		return nil, 0, graphQLResponse(&graphql.Result{Errors: result.Errors}).Errors
+	}
+	cost, errs := checkGraphQLLimits(schema, doc, operationName, variables)
+	if errs != nil {
+		return nil, 0, errs
+	}
+	return doc, cost, nil
+}
+
+func graphQLResponse(result *graphql.Result) *models.GraphQLResponse {
+	response := &models.GraphQLResponse{Data: result.Data}
+	for _, e := range result.Errors {
+		gqlErr := models.GraphQLError{Message: e.Message, Path: e.Path, Extensions: e.Extensions}
+		for _, loc := range e.Locations {
+			gqlErr.Locations = append(gqlErr.Locations, models.GraphQLLocation{Line: loc.Line, Column: loc.Column})
+		}
+		response.Errors = append(response.Errors, gqlErr)
+	}
+	return response
+}
+
+// gqlAsync runs a slow resolver in the background. Sibling fields start theirs before any is
+// awaited, so the providers in unifiedStats are fetched concurrently.
+func gqlAsync(fn func() (interface{}, error)) (interface{}, error) {
+	type result struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value, err}
+	}()
+	return func() (interface{}, error) {
+		r := <-done
+		return r.value, r.err
+	}, nil
+}
+
+// gqlResult drops the value when there's an error, so the field resolves to null rather than
+// a zero struct or typed nil pointer
+func gqlResult(value interface{}, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// gqlProfile resolves the optional profile argument, defaulting to the default profile
+func gqlProfile(p graphql.ResolveParams) (models.Profile, error) {
+	id, _ := p.Args["profile"].(string)
+	return GetProfile(id)
+}
+
+func gqlLimit(p graphql.ResolveParams, max int) int {
+	limit, _ := p.Args["limit"].(int)
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > max {
+		limit = max
+	}
+	return limit
+}
+
+func gqlDatabase() (*sql.DB, error) {
+	database := db.GetDB()
+	if database == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	return database, nil
+}
+
+var (
+	gqlProfileArg = &graphql.ArgumentConfig{Type: graphql.ID, Description: "Profile id (default profile if omitted)"}
+	gqlLimitArg   = &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 10}
+)
+
+func graphQLRootTypes() (query, subscription *graphql.Object) {
+	profileType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Profile",
+		Description: "A creator tracked by this site",
+		Fields: graphql.Fields{
+			"id":               &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"name":             &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"twitchChannel":    &graphql.Field{Type: graphql.String},
+			"githubUser":       &graphql.Field{Type: graphql.String},
+			"leetcodeUser":     &graphql.Field{Type: graphql.String},
+			"youtubeChannelId": &graphql.Field{Type: graphql.String},
+			"default":          &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+
+	youTubeVideoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "YouTubeVideo",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"title":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"url":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"thumbnail":   &graphql.Field{Type: graphql.String},
+			"publishedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"views":       &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"likes":       &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Description: "0 when the channel hides likes"},
+			"comments":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+	youTubeStatsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "YouTubeStats",
+		Fields: graphql.Fields{
+			"channelName":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"subscribers":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"views":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"videos":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"recentUploads": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(youTubeVideoType))), Description: "Newest first"},
+		},
+	})
+	languageCountType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LanguageCount",
+		Fields: graphql.Fields{
+			"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"repos": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	gitHubContributionsType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "GitHubContributions",
+		Description: "Contributions over the last year",
+		Fields: graphql.Fields{
+			"total":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"commits":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"pullRequests": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"issues":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"reviews":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	gitHubStatsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "GitHubStats",
+		Fields: graphql.Fields{
+			"username":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"publicRepos":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"followers":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"starsReceived": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Across all owned repos"},
+			"languages":     &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(languageCountType))), Description: "Main languages of non-fork repos, most used first"},
+			"contributions": &graphql.Field{Type: gitHubContributionsType, Description: "null when the server has no GitHub token"},
+		},
+	})
+	twitchStatsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TwitchStats",
+		Fields: graphql.Fields{
+			"displayName":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"description":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"broadcasterType": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"followers":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	leetCodeDifficultyType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LeetCodeDifficulty",
+		Fields: graphql.Fields{
+			"solved": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"total":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	leetCodeStatsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LeetCodeStats",
+		Fields: graphql.Fields{
+			"username":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"languages":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"solvedCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"ranking":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"easy":        &graphql.Field{Type: graphql.NewNonNull(leetCodeDifficultyType)},
+			"medium":      &graphql.Field{Type: graphql.NewNonNull(leetCodeDifficultyType)},
+			"hard":        &graphql.Field{Type: graphql.NewNonNull(leetCodeDifficultyType)},
+		},
+	})
+
+	statsMetricValueType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StatsMetricValue",
+		Fields: graphql.Fields{
+			"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"value": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+	statsSnapshotType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "StatsSnapshot",
+		Description: "Any provider's headline stats for one account",
+		Fields: graphql.Fields{
+			"provider":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"account":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"displayName": &graphql.Field{Type: graphql.String},
+			"url":         &graphql.Field{Type: graphql.String},
+			"fetchedAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"metrics": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(statsMetricValueType))),
+				Description: "Sorted by name",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					snapshot := p.Source.(*models.StatsSnapshot)
+					values := make([]map[string]interface{}, 0, len(snapshot.Metrics))
+					for _, name := range sortedMetricNames(snapshot.Metrics) {
+						values = append(values, map[string]interface{}{"name": name, "value": float64(snapshot.Metrics[name])})
+					}
+					return values, nil
+				},
+			},
+		},
+	})
+
+	// Each provider is fetched only when selected. A failing provider nulls its own field and
+	// reports an error at its path; the others still resolve.
+	unifiedStatsType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "UnifiedStats",
+		Description: "Current stats across providers for one profile",
+		Fields: graphql.Fields{
+			"profile": &graphql.Field{
+				Type: graphql.NewNonNull(profileType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+			"youtube": &graphql.Field{
+				Type: youTubeStatsType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					profile := p.Source.(models.Profile)
+					return gqlAsync(func() (interface{}, error) { return gqlResult(fetchYouTubeStatsGQL(p.Context, profile)) })
+				},
+			},
+			"github": &graphql.Field{
+				Type: gitHubStatsType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					profile := p.Source.(models.Profile)
+					return gqlAsync(func() (interface{}, error) { return gqlResult(fetchGitHubStatsGQL(p.Context, profile)) })
+				},
+			},
+			"twitch": &graphql.Field{
+				Type: twitchStatsType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					profile := p.Source.(models.Profile)
+					return gqlAsync(func() (interface{}, error) { return gqlResult(fetchTwitchStatsGQL(p.Context, profile)) })
+				},
+			},
+			"leetcode": &graphql.Field{
+				Type: leetCodeStatsType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					profile := p.Source.(models.Profile)
+					return gqlAsync(func() (interface{}, error) { return gqlResult(fetchLeetCodeStatsGQL(p.Context, profile)) })
+				},
+			},
+		},
+	})
+
+	statsProviderEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "StatsProvider",
+		Values: graphql.EnumValueConfigMap{
+			"YOUTUBE":  &graphql.EnumValueConfig{Value: "youtube"},
+			"GITHUB":   &graphql.EnumValueConfig{Value: "github"},
+			"TWITCH":   &graphql.EnumValueConfig{Value: "twitch"},
+			"LEETCODE": &graphql.EnumValueConfig{Value: "leetcode"},
+		},
+	})
+
+	// Counts are Float so values above 2^31 (YouTube views) aren't nulled by the Int scalar
+	statsBucketType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StatsBucket",
+		Fields: graphql.Fields{
+			"start":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"min":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"max":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"first":   &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"last":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"delta":   &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"samples": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	statsGrowthType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StatsGrowth",
+		Fields: graphql.Fields{
+			"from":    &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"to":      &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"start":   &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"end":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"change":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"percent": &graphql.Field{Type: graphql.Float, Description: "Null when the range started at 0"},
+			"perDay":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+	statsMilestoneType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StatsMilestone",
+		Fields: graphql.Fields{
+			"value":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"reachedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"label":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+	statsMetricSeriesType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StatsMetricSeries",
+		Fields: graphql.Fields{
+			"metric":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"buckets":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(statsBucketType)))},
+			"growth":     &graphql.Field{Type: statsGrowthType},
+			"milestones": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(statsMilestoneType)))},
+		},
+	})
+	statsHistoryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StatsHistory",
+		Fields: graphql.Fields{
+			"provider": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"profile":  &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"account":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"from":     &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"to":       &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"interval": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"metrics":  &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(statsMetricSeriesType)))},
+		},
+	})
+
+	checkinType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Checkin",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"username":    &graphql.Field{Type: graphql.String},
+			"lat":         &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"lon":         &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"city":        &graphql.Field{Type: graphql.String},
+			"country":     &graphql.Field{Type: graphql.String},
+			"platform":    &graphql.Field{Type: graphql.String},
+			"checkinTime": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	twitchFollowerType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TwitchFollower",
+		Fields: graphql.Fields{
+			"userId":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"userLogin":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"userName":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"followedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+	twitchRaidType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TwitchRaid",
+		Fields: graphql.Fields{
+			"fromBroadcasterUserLogin": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"fromBroadcasterUserName":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"viewers":                  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"createdAt":                &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+	twitchSubType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TwitchSub",
+		Fields: graphql.Fields{
+			"userLogin":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"userName":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"tier":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"isGift":          &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"gifterUserLogin": &graphql.Field{Type: graphql.String},
+			"gifterUserName":  &graphql.Field{Type: graphql.String},
+			"createdAt":       &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+	twitchBitsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TwitchBits",
+		Fields: graphql.Fields{
+			"userLogin":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"userName":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"isAnonymous": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"message":     &graphql.Field{Type: graphql.String},
+			"bits":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"createdAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	twitchEmoteType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TwitchEmote",
+		Fields: graphql.Fields{
+			"id":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"count": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	twitchChatMessageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TwitchChatMessage",
+		Fields: graphql.Fields{
+			"messageId":     &graphql.Field{Type: graphql.String},
+			"channel":       &graphql.Field{Type: graphql.String},
+			"username":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"displayName":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"message":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"color":         &graphql.Field{Type: graphql.String},
+			"isMod":         &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"isVip":         &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"isBroadcaster": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"emotes":        &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(twitchEmoteType))},
+			"time":          &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	// Premier League and La Liga matches share a shape
+	footballTeamType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "FootballTeam",
+		Fields: graphql.Fields{
+			"id":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"crest": &graphql.Field{Type: graphql.String},
+		},
+	})
+	footballResultType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "FootballResult",
+		Fields: graphql.Fields{
+			"home": &graphql.Field{Type: graphql.Int},
+			"away": &graphql.Field{Type: graphql.Int},
+		},
+	})
+	footballScoreType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "FootballScore",
+		Fields: graphql.Fields{
+			"winner":   &graphql.Field{Type: graphql.String},
+			"duration": &graphql.Field{Type: graphql.String},
+			"fullTime": &graphql.Field{Type: graphql.NewNonNull(footballResultType)},
+			"halfTime": &graphql.Field{Type: graphql.NewNonNull(footballResultType)},
+		},
+	})
+	footballMatchType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "FootballMatch",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"date":     &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"status":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"matchday": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"homeTeam": &graphql.Field{Type: graphql.NewNonNull(footballTeamType)},
+			"awayTeam": &graphql.Field{Type: graphql.NewNonNull(footballTeamType)},
+			"score":    &graphql.Field{Type: graphql.NewNonNull(footballScoreType)},
+		},
+	})
+
+	spotifyTrackType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SpotifyTrack",
+		Fields: graphql.Fields{
+			"title":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"artists":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+			"album":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"albumImage": &graphql.Field{Type: graphql.String},
+			"url":        &graphql.Field{Type: graphql.String},
+			"isPlaying":  &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"progressMs": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"durationMs": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	subscription = graphQLSubscriptionType(gqlEventTypes{
+		checkin:           checkinType,
+		twitchChatMessage: twitchChatMessageType,
+		twitchFollower:    twitchFollowerType,
+		twitchRaid:        twitchRaidType,
+		twitchSub:         twitchSubType,
+		twitchBits:        twitchBitsType,
+		spotifyTrack:      spotifyTrackType,
+	})
+
+	query = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"profiles": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(profileType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return Profiles(), nil
+				},
+			},
+			"profile": &graphql.Field{
+				Type: profileType,
+				Args: graphql.FieldConfigArgument{"id": {Type: graphql.ID}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _ := p.Args["id"].(string)
+					return gqlResult(GetProfile(id))
+				},
+			},
+			"statsProviders": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				Description: "Names accepted by stats(provider:)",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return StatsProviderNames(), nil
+				},
+			},
+			"stats": &graphql.Field{
+				Type:        statsSnapshotType,
+				Description: "Current stats from any provider, including ones unifiedStats doesn't have fields for",
+				Args: graphql.FieldConfigArgument{
+					"provider": {Type: graphql.NewNonNull(graphql.String)},
+					"profile":  gqlProfileArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					profile, err := gqlProfile(p)
+					if err != nil {
+						return nil, err
+					}
+					return gqlResult(ProviderStats(p.Context, p.Args["provider"].(string), profile))
+				},
+			},
+			"unifiedStats": &graphql.Field{
+				Type:        unifiedStatsType,
+				Description: "Current stats from each selected provider",
+				Args:        graphql.FieldConfigArgument{"profile": gqlProfileArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return gqlResult(gqlProfile(p))
+				},
+			},
+			"statsHistory": &graphql.Field{
+				Type:        statsHistoryType,
+				Description: "Recorded stats bucketed over time, with growth and milestones",
+				Args: graphql.FieldConfigArgument{
+					"provider": {Type: graphql.NewNonNull(statsProviderEnum)},
+					"profile":  gqlProfileArg,
+					"from":     {Type: graphql.String, Description: "RFC3339 or YYYY-MM-DD (default: 30 days before to)"},
+					"to":       {Type: graphql.String, Description: "RFC3339 or YYYY-MM-DD (default: now)"},
+					"interval": {Type: graphql.String, Description: "Bucket size, e.g. 1h, 6h, 1d, 1w"},
+					"metrics":  {Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "Metrics to include (default: all)"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					profile, err := gqlProfile(p)
+					if err != nil {
+						return nil, err
+					}
+					q := StatsHistoryQuery{Provider: p.Args["provider"].(string)}
+					q.From, _ = p.Args["from"].(string)
+					q.To, _ = p.Args["to"].(string)
+					q.Interval, _ = p.Args["interval"].(string)
+					if metrics, ok := p.Args["metrics"].([]interface{}); ok {
+						for _, m := range metrics {
+							q.Metrics = append(q.Metrics, m.(string))
+						}
+					}
+					return gqlResult(GetStatsHistory(profile, q))
+				},
+			},
+			"checkins": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(checkinType))),
+				Args: graphql.FieldConfigArgument{
+					"recentHours": {Type: graphql.Int, Description: "Only checkins from the last n hours (default: all)"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					database, err := gqlDatabase()
+					if err != nil {
+						return nil, err
+					}
+					if hours, ok := p.Args["recentHours"].(int); ok && hours > 0 {
+						return gqlResult(db.GetRecentCheckins(database, hours))
+					}
+					return gqlResult(db.GetCheckins(database))
+				},
+			},
+			"twitchFollowers": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(twitchFollowerType))),
+				Args: graphql.FieldConfigArgument{"profile": gqlProfileArg, "limit": gqlLimitArg},
+				Resolve: gqlTwitchActivity(func(database *sql.DB, channel string, limit int) (interface{}, error) {
+					return gqlResult(db.GetRecentTwitchFollowers(database, channel, limit))
+				}),
+			},
+			"twitchRaids": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(twitchRaidType))),
+				Args: graphql.FieldConfigArgument{"profile": gqlProfileArg, "limit": gqlLimitArg},
+				Resolve: gqlTwitchActivity(func(database *sql.DB, channel string, limit int) (interface{}, error) {
+					return gqlResult(db.GetRecentTwitchRaids(database, channel, limit))
+				}),
+			},
+			"twitchSubs": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(twitchSubType))),
+				Args: graphql.FieldConfigArgument{"profile": gqlProfileArg, "limit": gqlLimitArg},
+				Resolve: gqlTwitchActivity(func(database *sql.DB, channel string, limit int) (interface{}, error) {
+					return gqlResult(db.GetRecentTwitchSubs(database, channel, limit))
+				}),
+			},
+			"twitchBits": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(twitchBitsType))),
+				Args: graphql.FieldConfigArgument{"profile": gqlProfileArg, "limit": gqlLimitArg},
+				Resolve: gqlTwitchActivity(func(database *sql.DB, channel string, limit int) (interface{}, error) {
+					return gqlResult(db.GetRecentTwitchBits(database, channel, limit))
+				}),
+			},
+			"twitchChat": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(twitchChatMessageType))),
+				Description: "The most recent chat messages, oldest first",
+				Args: graphql.FieldConfigArgument{
+					"profile": gqlProfileArg,
+					"limit":   {Type: graphql.Int, DefaultValue: 50},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					profile, err := gqlProfile(p)
+					if err != nil {
+						return nil, err
+					}
+					if profile.TwitchChannel == "" {
+						return nil, fmt.Errorf("profile %s has no Twitch channel", profile.ID)
+					}
+					recent := GetRecentMessages(profile.TwitchChannel)
+					if limit := gqlLimit(p, 100); len(recent) > limit {
+						recent = recent[len(recent)-limit:]
+					}
+					return recent, nil
+				},
+			},
+			"premierLeagueSchedule": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(footballMatchType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return gqlResult(PLSchedule(p.Context))
+				},
+			},
+			"laLigaSchedule": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(footballMatchType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return gqlResult(LaLigaSchedule(p.Context))
+				},
+			},
+			"spotifyCurrentTrack": &graphql.Field{
+				Type:        spotifyTrackType,
+				Description: "The track playing on Spotify, or null when nothing is",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if graphQLDeps.NowPlaying == nil {
+						return nil, fmt.Errorf("spotify is not available")
+					}
+					track, err := graphQLDeps.NowPlaying(p.Context)
+					if track == nil || err != nil {
+						return nil, err
+					}
+					return track, nil
+				},
+			},
+		},
+	})
+	return query, subscription
+}
+
+// gqlTwitchActivity resolves a profile's recent Twitch activity rows
+func gqlTwitchActivity(fetch func(database *sql.DB, channel string, limit int) (interface{}, error)) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		profile, err := gqlProfile(p)
+		if err != nil {
+			return nil, err
+		}
+		if profile.TwitchChannel == "" {
+			return nil, fmt.Errorf("profile %s has no Twitch channel", profile.ID)
+		}
+		database, err := gqlDatabase()
+		if err != nil {
+			return nil, err
+		}
+		return fetch(database, profile.TwitchChannel, gqlLimit(p, 100))
+	}
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"majesticcoding.com/api/models"
+)
+
+// gqlEventTypes are the query types that subscriptions also return
+type gqlEventTypes struct {
+	checkin           *graphql.Object
+	twitchChatMessage *graphql.Object
+	twitchFollower    *graphql.Object
+	twitchRaid        *graphql.Object
+	twitchSub         *graphql.Object
+	twitchBits        *graphql.Object
+	spotifyTrack      *graphql.Object
+}
+
+// GraphQLRequestError is returned for a request that doesn't parse, validate or fit the budgets
+type GraphQLRequestError struct {
+	Errors []models.GraphQLError
+}
+
+func (e *GraphQLRequestError) Error() string {
+	if len(e.Errors) == 0 {
+		return "invalid graphql request"
+	}
+	return e.Errors[0].Message
+}
+
+// SubscribeGraphQL runs an operation from a subscription transport. Subscriptions send a
+// response per event until ctx is done; queries and mutations send one response. The returned
+// channel is closed when there's nothing more to send.
+func SubscribeGraphQL(ctx context.Context, query, operationName string, variables map[string]interface{}) (<-chan *models.GraphQLResponse, error) {
+	schema, err := GraphQLSchema()
+	if err != nil {
+		return nil, fmt.Errorf("graphql schema: %w", err)
+	}
+
+	doc, _, errs := prepareGraphQL(schema, query, operationName, variables)
+	if errs != nil {
+		return nil, &GraphQLRequestError{Errors: errs}
+	}
+
+	out := make(chan *models.GraphQLResponse)
+	if graphQLOperationType(doc, operationName) != ast.OperationTypeSubscription {
+		go func() {
+			defer close(out)
+			response, _ := ExecuteGraphQLQuery(ctx, query, operationName, variables)
+			select {
+			case out <- response:
+			case <-ctx.Done():
+			}
+		}()
+		return out, nil
+	}
+
+	results := graphql.ExecuteSubscription(graphql.ExecuteParams{
+		Schema:        schema,
+		AST:           doc,
+		OperationName: operationName,
+		Args:          variables,
+		Context:       ctx,
+	})
+	go func() {
+		defer close(out)
+		// graphql-go blocks sending each result, so keep draining after ctx is done
+		for result := range results {
+			select {
+			case out <- graphQLResponse(result):
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out, nil
+}
+
+// graphQLOperationType returns query, mutation or subscription for the operation that would
+// run, or "" if the document has no such operation
+func graphQLOperationType(doc *ast.Document, operationName string) string {
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (op.Name != nil && op.Name.Value == operationName) {
+			return op.Operation
+		}
+	}
+	return ""
+}
+
+// gqlSubscribe subscribes a field to a bus topic. The filter is built from the field's
+// arguments; each event is then resolved as the field's value.
+func gqlSubscribe(topic string, filter func(p graphql.ResolveParams) (func(payload interface{}) bool, error)) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		var keep func(payload interface{}) bool
+		if filter != nil {
+			var err error
+			if keep, err = filter(p); err != nil {
+				return nil, err
+			}
+		}
+		return SubscribeEvents(p.Context, topic, keep), nil
+	}
+}
+
+func gqlEventSource(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source, nil
+}
+
+// gqlChannelFilter keeps events for the profile argument's Twitch channel
+func gqlChannelFilter(channelOf func(payload interface{}) string) func(p graphql.ResolveParams) (func(payload interface{}) bool, error) {
+	return func(p graphql.ResolveParams) (func(payload interface{}) bool, error) {
+		profile, err := gqlProfile(p)
+		if err != nil {
+			return nil, err
+		}
+		if profile.TwitchChannel == "" {
+			return nil, fmt.Errorf("profile %s has no Twitch channel", profile.ID)
+		}
+		return func(payload interface{}) bool {
+			return strings.EqualFold(channelOf(payload), profile.TwitchChannel)
+		}, nil
+	}
+}
+
+// gqlTwitchEventSubscription subscribes to a profile's EventSub notifications of the given
+// types, decoding each one's event payload into a fresh value from newValue
+func gqlTwitchEventSubscription(eventType *graphql.Object, newValue func(event models.TwitchEvent) interface{}, types ...string) *graphql.Field {
+	byChannel := gqlChannelFilter(func(payload interface{}) string {
+		return payload.(models.TwitchEvent).Channel
+	})
+	return &graphql.Field{
+		Type: graphql.NewNonNull(eventType),
+		Args: graphql.FieldConfigArgument{"profile": gqlProfileArg},
+		Subscribe: gqlSubscribe(TopicTwitchEvent, func(p graphql.ResolveParams) (func(payload interface{}) bool, error) {
+			sameChannel, err := byChannel(p)
+			if err != nil {
+				return nil, err
+			}
+			return func(payload interface{}) bool {
+				event := payload.(models.TwitchEvent)
+				for _, t := range types {
+					if event.Type == t {
+						return sameChannel(payload)
+					}
+				}
+				return false
+			}, nil
+		}),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			event := p.Source.(models.TwitchEvent)
+			value := newValue(event)
+			if err := json.Unmarshal(event.Event, value); err != nil {
+				return nil, fmt.Errorf("decoding %s event: %w", event.Type, err)
+			}
+			return value, nil
+		},
+	}
+}
+
+func graphQLSubscriptionType(t gqlEventTypes) *graphql.Object {
+	chatMessageType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ChatMessage",
+		Description: "A message in site chat, including ones bridged from Twitch",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.String},
+			"username":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"content":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"timestamp": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"platform":  &graphql.Field{Type: graphql.String},
+			"badges":    &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+			"isAi":      &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+	nowPlayingType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "NowPlaying",
+		Fields: graphql.Fields{
+			"track":     &graphql.Field{Type: t.spotifyTrack, Description: "null when playback stopped"},
+			"changedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+	streamSessionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StreamSession",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"source":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"startedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"endedAt":   &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+	streamStatusType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StreamStatus",
+		Fields: graphql.Fields{
+			"channel": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"live":    &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"session": &graphql.Field{Type: graphql.NewNonNull(streamSessionType)},
+		},
+	})
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"chatMessage": &graphql.Field{
+				Type:      graphql.NewNonNull(chatMessageType),
+				Subscribe: gqlSubscribe(TopicSiteChatMessage, nil),
+				Resolve:   gqlEventSource,
+			},
+			"twitchMessage": &graphql.Field{
+				Type: graphql.NewNonNull(t.twitchChatMessage),
+				Args: graphql.FieldConfigArgument{"profile": gqlProfileArg},
+				Subscribe: gqlSubscribe(TopicTwitchChatMessage, gqlChannelFilter(func(payload interface{}) string {
+					return payload.(models.TwitchMessage).Channel
+				})),
+				Resolve: gqlEventSource,
+			},
+			"twitchFollow": gqlTwitchEventSubscription(t.twitchFollower, func(e models.TwitchEvent) interface{} {
+				return &models.TwitchFollower{Channel: e.Channel, CreatedAt: e.OccurredAt}
+			}, "channel.follow"),
+			"twitchRaid": gqlTwitchEventSubscription(t.twitchRaid, func(e models.TwitchEvent) interface{} {
+				return &models.TwitchRaid{CreatedAt: e.OccurredAt}
+			}, "channel.raid"),
+			"twitchSub": gqlTwitchEventSubscription(t.twitchSub, func(e models.TwitchEvent) interface{} {
+				return &models.TwitchSub{CreatedAt: e.OccurredAt}
+			}, "channel.subscribe", "channel.subscription.message"),
+			"twitchBits": gqlTwitchEventSubscription(t.twitchBits, func(e models.TwitchEvent) interface{} {
+				return &models.TwitchBits{CreatedAt: e.OccurredAt}
+			}, "channel.cheer"),
+			"checkinCreated": &graphql.Field{
+				Type:      graphql.NewNonNull(t.checkin),
+				Subscribe: gqlSubscribe(TopicCheckin, nil),
+				Resolve:   gqlEventSource,
+			},
+			"spotifyNowPlaying": &graphql.Field{
+				Type:        graphql.NewNonNull(nowPlayingType),
+				Description: "Sent when the track changes or playback starts or stops",
+				Subscribe:   gqlSubscribe(TopicNowPlaying, nil),
+				Resolve:     gqlEventSource,
+			},
+			"streamStatus": &graphql.Field{
+				Type:        graphql.NewNonNull(streamStatusType),
+				Description: "Sent when a stream session starts or ends",
+				Args:        graphql.FieldConfigArgument{"profile": gqlProfileArg},
+				Subscribe: gqlSubscribe(TopicStreamStatus, gqlChannelFilter(func(payload interface{}) string {
+					return payload.(models.StreamStatusChange).Channel
+				})),
+				Resolve: gqlEventSource,
+			},
+		},
+	})
+}
+
+// StartNowPlayingWatcher polls Spotify while anyone is subscribed to now-playing changes and
+// publishes when the track changes or playback starts or stops
+func StartNowPlayingWatcher(nowPlaying func(ctx context.Context) (*models.CurrentTrack, error)) {
+	go func() {
+		var last string
+		watching := false
+		for range time.Tick(5 * time.Second) {
+			if !HasEventSubscribers(TopicNowPlaying) {
+				// Start fresh next time so a new subscriber gets the current track
+				watching = false
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
+			track, err := nowPlaying(ctx)
+			cancel()
+			if err != nil {
+				log.Printf("⚠️ Now playing watcher: %v", err)
+				continue
+			}
+
+			key := ""
+			if track != nil && track.IsPlaying {
+				key = track.URL + "|" + track.Title
+			}
+			if watching && key == last {
+				continue
+			}
+			watching, last = true, key
+			if key == "" {
+				track = nil
+			}
+			PublishEvent(TopicNowPlaying, models.NowPlayingChange{Track: track, ChangedAt: time.Now().UTC()})
+		}
+	}()
+}
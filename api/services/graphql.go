@@ -4,74 +4,53 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strconv"
-	"strings"
 	"sync"
 
 	"majesticcoding.com/api/models"
 )
 
-// GetUnifiedStats fetches all stats concurrently and returns unified response
-func GetUnifiedStats(ctx context.Context) (*models.UnifiedStats, error) {
-	stats := &models.UnifiedStats{}
-
-	// Use WaitGroup for concurrent API calls
-	var wg sync.WaitGroup
-
-	// YouTube stats
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if youtubeStats, err := fetchYouTubeStatsGQL(ctx); err != nil {
-			log.Printf("YouTube stats error: %v", err)
-			stats.YouTube = &models.YouTubeStatsGQL{Error: err.Error()}
-		} else {
-			stats.YouTube = youtubeStats
-		}
-	}()
-
-	// GitHub stats
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if githubStats, err := fetchGitHubStatsGQL(ctx); err != nil {
-			log.Printf("GitHub stats error: %v", err)
-			stats.GitHub = &models.GitHubStatsGQL{Error: err.Error()}
-		} else {
-			stats.GitHub = githubStats
-		}
-	}()
-
-	// Twitch stats
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if twitchStats, err := fetchTwitchStatsGQL(ctx); err != nil {
-			log.Printf("Twitch stats error: %v", err)
-			stats.Twitch = &models.TwitchStatsGQL{Error: err.Error()}
-		} else {
-			stats.Twitch = twitchStats
-		}
-	}()
-
-	// LeetCode stats
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if leetcodeStats, err := fetchLeetCodeStatsGQL(ctx); err != nil {
-			log.Printf("LeetCode stats error: %v", err)
-			stats.LeetCode = &models.LeetCodeStatsGQL{Error: err.Error()}
-		} else {
-			stats.LeetCode = leetcodeStats
-		}
-	}()
-
-	// Wait for all goroutines to complete
+// GetUnifiedStats fetches all of a profile's stats concurrently and returns unified response.
+// Each provider goes through ProviderStats, so the cache and any in-flight fetch are shared.
+func GetUnifiedStats(ctx context.Context, profile models.Profile) (*models.UnifiedStats, error) {
+	ctx = WithStatsMemo(ctx)
+
+	// Each goroutine fills only its own variable; they're combined after Wait
+	var (
+		wg                                            sync.WaitGroup
+		youtube                                       *models.YouTubeStatsGQL
+		github                                        *models.GitHubStatsGQL
+		twitch                                        *models.TwitchStatsGQL
+		leetcode                                      *models.LeetCodeStatsGQL
+		youtubeErr, githubErr, twitchErr, leetcodeErr error
+	)
+	wg.Add(4)
+	go func() { defer wg.Done(); youtube, youtubeErr = fetchYouTubeStatsGQL(ctx, profile) }()
+	go func() { defer wg.Done(); github, githubErr = fetchGitHubStatsGQL(ctx, profile) }()
+	go func() { defer wg.Done(); twitch, twitchErr = fetchTwitchStatsGQL(ctx, profile) }()
+	go func() { defer wg.Done(); leetcode, leetcodeErr = fetchLeetCodeStatsGQL(ctx, profile) }()
 	wg.Wait()
 
+	stats := &models.UnifiedStats{YouTube: youtube, GitHub: github, Twitch: twitch, LeetCode: leetcode}
+	if youtubeErr != nil {
+		log.Printf("YouTube stats error: %v", youtubeErr)
+		stats.YouTube = &models.YouTubeStatsGQL{Error: youtubeErr.Error()}
+	}
+	if githubErr != nil {
+		log.Printf("GitHub stats error: %v", githubErr)
+		stats.GitHub = &models.GitHubStatsGQL{Error: githubErr.Error()}
+	}
+	if twitchErr != nil {
+		log.Printf("Twitch stats error: %v", twitchErr)
+		stats.Twitch = &models.TwitchStatsGQL{Error: twitchErr.Error()}
+	}
+	if leetcodeErr != nil {
+		log.Printf("LeetCode stats error: %v", leetcodeErr)
+		stats.LeetCode = &models.LeetCodeStatsGQL{Error: leetcodeErr.Error()}
+	}
+
 	// RAG context storage disabled to avoid embedding API quota issues
 	// go func() {
-	// 	if err := StoreSocialStatsContext(stats); err != nil {
+	// 	if err := StoreSocialStatsContext(profile, stats); err != nil {
 	// 		log.Printf("Failed to store social stats context: %v", err)
 	// 	}
 	// }()
@@ -79,189 +58,82 @@ func GetUnifiedStats(ctx context.Context) (*models.UnifiedStats, error) {
 	return stats, nil
 }
 
-// fetchYouTubeStatsGQL converts YouTube API response to GraphQL format
-func fetchYouTubeStatsGQL(ctx context.Context) (*models.YouTubeStatsGQL, error) {
-	// Use existing YouTube service logic
-	stats, err := FetchYouTubeStats()
+// fetchYouTubeStatsGQL converts a YouTube snapshot to GraphQL format
+func fetchYouTubeStatsGQL(ctx context.Context, profile models.Profile) (*models.YouTubeStatsGQL, error) {
+	snapshot, err := ProviderStats(ctx, "youtube", profile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch YouTube stats: %w", err)
 	}
 
-	// Convert string values to integers
-	subscribers := 0
-	views := 0
-	videos := 0
-	channelName := ""
-
-	if val, ok := stats["channelTitle"].(string); ok {
-		channelName = val
-	}
-	if val, ok := stats["subscribers"].(string); ok {
-		if converted, err := strconv.Atoi(val); err == nil {
-			subscribers = converted
-		}
+	stats := &models.YouTubeStatsGQL{
+		ChannelName:   snapshot.DisplayName,
+		Subscribers:   int(snapshot.Metrics["subscribers"]),
+		Views:         int(snapshot.Metrics["views"]),
+		Videos:        int(snapshot.Metrics["videos"]),
+		RecentUploads: []models.YouTubeVideo{},
 	}
-	if val, ok := stats["views"].(string); ok {
-		if converted, err := strconv.Atoi(val); err == nil {
-			views = converted
-		}
+	if details, ok := snapshot.Details.(*models.YouTubeDetails); ok {
+		stats.RecentUploads = details.RecentUploads
 	}
-	if val, ok := stats["videos"].(string); ok {
-		if converted, err := strconv.Atoi(val); err == nil {
-			videos = converted
-		}
-	}
-
-	return &models.YouTubeStatsGQL{
-		ChannelName: channelName,
-		Subscribers: subscribers,
-		Views:       views,
-		Videos:      videos,
-	}, nil
+	return stats, nil
 }
 
-// fetchGitHubStatsGQL converts GitHub API response to GraphQL format
-func fetchGitHubStatsGQL(ctx context.Context) (*models.GitHubStatsGQL, error) {
-	// Use existing GitHub service logic
-	stats, err := FetchGitHubStats("mattmajestic")
+// fetchGitHubStatsGQL converts a GitHub snapshot to GraphQL format
+func fetchGitHubStatsGQL(ctx context.Context, profile models.Profile) (*models.GitHubStatsGQL, error) {
+	snapshot, err := ProviderStats(ctx, "github", profile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch GitHub stats: %w", err)
 	}
 
-	return &models.GitHubStatsGQL{
-		Username:      stats.Username,
-		PublicRepos:   stats.PublicRepos,
-		Followers:     stats.Followers,
-		StarsReceived: stats.StarsReceived,
-	}, nil
+	stats := &models.GitHubStatsGQL{
+		Username:      snapshot.Account,
+		PublicRepos:   int(snapshot.Metrics["public_repos"]),
+		Followers:     int(snapshot.Metrics["followers"]),
+		StarsReceived: int(snapshot.Metrics["stars"]),
+		Languages:     []models.LanguageCount{},
+	}
+	if details, ok := snapshot.Details.(*models.GitHubDetails); ok {
+		stats.Languages = details.Languages
+		stats.Contributions = details.Contributions
+	}
+	return stats, nil
 }
 
-// fetchTwitchStatsGQL converts Twitch API response to GraphQL format
-func fetchTwitchStatsGQL(ctx context.Context) (*models.TwitchStatsGQL, error) {
-	// Use existing Twitch service logic
-	stats, err := FetchTwitchStats("MajesticCodingTwitch")
+// fetchTwitchStatsGQL converts a Twitch snapshot to GraphQL format
+func fetchTwitchStatsGQL(ctx context.Context, profile models.Profile) (*models.TwitchStatsGQL, error) {
+	snapshot, err := ProviderStats(ctx, "twitch", profile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Twitch stats: %w", err)
 	}
 
-	return &models.TwitchStatsGQL{
-		DisplayName:     stats.DisplayName,
-		Description:     stats.Description,
-		BroadcasterType: stats.BroadcasterType,
-		Followers:       stats.Followers,
-	}, nil
-}
-
-// fetchLeetCodeStatsGQL converts LeetCode API response to GraphQL format
-func fetchLeetCodeStatsGQL(ctx context.Context) (*models.LeetCodeStatsGQL, error) {
-	// Use existing LeetCode service logic
-	stats, err := FetchLeetCodeStats("mattmajestic")
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch LeetCode stats: %w", err)
+	stats := &models.TwitchStatsGQL{
+		DisplayName: snapshot.DisplayName,
+		Followers:   int(snapshot.Metrics["followers"]),
 	}
-
-	return &models.LeetCodeStatsGQL{
-		Username:    stats.Username,
-		Languages:   stats.Languages,
-		SolvedCount: stats.SolvedCount,
-		Ranking:     stats.Ranking,
-	}, nil
-}
-
-// ExecuteGraphQLQuery handles GraphQL query execution
-func ExecuteGraphQLQuery(ctx context.Context, query string, variables map[string]interface{}) (*models.GraphQLResponse, error) {
-	// Simple query parser - check if query contains "unifiedStats"
-	if strings.Contains(query, "unifiedStats") {
-		// Parse which providers are requested
-		includeYouTube := strings.Contains(query, "youtube")
-		includeGitHub := strings.Contains(query, "github")
-		includeTwitch := strings.Contains(query, "twitch")
-		includeLeetCode := strings.Contains(query, "leetcode")
-
-		stats, err := GetSelectiveStats(ctx, includeYouTube, includeGitHub, includeTwitch, includeLeetCode)
-		if err != nil {
-			return &models.GraphQLResponse{
-				Errors: []models.GraphQLError{{Message: err.Error()}},
-			}, nil
-		}
-
-		return &models.GraphQLResponse{
-			Data: map[string]interface{}{
-				"unifiedStats": stats,
-			},
-		}, nil
+	if details, ok := snapshot.Details.(*models.TwitchDetails); ok {
+		stats.Description = details.Description
+		stats.BroadcasterType = details.BroadcasterType
 	}
-
-	return &models.GraphQLResponse{
-		Errors: []models.GraphQLError{{Message: "Unsupported query. Try: query { unifiedStats { youtube { channelName subscribers views videos } github { username publicRepos followers starsReceived } twitch { displayName description broadcasterType followers } leetcode { username languages solvedCount ranking } } }"}},
-	}, nil
+	return stats, nil
 }
 
-// GetSelectiveStats fetches only the requested stats
-func GetSelectiveStats(ctx context.Context, includeYouTube, includeGitHub, includeTwitch, includeLeetCode bool) (*models.UnifiedStats, error) {
-	stats := &models.UnifiedStats{}
-
-	// Use WaitGroup for concurrent API calls
-	var wg sync.WaitGroup
-
-	// YouTube stats
-	if includeYouTube {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if youtubeStats, err := fetchYouTubeStatsGQL(ctx); err != nil {
-				log.Printf("YouTube stats error: %v", err)
-				stats.YouTube = &models.YouTubeStatsGQL{Error: err.Error()}
-			} else {
-				stats.YouTube = youtubeStats
-			}
-		}()
-	}
-
-	// GitHub stats
-	if includeGitHub {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if githubStats, err := fetchGitHubStatsGQL(ctx); err != nil {
-				log.Printf("GitHub stats error: %v", err)
-				stats.GitHub = &models.GitHubStatsGQL{Error: err.Error()}
-			} else {
-				stats.GitHub = githubStats
-			}
-		}()
+// fetchLeetCodeStatsGQL converts a LeetCode snapshot to GraphQL format
+func fetchLeetCodeStatsGQL(ctx context.Context, profile models.Profile) (*models.LeetCodeStatsGQL, error) {
+	snapshot, err := ProviderStats(ctx, "leetcode", profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch LeetCode stats: %w", err)
 	}
 
-	// Twitch stats
-	if includeTwitch {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if twitchStats, err := fetchTwitchStatsGQL(ctx); err != nil {
-				log.Printf("Twitch stats error: %v", err)
-				stats.Twitch = &models.TwitchStatsGQL{Error: err.Error()}
-			} else {
-				stats.Twitch = twitchStats
-			}
-		}()
+	stats := &models.LeetCodeStatsGQL{
+		Username:    snapshot.Account,
+		SolvedCount: int(snapshot.Metrics["solved"]),
+		Ranking:     int(snapshot.Metrics["ranking"]),
 	}
-
-	// LeetCode stats
-	if includeLeetCode {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if leetcodeStats, err := fetchLeetCodeStatsGQL(ctx); err != nil {
-				log.Printf("LeetCode stats error: %v", err)
-				stats.LeetCode = &models.LeetCodeStatsGQL{Error: err.Error()}
-			} else {
-				stats.LeetCode = leetcodeStats
-			}
-		}()
+	if details, ok := snapshot.Details.(*models.LeetCodeDetails); ok {
+		stats.Languages = details.Languages
+		stats.Easy = details.Easy
+		stats.Medium = details.Medium
+		stats.Hard = details.Hard
 	}
-
-	// Wait for all goroutines to complete
-	wg.Wait()
-
 	return stats, nil
 }
@@ -1,17 +1,31 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
 	"majesticcoding.com/api/models"
 )
 
-func FetchLaLigaSchedule() ([]models.LaLigaMatch, error) {
-	apiKey := os.Getenv("EPL_TOKEN")
+// laLigaScheduleCache holds the schedule. Fixtures rarely change, so a stale copy is served
+// for up to a day while it refreshes.
+var laLigaScheduleCache = NewCached[[]models.LaLigaMatch]("football.laliga", CachePolicy{
+	Fresh:       6 * time.Hour,
+	Stale:       24 * time.Hour,
+	NegativeTTL: 5 * time.Minute,
+	Tags:        []string{"football"},
+})
+
+// LaLigaSchedule returns the next two weeks of La Liga matches through the cache
+func LaLigaSchedule(ctx context.Context) ([]models.LaLigaMatch, error) {
+	return laLigaScheduleCache.Key("upcoming").Get(ctx, FetchLaLigaSchedule)
+}
+
+func FetchLaLigaSchedule(ctx context.Context) ([]models.LaLigaMatch, error) {
+	apiKey := conf().Football.Token
 	if apiKey == "" {
 		return nil, fmt.Errorf("EPL_TOKEN not found")
 	}
@@ -23,7 +37,7 @@ func FetchLaLigaSchedule() ([]models.LaLigaMatch, error) {
 
 	url := fmt.Sprintf("https://api.football-data.org/v4/competitions/PD/matches?dateFrom=%s&dateTo=%s", from, to)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("request creation failed: %w", err)
 	}
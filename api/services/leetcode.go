@@ -1,30 +1,74 @@
 package services
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"majesticcoding.com/api/models"
+	"majesticcoding.com/db"
 )
 
 const baseURL = "https://alfa-leetcode-api.onrender.com"
 
+// ErrRateLimited is returned (or matched via RateLimitError) when a stats provider throttles us
 var ErrRateLimited = errors.New("leetcode API rate limited")
 
-func FetchLeetCodeStats(username string) (*models.LeetCodeStats, error) {
-	summary := &models.LeetCodeStats{Username: username}
-	summary.Languages = "Python | SQL | Go"
+type leetCodeStatsProvider struct{}
 
+func (leetCodeStatsProvider) Name() string                    { return "leetcode" }
+func (leetCodeStatsProvider) Account(p models.Profile) string { return p.LeetCodeUser }
+func (leetCodeStatsProvider) DefaultInterval() time.Duration  { return time.Hour } // the public LeetCode API rate limits aggressively
+func (leetCodeStatsProvider) NewDetails() interface{}         { return &models.LeetCodeDetails{} }
+func (leetCodeStatsProvider) Fetch(ctx context.Context, username string) (*models.StatsSnapshot, error) {
+	return FetchLeetCodeStats(ctx, username)
+}
+
+func (leetCodeStatsProvider) Record(database *sql.DB, s *models.StatsSnapshot) error {
+	languages := ""
+	if details, ok := s.Details.(*models.LeetCodeDetails); ok {
+		languages = details.Languages
+	}
+	return db.InsertLeetCodeStats(database, s.Account, int(s.Metrics["solved"]), int(s.Metrics["ranking"]), languages)
+}
+
+func (leetCodeStatsProvider) Latest(database *sql.DB, username string) (*models.StatsSnapshot, error) {
+	stats, err := db.GetLatestLeetCodeStats(database, username)
+	if err != nil {
+		return nil, err
+	}
+	return &models.StatsSnapshot{
+		DisplayName: stats.Username,
+		URL:         "https://leetcode.com/u/" + stats.Username,
+		Metrics: map[string]int64{
+			"solved":  int64(stats.SolvedCount),
+			"ranking": int64(stats.Ranking),
+		},
+		Details: &models.LeetCodeDetails{Languages: stats.Languages},
+	}, nil
+}
+
+// FetchLeetCodeStats fetches a user's solved count, ranking and solved-by-difficulty breakdown
+func FetchLeetCodeStats(ctx context.Context, username string) (*models.StatsSnapshot, error) {
 	profileURL := fmt.Sprintf("%s/userProfile/%s", baseURL, username)
-	resp, err := http.Get(profileURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", profileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request creation failed: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching profile: %w", err)
 	}
 	defer resp.Body.Close()
+	if err := rateLimitFromResponse("leetcode", resp); err != nil {
+		return nil, err
+	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -39,8 +83,15 @@ func FetchLeetCodeStats(username string) (*models.LeetCodeStats, error) {
 
 	// Unmarshal only the fields we need
 	var parsed struct {
-		TotalSolved int `json:"totalSolved"`
-		Ranking     int `json:"ranking"`
+		TotalSolved    int `json:"totalSolved"`
+		TotalQuestions int `json:"totalQuestions"`
+		EasySolved     int `json:"easySolved"`
+		TotalEasy      int `json:"totalEasy"`
+		MediumSolved   int `json:"mediumSolved"`
+		TotalMedium    int `json:"totalMedium"`
+		HardSolved     int `json:"hardSolved"`
+		TotalHard      int `json:"totalHard"`
+		Ranking        int `json:"ranking"`
 	}
 
 	if err := json.Unmarshal(body, &parsed); err != nil {
@@ -51,8 +102,22 @@ func FetchLeetCodeStats(username string) (*models.LeetCodeStats, error) {
 		return nil, fmt.Errorf("error decoding profile JSON: %w\nRaw: %s", err, bodyStr)
 	}
 
-	summary.SolvedCount = parsed.TotalSolved
-	summary.Ranking = parsed.Ranking
-
-	return summary, nil
+	return &models.StatsSnapshot{
+		DisplayName: username,
+		URL:         "https://leetcode.com/u/" + username,
+		Metrics: map[string]int64{
+			"solved":        int64(parsed.TotalSolved),
+			"ranking":       int64(parsed.Ranking),
+			"easy_solved":   int64(parsed.EasySolved),
+			"medium_solved": int64(parsed.MediumSolved),
+			"hard_solved":   int64(parsed.HardSolved),
+		},
+		Details: &models.LeetCodeDetails{
+			Easy:           models.LeetCodeDifficulty{Solved: parsed.EasySolved, Total: parsed.TotalEasy},
+			Medium:         models.LeetCodeDifficulty{Solved: parsed.MediumSolved, Total: parsed.TotalMedium},
+			Hard:           models.LeetCodeDifficulty{Solved: parsed.HardSolved, Total: parsed.TotalHard},
+			TotalQuestions: parsed.TotalQuestions,
+			Languages:      "Python | SQL | Go",
+		},
+	}, nil
 }
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+type mastodonStatsProvider struct{}
+
+func (mastodonStatsProvider) Name() string                    { return "mastodon" }
+func (mastodonStatsProvider) Account(p models.Profile) string { return profileAccount(p, "mastodon") }
+func (mastodonStatsProvider) DefaultInterval() time.Duration  { return 15 * time.Minute }
+func (mastodonStatsProvider) NewDetails() interface{}         { return nil }
+
+// Fetch looks up an account given as user@instance on its home instance
+func (mastodonStatsProvider) Fetch(ctx context.Context, account string) (*models.StatsSnapshot, error) {
+	user, instance, ok := strings.Cut(strings.TrimPrefix(account, "@"), "@")
+	if !ok || user == "" || instance == "" {
+		return nil, fmt.Errorf("mastodon account %q should look like user@instance", account)
+	}
+
+	var acct struct {
+		DisplayName    string `json:"display_name"`
+		Username       string `json:"username"`
+		URL            string `json:"url"`
+		FollowersCount int64  `json:"followers_count"`
+		FollowingCount int64  `json:"following_count"`
+		StatusesCount  int64  `json:"statuses_count"`
+	}
+	endpoint := fmt.Sprintf("https://%s/api/v1/accounts/lookup?acct=%s", instance, url.QueryEscape(user))
+	if err := getStatsJSON(ctx, "mastodon", endpoint, nil, &acct); err != nil {
+		return nil, err
+	}
+
+	displayName := acct.DisplayName
+	if displayName == "" {
+		displayName = acct.Username
+	}
+	return &models.StatsSnapshot{
+		DisplayName: displayName,
+		URL:         acct.URL,
+		Metrics: map[string]int64{
+			"followers": acct.FollowersCount,
+			"following": acct.FollowingCount,
+			"posts":     acct.StatusesCount,
+		},
+	}, nil
+}
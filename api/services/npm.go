@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+// npmMaxPackages caps how many of a maintainer's packages are counted
+const npmMaxPackages = 250
+
+type npmStatsProvider struct{}
+
+func (npmStatsProvider) Name() string                    { return "npm" }
+func (npmStatsProvider) Account(p models.Profile) string { return profileAccount(p, "npm") }
+func (npmStatsProvider) DefaultInterval() time.Duration  { return 6 * time.Hour } // download counts update daily
+func (npmStatsProvider) NewDetails() interface{}         { return &models.NpmDetails{} }
+
+// Fetch totals last week's downloads across the packages a user maintains
+func (npmStatsProvider) Fetch(ctx context.Context, username string) (*models.StatsSnapshot, error) {
+	var search struct {
+		Objects []struct {
+			Package struct {
+				Name string `json:"name"`
+			} `json:"package"`
+		} `json:"objects"`
+	}
+	endpoint := fmt.Sprintf("https://registry.npmjs.org/-/v1/search?text=maintainer:%s&size=%d", url.QueryEscape(username), npmMaxPackages)
+	if err := getStatsJSON(ctx, "npm", endpoint, nil, &search); err != nil {
+		return nil, err
+	}
+
+	details := &models.NpmDetails{Packages: []models.NpmPackageDownloads{}}
+	var unscoped []string
+	for _, obj := range search.Objects {
+		name := obj.Package.Name
+		if !strings.HasPrefix(name, "@") {
+			unscoped = append(unscoped, name)
+			continue
+		}
+		// The bulk endpoint doesn't take scoped packages
+		var point struct {
+			Downloads int64 `json:"downloads"`
+		}
+		if err := getStatsJSON(ctx, "npm", "https://api.npmjs.org/downloads/point/last-week/"+name, nil, &point); err != nil {
+			return nil, fmt.Errorf("downloads for %s: %w", name, err)
+		}
+		details.Packages = append(details.Packages, models.NpmPackageDownloads{Name: name, Downloads: point.Downloads})
+	}
+
+	// Bulk queries take up to 128 packages
+	for start := 0; start < len(unscoped); start += 128 {
+		batch := unscoped[start:min(start+128, len(unscoped))]
+		points := map[string]*struct {
+			Downloads int64 `json:"downloads"`
+		}{}
+		endpoint := "https://api.npmjs.org/downloads/point/last-week/" + strings.Join(batch, ",")
+		if len(batch) == 1 {
+			// A single package gets the plain response rather than a map
+			var point struct {
+				Downloads int64 `json:"downloads"`
+			}
+			if err := getStatsJSON(ctx, "npm", endpoint, nil, &point); err != nil {
+				return nil, fmt.Errorf("downloads: %w", err)
+			}
+			details.Packages = append(details.Packages, models.NpmPackageDownloads{Name: batch[0], Downloads: point.Downloads})
+			continue
+		}
+		if err := getStatsJSON(ctx, "npm", endpoint, nil, &points); err != nil {
+			return nil, fmt.Errorf("downloads: %w", err)
+		}
+		for _, name := range batch {
+			if p := points[name]; p != nil {
+				details.Packages = append(details.Packages, models.NpmPackageDownloads{Name: name, Downloads: p.Downloads})
+			}
+		}
+	}
+
+	var total int64
+	for _, p := range details.Packages {
+		total += p.Downloads
+	}
+	sort.Slice(details.Packages, func(i, j int) bool {
+		return details.Packages[i].Downloads > details.Packages[j].Downloads
+	})
+
+	return &models.StatsSnapshot{
+		DisplayName: username,
+		URL:         "https://www.npmjs.com/~" + username,
+		Metrics: map[string]int64{
+			"packages":         int64(len(details.Packages)),
+			"weekly_downloads": total,
+		},
+		Details: details,
+	}, nil
+}
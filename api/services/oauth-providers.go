@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+// ErrReauthRequired means the provider rejected the refresh token; the user has to log in again
+var ErrReauthRequired = errors.New("refresh token rejected")
+
+// OAuthProvider describes how to exchange and refresh tokens for one provider
+type OAuthProvider struct {
+	Name     string
+	TokenURL string
+	// Credentials returns the client ID and secret; ClientIDEnv and ClientSecretEnv name
+	// them in errors
+	Credentials     func() (string, string)
+	ClientIDEnv     string
+	ClientSecretEnv string
+	BasicAuth       bool // send client credentials as HTTP basic auth instead of form fields
+
+	// Identify returns the account a fresh access token belongs to
+	Identify func(ctx context.Context, client *http.Client, accessToken string) (string, error)
+}
+
+var oauthProviders = map[string]*OAuthProvider{
+	models.ProviderTwitch: {
+		Name:            models.ProviderTwitch,
+		TokenURL:        "https://id.twitch.tv/oauth2/token",
+		Credentials:     func() (string, string) { return conf().Twitch.ClientID, conf().Twitch.ClientSecret },
+		ClientIDEnv:     "TWITCH_CLIENT_ID",
+		ClientSecretEnv: "TWITCH_CLIENT_SECRET",
+		Identify:        identifyTwitchAccount,
+	},
+	models.ProviderSpotify: {
+		Name:            models.ProviderSpotify,
+		TokenURL:        "https://accounts.spotify.com/api/token",
+		Credentials:     func() (string, string) { return conf().Spotify.ClientID, conf().Spotify.ClientSecret },
+		ClientIDEnv:     "SPOTIFY_CLIENT_ID",
+		ClientSecretEnv: "SPOTIFY_CLIENT_SECRET",
+		BasicAuth:       true,
+		Identify:        identifySpotifyAccount,
+	},
+}
+
+// ExchangeOAuthCode trades an authorization code for a token and works out which account it belongs to
+func ExchangeOAuthCode(ctx context.Context, provider, code, redirectURI string) (*models.OAuthToken, error) {
+	p, ok := oauthProviders[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown OAuth provider %q", provider)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	token, err := p.requestToken(ctx, client, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {redirectURI},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	token.Account, err = p.Identify(ctx, client, token.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify %s account: %w", provider, err)
+	}
+	return token, nil
+}
+
+// refresh uses a refresh token to get a new access token. Providers that don't rotate
+// refresh tokens keep the old one.
+func (p *OAuthProvider) refresh(ctx context.Context, client *http.Client, refreshToken string) (*models.OAuthToken, error) {
+	token, err := p.requestToken(ctx, client, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+func (p *OAuthProvider) requestToken(ctx context.Context, client *http.Client, form url.Values) (*models.OAuthToken, error) {
+	clientID, clientSecret := p.Credentials()
+	if clientID == "" || clientSecret == "" {
+		return nil, fmt.Errorf("%s or %s not set", p.ClientIDEnv, p.ClientSecretEnv)
+	}
+	if !p.BasicAuth {
+		form.Set("client_id", clientID)
+		form.Set("client_secret", clientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if p.BasicAuth {
+		req.SetBasicAuth(clientID, clientSecret)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	rejected := resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized
+	if rejected && form.Get("grant_type") == "refresh_token" {
+		return nil, fmt.Errorf("%w: status %d: %s", ErrReauthRequired, resp.StatusCode, string(body))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		AccessToken  string          `json:"access_token"`
+		RefreshToken string          `json:"refresh_token"`
+		TokenType    string          `json:"token_type"`
+		ExpiresIn    int             `json:"expires_in"`
+		Scope        json.RawMessage `json:"scope"` // a list on Twitch, a string on Spotify
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("token response had no access token")
+	}
+
+	return &models.OAuthToken{
+		Provider:     p.Name,
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+		TokenType:    result.TokenType,
+		Scopes:       parseOAuthScopes(result.Scope),
+		ExpiresAt:    time.Now().Add(time.Duration(result.ExpiresIn) * time.Second),
+	}, nil
+}
+
+func parseOAuthScopes(raw json.RawMessage) string {
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return strings.Join(list, " ")
+	}
+	var s string
+	json.Unmarshal(raw, &s)
+	return s
+}
+
+func identifyTwitchAccount(ctx context.Context, client *http.Client, accessToken string) (string, error) {
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://id.twitch.tv/oauth2/validate", nil)
+	req.Header.Set("Authorization", "OAuth "+accessToken)
+
+	var result struct {
+		Login string `json:"login"`
+	}
+	if err := getOAuthJSON(client, req, &result); err != nil {
+		return "", err
+	}
+	return strings.ToLower(result.Login), nil
+}
+
+func identifySpotifyAccount(ctx context.Context, client *http.Client, accessToken string) (string, error) {
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://api.spotify.com/v1/me", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := getOAuthJSON(client, req, &result); err != nil {
+		return "", err
+	}
+	return result.ID, nil
+}
+
+func getOAuthJSON(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
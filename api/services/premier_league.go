@@ -1,17 +1,31 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
 	"majesticcoding.com/api/models"
 )
 
-func FetchPLSchedule() ([]models.PLMatch, error) {
-	apiKey := os.Getenv("EPL_TOKEN")
+// plScheduleCache holds the schedule. Fixtures rarely change, so a stale copy is served
+// for up to a day while it refreshes.
+var plScheduleCache = NewCached[[]models.PLMatch]("football.epl", CachePolicy{
+	Fresh:       6 * time.Hour,
+	Stale:       24 * time.Hour,
+	NegativeTTL: 5 * time.Minute,
+	Tags:        []string{"football"},
+})
+
+// PLSchedule returns the next two weeks of Premier League matches through the cache
+func PLSchedule(ctx context.Context) ([]models.PLMatch, error) {
+	return plScheduleCache.Key("upcoming").Get(ctx, FetchPLSchedule)
+}
+
+func FetchPLSchedule(ctx context.Context) ([]models.PLMatch, error) {
+	apiKey := conf().Football.Token
 	if apiKey == "" {
 		return nil, fmt.Errorf("EPL_TOKEN not found")
 	}
@@ -23,7 +37,7 @@ func FetchPLSchedule() ([]models.PLMatch, error) {
 
 	url := fmt.Sprintf("https://api.football-data.org/v4/competitions/PL/matches?dateFrom=%s&dateTo=%s", from, to)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("request creation failed: %w", err)
 	}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"majesticcoding.com/api/config"
+	"majesticcoding.com/api/models"
+)
+
+// ErrUnknownProfile is returned when a request names a profile that isn't configured
+var ErrUnknownProfile = errors.New("unknown profile")
+
+var (
+	profilesMu   sync.Mutex
+	profiles     []models.Profile
+	profilesFrom config.ProfilesConfig // the settings profiles were loaded from
+
+	profileIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+)
+
+// Profiles returns every configured profile, default first.
+//
+// Profiles come from the JSON array in PROFILES_FILE or PROFILES. Without either, a single
+// default profile is built from TWITCH_CHANNEL, GITHUB_USERNAME, LEETCODE_USERNAME, YT_CHANNEL_ID
+// and STATS_ACCOUNT_<PROVIDER>. They're loaded again when a config reload changes TWITCH_CHANNEL.
+func Profiles() []models.Profile {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	if current := conf().Profiles; profiles == nil || current != profilesFrom {
+		loaded, err := loadProfiles()
+		if err != nil {
+			log.Printf("❌ Invalid profile config, using the default profile only: %v", err)
+			loaded = []models.Profile{defaultProfileFromConfig()}
+		}
+		profiles, profilesFrom = loaded, current
+		log.Printf("👥 Loaded %d profile(s), default %q", len(profiles), profiles[0].ID)
+	}
+
+	copied := make([]models.Profile, len(profiles))
+	copy(copied, profiles)
+	return copied
+}
+
+// DefaultProfile returns the profile used when a request doesn't name one
+func DefaultProfile() models.Profile {
+	return Profiles()[0]
+}
+
+// GetProfile looks up a profile by id. An empty id returns the default profile.
+func GetProfile(id string) (models.Profile, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if id == "" {
+		return DefaultProfile(), nil
+	}
+	for _, p := range Profiles() {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return models.Profile{}, fmt.Errorf("%w: %q", ErrUnknownProfile, id)
+}
+
+// ProfileForTwitchChannel finds the profile that owns a Twitch channel
+func ProfileForTwitchChannel(channel string) (models.Profile, bool) {
+	channel = strings.ToLower(strings.TrimPrefix(channel, "#"))
+	for _, p := range Profiles() {
+		if p.TwitchChannel != "" && p.TwitchChannel == channel {
+			return p, true
+		}
+	}
+	return models.Profile{}, false
+}
+
+// TwitchChannels returns the Twitch channel of every profile that has one
+func TwitchChannels() []string {
+	var channels []string
+	for _, p := range Profiles() {
+		if p.TwitchChannel != "" {
+			channels = append(channels, p.TwitchChannel)
+		}
+	}
+	return channels
+}
+
+func loadProfiles() ([]models.Profile, error) {
+	profilesConf := conf().Profiles
+	raw := []byte(profilesConf.Inline)
+	if path := profilesConf.File; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		raw = data
+	}
+	if len(raw) == 0 {
+		return []models.Profile{defaultProfileFromConfig()}, nil
+	}
+
+	var list []models.Profile
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("parse profiles: %w", err)
+	}
+	return normalizeProfiles(list)
+}
+
+// normalizeProfiles validates ids and moves the default profile to the front. The profile
+// with id "default", or else the first one marked default, or else the first one listed, wins.
+func normalizeProfiles(list []models.Profile) ([]models.Profile, error) {
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no profiles configured")
+	}
+
+	seen := make(map[string]bool)
+	channels := make(map[string]string)
+	defaultIndex := -1
+	for i := range list {
+		p := &list[i]
+		p.ID = strings.ToLower(strings.TrimSpace(p.ID))
+		p.TwitchChannel = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(p.TwitchChannel), "#"))
+
+		if !profileIDPattern.MatchString(p.ID) {
+			return nil, fmt.Errorf("invalid profile id %q", p.ID)
+		}
+		if seen[p.ID] {
+			return nil, fmt.Errorf("duplicate profile id %q", p.ID)
+		}
+		seen[p.ID] = true
+		if owner, ok := channels[p.TwitchChannel]; ok && p.TwitchChannel != "" {
+			return nil, fmt.Errorf("twitch channel %q is used by both %q and %q", p.TwitchChannel, owner, p.ID)
+		}
+		channels[p.TwitchChannel] = p.ID
+		if p.Name == "" {
+			p.Name = p.ID
+		}
+
+		if p.ID == models.DefaultProfileID || (p.Default && defaultIndex == -1) {
+			defaultIndex = i
+		}
+	}
+	if defaultIndex == -1 {
+		defaultIndex = 0
+	}
+
+	ordered := make([]models.Profile, 0, len(list))
+	ordered = append(ordered, list[defaultIndex])
+	for i, p := range list {
+		if i != defaultIndex {
+			p.Default = false
+			ordered = append(ordered, p)
+		}
+	}
+	ordered[0].Default = true
+	return ordered, nil
+}
+
+// defaultProfileFromConfig is the single-creator setup this site started with
+func defaultProfileFromConfig() models.Profile {
+	c := conf()
+
+	// Providers without a field of their own read stats.accounts (STATS_ACCOUNT_<PROVIDER>,
+	// e.g. STATS_ACCOUNT_BLUESKY)
+	accounts := map[string]string{}
+	for _, name := range StatsProviderNames() {
+		if v := c.Stats.Accounts[name]; v != "" {
+			accounts[name] = v
+		}
+	}
+
+	return models.Profile{
+		ID:               models.DefaultProfileID,
+		Name:             c.Profiles.Name,
+		TwitchChannel:    strings.ToLower(c.Profiles.TwitchChannel),
+		GitHubUser:       c.Profiles.GitHubUser,
+		LeetCodeUser:     c.Profiles.LeetCodeUser,
+		YouTubeChannelID: c.Profiles.YouTubeChannelID,
+		Accounts:         accounts,
+		Default:          true,
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// RedisSet stores a key-value pair with optional TTL (in seconds)
+func RedisSet(key string, value interface{}, ttlSeconds int) error {
+	ctx, cancel := cacheContext()
+	defer cancel()
+
+	err := CacheBackend().Set(ctx, key, fmt.Sprintf("%v", value), time.Duration(ttlSeconds)*time.Second)
+	if err != nil {
+		log.Printf("Redis SET error for key %s: %v", key, err)
+	}
+	return err
+}
+
+// RedisGet retrieves a value by key, or "" if it doesn't exist
+func RedisGet(key string) (string, error) {
+	ctx, cancel := cacheContext()
+	defer cancel()
+
+	value, _, err := CacheBackend().Get(ctx, key)
+	if err != nil {
+		log.Printf("Redis GET error for key %s: %v", key, err)
+		return "", err
+	}
+	return value, nil
+}
+
+// RedisDelete removes a key
+func RedisDelete(key string) error {
+	ctx, cancel := cacheContext()
+	defer cancel()
+
+	if _, err := CacheBackend().Delete(ctx, key); err != nil {
+		log.Printf("Redis DELETE error for key %s: %v", key, err)
+		return err
+	}
+	return nil
+}
+
+// RedisSetJSON stores a JSON object with optional TTL
+func RedisSetJSON(key string, value interface{}, ttlSeconds int) error {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value to JSON: %v", err)
+	}
+	return RedisSet(key, string(jsonValue), ttlSeconds)
+}
+
+// RedisGetJSON retrieves and unmarshals a JSON object
+func RedisGetJSON(key string, dest interface{}) error {
+	jsonStr, err := RedisGet(key)
+	if err != nil {
+		return err
+	}
+	if jsonStr == "" {
+		return fmt.Errorf("key not found")
+	}
+	return json.Unmarshal([]byte(jsonStr), dest)
+}
+
+// RedisGetRawJSON retrieves raw JSON string without unmarshaling
+func RedisGetRawJSON(key string) (string, error) {
+	return RedisGet(key)
+}
+
+// RedisClearPattern deletes keys matching a pattern (for cache clearing)
+func RedisClearPattern(pattern string) error {
+	ctx, cancel := cacheContext()
+	defer cancel()
+
+	keys, err := CacheBackend().Scan(ctx, pattern)
+	if err != nil {
+		log.Printf("Redis SCAN error for pattern %s: %v", pattern, err)
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if _, err := CacheBackend().Delete(ctx, keys...); err != nil {
+		log.Printf("Failed to delete keys matching %s: %v", pattern, err)
+		return err
+	}
+	log.Printf("Cleared %d cache keys matching pattern: %s", len(keys), pattern)
+	return nil
+}
+
+// RedisGetKeys returns all keys matching a pattern
+func RedisGetKeys(pattern string) ([]string, error) {
+	ctx, cancel := cacheContext()
+	defer cancel()
+
+	keys, err := CacheBackend().Scan(ctx, pattern)
+	if err != nil {
+		log.Printf("Redis SCAN error for pattern %s: %v", pattern, err)
+	}
+	return keys, err
+}
+
+// RedisSetAdd adds a member to a Redis set with optional TTL
+func RedisSetAdd(setKey, member string, ttlSeconds int) error {
+	ctx, cancel := cacheContext()
+	defer cancel()
+
+	err := CacheBackend().SetAdd(ctx, setKey, time.Duration(ttlSeconds)*time.Second, member)
+	if err != nil {
+		log.Printf("Redis SADD error for set %s: %v", setKey, err)
+	}
+	return err
+}
+
+// RedisSetCount returns the number of members in a Redis set
+func RedisSetCount(setKey string) (int, error) {
+	ctx, cancel := cacheContext()
+	defer cancel()
+
+	count, err := CacheBackend().SetCount(ctx, setKey)
+	if err != nil {
+		log.Printf("Redis SCARD error for set %s: %v", setKey, err)
+	}
+	return count, err
+}
+
+// RedisSetMembers returns the members of a Redis set
+func RedisSetMembers(setKey string) ([]string, error) {
+	ctx, cancel := cacheContext()
+	defer cancel()
+
+	members, err := CacheBackend().SetMembers(ctx, setKey)
+	if err != nil {
+		log.Printf("Redis SMEMBERS error for set %s: %v", setKey, err)
+	}
+	return members, err
+}
@@ -38,7 +38,7 @@ type googleSpeechResponse struct {
 }
 
 func TranscribeAudio(reader io.Reader, filename, contentType string) (string, error) {
-	apiKey := os.Getenv("GCP_API_KEY")
+	apiKey := conf().Google.APIKey
 	if apiKey == "" {
 		return "", fmt.Errorf("GCP_API_KEY not set")
 	}
@@ -118,8 +118,8 @@ func googleEncodingFor(contentType, filename string) string {
 }
 
 func transcribeWithWhisper(reader io.Reader, filename, contentType string) (string, error) {
-	whisperPath := os.Getenv("WHISPER_CPP_PATH")
-	modelPath := os.Getenv("WHISPER_MODEL_PATH")
+	whisperPath := conf().Speech.WhisperCPPPath
+	modelPath := conf().Speech.WhisperModelPath
 	if whisperPath == "" || modelPath == "" {
 		return "", fmt.Errorf("GOOGLE_SPEECH_API_KEY not set and WHISPER_CPP_PATH/WHISPER_MODEL_PATH not set")
 	}
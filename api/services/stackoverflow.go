@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+type stackOverflowStatsProvider struct{}
+
+func (stackOverflowStatsProvider) Name() string { return "stackoverflow" }
+func (stackOverflowStatsProvider) Account(p models.Profile) string {
+	return profileAccount(p, "stackoverflow")
+}
+func (stackOverflowStatsProvider) DefaultInterval() time.Duration { return time.Hour } // 300 requests a day without a key
+func (stackOverflowStatsProvider) NewDetails() interface{}        { return nil }
+
+// Fetch reads a user's reputation and badges by numeric user id. STACKEXCHANGE_KEY raises the
+// daily quota.
+func (stackOverflowStatsProvider) Fetch(ctx context.Context, userID string) (*models.StatsSnapshot, error) {
+	params := url.Values{"site": {"stackoverflow"}}
+	if key := conf().Stats.StackExchangeKey; key != "" {
+		params.Set("key", key)
+	}
+
+	var result struct {
+		Items []struct {
+			DisplayName string `json:"display_name"`
+			Link        string `json:"link"`
+			Reputation  int64  `json:"reputation"`
+			BadgeCounts struct {
+				Gold   int64 `json:"gold"`
+				Silver int64 `json:"silver"`
+				Bronze int64 `json:"bronze"`
+			} `json:"badge_counts"`
+		} `json:"items"`
+	}
+	endpoint := fmt.Sprintf("https://api.stackexchange.com/2.3/users/%s?%s", url.PathEscape(userID), params.Encode())
+	if err := getStatsJSON(ctx, "stackoverflow", endpoint, nil, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Items) == 0 {
+		return nil, fmt.Errorf("no stackoverflow user %s", userID)
+	}
+
+	user := result.Items[0]
+	return &models.StatsSnapshot{
+		DisplayName: html.UnescapeString(user.DisplayName), // the API HTML-escapes names
+		URL:         user.Link,
+		Metrics: map[string]int64{
+			"reputation":    user.Reputation,
+			"gold_badges":   user.BadgeCounts.Gold,
+			"silver_badges": user.BadgeCounts.Silver,
+			"bronze_badges": user.BadgeCounts.Bronze,
+		},
+	}, nil
+}
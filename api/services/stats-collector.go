@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/db"
+)
+
+// RateLimitError means a provider asked us to slow down. errors.Is matches it against ErrRateLimited.
+type RateLimitError struct {
+	Provider   string
+	RetryAfter time.Duration // zero when the provider didn't say
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s API rate limited, retry after %s", e.Provider, e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("%s API rate limited", e.Provider)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// rateLimitFromResponse returns a RateLimitError if the response is a rate limit rejection.
+// It understands Retry-After and the X-RateLimit-Reset/Ratelimit-Reset epoch headers.
+func rateLimitFromResponse(provider string, resp *http.Response) error {
+	limited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0")
+	if !limited {
+		return nil
+	}
+
+	rl := &RateLimitError{Provider: provider}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		rl.RetryAfter = time.Duration(secs) * time.Second
+	} else {
+		for _, h := range []string{"X-RateLimit-Reset", "Ratelimit-Reset"} {
+			if reset, err := strconv.ParseInt(resp.Header.Get(h), 10, 64); err == nil {
+				rl.RetryAfter = time.Until(time.Unix(reset, 0))
+				break
+			}
+		}
+	}
+	if rl.RetryAfter < 0 {
+		rl.RetryAfter = 0
+	}
+	return rl
+}
+
+const (
+	statsCacheTTL      = 30 * time.Minute
+	statsMaxBackoff    = 6 * time.Hour
+	statsJitterPercent = 10
+	statsPollTimeout   = 30 * time.Second
+)
+
+// statsJob polls one provider for one profile and tracks how it went
+type statsJob struct {
+	provider StatsProvider
+	profile  models.Profile
+	account  string
+	interval time.Duration
+
+	mu     sync.Mutex
+	status models.StatsCollectorJob
+}
+
+var (
+	statsJobs        = make(map[string]*statsJob) // "provider:profile"
+	statsJobsMu      sync.RWMutex
+	statsCollectorOn bool
+)
+
+// StartStatsCollector polls every provider for every profile on its own interval, writing a
+// history row per poll and keeping the Redis stats cache warm.
+//
+// Intervals default per provider and can be overridden with STATS_INTERVAL_<PROVIDER>
+// (e.g. STATS_INTERVAL_GITHUB=5m). Set one to "off" to stop polling that provider.
+func StartStatsCollector() {
+	statsJobsMu.Lock()
+	defer statsJobsMu.Unlock()
+	if statsCollectorOn {
+		return
+	}
+	statsCollectorOn = true
+
+	for _, provider := range statsProviders {
+		if f, ok := provider.(statsFeature); ok && !FeatureEnabled(f.Feature()) {
+			log.Printf("⏸️ Stats collector skipping %s (%s is off)", provider.Name(), f.Feature())
+			continue
+		}
+		interval, ok := statsInterval(provider)
+		if !ok {
+			log.Printf("⏸️ Stats collector disabled for %s", provider.Name())
+			continue
+		}
+		for _, profile := range Profiles() {
+			job := newStatsJob(provider, profile, interval)
+			if job.account == "" {
+				continue
+			}
+			statsJobs[statsJobKey(provider.Name(), profile.ID)] = job
+			go job.run()
+		}
+	}
+	log.Printf("📊 Stats collector started with %d jobs", len(statsJobs))
+}
+
+// CollectStats fetches a provider's stats for a profile right now, recording them like a
+// scheduled poll. Callers should normally go through ProviderStats, which checks the cache
+// first, shares concurrent fetches and caches the result.
+func CollectStats(ctx context.Context, provider string, profile models.Profile) (*models.StatsSnapshot, error) {
+	statsJobsMu.RLock()
+	job, ok := statsJobs[statsJobKey(provider, profile.ID)]
+	statsJobsMu.RUnlock()
+	if ok {
+		return job.collect(ctx)
+	}
+
+	p, ok := statsProviderFor(provider)
+	if !ok {
+		return nil, fmt.Errorf("unknown stats provider %q", provider)
+	}
+	interval, _ := statsInterval(p)
+	job = newStatsJob(p, profile, interval)
+	if job.account == "" {
+		return nil, fmt.Errorf("profile %s has no %s account", profile.ID, provider)
+	}
+	return job.collect(ctx)
+}
+
+// StatsAccount returns the profile's account on a provider. ok is false for unknown providers.
+func StatsAccount(provider string, profile models.Profile) (account string, ok bool) {
+	p, ok := statsProviderFor(provider)
+	if !ok {
+		return "", false
+	}
+	return p.Account(profile), true
+}
+
+// StatsCollectorStatus returns the state of every scheduled job, ordered by provider then profile
+func StatsCollectorStatus() []models.StatsCollectorJob {
+	statsJobsMu.RLock()
+	defer statsJobsMu.RUnlock()
+
+	out := make([]models.StatsCollectorJob, 0, len(statsJobs))
+	for _, job := range statsJobs {
+		job.mu.Lock()
+		out = append(out, job.status)
+		job.mu.Unlock()
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].Profile < out[j].Profile
+	})
+	return out
+}
+
+func statsJobKey(provider, profileID string) string {
+	return provider + ":" + profileID
+}
+
+func newStatsJob(provider StatsProvider, profile models.Profile, interval time.Duration) *statsJob {
+	job := &statsJob{
+		provider: provider,
+		profile:  profile,
+		account:  provider.Account(profile),
+		interval: interval,
+	}
+	job.status = models.StatsCollectorJob{
+		Provider: provider.Name(),
+		Profile:  profile.ID,
+		Account:  job.account,
+		Interval: interval.String(),
+	}
+	return job
+}
+
+// statsInterval reads the provider's entry in stats.intervals (STATS_INTERVAL_<PROVIDER>),
+// falling back to the provider's default
+func statsInterval(provider StatsProvider) (time.Duration, bool) {
+	key := "STATS_INTERVAL_" + strings.ToUpper(provider.Name())
+	raw := conf().Stats.Intervals[strings.ToLower(provider.Name())]
+	switch {
+	case raw == "":
+		return provider.DefaultInterval(), true
+	case raw == "off" || raw == "0":
+		return 0, false
+	}
+
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval < time.Minute {
+		log.Printf("⚠️ Invalid %s=%q (want a duration of at least 1m), using %s", key, raw, provider.DefaultInterval())
+		return provider.DefaultInterval(), true
+	}
+	return interval, true
+}
+
+// run polls until the process exits. The first poll is staggered so jobs don't all fire at boot.
+func (j *statsJob) run() {
+	delay := time.Duration(rand.Int63n(int64(30 * time.Second)))
+	for {
+		j.mu.Lock()
+		j.status.NextRun = time.Now().Add(delay)
+		j.mu.Unlock()
+
+		time.Sleep(delay)
+		_, err := statsCacheKey(j.provider, j.account).Refresh(context.Background(), j.collect)
+		delay = j.nextDelay(err)
+	}
+}
+
+// collect fetches and records one poll, updating the job's status. Its caller caches the result.
+func (j *statsJob) collect(ctx context.Context) (*models.StatsSnapshot, error) {
+	started := time.Now()
+	snapshot, err := fetchStatsSnapshot(ctx, j.provider, j.account)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Runs++
+	j.status.LastRun = &started
+
+	if err != nil {
+		j.status.ConsecutiveFailures++
+		j.status.LastError = err.Error()
+		j.status.LastErrorAt = &started
+		var rl *RateLimitError
+		if errors.As(err, &rl) && rl.RetryAfter > 0 {
+			until := started.Add(rl.RetryAfter)
+			j.status.RateLimitedUntil = &until
+		}
+		log.Printf("❌ Stats poll failed for %s/%s: %v", j.provider.Name(), j.profile.ID, err)
+		return nil, err
+	}
+
+	j.status.ConsecutiveFailures = 0
+	j.status.LastSuccess = &started
+	j.status.RateLimitedUntil = nil
+
+	recorder, ok := j.provider.(statsRecorder)
+	if database := db.GetDB(); ok && database != nil {
+		if err := recorder.Record(database, snapshot); err != nil {
+			log.Printf("❌ Failed to save %s stats: %v", j.provider.Name(), err)
+			j.status.LastError = err.Error()
+			j.status.LastErrorAt = &started
+		}
+	}
+	return snapshot, nil
+}
+
+// nextDelay is the interval with jitter. Every failure doubles it, up to statsMaxBackoff,
+// and a rate limit waits at least as long as the provider asked.
+func (j *statsJob) nextDelay(err error) time.Duration {
+	delay := j.interval
+	if err != nil {
+		j.mu.Lock()
+		failures := j.status.ConsecutiveFailures
+		j.mu.Unlock()
+
+		for i := 0; i < failures && delay < statsMaxBackoff; i++ {
+			delay *= 2
+		}
+		var rl *RateLimitError
+		if errors.As(err, &rl) && rl.RetryAfter > delay {
+			delay = rl.RetryAfter
+		}
+		if delay > statsMaxBackoff {
+			delay = statsMaxBackoff
+		}
+	}
+
+	jitter := int64(delay) * statsJitterPercent / 100
+	return delay + time.Duration(rand.Int63n(2*jitter+1)-jitter)
+}
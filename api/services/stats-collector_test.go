@@ -0,0 +1,35 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStatsJobNextDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		err      error
+		want     time.Duration
+	}{
+		{"success", 0, nil, 10 * time.Minute},
+		{"first failure", 1, errors.New("upstream 500"), 20 * time.Minute},
+		{"third failure", 3, errors.New("upstream 500"), 80 * time.Minute},
+		{"capped", 10, errors.New("upstream 500"), statsMaxBackoff},
+		{"rate limited", 1, &RateLimitError{Provider: "github"}, 20 * time.Minute},
+		{"retry-after is a floor", 1, &RateLimitError{Provider: "github", RetryAfter: time.Hour}, time.Hour},
+		{"retry-after below the backoff", 2, &RateLimitError{Provider: "github", RetryAfter: time.Minute}, 40 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := &statsJob{interval: 10 * time.Minute}
+			j.status.ConsecutiveFailures = tt.failures
+			got := j.nextDelay(tt.err)
+			jitter := tt.want * statsJitterPercent / 100
+			if got < tt.want-jitter || got > tt.want+jitter {
+				t.Errorf("nextDelay = %s, want %s ± %s", got, tt.want, jitter)
+			}
+		})
+	}
+}
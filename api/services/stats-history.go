@@ -0,0 +1,312 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/db"
+)
+
+// ErrStatsHistoryQuery is returned for an unknown provider or metric, or a bad time range or interval
+var ErrStatsHistoryQuery = errors.New("invalid stats history query")
+
+// statsMetric is one charted column of a provider's history table
+type statsMetric struct {
+	name          string
+	label         string // used in milestone labels, e.g. "followers"
+	column        string
+	lowerIsBetter bool // rankings improve as they fall
+}
+
+type statsHistoryTable struct {
+	table   string
+	account string
+	metrics []statsMetric
+}
+
+var statsHistoryTables = map[string]statsHistoryTable{
+	"youtube": {table: "youtube_stats", account: "channel_id", metrics: []statsMetric{
+		{name: "subscribers", label: "subscribers", column: "subscriber_count"},
+		{name: "views", label: "views", column: "view_count"},
+		{name: "videos", label: "videos", column: "video_count"},
+	}},
+	"github": {table: "github_stats", account: "username", metrics: []statsMetric{
+		{name: "followers", label: "followers", column: "followers"},
+		{name: "public_repos", label: "public repos", column: "public_repos"},
+		{name: "stars", label: "stars", column: "total_stars"},
+	}},
+	"twitch": {table: "twitch_stats", account: "username", metrics: []statsMetric{
+		{name: "followers", label: "followers", column: "follower_count"},
+	}},
+	"leetcode": {table: "leetcode_stats", account: "username", metrics: []statsMetric{
+		{name: "solved", label: "problems solved", column: "solved_count"},
+		{name: "ranking", label: "ranking", column: "ranking", lowerIsBetter: true},
+	}},
+}
+
+const (
+	statsHistoryDefaultRange = 30 * 24 * time.Hour
+	statsHistoryMaxBuckets   = 1000
+)
+
+// StatsHistoryQuery holds the raw query parameters of a history request
+type StatsHistoryQuery struct {
+	Provider string
+	From     string // RFC3339 or YYYY-MM-DD; defaults to 30 days before To
+	To       string // defaults to now
+	Interval string // e.g. 1h, 6h, 1d, 1w; picked from the range when empty
+	Metrics  []string
+}
+
+// GetStatsHistory returns bucketed series, growth and milestones for a provider's metrics
+func GetStatsHistory(profile models.Profile, q StatsHistoryQuery) (*models.StatsHistory, error) {
+	table, ok := statsHistoryTables[q.Provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown provider %q", ErrStatsHistoryQuery, q.Provider)
+	}
+	account, _ := StatsAccount(q.Provider, profile)
+	if account == "" {
+		return nil, fmt.Errorf("%w: profile %s has no %s account", ErrStatsHistoryQuery, profile.ID, q.Provider)
+	}
+	metrics, err := selectStatsMetrics(table, q.Metrics)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := parseStatsHistoryRange(q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := parseStatsHistoryInterval(q.Interval, to.Sub(from))
+	if err != nil {
+		return nil, err
+	}
+
+	database := db.GetDB()
+	if database == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+
+	history := &models.StatsHistory{
+		Provider: q.Provider,
+		Profile:  profile.ID,
+		Account:  account,
+		From:     from,
+		To:       to,
+		Interval: formatStatsInterval(interval),
+		Metrics:  []models.StatsMetricSeries{},
+	}
+	for _, m := range metrics {
+		col := db.StatsHistoryColumn{Table: table.table, Account: table.account, Column: m.column}
+
+		buckets, err := db.GetStatsHistoryBuckets(database, col, account, from, to, int(interval.Seconds()))
+		if err != nil {
+			return nil, fmt.Errorf("%s %s buckets: %w", q.Provider, m.name, err)
+		}
+		changes, err := db.GetStatsHistoryChanges(database, col, account, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s changes: %w", q.Provider, m.name, err)
+		}
+
+		fillStatsDeltas(buckets)
+		history.Metrics = append(history.Metrics, models.StatsMetricSeries{
+			Metric:     m.name,
+			Buckets:    buckets,
+			Growth:     statsGrowth(buckets),
+			Milestones: statsMilestones(m, changes),
+		})
+	}
+	return history, nil
+}
+
+func selectStatsMetrics(table statsHistoryTable, names []string) ([]statsMetric, error) {
+	if len(names) == 0 {
+		return table.metrics, nil
+	}
+	var selected []statsMetric
+	for _, name := range names {
+		found := false
+		for _, m := range table.metrics {
+			if m.name == strings.TrimSpace(name) {
+				selected = append(selected, m)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: unknown metric %q", ErrStatsHistoryQuery, name)
+		}
+	}
+	return selected, nil
+}
+
+func parseStatsHistoryRange(fromRaw, toRaw string) (time.Time, time.Time, error) {
+	to := time.Now().UTC()
+	if toRaw != "" {
+		t, err := parseStatsHistoryTime(toRaw)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: to %q", ErrStatsHistoryQuery, toRaw)
+		}
+		to = t
+	}
+	from := to.Add(-statsHistoryDefaultRange)
+	if fromRaw != "" {
+		t, err := parseStatsHistoryTime(fromRaw)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: from %q", ErrStatsHistoryQuery, fromRaw)
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be before to", ErrStatsHistoryQuery)
+	}
+	return from, to, nil
+}
+
+func parseStatsHistoryTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// parseStatsHistoryInterval accepts Go durations plus <n>d and <n>w. With no interval it picks
+// the smallest of 1h, 6h, 1d and 1w that keeps the series under 200 buckets.
+func parseStatsHistoryInterval(raw string, span time.Duration) (time.Duration, error) {
+	var interval time.Duration
+	switch {
+	case raw == "":
+		for _, candidate := range []time.Duration{time.Hour, 6 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour} {
+			interval = candidate
+			if span/candidate <= 200 {
+				break
+			}
+		}
+	case strings.HasSuffix(raw, "d") || strings.HasSuffix(raw, "w"):
+		n, err := strconv.Atoi(raw[:len(raw)-1])
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("%w: interval %q", ErrStatsHistoryQuery, raw)
+		}
+		interval = time.Duration(n) * 24 * time.Hour
+		if strings.HasSuffix(raw, "w") {
+			interval *= 7
+		}
+	default:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return 0, fmt.Errorf("%w: interval %q", ErrStatsHistoryQuery, raw)
+		}
+		interval = d
+	}
+
+	if interval < time.Minute {
+		return 0, fmt.Errorf("%w: interval must be at least 1m", ErrStatsHistoryQuery)
+	}
+	if span/interval > statsHistoryMaxBuckets {
+		return 0, fmt.Errorf("%w: more than %d buckets, use a larger interval", ErrStatsHistoryQuery, statsHistoryMaxBuckets)
+	}
+	return interval, nil
+}
+
+func formatStatsInterval(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d%(7*day) == 0:
+		return fmt.Sprintf("%dw", d/(7*day))
+	case d%day == 0:
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
+
+// fillStatsDeltas sets each bucket's delta against the previous bucket's last sample
+func fillStatsDeltas(buckets []models.StatsBucket) {
+	for i := range buckets {
+		if i == 0 {
+			buckets[i].Delta = buckets[i].Last - buckets[i].First
+		} else {
+			buckets[i].Delta = buckets[i].Last - buckets[i-1].Last
+		}
+	}
+}
+
+func statsGrowth(buckets []models.StatsBucket) *models.StatsGrowth {
+	if len(buckets) == 0 {
+		return nil
+	}
+	first, last := buckets[0], buckets[len(buckets)-1]
+	g := &models.StatsGrowth{
+		From:   first.FirstAt,
+		To:     last.LastAt,
+		Start:  first.First,
+		End:    last.Last,
+		Change: last.Last - first.First,
+	}
+	if g.Start != 0 {
+		pct := float64(g.Change) / float64(g.Start) * 100
+		g.Percent = &pct
+	}
+	if days := g.To.Sub(g.From).Hours() / 24; days > 0 {
+		g.PerDay = float64(g.Change) / days
+	}
+	return g
+}
+
+// statsMilestones finds the round numbers (10, 25, 50, 100, 250, ...) a metric crossed. A
+// metric where lower is better, like a ranking, crosses them on the way down.
+func statsMilestones(m statsMetric, changes []models.StatsChange) []models.StatsMilestone {
+	milestones := []models.StatsMilestone{}
+	for _, c := range changes {
+		if c.Previous == nil {
+			continue
+		}
+		prev := *c.Previous
+		values := statsMilestoneValues(prev, c.Value)
+		if m.lowerIsBetter {
+			// Listed in the order they were passed on the way down
+			slices.Reverse(values)
+		}
+		for _, v := range values {
+			crossed := prev < v && c.Value >= v
+			label := fmt.Sprintf("crossed %s %s", formatStatsCount(v), m.label)
+			if m.lowerIsBetter {
+				crossed = prev > v && c.Value <= v
+				label = fmt.Sprintf("reached top %s", formatStatsCount(v))
+			}
+			if crossed {
+				milestones = append(milestones, models.StatsMilestone{Value: v, ReachedAt: c.At, Label: label})
+			}
+		}
+	}
+	return milestones
+}
+
+// statsMilestoneValues returns the milestone values between a and b, ascending
+func statsMilestoneValues(a, b int64) []int64 {
+	lo, hi := a, b
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	var values []int64
+	for scale := int64(10); scale <= hi && scale > 0; scale *= 10 {
+		for _, v := range []int64{scale, scale * 5 / 2, scale * 5} {
+			if v >= lo && v <= hi {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// formatStatsCount renders 12345 as "12,345"
+func formatStatsCount(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
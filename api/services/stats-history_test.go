@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+func TestParseStatsHistoryInterval(t *testing.T) {
+	day := 24 * time.Hour
+
+	tests := []struct {
+		raw  string
+		span time.Duration
+		want time.Duration
+		err  bool
+	}{
+		// Picked from the span: the smallest that stays under 200 buckets
+		{"", 2 * day, time.Hour, false},
+		{"", 200 * time.Hour, time.Hour, false},
+		{"", 30 * day, 6 * time.Hour, false},
+		{"", 100 * day, day, false},
+		{"", 3 * 365 * day, 7 * day, false},
+		{"", 20 * 365 * day, 0, true}, // even weekly is over the bucket limit
+
+		{"6h", 30 * day, 6 * time.Hour, false},
+		{"90m", day, 90 * time.Minute, false},
+		{"1d", 30 * day, day, false},
+		{"2w", 365 * day, 14 * day, false},
+		{"1m", time.Hour, time.Minute, false},
+
+		{"30s", time.Hour, 0, true},
+		{"0d", 30 * day, 0, true},
+		{"-1w", 30 * day, 0, true},
+		{"d", 30 * day, 0, true},
+		{"daily", 30 * day, 0, true},
+		{"1h", 60 * day, 0, true}, // 1,440 buckets
+	}
+	for _, tt := range tests {
+		got, err := parseStatsHistoryInterval(tt.raw, tt.span)
+		if tt.err {
+			if !errors.Is(err, ErrStatsHistoryQuery) {
+				t.Errorf("%q over %s: err = %v, want ErrStatsHistoryQuery", tt.raw, tt.span, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q over %s: got %s, %v; want %s", tt.raw, tt.span, got, err, tt.want)
+		}
+	}
+}
+
+func TestFillStatsDeltas(t *testing.T) {
+	tests := []struct {
+		name    string
+		buckets [][2]int64 // first, last
+		want    []int64
+	}{
+		{"empty", nil, []int64{}},
+		{"one bucket against its own first sample", [][2]int64{{100, 120}}, []int64{20}},
+		{"later buckets against the previous last", [][2]int64{{100, 120}, {125, 130}, {130, 128}},
+			[]int64{20, 10, -2}},
+		{"a gap between buckets counts", [][2]int64{{10, 10}, {50, 60}}, []int64{0, 50}},
+	}
+
+	for _, tt := range tests {
+		buckets := make([]models.StatsBucket, len(tt.buckets))
+		for i, b := range tt.buckets {
+			buckets[i] = models.StatsBucket{First: b[0], Last: b[1]}
+		}
+		fillStatsDeltas(buckets)
+
+		got := []int64{}
+		for _, b := range buckets {
+			got = append(got, b.Delta)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: deltas = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStatsMilestones(t *testing.T) {
+	followers := statsMetric{name: "followers", label: "followers"}
+	ranking := statsMetric{name: "ranking", label: "ranking", lowerIsBetter: true}
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// changes builds a series from values; the first has no previous sample
+	changes := func(values ...int64) []models.StatsChange {
+		var out []models.StatsChange
+		for i, v := range values {
+			c := models.StatsChange{At: start.Add(time.Duration(i) * time.Hour), Value: v}
+			if i > 0 {
+				prev := values[i-1]
+				c.Previous = &prev
+			}
+			out = append(out, c)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		metric statsMetric
+		series []models.StatsChange
+		want   []string
+	}{
+		{"first sample is never a milestone", followers, changes(1000), []string{}},
+		{"one crossing", followers, changes(990, 1005), []string{"crossed 1,000 followers"}},
+		{"landing exactly on it", followers, changes(249, 250), []string{"crossed 250 followers"}},
+		{"several in one jump", followers, changes(40, 260),
+			[]string{"crossed 50 followers", "crossed 100 followers", "crossed 250 followers"}},
+		{"starting on one doesn't count", followers, changes(100, 120), []string{}},
+		{"falling back and rising again", followers, changes(95, 105, 95, 101),
+			[]string{"crossed 100 followers", "crossed 100 followers"}},
+		{"losses aren't milestones", followers, changes(1200, 900), []string{}},
+		{"rankings cross on the way down", ranking, changes(12000, 9000, 2400),
+			[]string{"reached top 10,000", "reached top 5,000", "reached top 2,500"}},
+		{"a worse ranking isn't a milestone", ranking, changes(900, 1100), []string{}},
+	}
+
+	for _, tt := range tests {
+		got := []string{}
+		for _, m := range statsMilestones(tt.metric, tt.series) {
+			got = append(got, m.Label)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
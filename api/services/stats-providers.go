@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+// StatsProvider is one source of profile stats. Adding a provider to statsProviders is all it
+// takes for the collector to poll it, the cache to keep it and /api/stats/{name} and the
+// GraphQL stats field to serve it.
+type StatsProvider interface {
+	// Name is the provider's id in routes, cache keys and STATS_INTERVAL_<NAME>
+	Name() string
+	// Account is the profile's account on the provider, or "" if it has none
+	Account(profile models.Profile) string
+	DefaultInterval() time.Duration
+	// Fetch loads the account's current stats. Provider, Account and FetchedAt are filled in
+	// by the caller.
+	Fetch(ctx context.Context, account string) (*models.StatsSnapshot, error)
+	// NewDetails returns a pointer to an empty Details value for decoding cached snapshots,
+	// or nil if the provider has no details
+	NewDetails() interface{}
+}
+
+// statsRecorder is implemented by providers with a history table: the collector writes a row
+// per poll, and the last row stands in when the provider is rate limiting us
+type statsRecorder interface {
+	Record(database *sql.DB, snapshot *models.StatsSnapshot) error
+	Latest(database *sql.DB, account string) (*models.StatsSnapshot, error)
+}
+
+// statsFeature is implemented by providers that only work with a feature on (see Features):
+// the collector doesn't poll them without it
+type statsFeature interface {
+	Feature() string
+}
+
+var statsProviders = []StatsProvider{
+	youTubeStatsProvider{},
+	gitHubStatsProvider{},
+	twitchStatsProvider{},
+	leetCodeStatsProvider{},
+	blueskyStatsProvider{},
+	mastodonStatsProvider{},
+	devToStatsProvider{},
+	stackOverflowStatsProvider{},
+	npmStatsProvider{},
+}
+
+// StatsProviderNames lists every registered provider
+func StatsProviderNames() []string {
+	names := make([]string, len(statsProviders))
+	for i, p := range statsProviders {
+		names[i] = p.Name()
+	}
+	return names
+}
+
+func statsProviderFor(name string) (StatsProvider, bool) {
+	for _, p := range statsProviders {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// fetchStatsSnapshot fetches and stamps a snapshot
+func fetchStatsSnapshot(ctx context.Context, p StatsProvider, account string) (*models.StatsSnapshot, error) {
+	snapshot, err := p.Fetch(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Provider = p.Name()
+	snapshot.Account = account
+	snapshot.FetchedAt = time.Now().UTC()
+	if snapshot.Metrics == nil {
+		snapshot.Metrics = map[string]int64{}
+	}
+	return snapshot, nil
+}
+
+// decodeStatsSnapshot reads back a cached snapshot with the provider's Details type
+func decodeStatsSnapshot(p StatsProvider, raw []byte) (*models.StatsSnapshot, error) {
+	var cached struct {
+		models.StatsSnapshot
+		Details json.RawMessage `json:"details"`
+	}
+	if err := json.Unmarshal(raw, &cached); err != nil {
+		return nil, err
+	}
+	if cached.Provider != p.Name() {
+		return nil, fmt.Errorf("not a %s stats snapshot", p.Name()) // written before snapshots
+	}
+
+	snapshot := cached.StatsSnapshot
+	if details := p.NewDetails(); details != nil && len(cached.Details) > 0 && string(cached.Details) != "null" {
+		if err := json.Unmarshal(cached.Details, details); err != nil {
+			return nil, err
+		}
+		snapshot.Details = details
+	}
+	return &snapshot, nil
+}
+
+// RecordedStats returns the last snapshot recorded for an account, for providers that keep a
+// history table. Stats served from it are as old as its FetchedAt.
+func RecordedStats(database *sql.DB, provider, account string) (*models.StatsSnapshot, error) {
+	p, ok := statsProviderFor(provider)
+	if !ok {
+		return nil, fmt.Errorf("unknown stats provider %q", provider)
+	}
+	recorder, ok := p.(statsRecorder)
+	if !ok {
+		return nil, fmt.Errorf("%s stats aren't recorded", provider)
+	}
+	snapshot, err := recorder.Latest(database, account)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Provider = provider
+	snapshot.Account = account
+	return snapshot, nil
+}
+
+// profileAccount is the profile's account on a provider without a field of its own
+func profileAccount(profile models.Profile, provider string) string {
+	return profile.Accounts[provider]
+}
+
+// getStatsJSON GETs url and decodes the JSON response into out, turning rate limits into
+// RateLimitError and other failures into errors naming the provider
+func getStatsJSON(ctx context.Context, provider, url string, header http.Header, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("request creation failed: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	if err := rateLimitFromResponse(provider, resp); err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s account not found", provider)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s API returned status %d: %s", provider, resp.StatusCode, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s response: %w", provider, err)
+	}
+	return nil
+}
+
+// sortedMetricNames returns a snapshot's metric names in a stable order
+func sortedMetricNames(metrics map[string]int64) []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
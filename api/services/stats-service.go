@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+// statsCaches holds each provider's snapshots. One fetch is shared between everyone asking
+// for the same provider and account at once: REST handlers, GraphQL resolvers, the bot and
+// scheduled polls.
+var (
+	statsCaches   = map[string]*Cached[*models.StatsSnapshot]{}
+	statsCachesMu sync.Mutex
+)
+
+const statsNegativeTTL = time.Minute
+
+type statsMemoKey struct{}
+
+// statsMemo remembers the stats a request has already loaded, so resolving the same provider
+// twice in one GraphQL query (aliases, several profiles sharing an account) costs one lookup
+type statsMemo struct {
+	mu      sync.Mutex
+	entries map[string]statsMemoEntry
+}
+
+type statsMemoEntry struct {
+	snapshot *models.StatsSnapshot
+	err      error
+}
+
+// WithStatsMemo returns a context whose ProviderStats lookups are memoized for its lifetime.
+// Wrap each request's context once, at the top.
+func WithStatsMemo(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(statsMemoKey{}).(*statsMemo); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, statsMemoKey{}, &statsMemo{entries: make(map[string]statsMemoEntry)})
+}
+
+// ProviderStats returns a provider's current stats for a profile. It tries, in order, the
+// request's memo, the stats cache the collector keeps warm, and an upstream fetch shared with
+// any concurrent caller. The fetch outlives a caller that gives up, so the others still get it
+// and it still lands in the cache.
+func ProviderStats(ctx context.Context, provider string, profile models.Profile) (*models.StatsSnapshot, error) {
+	p, ok := statsProviderFor(provider)
+	if !ok {
+		return nil, fmt.Errorf("unknown stats provider %q", provider)
+	}
+	account := p.Account(profile)
+	if account == "" {
+		return nil, fmt.Errorf("profile %s has no %s account", profile.ID, provider)
+	}
+	key := statsCacheKey(p, account)
+
+	memo, _ := ctx.Value(statsMemoKey{}).(*statsMemo)
+	if memo != nil {
+		memo.mu.Lock()
+		entry, ok := memo.entries[key.String()]
+		memo.mu.Unlock()
+		if ok {
+			return entry.snapshot, entry.err
+		}
+	}
+
+	snapshot, err := key.Get(ctx, func(fetchCtx context.Context) (*models.StatsSnapshot, error) {
+		return CollectStats(fetchCtx, provider, profile)
+	})
+
+	if memo != nil && ctx.Err() == nil {
+		memo.mu.Lock()
+		memo.entries[key.String()] = statsMemoEntry{snapshot, err}
+		memo.mu.Unlock()
+	}
+	return snapshot, err
+}
+
+// statsCacheKey is where a provider's snapshot for an account is cached. Entries stay fresh
+// across the gap between polls and are served stale for as long as the collector can back off.
+func statsCacheKey(p StatsProvider, account string) CacheKey[*models.StatsSnapshot] {
+	statsCachesMu.Lock()
+	defer statsCachesMu.Unlock()
+
+	cache, ok := statsCaches[p.Name()]
+	if !ok {
+		fresh := statsCacheTTL
+		if interval, ok := statsInterval(p); ok && interval*2 > fresh {
+			fresh = interval * 2
+		}
+		cache = NewCached[*models.StatsSnapshot]("stats."+p.Name(), CachePolicy{
+			Fresh:       fresh,
+			Stale:       statsMaxBackoff,
+			NegativeTTL: statsNegativeTTL,
+			Tags:        []string{"stats"},
+		}).WithDecoder(func(raw []byte) (*models.StatsSnapshot, error) {
+			return decodeStatsSnapshot(p, raw)
+		})
+		statsCaches[p.Name()] = cache
+	}
+	return cache.Key(account)
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+// fakeStatsProviders swaps every stats provider for one whose Fetch counts calls and waits on
+// release, so tests can pile up concurrent callers before the fetch returns
+type fakeStatsProviders struct {
+	calls   map[string]*int32
+	release chan struct{}
+}
+
+type fakeStatsProvider struct {
+	StatsProvider
+	calls   *int32
+	release chan struct{}
+}
+
+func (p fakeStatsProvider) Fetch(ctx context.Context, account string) (*models.StatsSnapshot, error) {
+	atomic.AddInt32(p.calls, 1)
+	select {
+	case <-p.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	metrics := map[string]map[string]int64{
+		"youtube":  {"subscribers": 1200, "views": 50000, "videos": 42},
+		"github":   {"public_repos": 7, "followers": 30, "stars": 99},
+		"twitch":   {"followers": 321},
+		"leetcode": {"solved": 250, "ranking": 12000},
+	}[p.Name()]
+	return &models.StatsSnapshot{DisplayName: account, Metrics: metrics}, nil
+}
+
+func useFakeStatsProviders(t *testing.T) *fakeStatsProviders {
+	t.Helper()
+	f := &fakeStatsProviders{calls: map[string]*int32{}, release: make(chan struct{})}
+
+	original := statsProviders
+	fakes := make([]StatsProvider, len(original))
+	for i, provider := range original {
+		calls := new(int32)
+		f.calls[provider.Name()] = calls
+		fakes[i] = fakeStatsProvider{StatsProvider: provider, calls: calls, release: f.release}
+	}
+	statsProviders = fakes
+	t.Cleanup(func() { statsProviders = original })
+	useTestCache(t)
+	return f
+}
+
+// useTestCache gives the test an empty in-memory cache backend
+func useTestCache(t *testing.T) *MemoryCache {
+	t.Helper()
+	original := CacheBackend()
+	memory := NewMemoryCache(100)
+	useCacheBackend(memory)
+	t.Cleanup(func() { useCacheBackend(original) })
+	return memory
+}
+
+func (f *fakeStatsProviders) count(provider string) int {
+	return int(atomic.LoadInt32(f.calls[provider]))
+}
+
+var testStatsProfile = models.Profile{
+	ID:               "test",
+	TwitchChannel:    "testchannel",
+	GitHubUser:       "testuser",
+	LeetCodeUser:     "testcoder",
+	YouTubeChannelID: "UCtest",
+}
+
+func TestProviderStatsSharesConcurrentFetches(t *testing.T) {
+	f := useFakeStatsProviders(t)
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make([]*models.StatsSnapshot, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = ProviderStats(context.Background(), "github", testStatsProfile)
+		}(i)
+	}
+
+	// Let every caller join the in-flight fetch before it returns
+	waitFor(t, func() bool { return f.count("github") == 1 })
+	time.Sleep(50 * time.Millisecond)
+	close(f.release)
+	wg.Wait()
+
+	if n := f.count("github"); n != 1 {
+		t.Fatalf("expected 1 upstream fetch, got %d", n)
+	}
+	for i := range results {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: %v", i, errs[i])
+		}
+		if stats := results[i]; stats.Provider != "github" || stats.Metrics["followers"] != 30 {
+			t.Fatalf("caller %d got %+v", i, stats)
+		}
+	}
+}
+
+func TestProviderStatsMemoizesPerRequest(t *testing.T) {
+	f := useFakeStatsProviders(t)
+	close(f.release)
+
+	ctx := WithStatsMemo(context.Background())
+	for i := 0; i < 3; i++ {
+		if _, err := ProviderStats(ctx, "twitch", testStatsProfile); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := f.count("twitch"); n != 1 {
+		t.Fatalf("expected 1 fetch within a request, got %d", n)
+	}
+
+	// A new request doesn't see the old memo, so with the cache cleared it fetches again
+	if _, err := InvalidateCacheTags("stats"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ProviderStats(WithStatsMemo(context.Background()), "twitch", testStatsProfile); err != nil {
+		t.Fatal(err)
+	}
+	if n := f.count("twitch"); n != 2 {
+		t.Fatalf("expected a second request to fetch, got %d fetches", n)
+	}
+}
+
+func TestProviderStatsCallerCancelDoesNotAbortSharedFetch(t *testing.T) {
+	f := useFakeStatsProviders(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := ProviderStats(ctx, "leetcode", testStatsProfile)
+		first <- err
+	}()
+	waitFor(t, func() bool { return f.count("leetcode") == 1 })
+
+	second := make(chan *models.StatsSnapshot, 1)
+	go func() {
+		stats, _ := ProviderStats(context.Background(), "leetcode", testStatsProfile)
+		second <- stats
+	}()
+	// Let the second caller join the in-flight fetch before the first gives up
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled caller to get context.Canceled, got %v", err)
+	}
+
+	close(f.release)
+	stats := <-second
+	if stats == nil || stats.Metrics["solved"] != 250 {
+		t.Fatalf("expected the other caller to get the shared result, got %+v", stats)
+	}
+	if n := f.count("leetcode"); n != 1 {
+		t.Fatalf("expected 1 upstream fetch, got %d", n)
+	}
+}
+
+func TestGetUnifiedStatsConcurrentCallers(t *testing.T) {
+	f := useFakeStatsProviders(t)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]*models.UnifiedStats, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = GetUnifiedStats(context.Background(), testStatsProfile)
+		}(i)
+	}
+	waitFor(t, func() bool {
+		return f.count("youtube") == 1 && f.count("github") == 1 && f.count("twitch") == 1 && f.count("leetcode") == 1
+	})
+	time.Sleep(50 * time.Millisecond)
+	close(f.release)
+	wg.Wait()
+
+	for _, provider := range []string{"youtube", "github", "twitch", "leetcode"} {
+		if n := f.count(provider); n != 1 {
+			t.Errorf("expected 1 %s fetch, got %d", provider, n)
+		}
+	}
+	for i, stats := range results {
+		if stats.YouTube.Subscribers != 1200 || stats.GitHub.StarsReceived != 99 ||
+			stats.Twitch.Followers != 321 || stats.LeetCode.SolvedCount != 250 {
+			t.Fatalf("caller %d got incomplete stats: %+v", i, stats)
+		}
+	}
+}
+
+func TestDecodeStatsSnapshotRestoresDetails(t *testing.T) {
+	github, _ := statsProviderFor("github")
+	snapshot := &models.StatsSnapshot{
+		Provider: "github",
+		Account:  "testuser",
+		Metrics:  map[string]int64{"stars": 99},
+		Details: &models.GitHubDetails{
+			Languages:     []models.LanguageCount{{Name: "Go", Repos: 5}},
+			Contributions: &models.GitHubContributions{Total: 420},
+		},
+	}
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := decodeStatsSnapshot(github, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	details, ok := decoded.Details.(*models.GitHubDetails)
+	if !ok || len(details.Languages) != 1 || details.Contributions.Total != 420 || decoded.Metrics["stars"] != 99 {
+		t.Fatalf("details not restored: %+v", decoded)
+	}
+
+	// Entries cached before snapshots existed are treated as misses
+	if _, err := decodeStatsSnapshot(github, []byte(`{"username":"testuser","stars_received":99}`)); err == nil {
+		t.Fatal("expected a pre-snapshot cache entry to be rejected")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
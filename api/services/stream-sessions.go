@@ -0,0 +1,259 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/db"
+)
+
+// Rough streamer share in USD; Twitch doesn't expose the real split
+var subRevenueByTier = map[string]float64{
+	"1000": 2.50,
+	"2000": 5.00,
+	"3000": 12.50,
+}
+
+const bitRevenue = 0.01 // per bit
+
+// StartStreamSessionTracking opens and closes each profile's stream sessions from stream.online
+// and stream.offline notifications. The IVS status poller reports through recordIVSStreamStatus.
+func StartStreamSessionTracking() {
+	AddTwitchEventListener(handleStreamSessionEvent)
+}
+
+func handleStreamSessionEvent(event models.TwitchEvent) {
+	database := db.GetDB()
+	if database == nil || event.Channel == "" {
+		return
+	}
+
+	switch event.Type {
+	case "stream.online":
+		var e struct {
+			ID        string    `json:"id"`
+			StartedAt time.Time `json:"started_at"`
+		}
+		json.Unmarshal(event.Event, &e)
+		if e.StartedAt.IsZero() {
+			e.StartedAt = event.OccurredAt
+		}
+		openStreamSession(database, event.Channel, models.StreamSourceEventSub, e.ID, e.StartedAt)
+
+	case "stream.offline":
+		closeStreamSession(database, event.Channel, "", event.OccurredAt)
+	}
+}
+
+// ivsLive is the result of the last IVS status check
+var ivsLive atomic.Bool
+
+// StartIVSStatusPoller checks whether the IVS stream is live on an interval and records the
+// default profile's stream sessions from it. IVSStreamLive returns the last result.
+func StartIVSStatusPoller(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			live := models.NewStream("", conf().Stream.AWSStreamingURL).IsActive
+			if ivsLive.Swap(live) != live {
+				log.Printf("📡 IVS stream live: %t", live)
+			}
+			recordIVSStreamStatus(live)
+			<-ticker.C
+		}
+	}()
+}
+
+// IVSStreamLive reports whether the IVS stream was live at the last check
+func IVSStreamLive() bool {
+	return ivsLive.Load()
+}
+
+// recordIVSStreamStatus feeds the AWS IVS live check into session tracking for the default
+// profile, which owns the IVS stream. IVS only closes sessions it opened, since the Twitch
+// broadcast can run without the IVS stream.
+func recordIVSStreamStatus(live bool) {
+	database := db.GetDB()
+	channel := DefaultProfile().TwitchChannel
+	if database == nil || channel == "" {
+		return
+	}
+	if live {
+		openStreamSession(database, channel, models.StreamSourceIVS, "", time.Now())
+	} else {
+		closeStreamSession(database, channel, models.StreamSourceIVS, time.Now())
+	}
+}
+
+func openStreamSession(database *sql.DB, channel, source, twitchID string, startedAt time.Time) {
+	session, started, err := db.StartStreamSession(database, models.StreamSession{
+		Channel:   channel,
+		Source:    source,
+		TwitchID:  twitchID,
+		StartedAt: startedAt,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to start stream session: %v", err)
+		return
+	}
+	if started {
+		log.Printf("🎬 Stream session %d started for %s (%s)", session.ID, channel, source)
+		PublishEvent(TopicStreamStatus, models.StreamStatusChange{Channel: channel, Live: true, Session: session})
+		return
+	}
+
+	// IVS noticed the stream first; attach Twitch's stream id now that we have it
+	if twitchID != "" && session.TwitchID == "" {
+		if err := db.SetStreamSessionTwitchID(database, session.ID, twitchID, startedAt); err != nil {
+			log.Printf("⚠️ Failed to update stream session %d: %v", session.ID, err)
+		}
+	}
+}
+
+// closeStreamSession ends the channel's open session. If onlySource is set, sessions opened by
+// another source are left alone.
+func closeStreamSession(database *sql.DB, channel, onlySource string, endedAt time.Time) {
+	session, err := db.GetOpenStreamSession(database, channel)
+	if err == sql.ErrNoRows {
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to look up open stream session: %v", err)
+		return
+	}
+	if onlySource != "" && session.Source != onlySource {
+		return
+	}
+
+	if err := db.EndStreamSession(database, session.ID, endedAt); err != nil {
+		log.Printf("❌ Failed to end stream session %d: %v", session.ID, err)
+		return
+	}
+	log.Printf("🏁 Stream session %d ended after %s", session.ID, endedAt.Sub(session.StartedAt).Round(time.Minute))
+	session.EndedAt = &endedAt
+	PublishEvent(TopicStreamStatus, models.StreamStatusChange{Channel: channel, Live: false, Session: session})
+}
+
+// CurrentStreamSession returns the open session for a channel, or sql.ErrNoRows
+func CurrentStreamSession(channel string) (models.StreamSession, error) {
+	database := db.GetDB()
+	if database == nil {
+		return models.StreamSession{}, fmt.Errorf("database not available")
+	}
+	return db.GetOpenStreamSession(database, channel)
+}
+
+// BuildStreamReport gathers everything that happened during a session
+func BuildStreamReport(session models.StreamSession) (models.StreamReport, error) {
+	database := db.GetDB()
+	if database == nil {
+		return models.StreamReport{}, fmt.Errorf("database not available")
+	}
+
+	end := time.Now()
+	if session.EndedAt != nil {
+		end = *session.EndedAt
+	}
+	duration := end.Sub(session.StartedAt)
+
+	// Keep the rate chart around 100 points or fewer
+	bucket := 5 * time.Minute
+	if duration > 8*time.Hour {
+		bucket = 15 * time.Minute
+	}
+
+	report := models.StreamReport{
+		Session:         session,
+		DurationMinutes: int(duration.Minutes()),
+	}
+	if err := db.GetStreamActivity(database, &report, session.Channel, session.StartedAt, end, int(bucket.Seconds())); err != nil {
+		return report, err
+	}
+
+	for tier, n := range report.Subs.ByTier {
+		share, ok := subRevenueByTier[tier]
+		if !ok {
+			share = subRevenueByTier["1000"]
+		}
+		report.Revenue.Subs += share * float64(n)
+	}
+	report.Revenue.Subs = roundCents(report.Revenue.Subs)
+	report.Revenue.Bits = roundCents(float64(report.Bits) * bitRevenue)
+	report.Revenue.Total = roundCents(report.Revenue.Subs + report.Revenue.Bits)
+
+	return report, nil
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// WriteStreamReportCSV writes a report as section,name,value rows
+func WriteStreamReportCSV(w io.Writer, r models.StreamReport) error {
+	cw := csv.NewWriter(w)
+	row := func(section, name string, value interface{}) {
+		cw.Write([]string{section, name, fmt.Sprint(value)})
+	}
+	ts := func(t time.Time) string { return t.UTC().Format(time.RFC3339) }
+
+	row("section", "name", "value")
+	row("session", "id", r.Session.ID)
+	row("session", "channel", r.Session.Channel)
+	row("session", "started_at", ts(r.Session.StartedAt))
+	if r.Session.EndedAt != nil {
+		row("session", "ended_at", ts(*r.Session.EndedAt))
+	}
+	row("session", "duration_minutes", r.DurationMinutes)
+
+	row("chat", "messages", r.Messages)
+	row("chat", "unique_chatters", r.UniqueChatters)
+	for _, b := range r.MessageRate {
+		row("message_rate", ts(b.Start), b.Messages)
+	}
+	for _, c := range r.TopChatters {
+		row("top_chatters", c.Name, c.Count)
+	}
+
+	row("followers", "new", len(r.NewFollowers))
+	for _, name := range r.NewFollowers {
+		row("new_followers", name, "")
+	}
+	for _, raid := range r.Raids {
+		row("raids", raid.Channel, raid.Viewers)
+	}
+
+	row("subs", "new", r.Subs.New)
+	row("subs", "gifted", r.Subs.Gifted)
+	row("subs", "resubs", r.Subs.Resubs)
+	tiers := make([]string, 0, len(r.Subs.ByTier))
+	for tier := range r.Subs.ByTier {
+		tiers = append(tiers, tier)
+	}
+	sort.Strings(tiers)
+	for _, tier := range tiers {
+		row("subs", "tier_"+tier, r.Subs.ByTier[tier])
+	}
+	row("bits", "total", r.Bits)
+
+	row("revenue_estimate", "subs", strconv.FormatFloat(r.Revenue.Subs, 'f', 2, 64))
+	row("revenue_estimate", "bits", strconv.FormatFloat(r.Revenue.Bits, 'f', 2, 64))
+	row("revenue_estimate", "total", strconv.FormatFloat(r.Revenue.Total, 'f', 2, 64))
+
+	for _, c := range r.CheckinCountries {
+		row("checkin_countries", c.Name, c.Count)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,430 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/db"
+)
+
+const (
+	tokenRefreshSkew     = 10 * time.Minute // refresh this long before a token expires
+	tokenRefreshInterval = time.Minute
+
+	tokenCipherPrefix = "enc:v1:"
+	tokenPlainPrefix  = "plain:"
+)
+
+// ErrNoToken means the provider has never been connected
+var ErrNoToken = errors.New("no token stored")
+
+// TokenManager stores user OAuth tokens encrypted in bronze.oauth_tokens and refreshes
+// them before they expire. Refreshes of the same token are serialised across instances with
+// an advisory lock, so a background refresh and a request that finds the token expiring
+// never both spend the refresh token.
+type TokenManager struct {
+	key    []byte // AES-256 key; nil stores tokens unencrypted
+	http   *http.Client
+	locks  sync.Map // provider|account -> *sync.Mutex, so waiters in this process don't each hold a connection
+	mu     sync.Mutex
+	memory map[string]models.StoredOAuthToken // provider|account -> token, used when the database is unavailable
+}
+
+var (
+	tokenManager     *TokenManager
+	tokenManagerOnce sync.Once
+)
+
+// Tokens returns the shared token manager
+func Tokens() *TokenManager {
+	tokenManagerOnce.Do(func() {
+		tokenManager = NewTokenManager(conf().Tokens.EncryptionKey)
+	})
+	return tokenManager
+}
+
+// NewTokenManager creates a manager. secret may be a base64 encoded 32 byte key or any
+// passphrase, which is hashed into a key. Without one tokens are stored unencrypted.
+func NewTokenManager(secret string) *TokenManager {
+	m := &TokenManager{
+		http:   &http.Client{Timeout: 10 * time.Second},
+		memory: make(map[string]models.StoredOAuthToken),
+	}
+
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		log.Println("⚠️ TOKEN_ENCRYPTION_KEY not set, OAuth tokens will be stored unencrypted")
+		return m
+	}
+	if raw, err := base64.StdEncoding.DecodeString(secret); err == nil && len(raw) == 32 {
+		m.key = raw
+	} else {
+		sum := sha256.Sum256([]byte(secret))
+		m.key = sum[:]
+	}
+	return m
+}
+
+// StartTokenRefresher migrates the legacy token tables and refreshes expiring tokens in the background
+func StartTokenRefresher() {
+	m := Tokens()
+	m.migrateLegacyTokens()
+
+	go func() {
+		for {
+			m.refreshExpiring()
+			time.Sleep(tokenRefreshInterval)
+		}
+	}()
+}
+
+// Save encrypts and stores a token, replacing any previous token for the account
+func (m *TokenManager) Save(token models.OAuthToken) error {
+	if token.Account == "" {
+		token.Account = "default"
+	}
+	access, err := m.encrypt(token.AccessToken)
+	if err != nil {
+		return err
+	}
+	refresh, err := m.encrypt(token.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	stored := models.StoredOAuthToken{
+		OAuthToken:      token,
+		AccessTokenEnc:  access,
+		RefreshTokenEnc: refresh,
+		Status:          models.TokenOK,
+		LastRefreshAt:   &now,
+	}
+
+	database := db.GetDB()
+	if database == nil {
+		m.mu.Lock()
+		m.memory[tokenKey(token.Provider, token.Account)] = stored
+		m.mu.Unlock()
+		return nil
+	}
+	return db.SaveOAuthToken(database, stored)
+}
+
+// AccessToken returns a valid access token for the account, refreshing it first if it's
+// about to expire. An empty account uses the most recently connected one.
+func (m *TokenManager) AccessToken(provider, account string) (string, error) {
+	token, err := m.load(provider, account)
+	if err != nil {
+		return "", err
+	}
+
+	if time.Until(token.ExpiresAt) < time.Minute {
+		refreshed, err := m.Refresh(provider, token.Account)
+		if err != nil {
+			if time.Now().Before(token.ExpiresAt) {
+				return token.AccessToken, nil
+			}
+			return "", fmt.Errorf("%s token expired and refresh failed: %w", provider, err)
+		}
+		token = refreshed
+	}
+	return token.AccessToken, nil
+}
+
+// Refresh renews a token now, unless another caller, here or in another instance, refreshed
+// it while we waited for the lock
+func (m *TokenManager) Refresh(provider, account string) (models.OAuthToken, error) {
+	lock, _ := m.locks.LoadOrStore(tokenKey(provider, account), &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if database := db.GetDB(); database != nil {
+		unlock, err := db.LockOAuthToken(database, provider, account)
+		if err != nil {
+			return models.OAuthToken{}, fmt.Errorf("lock %s token: %w", provider, err)
+		}
+		defer unlock()
+	}
+
+	// Expiry is checked again under the lock: the token we were asked about may be stale
+	stored, err := m.loadStored(provider, account)
+	if err != nil {
+		return models.OAuthToken{}, err
+	}
+	current, err := m.decryptStored(stored)
+	if err != nil {
+		return current, err
+	}
+	if time.Until(current.ExpiresAt) > tokenRefreshSkew {
+		return current, nil
+	}
+	// A caller ahead of us had the refresh token rejected; it won't work any better now
+	if stored.Status == models.TokenReauthRequired {
+		return current, fmt.Errorf("%w: %s token for %s needs a new login", ErrReauthRequired, provider, current.Account)
+	}
+	if current.RefreshToken == "" {
+		return current, fmt.Errorf("%s token for %s has no refresh token", provider, account)
+	}
+
+	p, ok := oauthProviders[provider]
+	if !ok {
+		return current, fmt.Errorf("unknown OAuth provider %q", provider)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	refreshed, err := p.refresh(ctx, m.http, current.RefreshToken)
+	if err != nil {
+		m.recordFailure(provider, current.Account, err)
+		return current, err
+	}
+
+	refreshed.Account = current.Account
+	if refreshed.Scopes == "" {
+		refreshed.Scopes = current.Scopes
+	}
+	if err := m.Save(*refreshed); err != nil {
+		return *refreshed, fmt.Errorf("refreshed %s token but failed to save it: %w", provider, err)
+	}
+	log.Printf("🔄 Refreshed %s token for %s (expires %s)", provider, current.Account, refreshed.ExpiresAt.Format(time.RFC3339))
+	return *refreshed, nil
+}
+
+func (m *TokenManager) recordFailure(provider, account string, err error) {
+	status := models.TokenRefreshFailed
+	if errors.Is(err, ErrReauthRequired) {
+		status = models.TokenReauthRequired
+	}
+	log.Printf("❌ Failed to refresh %s token for %s: %v", provider, account, err)
+
+	if database := db.GetDB(); database != nil {
+		if dbErr := db.RecordOAuthRefreshFailure(database, provider, account, status, err.Error()); dbErr != nil {
+			log.Printf("⚠️ Failed to record %s refresh failure: %v", provider, dbErr)
+		}
+		return
+	}
+
+	m.mu.Lock()
+	if stored, ok := m.memory[tokenKey(provider, account)]; ok {
+		stored.Status = status
+		stored.LastError = err.Error()
+		stored.Failures++
+		m.memory[tokenKey(provider, account)] = stored
+	}
+	m.mu.Unlock()
+}
+
+// Status describes the stored token and its last refresh for the status endpoints
+func (m *TokenManager) Status(provider, account string) models.TokenStatus {
+	status := models.TokenStatus{Provider: provider}
+
+	stored, err := m.loadStored(provider, account)
+	if err != nil {
+		status.Status = "not_connected"
+		if !errors.Is(err, ErrNoToken) {
+			status.LastError = err.Error()
+		}
+		return status
+	}
+
+	expiresAt := stored.ExpiresAt
+	status.Account = stored.Account
+	status.Status = stored.Status
+	status.ExpiresAt = &expiresAt
+	status.Scopes = stored.Scopes
+	status.LastRefreshAt = stored.LastRefreshAt
+	status.LastError = stored.LastError
+	status.Failures = stored.Failures
+	status.Connected = time.Now().Before(stored.ExpiresAt) ||
+		(stored.RefreshTokenEnc != "" && stored.Status != models.TokenReauthRequired)
+	return status
+}
+
+func (m *TokenManager) refreshExpiring() {
+	database := db.GetDB()
+	if database == nil {
+		return
+	}
+
+	expiring, err := db.GetExpiringOAuthTokens(database, time.Now().Add(tokenRefreshSkew))
+	if err != nil {
+		log.Printf("❌ Failed to list expiring OAuth tokens: %v", err)
+		return
+	}
+	for _, t := range expiring {
+		m.Refresh(t.Provider, t.Account)
+	}
+}
+
+func tokenKey(provider, account string) string {
+	return provider + "|" + account
+}
+
+func (m *TokenManager) load(provider, account string) (models.OAuthToken, error) {
+	stored, err := m.loadStored(provider, account)
+	if err != nil {
+		return models.OAuthToken{}, err
+	}
+	return m.decryptStored(stored)
+}
+
+func (m *TokenManager) decryptStored(stored models.StoredOAuthToken) (models.OAuthToken, error) {
+	var err error
+	token := stored.OAuthToken
+	if token.AccessToken, err = m.decrypt(stored.AccessTokenEnc); err != nil {
+		return token, err
+	}
+	if token.RefreshToken, err = m.decrypt(stored.RefreshTokenEnc); err != nil {
+		return token, err
+	}
+	return token, nil
+}
+
+func (m *TokenManager) loadStored(provider, account string) (models.StoredOAuthToken, error) {
+	database := db.GetDB()
+	if database == nil {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if account != "" {
+			if stored, ok := m.memory[tokenKey(provider, account)]; ok {
+				return stored, nil
+			}
+			return models.StoredOAuthToken{}, ErrNoToken
+		}
+
+		// Like the database, an empty account means the most recently saved one
+		var latest *models.StoredOAuthToken
+		for _, stored := range m.memory {
+			if stored.Provider == provider && (latest == nil || stored.LastRefreshAt.After(*latest.LastRefreshAt)) {
+				latest = &stored
+			}
+		}
+		if latest == nil {
+			return models.StoredOAuthToken{}, ErrNoToken
+		}
+		return *latest, nil
+	}
+
+	stored, err := db.GetOAuthToken(database, provider, account)
+	if err == sql.ErrNoRows {
+		return stored, ErrNoToken
+	}
+	return stored, err
+}
+
+// migrateLegacyTokens moves tokens from the old plaintext single-row tables into the store
+func (m *TokenManager) migrateLegacyTokens() {
+	database := db.GetDB()
+	if database == nil {
+		return
+	}
+
+	legacy := map[string]func() (models.OAuthToken, error){
+		models.ProviderTwitch: func() (models.OAuthToken, error) {
+			access, refresh, tokenType, scopes, expiresAt, err := db.GetTwitchToken(database)
+			return models.OAuthToken{AccessToken: access, RefreshToken: refresh, TokenType: tokenType,
+				Scopes: scopes, ExpiresAt: expiresAt}, err
+		},
+		models.ProviderSpotify: func() (models.OAuthToken, error) {
+			access, refresh, tokenType, expiresAt, err := db.GetSpotifyToken(database)
+			return models.OAuthToken{AccessToken: access, RefreshToken: refresh, TokenType: tokenType,
+				ExpiresAt: expiresAt}, err
+		},
+	}
+
+	for provider, read := range legacy {
+		token, err := read()
+		if err != nil {
+			continue // nothing to migrate
+		}
+		if _, err := m.loadStored(provider, ""); err == nil {
+			db.ClearLegacyTokens(database, provider)
+			continue
+		}
+
+		token.Provider = provider
+		token.Account = "default"
+		if err := m.Save(token); err != nil {
+			log.Printf("❌ Failed to migrate legacy %s token: %v", provider, err)
+			continue
+		}
+		if err := db.ClearLegacyTokens(database, provider); err != nil {
+			log.Printf("⚠️ Migrated %s token but failed to clear the legacy table: %v", provider, err)
+		}
+		log.Printf("🔐 Migrated legacy %s token into the token store", provider)
+	}
+}
+
+func (m *TokenManager) encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	if m.key == nil {
+		return tokenPlainPrefix + plaintext, nil
+	}
+
+	gcm, err := m.gcm()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return tokenCipherPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (m *TokenManager) decrypt(stored string) (string, error) {
+	switch {
+	case stored == "":
+		return "", nil
+	case strings.HasPrefix(stored, tokenPlainPrefix):
+		return strings.TrimPrefix(stored, tokenPlainPrefix), nil
+	case !strings.HasPrefix(stored, tokenCipherPrefix):
+		return "", fmt.Errorf("unrecognised token encoding")
+	case m.key == nil:
+		return "", fmt.Errorf("token is encrypted but TOKEN_ENCRYPTION_KEY is not set")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, tokenCipherPrefix))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := m.gcm()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted token is too short")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt token (wrong TOKEN_ENCRYPTION_KEY?): %w", err)
+	}
+	return string(plain), nil
+}
+
+func (m *TokenManager) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(m.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
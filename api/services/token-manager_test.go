@@ -0,0 +1,173 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+func TestTokenManagerEncryption(t *testing.T) {
+	rawKey := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+	tests := []struct {
+		name   string
+		secret string
+		prefix string
+	}{
+		{"base64 key", rawKey, tokenCipherPrefix},
+		{"passphrase", "correct horse battery staple", tokenCipherPrefix},
+		{"no key", "", tokenPlainPrefix},
+	}
+	for _, tt := range tests {
+		m := NewTokenManager(tt.secret)
+		for _, plain := range []string{"access-token", "ünïcode token"} {
+			sealed, err := m.encrypt(plain)
+			if err != nil {
+				t.Fatalf("%s: encrypt: %v", tt.name, err)
+			}
+			if !strings.HasPrefix(sealed, tt.prefix) || (tt.prefix == tokenCipherPrefix && strings.Contains(sealed, plain)) {
+				t.Errorf("%s: stored %q", tt.name, sealed)
+			}
+			if got, err := m.decrypt(sealed); err != nil || got != plain {
+				t.Errorf("%s: decrypt = %q, %v; want %q", tt.name, got, err, plain)
+			}
+		}
+		if sealed, _ := m.encrypt(""); sealed != "" {
+			t.Errorf("%s: empty token stored as %q", tt.name, sealed)
+		}
+	}
+
+	// Each encryption uses a fresh nonce
+	m := NewTokenManager(rawKey)
+	a, _ := m.encrypt("same")
+	b, _ := m.encrypt("same")
+	if a == b {
+		t.Error("encrypting twice gave the same ciphertext")
+	}
+
+	sealed, _ := m.encrypt("secret")
+	for name, manager := range map[string]*TokenManager{
+		"wrong key": NewTokenManager("another passphrase"),
+		"no key":    NewTokenManager(""),
+	} {
+		if _, err := manager.decrypt(sealed); err == nil {
+			t.Errorf("%s: decrypted a token sealed with another key", name)
+		}
+	}
+}
+
+func TestTokenManagerDecryptLegacy(t *testing.T) {
+	tests := []struct {
+		stored string
+		want   string
+		err    bool
+	}{
+		{"", "", false},
+		{tokenPlainPrefix + "legacy-token", "legacy-token", false},
+		{tokenPlainPrefix, "", false},
+		{"legacy-token", "", true}, // no prefix at all
+		{tokenCipherPrefix + "not base64!", "", true},
+		{tokenCipherPrefix + base64.StdEncoding.EncodeToString([]byte("short")), "", true},
+	}
+
+	// Plain tokens written before a key was set still read once one is
+	m := NewTokenManager("a passphrase")
+	for _, tt := range tests {
+		got, err := m.decrypt(tt.stored)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("decrypt(%q) = %q, %v; want %q, error %t", tt.stored, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+// useTestOAuthProvider registers a provider whose token endpoint counts refreshes and
+// answers with status, handing out access-1, access-2, ...
+func useTestOAuthProvider(t *testing.T, status int) *atomic.Int32 {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		time.Sleep(20 * time.Millisecond) // long enough for other callers to queue on the lock
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"access_token":"access-%d","expires_in":3600}`, n)
+	}))
+	t.Cleanup(server.Close)
+
+	oauthProviders["test"] = &OAuthProvider{
+		Name:            "test",
+		TokenURL:        server.URL,
+		Credentials:     func() (string, string) { return "id", "secret" },
+		ClientIDEnv:     "TEST_CLIENT_ID",
+		ClientSecretEnv: "TEST_CLIENT_SECRET",
+	}
+	t.Cleanup(func() { delete(oauthProviders, "test") })
+	return &calls
+}
+
+func TestTokenManagerRefresh(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiresIn time.Duration
+		status    int
+		wantCalls int32
+		wantToken string
+		wantState string
+	}{
+		{"fresh token isn't refreshed", time.Hour, http.StatusOK, 0, "access-0", models.TokenOK},
+		{"concurrent refreshes spend the refresh token once", time.Minute, http.StatusOK, 1, "access-1", models.TokenOK},
+		{"rejected refresh token", time.Minute, http.StatusBadRequest, 1, "access-0", models.TokenReauthRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := useTestOAuthProvider(t, tt.status)
+			m := NewTokenManager("a passphrase")
+			save := func(account, access string) {
+				if err := m.Save(models.OAuthToken{Provider: "test", Account: account, AccessToken: access,
+					RefreshToken: "refresh", ExpiresAt: time.Now().Add(tt.expiresIn)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			save("alice", "access-0")
+			save("bob", "bob-token")
+
+			// The first caller refreshes; the rest find the new token once they get the lock
+			var wg sync.WaitGroup
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					m.Refresh("test", "alice")
+				}()
+			}
+			wg.Wait()
+
+			if n := calls.Load(); n != tt.wantCalls {
+				t.Errorf("token endpoint called %d times, want %d", n, tt.wantCalls)
+			}
+			token, err := m.load("test", "alice")
+			if err != nil || token.AccessToken != tt.wantToken {
+				t.Errorf("alice's token = %q, %v; want %q", token.AccessToken, err, tt.wantToken)
+			}
+			if status := m.Status("test", "alice"); status.Status != tt.wantState {
+				t.Errorf("alice's status = %q, want %q", status.Status, tt.wantState)
+			}
+
+			// Another account of the same provider is kept apart
+			if bob, err := m.load("test", "bob"); err != nil || bob.AccessToken != "bob-token" {
+				t.Errorf("bob's token = %q, %v", bob.AccessToken, err)
+			}
+			if status := m.Status("test", "bob"); status.Status != models.TokenOK {
+				t.Errorf("bob's status = %q", status.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/db"
+)
+
+const (
+	chatRollupBatchSize = 2000
+	chatWordMinLength   = 3
+	chatWordMaxLength   = 50
+)
+
+// ErrAnalyticsQuery is returned for an unknown window or time zone
+var ErrAnalyticsQuery = errors.New("invalid analytics query")
+
+// Common words left out of the word frequency rollup
+var chatStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "you": true, "that": true, "this": true, "with": true,
+	"are": true, "was": true, "but": true, "not": true, "have": true, "just": true, "what": true,
+	"its": true, "it's": true, "can": true, "your": true, "all": true, "from": true, "they": true,
+	"how": true, "out": true, "get": true, "there": true, "about": true, "one": true, "like": true,
+	"has": true, "had": true, "she": true, "him": true, "her": true, "his": true, "them": true,
+	"who": true, "why": true, "when": true, "then": true, "than": true, "too": true, "did": true,
+	"don't": true, "i'm": true, "yes": true, "yeah": true, "also": true, "will": true, "would": true,
+}
+
+// StartChatAnalyticsRollup folds new chat messages into the analytics rollup tables on an interval
+func StartChatAnalyticsRollup(interval time.Duration) {
+	go func() {
+		for {
+			if n, err := RefreshChatRollups(); err != nil {
+				log.Printf("❌ Chat analytics rollup failed: %v", err)
+			} else if n > 0 {
+				log.Printf("📈 Rolled up %d chat messages", n)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// RefreshChatRollups processes every message added since the last run in each profile's channel and
+// returns how many it processed
+func RefreshChatRollups() (int, error) {
+	database := db.GetDB()
+	if database == nil {
+		return 0, fmt.Errorf("database not available")
+	}
+
+	processed := 0
+	seen := make(map[string]bool)
+	for _, profile := range Profiles() {
+		channel := profile.TwitchChannel
+		if channel == "" || seen[channel] {
+			continue
+		}
+		seen[channel] = true
+
+		n, err := refreshChannelRollups(database, channel)
+		processed += n
+		if err != nil {
+			return processed, fmt.Errorf("%s: %w", channel, err)
+		}
+	}
+	return processed, nil
+}
+
+func refreshChannelRollups(database *sql.DB, channel string) (int, error) {
+	cursor, err := db.GetChatRollupCursor(database, channel)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for {
+		batch, err := db.GetTwitchMessagesAfter(database, channel, cursor, chatRollupBatchSize)
+		if err != nil {
+			return processed, err
+		}
+		if len(batch) == 0 {
+			return processed, nil
+		}
+
+		rollup := aggregateChatMessages(batch)
+		if err := db.ApplyChatRollup(database, channel, rollup); err != nil {
+			return processed, err
+		}
+		cursor = rollup.LastMessageID
+		processed += len(batch)
+
+		if len(batch) < chatRollupBatchSize {
+			return processed, nil
+		}
+	}
+}
+
+func aggregateChatMessages(msgs []models.TwitchMessage) *models.ChatRollup {
+	r := &models.ChatRollup{
+		Chatters:   make(map[string]*models.ChatterRollup),
+		Hourly:     make(map[time.Time]int),
+		Words:      make(map[models.DayTerm]int),
+		Emotes:     make(map[models.DayTerm]int),
+		EmoteNames: make(map[string]string),
+	}
+
+	for _, m := range msgs {
+		if m.ID > r.LastMessageID {
+			r.LastMessageID = m.ID
+		}
+		at := m.Time.UTC()
+		day := at.Format("2006-01-02")
+		username := strings.ToLower(m.Username)
+
+		c, ok := r.Chatters[username]
+		if !ok {
+			c = &models.ChatterRollup{FirstSeen: at, LastSeen: at, Days: make(map[string]int)}
+			r.Chatters[username] = c
+		}
+		if m.DisplayName != "" {
+			c.DisplayName = m.DisplayName
+		}
+		c.Messages++
+		c.Days[day]++
+		if at.Before(c.FirstSeen) {
+			c.FirstSeen = at
+		}
+		if at.After(c.LastSeen) {
+			c.LastSeen = at
+		}
+
+		r.Hourly[at.Truncate(time.Hour)]++
+
+		emoteNames := make(map[string]bool, len(m.Emotes))
+		for _, e := range m.Emotes {
+			r.Emotes[models.DayTerm{Day: day, Term: e.ID}] += e.Count
+			r.EmoteNames[e.ID] = e.Name
+			emoteNames[e.Name] = true
+		}
+
+		// Bot commands would drown out real conversation
+		if strings.HasPrefix(strings.TrimSpace(m.Message), "!") {
+			continue
+		}
+		for _, word := range chatWords(m.Message, emoteNames) {
+			r.Words[models.DayTerm{Day: day, Term: word}]++
+		}
+	}
+	return r
+}
+
+// chatWords splits a message into lowercase words, skipping emotes, links, mentions and stop words
+func chatWords(message string, emotes map[string]bool) []string {
+	var words []string
+	for _, token := range strings.Fields(message) {
+		if emotes[token] || strings.HasPrefix(token, "@") || strings.Contains(token, "://") {
+			continue
+		}
+		word := strings.ToLower(strings.TrimFunc(token, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}))
+		if len(word) < chatWordMinLength || len(word) > chatWordMaxLength || chatStopWords[word] {
+			continue
+		}
+		if _, err := strconv.Atoi(word); err == nil {
+			continue
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+// ParseAnalyticsWindow turns "today", "all", "<n>d" or "<n>w" into the first UTC day of the window.
+// "all" returns the zero time.
+func ParseAnalyticsWindow(window string) (time.Time, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	window = strings.ToLower(strings.TrimSpace(window))
+
+	switch window {
+	case "", "7d":
+		return today.AddDate(0, 0, -6), nil
+	case "today", "1d":
+		return today, nil
+	case "all":
+		return time.Time{}, nil
+	}
+
+	if len(window) < 2 {
+		return time.Time{}, fmt.Errorf("%w: window %q", ErrAnalyticsQuery, window)
+	}
+	n, err := strconv.Atoi(window[:len(window)-1])
+	if err != nil || n < 1 {
+		return time.Time{}, fmt.Errorf("%w: window %q", ErrAnalyticsQuery, window)
+	}
+	switch window[len(window)-1] {
+	case 'd':
+	case 'w':
+		n *= 7
+	default:
+		return time.Time{}, fmt.Errorf("%w: window %q", ErrAnalyticsQuery, window)
+	}
+	if n > 3650 {
+		return time.Time{}, nil
+	}
+	return today.AddDate(0, 0, -(n - 1)), nil
+}
+
+// ChatLeaderboard ranks a channel's chatters by messages within the window
+func ChatLeaderboard(channel, window string, limit int) ([]models.LeaderboardEntry, error) {
+	since, err := ParseAnalyticsWindow(window)
+	if err != nil {
+		return nil, err
+	}
+	database := db.GetDB()
+	if database == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	return db.GetChatLeaderboard(database, channel, since, limit)
+}
+
+// ChatterProfile returns a chatter's lifetime stats in a channel with their last 30 days of activity
+func ChatterProfile(channel, username string) (models.ChatterStats, error) {
+	database := db.GetDB()
+	if database == nil {
+		return models.ChatterStats{}, fmt.Errorf("database not available")
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	stats, err := db.GetChatterStats(database, channel, strings.ToLower(username), today.AddDate(0, 0, -29))
+	if err != nil {
+		return stats, err
+	}
+
+	// The stored streak only moves when they chat; it's broken once a full day is missed
+	if stats.LastStreakDay < today.AddDate(0, 0, -1).Format("2006-01-02") {
+		stats.CurrentStreak = 0
+	}
+	return stats, nil
+}
+
+// TopChatWords returns the most used words in a channel within the window
+func TopChatWords(channel, window string, limit int) ([]models.TermCount, error) {
+	since, err := ParseAnalyticsWindow(window)
+	if err != nil {
+		return nil, err
+	}
+	database := db.GetDB()
+	if database == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+	return db.GetTopChatWords(database, channel, since, limit)
+}
+
+// TopChatEmotes returns the most used emotes in a channel within the window
+func TopChatEmotes(channel, window string, limit int) ([]models.TermCount, error) {
+	since, err := ParseAnalyticsWindow(window)
+	if err != nil {
+		return nil, err
+	}
+	database := db.GetDB()
+	if database == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+
+	emotes, err := db.GetTopChatEmotes(database, channel, since, limit)
+	for i := range emotes {
+		emotes[i].ImageURL = "https://static-cdn.jtvnw.net/emoticons/v2/" + emotes[i].EmoteID + "/default/dark/1.0"
+	}
+	return emotes, err
+}
+
+// ChatActivityHeatmap counts a channel's messages by weekday and hour within the window
+func ChatActivityHeatmap(channel, window, timeZone string) (models.ChatHeatmap, error) {
+	heatmap := models.ChatHeatmap{TimeZone: "UTC"}
+	if timeZone != "" {
+		if _, err := time.LoadLocation(timeZone); err != nil {
+			return heatmap, fmt.Errorf("%w: time zone %q", ErrAnalyticsQuery, timeZone)
+		}
+		heatmap.TimeZone = timeZone
+	}
+
+	since, err := ParseAnalyticsWindow(window)
+	if err != nil {
+		return heatmap, err
+	}
+	if !since.IsZero() {
+		heatmap.Since = &since
+	}
+
+	database := db.GetDB()
+	if database == nil {
+		return heatmap, fmt.Errorf("database not available")
+	}
+	heatmap.Hours, err = db.GetChatHeatmap(database, channel, since, heatmap.TimeZone)
+	return heatmap, err
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+func TestAggregateChatMessages(t *testing.T) {
+	at := func(s string) time.Time {
+		parsed, _ := time.Parse(time.RFC3339, s)
+		return parsed
+	}
+	kappa := models.TwitchEmote{ID: "25", Name: "Kappa", Count: 2}
+
+	rollup := aggregateChatMessages([]models.TwitchMessage{
+		{ID: 7, Username: "Alice", DisplayName: "Alice", Message: "Hello stream, hello chat!", Time: at("2026-03-01T23:30:00Z")},
+		{ID: 9, Username: "alice", Message: "Kappa Kappa gg", Emotes: []models.TwitchEmote{kappa}, Time: at("2026-03-02T00:10:00-02:00")},
+		{ID: 8, Username: "bob", DisplayName: "Bob", Message: "!uptime please", Time: at("2026-03-01T23:45:00Z")},
+		{ID: 10, Username: "bob", Message: "@alice see https://example.com 1234 the hello", Time: at("2026-03-01T23:59:00Z")},
+	})
+
+	if rollup.LastMessageID != 10 {
+		t.Errorf("LastMessageID = %d, want 10", rollup.LastMessageID)
+	}
+
+	alice := rollup.Chatters["alice"]
+	if alice == nil || alice.Messages != 2 || alice.DisplayName != "Alice" {
+		t.Fatalf("alice = %+v", alice)
+	}
+	// -02:00 puts the second message on the next UTC day
+	if !reflect.DeepEqual(alice.Days, map[string]int{"2026-03-01": 1, "2026-03-02": 1}) {
+		t.Errorf("alice days = %v", alice.Days)
+	}
+	if !alice.FirstSeen.Equal(at("2026-03-01T23:30:00Z")) || !alice.LastSeen.Equal(at("2026-03-02T02:10:00Z")) {
+		t.Errorf("alice seen %s to %s", alice.FirstSeen, alice.LastSeen)
+	}
+	if bob := rollup.Chatters["bob"]; bob == nil || bob.Messages != 2 {
+		t.Errorf("bob = %+v", bob)
+	}
+
+	wantHourly := map[time.Time]int{at("2026-03-01T23:00:00Z"): 3, at("2026-03-02T02:00:00Z"): 1}
+	if !reflect.DeepEqual(rollup.Hourly, wantHourly) {
+		t.Errorf("hourly = %v", rollup.Hourly)
+	}
+
+	// Commands, emotes, mentions, links, numbers, stop words and short words are left out
+	wantWords := map[models.DayTerm]int{
+		{Day: "2026-03-01", Term: "hello"}:  3,
+		{Day: "2026-03-01", Term: "stream"}: 1,
+		{Day: "2026-03-01", Term: "chat"}:   1,
+		{Day: "2026-03-01", Term: "see"}:    1,
+	}
+	if !reflect.DeepEqual(rollup.Words, wantWords) {
+		t.Errorf("words = %v", rollup.Words)
+	}
+
+	if n := rollup.Emotes[models.DayTerm{Day: "2026-03-02", Term: "25"}]; n != 2 || rollup.EmoteNames["25"] != "Kappa" {
+		t.Errorf("emotes = %v, names = %v", rollup.Emotes, rollup.EmoteNames)
+	}
+}
+
+func TestParseAnalyticsWindow(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	tests := []struct {
+		window string
+		want   time.Time
+		err    bool
+	}{
+		{"", today.AddDate(0, 0, -6), false},
+		{"7d", today.AddDate(0, 0, -6), false},
+		{"today", today, false},
+		{"1D", today, false},
+		{" 30d ", today.AddDate(0, 0, -29), false},
+		{"2w", today.AddDate(0, 0, -13), false},
+		{"all", time.Time{}, false},
+		{"9999d", time.Time{}, false},
+		{"0d", time.Time{}, true},
+		{"-3d", time.Time{}, true},
+		{"3m", time.Time{}, true},
+		{"d", time.Time{}, true},
+		{"week", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAnalyticsWindow(tt.window)
+		if tt.err {
+			if !errors.Is(err, ErrAnalyticsQuery) {
+				t.Errorf("%q: err = %v, want ErrAnalyticsQuery", tt.window, err)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("%q: got %s, %v; want %s", tt.window, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/db"
+)
+
+// TwitchBotDeps are lookups the bot needs from outside the services package
+type TwitchBotDeps struct {
+	NowPlaying func(ctx context.Context) (*models.CurrentTrack, error)
+}
+
+// TwitchBot answers chat commands in a Twitch channel. Without a user token it
+// still runs commands from the anonymous chat feed but can only log its replies.
+type TwitchBot struct {
+	channel  string
+	registry *TwitchCommandRegistry
+	client   *twitch.Client
+	login    string
+
+	mu   sync.Mutex
+	sent map[string]time.Time // replies we sent recently, which the chat feed hands back to us
+}
+
+// twitchBotEchoWindow is how long a reply the bot sent is recognised when it comes back
+const twitchBotEchoWindow = 30 * time.Second
+
+var twitchBot *TwitchBot
+
+// StartTwitchBot registers the built-in and custom commands and logs the bot into chat.
+// Call it before StartTwitchChatFeed so the feed knows whether to hand commands over.
+func StartTwitchBot(channel string, deps TwitchBotDeps) *TwitchBot {
+	bot := &TwitchBot{
+		channel:  strings.ToLower(channel),
+		registry: NewTwitchCommandRegistry(),
+		sent:     make(map[string]time.Time),
+	}
+	bot.registerBuiltins(deps)
+	if err := bot.ReloadCustomCommands(); err != nil {
+		log.Printf("⚠️ Failed to load custom bot commands: %v", err)
+	}
+
+	if _, err := getTwitchUserToken(); err != nil {
+		log.Printf("ℹ️ Twitch bot running read-only (no user token: %v)", err)
+	} else {
+		cfg := LoadTwitchIRCConfig(channel)
+		bot.login = cfg.Login
+		bot.client = NewAuthenticatedTwitchClient(cfg, "")
+		bot.client.OnPrivateMessage(bot.HandleMessage)
+		bot.client.OnConnect(func() {
+			log.Printf("🤖 Twitch bot connected to #%s as %s", bot.channel, bot.login)
+		})
+		bot.client.Join(bot.channel)
+
+		go connectTwitchIRC(bot.client, "Twitch bot", getTwitchUserToken, nil)
+	}
+
+	twitchBot = bot
+	return bot
+}
+
+// GetTwitchBot returns the running bot, or nil if it was never started
+func GetTwitchBot() *TwitchBot {
+	return twitchBot
+}
+
+// Registry exposes the bot's commands
+func (b *TwitchBot) Registry() *TwitchCommandRegistry {
+	return b.registry
+}
+
+// HandleMessage runs any command in msg and replies in-thread. The bot usually logs in as the
+// broadcaster, so only its own replies are skipped, not everything from its login.
+func (b *TwitchBot) HandleMessage(msg twitch.PrivateMessage) {
+	if b.login != "" && strings.EqualFold(msg.User.Name, b.login) && b.sentRecently(msg.Message) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	reply, ok := b.registry.Dispatch(ctx, b.channel, msg)
+	if !ok || reply == "" {
+		return
+	}
+	b.Say(reply, msg.ID)
+}
+
+// Say sends text to the channel, as a threaded reply when parentID is set
+func (b *TwitchBot) Say(text, parentID string) {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > twitchMaxMessageLen {
+		text = string(runes[:twitchMaxMessageLen-1]) + "…"
+	}
+
+	if b.client == nil {
+		log.Printf("🤖 (read-only) would reply: %s", text)
+		return
+	}
+	b.remember(text)
+	if parentID != "" {
+		b.client.Reply(b.channel, parentID, text)
+		return
+	}
+	b.client.Say(b.channel, text)
+}
+
+// remember records a reply so it isn't handled as a command when it comes back
+func (b *TwitchBot) remember(text string) {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for t, at := range b.sent {
+		if now.Sub(at) > twitchBotEchoWindow {
+			delete(b.sent, t)
+		}
+	}
+	b.sent[text] = now
+}
+
+// sentRecently reports whether the bot sent text within the echo window, forgetting it if so
+func (b *TwitchBot) sentRecently(text string) bool {
+	text = strings.Join(strings.Fields(text), " ")
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	at, ok := b.sent[text]
+	if ok {
+		delete(b.sent, text)
+	}
+	return ok && time.Since(at) <= twitchBotEchoWindow
+}
+
+// ReloadCustomCommands replaces the custom commands with the ones stored in Postgres
+func (b *TwitchBot) ReloadCustomCommands() error {
+	database := db.GetDB()
+	if database == nil {
+		return fmt.Errorf("database not available")
+	}
+
+	commands, err := db.GetTwitchBotCommands(database)
+	if err != nil {
+		return err
+	}
+
+	b.registry.UnregisterCustom()
+	loaded := 0
+	for _, c := range commands {
+		if !c.Enabled {
+			continue
+		}
+		if err := b.registry.Register(customTwitchCommand(c)); err != nil {
+			log.Printf("⚠️ Skipping custom command !%s: %v", c.Name, err)
+			continue
+		}
+		loaded++
+	}
+	log.Printf("🤖 Loaded %d custom Twitch bot commands", loaded)
+	return nil
+}
+
+func customTwitchCommand(c models.TwitchBotCommand) *TwitchCommand {
+	permission, _ := ParseTwitchPermission(c.Permission)
+	response := c.Response
+	return &TwitchCommand{
+		Name:       c.Name,
+		Aliases:    append([]string(nil), c.Aliases...),
+		Permission: permission,
+		Cooldown:   time.Duration(c.CooldownSeconds) * time.Second,
+		Handler: func(cc *TwitchCommandContext) (string, error) {
+			return renderCustomCommand(response, cc), nil
+		},
+	}
+}
+
+func (b *TwitchBot) registerBuiltins(deps TwitchBotDeps) {
+	builtins := []*TwitchCommand{
+		{
+			Name:        "commands",
+			Aliases:     []string{"help"},
+			Description: "List available commands",
+			Cooldown:    10 * time.Second,
+			Handler:     b.commandsCommand,
+		},
+		{
+			Name:         "song",
+			Aliases:      []string{"music", "nowplaying"},
+			Description:  "Show the current Spotify track",
+			Cooldown:     10 * time.Second,
+			UserCooldown: 30 * time.Second,
+			Handler: func(cc *TwitchCommandContext) (string, error) {
+				if deps.NowPlaying == nil {
+					return "", fmt.Errorf("spotify lookup not configured")
+				}
+				track, err := deps.NowPlaying(cc.Ctx)
+				if err != nil {
+					return "", err
+				}
+				if track == nil || !track.IsPlaying {
+					return "🎵 Nothing playing right now.", nil
+				}
+				reply := fmt.Sprintf("🎵 %s — %s", track.Title, strings.Join(track.Artists, ", "))
+				if track.URL != "" {
+					reply += " " + track.URL
+				}
+				return reply, nil
+			},
+		},
+		{
+			Name:         "stats",
+			Description:  "Show channel stats across platforms",
+			Cooldown:     60 * time.Second,
+			UserCooldown: 2 * time.Minute,
+			Handler:      statsCommand,
+		},
+		{
+			Name:         "epl",
+			Aliases:      []string{"football", "pl"},
+			Description:  "Show upcoming Premier League matches",
+			Cooldown:     30 * time.Second,
+			UserCooldown: time.Minute,
+			Handler:      eplCommand,
+		},
+		{
+			Name:         "ask",
+			Aliases:      []string{"ai"},
+			Description:  "Ask the AI a question",
+			Cooldown:     5 * time.Second,
+			UserCooldown: 60 * time.Second,
+			Handler:      askCommand,
+		},
+		{
+			Name:        "uptime",
+			Description: "Show how long the stream has been live",
+			Cooldown:    15 * time.Second,
+			Handler:     b.uptimeCommand,
+		},
+		{
+			Name:         "checkin",
+			Description:  "Check in on the globe: !checkin <city>",
+			UserCooldown: 30 * time.Second,
+			Handler: func(cc *TwitchCommandContext) (string, error) {
+				if cc.RawArgs == "" {
+					return fmt.Sprintf("@%s usage: !checkin <city>", cc.User()), nil
+				}
+				log.Printf("🌍 Processing !checkin command from %s: %s", cc.User(), cc.RawArgs)
+				result, err := CreateCheckin(cc.Ctx, CheckinRequest{
+					Query:    cc.RawArgs,
+					Username: cc.Message.Username,
+					Platform: "twitch",
+					Channel:  cc.Channel,
+				})
+				if errors.Is(err, ErrCheckinLocation) {
+					return fmt.Sprintf("@%s I couldn't find %q on the map 🤔", cc.User(), cc.RawArgs), nil
+				}
+				if err != nil {
+					return "", err
+				}
+				if result.Duplicate {
+					return fmt.Sprintf("@%s you already checked in from %s this stream 🌍", cc.User(), result.Place), nil
+				}
+				return fmt.Sprintf("📍 @%s checked in from %s!", cc.User(), result.Place), nil
+			},
+		},
+	}
+
+	for _, cmd := range builtins {
+		cmd.Builtin = true
+		if err := b.registry.Register(cmd); err != nil {
+			log.Printf("⚠️ Failed to register !%s: %v", cmd.Name, err)
+		}
+	}
+}
+
+func (b *TwitchBot) commandsCommand(cc *TwitchCommandContext) (string, error) {
+	var names []string
+	for _, info := range b.registry.List() {
+		if p, _ := ParseTwitchPermission(info.Permission); p <= cc.Permission {
+			names = append(names, "!"+info.Name)
+		}
+	}
+	return "🤖 Commands: " + strings.Join(names, " "), nil
+}
+
+func statsCommand(cc *TwitchCommandContext) (string, error) {
+	profile, ok := ProfileForTwitchChannel(cc.Channel)
+	if !ok {
+		profile = DefaultProfile()
+	}
+	stats, err := GetUnifiedStats(cc.Ctx, profile)
+	if err != nil {
+		return "", err
+	}
+
+	var parts []string
+	if stats.YouTube != nil && stats.YouTube.Error == "" {
+		parts = append(parts, fmt.Sprintf("YouTube %d subs", stats.YouTube.Subscribers))
+	}
+	if stats.GitHub != nil && stats.GitHub.Error == "" {
+		parts = append(parts, fmt.Sprintf("GitHub %d followers / %d ⭐", stats.GitHub.Followers, stats.GitHub.StarsReceived))
+	}
+	if stats.Twitch != nil && stats.Twitch.Error == "" {
+		parts = append(parts, fmt.Sprintf("Twitch %d followers", stats.Twitch.Followers))
+	}
+	if stats.LeetCode != nil && stats.LeetCode.Error == "" {
+		parts = append(parts, fmt.Sprintf("LeetCode %d solved", stats.LeetCode.SolvedCount))
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("no stats available")
+	}
+	return "📊 " + strings.Join(parts, " · "), nil
+}
+
+func eplCommand(cc *TwitchCommandContext) (string, error) {
+	matches, err := PLSchedule(cc.Ctx)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	var upcoming []string
+	for _, m := range matches {
+		if m.Date.Before(now) {
+			continue
+		}
+		upcoming = append(upcoming, fmt.Sprintf("%s vs %s (%s)",
+			m.HomeTeam.Name, m.AwayTeam.Name, m.Date.UTC().Format("Mon 15:04 UTC")))
+		if len(upcoming) == 3 {
+			break
+		}
+	}
+	if len(upcoming) == 0 {
+		return "⚽ No upcoming Premier League matches this week.", nil
+	}
+	return "⚽ Next up: " + strings.Join(upcoming, " | "), nil
+}
+
+func askCommand(cc *TwitchCommandContext) (string, error) {
+	if cc.RawArgs == "" {
+		return fmt.Sprintf("@%s usage: !ask <question>", cc.User()), nil
+	}
+
+	provider := GetFallbackProvider()
+	if provider == "" {
+		return "", fmt.Errorf("no AI provider configured")
+	}
+
+	prompt := "You are a friendly bot in a Twitch coding stream chat. Answer in at most two short sentences, plain text, no markdown.\n\nQuestion: " + cc.RawArgs
+	resp, err := GenerateAIResponse(AIRequest{Prompt: prompt, Provider: provider})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("@%s %s", cc.User(), resp.Response), nil
+}
+
+func (b *TwitchBot) uptimeCommand(cc *TwitchCommandContext) (string, error) {
+	startedAt, live, err := FetchTwitchStreamStart(b.channel)
+	if err != nil {
+		return "", err
+	}
+	if !live {
+		return fmt.Sprintf("📴 %s is offline.", b.channel), nil
+	}
+	return fmt.Sprintf("⏱️ Live for %s", formatUptime(time.Since(startedAt))), nil
+}
+
+func formatUptime(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h := int(d / time.Hour)
+	m := int((d % time.Hour) / time.Minute)
+	if h == 0 {
+		return fmt.Sprintf("%dm", m)
+	}
+	return fmt.Sprintf("%dh %dm", h, m)
+}
+
+// FetchTwitchStreamStart returns when the channel's current stream started, and false if it is offline
+func FetchTwitchStreamStart(login string) (time.Time, bool, error) {
+	token, err := getTwitchToken()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	clientID := conf().Twitch.ClientID
+	if clientID == "" {
+		return time.Time{}, false, fmt.Errorf("TWITCH_CLIENT_ID not set")
+	}
+
+	req, _ := http.NewRequest("GET", "https://api.twitch.tv/helix/streams?user_login="+login, nil)
+	req.Header.Set("Client-ID", clientID)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("streams request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, false, fmt.Errorf("streams API returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data []struct {
+			StartedAt time.Time `json:"started_at"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to decode streams: %w", err)
+	}
+
+	if len(result.Data) == 0 {
+		return time.Time{}, false, nil
+	}
+	return result.Data[0].StartedAt, true, nil
+}
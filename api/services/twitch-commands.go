@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+	"majesticcoding.com/api/models"
+)
+
+// TwitchPermission is the minimum role needed to run a command
+type TwitchPermission int
+
+const (
+	PermissionEveryone TwitchPermission = iota
+	PermissionSubscriber
+	PermissionVIP
+	PermissionModerator
+	PermissionBroadcaster
+)
+
+var twitchPermissionNames = map[TwitchPermission]string{
+	PermissionEveryone:    "everyone",
+	PermissionSubscriber:  "subscriber",
+	PermissionVIP:         "vip",
+	PermissionModerator:   "moderator",
+	PermissionBroadcaster: "broadcaster",
+}
+
+func (p TwitchPermission) String() string {
+	if name, ok := twitchPermissionNames[p]; ok {
+		return name
+	}
+	return "everyone"
+}
+
+// ParseTwitchPermission converts a permission name into its level
+func ParseTwitchPermission(name string) (TwitchPermission, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return PermissionEveryone, nil
+	}
+	for p, n := range twitchPermissionNames {
+		if n == name || (name == "mod" && p == PermissionModerator) || (name == "sub" && p == PermissionSubscriber) {
+			return p, nil
+		}
+	}
+	return PermissionEveryone, fmt.Errorf("unknown permission %q", name)
+}
+
+// TwitchUserPermission derives the highest role of a chatter from their badge flags
+func TwitchUserPermission(msg models.TwitchMessage) TwitchPermission {
+	switch {
+	case msg.IsBroadcaster || msg.Badges["broadcaster"] > 0:
+		return PermissionBroadcaster
+	case msg.IsMod || msg.Badges["moderator"] > 0:
+		return PermissionModerator
+	case msg.IsVip || msg.Badges["vip"] > 0:
+		return PermissionVIP
+	case msg.Badges["subscriber"] > 0 || msg.Badges["founder"] > 0:
+		return PermissionSubscriber
+	default:
+		return PermissionEveryone
+	}
+}
+
+// TwitchCommandContext is passed to command handlers
+type TwitchCommandContext struct {
+	Ctx        context.Context
+	Channel    string
+	Command    string   // the name or alias that was typed
+	Args       []string // words after the command
+	RawArgs    string
+	Message    models.TwitchMessage
+	MessageID  string
+	Permission TwitchPermission
+}
+
+// User returns the chatter's display name, falling back to their login
+func (c *TwitchCommandContext) User() string {
+	if c.Message.DisplayName != "" {
+		return c.Message.DisplayName
+	}
+	return c.Message.Username
+}
+
+// TwitchCommand is a chat command such as !song. Handler returns the reply to send, or "" for none.
+type TwitchCommand struct {
+	Name         string
+	Aliases      []string
+	Description  string
+	Permission   TwitchPermission
+	Cooldown     time.Duration // shared by the whole channel
+	UserCooldown time.Duration // per chatter
+	Builtin      bool
+	Handler      func(*TwitchCommandContext) (string, error)
+}
+
+// TwitchCommandRegistry looks up commands by name or alias and enforces cooldowns
+type TwitchCommandRegistry struct {
+	mu        sync.Mutex
+	commands  map[string]*TwitchCommand
+	aliases   map[string]string
+	lastUsed  map[string]time.Time
+	userUsage map[string]time.Time // "command|login" -> last use
+}
+
+// NewTwitchCommandRegistry creates an empty registry
+func NewTwitchCommandRegistry() *TwitchCommandRegistry {
+	return &TwitchCommandRegistry{
+		commands:  make(map[string]*TwitchCommand),
+		aliases:   make(map[string]string),
+		lastUsed:  make(map[string]time.Time),
+		userUsage: make(map[string]time.Time),
+	}
+}
+
+// Register adds or replaces a command. Names and aliases are case-insensitive and given without "!".
+func (r *TwitchCommandRegistry) Register(cmd *TwitchCommand) error {
+	name := normalizeCommandName(cmd.Name)
+	if name == "" || cmd.Handler == nil {
+		return fmt.Errorf("command needs a name and a handler")
+	}
+	cmd.Name = name
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.commands[name]; ok && existing.Builtin && !cmd.Builtin {
+		return fmt.Errorf("!%s is a built-in command", name)
+	}
+	// Aliases are resolved before names, so a command named like one could never be reached
+	if owner, ok := r.aliases[name]; ok && owner != name {
+		return fmt.Errorf("!%s is already an alias of !%s", name, owner)
+	}
+	for _, alias := range cmd.Aliases {
+		alias = normalizeCommandName(alias)
+		if owner, ok := r.aliases[alias]; ok && owner != name {
+			return fmt.Errorf("alias !%s already belongs to !%s", alias, owner)
+		}
+		if existing, ok := r.commands[alias]; ok && existing.Name != name {
+			return fmt.Errorf("alias !%s clashes with an existing command", alias)
+		}
+	}
+
+	r.removeLocked(name)
+	r.commands[name] = cmd
+	for i, alias := range cmd.Aliases {
+		alias = normalizeCommandName(alias)
+		cmd.Aliases[i] = alias
+		r.aliases[alias] = name
+	}
+	return nil
+}
+
+// Unregister removes a command and its aliases
+func (r *TwitchCommandRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.removeLocked(normalizeCommandName(name))
+}
+
+// UnregisterCustom removes every command that is not built in
+func (r *TwitchCommandRegistry) UnregisterCustom() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, cmd := range r.commands {
+		if !cmd.Builtin {
+			r.removeLocked(name)
+		}
+	}
+}
+
+func (r *TwitchCommandRegistry) removeLocked(name string) {
+	if cmd, ok := r.commands[name]; ok {
+		for _, alias := range cmd.Aliases {
+			delete(r.aliases, alias)
+		}
+		delete(r.commands, name)
+	}
+}
+
+// Lookup finds a command by name or alias
+func (r *TwitchCommandRegistry) Lookup(name string) (*TwitchCommand, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lookupLocked(normalizeCommandName(name))
+}
+
+func (r *TwitchCommandRegistry) lookupLocked(name string) (*TwitchCommand, bool) {
+	if target, ok := r.aliases[name]; ok {
+		name = target
+	}
+	cmd, ok := r.commands[name]
+	return cmd, ok
+}
+
+// IsBuiltin reports whether name or alias belongs to a built-in command
+func (r *TwitchCommandRegistry) IsBuiltin(name string) bool {
+	cmd, ok := r.Lookup(name)
+	return ok && cmd.Builtin
+}
+
+// List describes all registered commands, sorted by name
+func (r *TwitchCommandRegistry) List() []models.TwitchBotCommandInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]models.TwitchBotCommandInfo, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		list = append(list, models.TwitchBotCommandInfo{
+			Name:            cmd.Name,
+			Aliases:         cmd.Aliases,
+			Description:     cmd.Description,
+			Permission:      cmd.Permission.String(),
+			CooldownSeconds: int(cmd.Cooldown / time.Second),
+			Builtin:         cmd.Builtin,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Dispatch runs the command in msg, if any. It returns the reply and whether a command was run.
+func (r *TwitchCommandRegistry) Dispatch(ctx context.Context, channel string, msg twitch.PrivateMessage) (string, bool) {
+	text := strings.TrimSpace(msg.Message)
+	if !strings.HasPrefix(text, "!") {
+		return "", false
+	}
+
+	fields := strings.Fields(text[1:])
+	if len(fields) == 0 {
+		return "", false
+	}
+	typed := strings.ToLower(fields[0])
+
+	tm := toTwitchMessage(msg)
+	cc := &TwitchCommandContext{
+		Ctx:        ctx,
+		Channel:    channel,
+		Command:    typed,
+		Args:       fields[1:],
+		RawArgs:    strings.TrimSpace(strings.TrimPrefix(text[1:], fields[0])),
+		Message:    tm,
+		MessageID:  msg.ID,
+		Permission: TwitchUserPermission(tm),
+	}
+
+	r.mu.Lock()
+	cmd, ok := r.lookupLocked(typed)
+	if !ok {
+		r.mu.Unlock()
+		return "", false
+	}
+	if cc.Permission < cmd.Permission {
+		r.mu.Unlock()
+		log.Printf("🚫 %s tried !%s without %s permission", cc.User(), cmd.Name, cmd.Permission)
+		return "", false
+	}
+
+	// Moderators and the broadcaster skip cooldowns
+	now := time.Now()
+	userKey := cmd.Name + "|" + strings.ToLower(tm.Username)
+	if cc.Permission < PermissionModerator {
+		if at, ok := r.lastUsed[cmd.Name]; ok && now.Sub(at) < cmd.Cooldown {
+			r.mu.Unlock()
+			return "", false
+		}
+		if at, ok := r.userUsage[userKey]; ok && now.Sub(at) < cmd.UserCooldown {
+			r.mu.Unlock()
+			return "", false
+		}
+	}
+	r.lastUsed[cmd.Name] = now
+	r.userUsage[userKey] = now
+	r.pruneLocked(now)
+	r.mu.Unlock()
+
+	reply, err := cmd.Handler(cc)
+	if err != nil {
+		log.Printf("❌ !%s failed for %s: %v", cmd.Name, cc.User(), err)
+		return fmt.Sprintf("Sorry @%s, !%s isn't working right now.", cc.User(), cmd.Name), true
+	}
+	return reply, true
+}
+
+// pruneLocked drops per-user cooldown entries older than an hour. Caller must hold r.mu.
+func (r *TwitchCommandRegistry) pruneLocked(now time.Time) {
+	if len(r.userUsage) < 1000 {
+		return
+	}
+	for k, at := range r.userUsage {
+		if now.Sub(at) > time.Hour {
+			delete(r.userUsage, k)
+		}
+	}
+}
+
+func normalizeCommandName(name string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "!"))
+}
+
+// renderCustomCommand fills {user}, {args} and {channel} in a custom command response
+func renderCustomCommand(response string, cc *TwitchCommandContext) string {
+	return strings.NewReplacer(
+		"{user}", cc.User(),
+		"{args}", cc.RawArgs,
+		"{channel}", cc.Channel,
+	).Replace(response)
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+)
+
+func chatMessage(login, text string, badges map[string]int) twitch.PrivateMessage {
+	return twitch.PrivateMessage{
+		User:    twitch.User{Name: login, DisplayName: login, Badges: badges},
+		Message: text,
+		ID:      login + "-" + text,
+	}
+}
+
+func TestTwitchCommandRegistryRegister(t *testing.T) {
+	r := NewTwitchCommandRegistry()
+	echo := func(*TwitchCommandContext) (string, error) { return "ok", nil }
+	if err := r.Register(&TwitchCommand{Name: "discord", Aliases: []string{"dc"}, Builtin: true, Handler: echo}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(&TwitchCommand{Name: "socials", Handler: echo}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cmd     TwitchCommand
+		wantErr string
+	}{
+		{"new command", TwitchCommand{Name: "!Lurk", Aliases: []string{"afk"}}, ""},
+		{"replaces a custom command", TwitchCommand{Name: "socials", Aliases: []string{"links"}}, ""},
+		{"shadows a built-in", TwitchCommand{Name: "discord"}, "built-in"},
+		{"named like an alias", TwitchCommand{Name: "dc"}, "alias of !discord"},
+		{"alias taken by another command", TwitchCommand{Name: "server", Aliases: []string{"dc"}}, "belongs to !discord"},
+		{"alias named like a command", TwitchCommand{Name: "links2", Aliases: []string{"socials"}}, "clashes"},
+		{"no name", TwitchCommand{Name: " ! "}, "needs a name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := tt.cmd
+			cmd.Handler = echo
+			err := r.Register(&cmd)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if cmd, ok := r.Lookup("!DC"); !ok || cmd.Name != "discord" {
+		t.Errorf("!dc resolves to %+v", cmd)
+	}
+	if cmd, ok := r.Lookup("afk"); !ok || cmd.Name != "lurk" {
+		t.Errorf("!afk resolves to %+v", cmd)
+	}
+}
+
+func TestTwitchCommandRegistryDispatch(t *testing.T) {
+	r := NewTwitchCommandRegistry()
+	runs := map[string]int{}
+	counter := func(cc *TwitchCommandContext) (string, error) {
+		runs[cc.Message.Username]++
+		return "hi " + cc.User() + " " + cc.RawArgs, nil
+	}
+	r.Register(&TwitchCommand{Name: "hello", Aliases: []string{"hi"}, Cooldown: time.Hour, Handler: counter})
+	r.Register(&TwitchCommand{Name: "wave", UserCooldown: time.Hour, Handler: counter})
+	r.Register(&TwitchCommand{Name: "title", Permission: PermissionModerator, Handler: counter})
+	r.Register(&TwitchCommand{Name: "raid", Permission: PermissionBroadcaster, Handler: counter})
+
+	viewer := map[string]int{}
+	mod := map[string]int{"moderator": 1}
+	broadcaster := map[string]int{"broadcaster": 1}
+
+	steps := []struct {
+		name  string
+		msg   twitch.PrivateMessage
+		ran   bool
+		reply string
+	}{
+		{"not a command", chatMessage("alice", "hello there", viewer), false, ""},
+		{"unknown command", chatMessage("alice", "!nope", viewer), false, ""},
+		{"alias with args", chatMessage("alice", "!HI  there friend", viewer), true, "hi alice there friend"},
+		{"channel cooldown", chatMessage("bob", "!hello", viewer), false, ""},
+		{"mods skip cooldowns", chatMessage("mia", "!hello", mod), true, "hi mia "},
+		{"first wave", chatMessage("alice", "!wave", viewer), true, "hi alice "},
+		{"per-user cooldown", chatMessage("alice", "!wave", viewer), false, ""},
+		{"another user's wave", chatMessage("bob", "!wave", viewer), true, "hi bob "},
+		{"moderator only", chatMessage("bob", "!title", viewer), false, ""},
+		{"moderator", chatMessage("mia", "!title", mod), true, "hi mia "},
+		{"broadcaster only", chatMessage("mia", "!raid", mod), false, ""},
+		{"broadcaster", chatMessage("majestic", "!raid", broadcaster), true, "hi majestic "},
+	}
+	for _, s := range steps {
+		reply, ran := r.Dispatch(context.Background(), "majestic", s.msg)
+		if ran != s.ran || reply != s.reply {
+			t.Errorf("%s: got %v %q, want %v %q", s.name, ran, reply, s.ran, s.reply)
+		}
+	}
+	if runs["alice"] != 2 || runs["bob"] != 1 || runs["mia"] != 2 || runs["majestic"] != 1 {
+		t.Errorf("handler runs = %v", runs)
+	}
+}
+
+func TestTwitchBotRunsBroadcasterCommands(t *testing.T) {
+	bot := &TwitchBot{channel: "majestic", login: "majestic", registry: NewTwitchCommandRegistry(), sent: map[string]time.Time{}}
+	runs := 0
+	bot.registry.Register(&TwitchCommand{Name: "echo", Permission: PermissionBroadcaster, Handler: func(cc *TwitchCommandContext) (string, error) {
+		runs++
+		return "!echo " + cc.RawArgs, nil
+	}})
+
+	// The bot logs in as the broadcaster; their commands run, its own replies don't
+	bot.HandleMessage(chatMessage("majestic", "!echo once", map[string]int{"broadcaster": 1}))
+	bot.remember("!echo once")
+	bot.HandleMessage(chatMessage("majestic", "!echo once", map[string]int{"broadcaster": 1}))
+	bot.HandleMessage(chatMessage("majestic", "!echo twice", map[string]int{"broadcaster": 1}))
+	if runs != 2 {
+		t.Errorf("broadcaster command ran %d times, want 2", runs)
+	}
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	eventSubSignaturePrefix = "sha256="
+	eventSubMaxMessageAge   = 10 * time.Minute
+)
+
+// Webhook request headers set by Twitch
+const (
+	eventSubHeaderID        = "Twitch-Eventsub-Message-Id"
+	eventSubHeaderTimestamp = "Twitch-Eventsub-Message-Timestamp"
+	eventSubHeaderSignature = "Twitch-Eventsub-Message-Signature"
+	eventSubHeaderType      = "Twitch-Eventsub-Message-Type"
+)
+
+// VerifyEventSubSignature checks the HMAC-SHA256 Twitch computes over id + timestamp + body
+func VerifyEventSubSignature(secret, messageID, timestamp string, body []byte, signature string) bool {
+	if secret == "" || !strings.HasPrefix(signature, eventSubSignaturePrefix) {
+		return false
+	}
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, eventSubSignaturePrefix))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(messageID))
+	mac.Write([]byte(timestamp))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// HandleEventSubWebhook processes a webhook callback and returns the HTTP status
+// and plain-text body to reply with. Notifications go through the same handlers
+// as the WebSocket transport.
+func HandleEventSubWebhook(header http.Header, body []byte) (int, string) {
+	enabled := false
+	for _, c := range eventSubClients {
+		enabled = enabled || c.cfg.Transport == EventSubTransportWebhook
+	}
+	if !enabled {
+		return http.StatusNotFound, "webhook transport not enabled"
+	}
+
+	c := webhookClientFor(body)
+	if c == nil {
+		// Guessing would credit another channel with the event. Twitch retries, so a
+		// notification that arrives before its profile's broadcaster id is known isn't lost.
+		log.Printf("⚠️ EventSub webhook %s matches no profile's broadcaster", header.Get(eventSubHeaderID))
+		return http.StatusNotFound, "no profile for this subscription"
+	}
+	return c.handleWebhook(header, body, time.Now())
+}
+
+// webhookClientFor picks the profile's client whose broadcaster the subscription condition
+// names, or nil if none does
+func webhookClientFor(body []byte) *EventSubClient {
+	var payload struct {
+		Subscription struct {
+			Condition map[string]interface{} `json:"condition"`
+		} `json:"subscription"`
+	}
+	json.Unmarshal(body, &payload)
+
+	for _, c := range eventSubClients {
+		if c.cfg.Transport == EventSubTransportWebhook && c.conditionMatches(payload.Subscription.Condition) {
+			return c
+		}
+	}
+	return nil
+}
+
+// conditionMatches reports whether a subscription condition is for this client's broadcaster
+func (c *EventSubClient) conditionMatches(condition map[string]interface{}) bool {
+	c.mu.RLock()
+	id := c.broadcasterID
+	c.mu.RUnlock()
+	if id == "" {
+		return false
+	}
+	for key, value := range condition {
+		if strings.HasSuffix(key, "broadcaster_user_id") && value == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *EventSubClient) handleWebhook(header http.Header, body []byte, now time.Time) (int, string) {
+	messageID := header.Get(eventSubHeaderID)
+	timestamp := header.Get(eventSubHeaderTimestamp)
+
+	if !VerifyEventSubSignature(c.cfg.Secret, messageID, timestamp, body, header.Get(eventSubHeaderSignature)) {
+		log.Printf("🚫 Rejected EventSub webhook %s: bad signature", messageID)
+		return http.StatusForbidden, "invalid signature"
+	}
+
+	sentAt, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return http.StatusBadRequest, "invalid timestamp"
+	}
+	if age := now.Sub(sentAt); age > eventSubMaxMessageAge || age < -eventSubMaxMessageAge {
+		log.Printf("🚫 Rejected EventSub webhook %s: timestamp %s outside replay window", messageID, timestamp)
+		return http.StatusForbidden, "message too old"
+	}
+
+	var payload struct {
+		Challenge    string               `json:"challenge"`
+		Subscription *SubscriptionPayload `json:"subscription"`
+		Event        json.RawMessage      `json:"event"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return http.StatusBadRequest, "invalid body"
+	}
+
+	// Acknowledge redeliveries so Twitch stops retrying, but don't handle them twice
+	if c.seen.isDuplicate(messageID) {
+		return http.StatusNoContent, ""
+	}
+
+	switch header.Get(eventSubHeaderType) {
+	case "webhook_callback_verification":
+		if payload.Subscription != nil {
+			log.Printf("🤝 Verified EventSub webhook for %s", payload.Subscription.Type)
+		}
+		return http.StatusOK, payload.Challenge
+
+	case "notification":
+		if payload.Subscription == nil {
+			return http.StatusBadRequest, "missing subscription"
+		}
+		var message EventSubMessage
+		message.Metadata.MessageID = messageID
+		message.Metadata.MessageType = "notification"
+		message.Metadata.MessageTimestamp = sentAt
+		message.Metadata.SubscriptionType = payload.Subscription.Type
+		message.Metadata.SubscriptionVersion = payload.Subscription.Version
+		message.Payload.Subscription = payload.Subscription
+		message.Payload.Event = payload.Event
+
+		log.Printf("🔔 Webhook notification received: %s", payload.Subscription.Type)
+		c.handleNotification(message)
+		return http.StatusNoContent, ""
+
+	case "revocation":
+		if payload.Subscription != nil {
+			log.Printf("⚠️ Subscription revoked: %s (%s)", payload.Subscription.Type, payload.Subscription.Status)
+			go c.resubscribe(*payload.Subscription)
+		}
+		return http.StatusNoContent, ""
+
+	default:
+		return http.StatusBadRequest, "unknown message type"
+	}
+}
+
+// runWebhook makes sure every subscription exists for the callback URL, retrying with backoff,
+// and checks again every ResyncInterval. Twitch can't deliver a revocation to a callback that's
+// failing, so subscriptions it disables that way are only recreated by the re-check.
+func (c *EventSubClient) runWebhook() {
+	if err := c.validateWebhookConfig(); err != nil {
+		log.Printf("❌ EventSub webhook transport not started: %v", err)
+		return
+	}
+
+	backoff := c.cfg.MinBackoff
+	synced := false
+	for !c.stopped() {
+		wait := c.cfg.ResyncInterval
+		if err := c.syncWebhookSubscriptions(); err != nil {
+			log.Printf("❌ EventSub webhook subscription sync failed: %v (retrying in %s)", err, backoff)
+			wait = backoff
+			backoff *= 2
+			if backoff > c.cfg.MaxBackoff {
+				backoff = c.cfg.MaxBackoff
+			}
+		} else {
+			if !synced {
+				log.Printf("✅ EventSub webhooks in place for %s; re-checking every %s", c.cfg.BroadcasterLogin, wait)
+				synced = true
+			}
+			backoff = c.cfg.MinBackoff
+		}
+
+		if !c.sleep(wait) {
+			return
+		}
+	}
+}
+
+func (c *EventSubClient) validateWebhookConfig() error {
+	if len(c.cfg.Secret) < 10 || len(c.cfg.Secret) > 100 {
+		return fmt.Errorf("TWITCH_EVENTSUB_SECRET must be 10-100 characters")
+	}
+	u, err := url.Parse(c.cfg.CallbackURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("TWITCH_EVENTSUB_CALLBACK_URL must be an absolute URL")
+	}
+	return nil
+}
+
+// syncWebhookSubscriptions creates any subscription that isn't already registered for our callback
+func (c *EventSubClient) syncWebhookSubscriptions() error {
+	token, err := c.cfg.AppToken()
+	if err != nil {
+		return fmt.Errorf("app token: %w", err)
+	}
+
+	broadcasterID, err := c.lookupBroadcasterID(token)
+	if err != nil {
+		return fmt.Errorf("broadcaster lookup: %w", err)
+	}
+
+	existing, err := c.listWebhookSubscriptions(token)
+	if err != nil {
+		return err
+	}
+
+	created, failed := 0, 0
+	for _, sub := range EventSubSubscriptions {
+		if existing[sub.Type+"|"+sub.Version] {
+			continue
+		}
+		if err := c.createSubscription(sub.Type, sub.Version, sub.Condition(broadcasterID), c.transport(), token); err != nil {
+			log.Printf("❌ Failed to create %s webhook subscription: %v", sub.Type, err)
+			failed++
+			continue
+		}
+		created++
+	}
+
+	if created > 0 || failed > 0 {
+		log.Printf("✅ EventSub webhooks: %d existing, %d created, %d failed", len(existing), created, failed)
+	}
+	return nil
+}
+
+// listWebhookSubscriptions returns "type|version" for our broadcaster's live subscriptions pointing at our callback
+func (c *EventSubClient) listWebhookSubscriptions(token string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	cursor := ""
+
+	for {
+		endpoint := c.cfg.APIBaseURL + "/eventsub/subscriptions"
+		if cursor != "" {
+			endpoint += "?after=" + url.QueryEscape(cursor)
+		}
+
+		req, _ := http.NewRequest("GET", endpoint, nil)
+		req.Header.Set("Client-ID", c.cfg.ClientID)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Data []struct {
+				Type      string                 `json:"type"`
+				Version   string                 `json:"version"`
+				Status    string                 `json:"status"`
+				Condition map[string]interface{} `json:"condition"`
+				Transport struct {
+					Method   string `json:"method"`
+					Callback string `json:"callback"`
+				} `json:"transport"`
+			} `json:"data"`
+			Pagination struct {
+				Cursor string `json:"cursor"`
+			} `json:"pagination"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("list subscriptions returned status %d", resp.StatusCode)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode subscriptions: %w", err)
+		}
+
+		for _, s := range result.Data {
+			live := s.Status == "enabled" || s.Status == "webhook_callback_verification_pending"
+			// Other profiles' subscriptions share the callback, so match the broadcaster too
+			if live && s.Transport.Method == "webhook" && s.Transport.Callback == c.cfg.CallbackURL && c.conditionMatches(s.Condition) {
+				existing[s.Type+"|"+s.Version] = true
+			}
+		}
+
+		if result.Pagination.Cursor == "" {
+			return existing, nil
+		}
+		cursor = result.Pagination.Cursor
+	}
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+const testWebhookSecret = "0123456789abcdef"
+
+func signEventSub(secret, messageID, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(messageID))
+	mac.Write([]byte(timestamp))
+	mac.Write(body)
+	return eventSubSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyEventSubSignature(t *testing.T) {
+	body := []byte(`{"event":{"user_name":"alice"}}`)
+	const id, ts = "msg-1", "2026-01-02T03:04:05.123456789Z"
+	good := signEventSub(testWebhookSecret, id, ts, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		id, ts    string
+		body      string
+		signature string
+		want      bool
+	}{
+		{"valid", testWebhookSecret, id, ts, string(body), good, true},
+		{"tampered body", testWebhookSecret, id, ts, `{"event":{"user_name":"mallory"}}`, good, false},
+		{"tampered id", testWebhookSecret, "msg-2", ts, string(body), good, false},
+		{"tampered timestamp", testWebhookSecret, id, "2026-01-02T03:04:06Z", string(body), good, false},
+		{"wrong secret", "fedcba9876543210", id, ts, string(body), good, false},
+		{"no secret configured", "", id, ts, string(body), signEventSub("", id, ts, body), false},
+		{"missing prefix", testWebhookSecret, id, ts, string(body), good[len(eventSubSignaturePrefix):], false},
+		{"not hex", testWebhookSecret, id, ts, string(body), eventSubSignaturePrefix + "zz", false},
+		{"empty", testWebhookSecret, id, ts, string(body), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyEventSubSignature(tt.secret, tt.id, tt.ts, []byte(tt.body), tt.signature); got != tt.want {
+				t.Errorf("VerifyEventSubSignature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleWebhook(t *testing.T) {
+	var mu sync.Mutex
+	var received []models.TwitchEvent
+	twitchEventListeners = []func(models.TwitchEvent){func(e models.TwitchEvent) {
+		mu.Lock()
+		received = append(received, e)
+		mu.Unlock()
+	}}
+	t.Cleanup(func() { twitchEventListeners = nil })
+
+	client := NewEventSubClient(EventSubConfig{
+		Transport:   EventSubTransportWebhook,
+		CallbackURL: "https://example.com/api/twitch/eventsub",
+		Secret:      testWebhookSecret,
+	})
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	challenge := `{"challenge":"pogchamp-kappa-360","subscription":{"type":"channel.follow","version":"2"}}`
+	follow := `{"subscription":{"type":"channel.follow","version":"2"},"event":{"user_name":"alice"}}`
+
+	tests := []struct {
+		name        string
+		id          string
+		messageType string
+		sentAt      time.Time
+		body        string
+		tamper      bool
+		status      int
+		reply       string
+	}{
+		{"challenge", "verify-1", "webhook_callback_verification", now, challenge, false, http.StatusOK, "pogchamp-kappa-360"},
+		{"challenge with a bad signature", "verify-2", "webhook_callback_verification", now, challenge, true, http.StatusForbidden, "invalid signature"},
+		{"notification", "note-1", "notification", now.Add(-time.Minute), follow, false, http.StatusNoContent, ""},
+		{"redelivered notification", "note-1", "notification", now.Add(-time.Minute), follow, false, http.StatusNoContent, ""},
+		{"tampered notification", "note-2", "notification", now, follow, true, http.StatusForbidden, "invalid signature"},
+		{"stale timestamp", "note-3", "notification", now.Add(-11 * time.Minute), follow, false, http.StatusForbidden, "message too old"},
+		{"future timestamp", "note-4", "notification", now.Add(11 * time.Minute), follow, false, http.StatusForbidden, "message too old"},
+		{"inside the window", "note-5", "notification", now.Add(-9 * time.Minute), follow, false, http.StatusNoContent, ""},
+		{"unknown type", "other-1", "something_else", now, follow, false, http.StatusBadRequest, "unknown message type"},
+	}
+	// In order: the redelivery is only a duplicate after the first delivery
+	for _, tt := range tests {
+		timestamp := tt.sentAt.Format(time.RFC3339Nano)
+		signature := signEventSub(testWebhookSecret, tt.id, timestamp, []byte(tt.body))
+		body := tt.body
+		if tt.tamper {
+			body = tt.body + " "
+		}
+
+		header := http.Header{}
+		header.Set(eventSubHeaderID, tt.id)
+		header.Set(eventSubHeaderTimestamp, timestamp)
+		header.Set(eventSubHeaderSignature, signature)
+		header.Set(eventSubHeaderType, tt.messageType)
+
+		status, reply := client.handleWebhook(header, []byte(body), now)
+		if status != tt.status || reply != tt.reply {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, status, reply, tt.status, tt.reply)
+		}
+	}
+
+	// note-1 and note-5; the redelivery and the rejected messages aren't handled
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[0].UserName != "alice" {
+		t.Errorf("unexpected events delivered: %+v", received)
+	}
+}
+
+func TestWebhookClientFor(t *testing.T) {
+	newClient := func(broadcasterID string) *EventSubClient {
+		c := NewEventSubClient(EventSubConfig{Transport: EventSubTransportWebhook, Secret: testWebhookSecret})
+		c.broadcasterID = broadcasterID
+		return c
+	}
+	first, second, pending := newClient("111"), newClient("222"), newClient("")
+	original := eventSubClients
+	eventSubClients = []*EventSubClient{first, pending, second}
+	t.Cleanup(func() { eventSubClients = original })
+
+	tests := []struct {
+		name string
+		body string
+		want *EventSubClient
+	}{
+		{"first profile", `{"subscription":{"condition":{"broadcaster_user_id":"111"}}}`, first},
+		{"second profile", `{"subscription":{"condition":{"to_broadcaster_user_id":"222"}}}`, second},
+		{"unknown broadcaster", `{"subscription":{"condition":{"broadcaster_user_id":"333"}}}`, nil},
+		{"no condition", `{"subscription":{}}`, nil},
+	}
+	for _, tt := range tests {
+		if got := webhookClientFor([]byte(tt.body)); got != tt.want {
+			t.Errorf("%s: got client %p, want %p", tt.name, got, tt.want)
+		}
+	}
+
+	if status, _ := HandleEventSubWebhook(http.Header{}, []byte(tests[2].body)); status != http.StatusNotFound {
+		t.Errorf("unmatched webhook status = %d, want 404", status)
+	}
+}
+
+// fakeWebhookSubscriptions lists and creates webhook subscriptions like Helix does
+type fakeWebhookSubscriptions struct {
+	mu      sync.Mutex
+	live    map[string]json.RawMessage // condition by type
+	created chan string
+}
+
+func (f *fakeWebhookSubscriptions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Method == http.MethodPost {
+		var body struct {
+			Type      string          `json:"type"`
+			Condition json.RawMessage `json:"condition"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.live[body.Type] = body.Condition
+		f.created <- body.Type
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var data []map[string]interface{}
+	for subType, condition := range f.live {
+		data = append(data, map[string]interface{}{
+			"type": subType, "version": versionOf(subType), "status": "enabled", "condition": condition,
+			"transport": map[string]string{"method": "webhook", "callback": "https://example.com/webhook"},
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func versionOf(subType string) string {
+	for _, sub := range EventSubSubscriptions {
+		if sub.Type == subType {
+			return sub.Version
+		}
+	}
+	return ""
+}
+
+func TestRunWebhookResyncs(t *testing.T) {
+	subs := &fakeWebhookSubscriptions{live: map[string]json.RawMessage{}, created: make(chan string, 64)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/helix/users", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":[{"id":"1234"}]}`)
+	})
+	mux.Handle("/helix/eventsub/subscriptions", subs)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := NewEventSubClient(EventSubConfig{
+		Transport:        EventSubTransportWebhook,
+		APIBaseURL:       server.URL + "/helix",
+		ClientID:         "client",
+		BroadcasterLogin: "majestic",
+		AppToken:         func() (string, error) { return "app-token", nil },
+		CallbackURL:      "https://example.com/webhook",
+		Secret:           testWebhookSecret,
+		MinBackoff:       10 * time.Millisecond,
+		MaxBackoff:       50 * time.Millisecond,
+		ResyncInterval:   20 * time.Millisecond,
+	})
+	go client.Run()
+	t.Cleanup(client.Stop)
+
+	drainSubs(t, subs.created, len(EventSubSubscriptions))
+
+	// Twitch disabled one without a revocation reaching us; the next re-check recreates it
+	subs.mu.Lock()
+	delete(subs.live, "stream.online")
+	subs.mu.Unlock()
+
+	if got := drainSubs(t, subs.created, 1); got[0] != "stream.online" {
+		t.Errorf("recreated %v, want stream.online", got)
+	}
+
+	// Subscriptions still in place aren't created again
+	time.Sleep(100 * time.Millisecond)
+	if n := len(subs.created); n != 0 {
+		t.Errorf("%d subscriptions created again while all were in place", n)
+	}
+}
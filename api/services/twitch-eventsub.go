@@ -2,12 +2,16 @@ package services
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
+	"net"
 	"net/http"
-	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,12 +20,90 @@ import (
 	"majesticcoding.com/db"
 )
 
+const (
+	helixDefaultURL        = "https://api.twitch.tv/helix"
+	eventSubWelcomeTimeout = 10 * time.Second
+	eventSubSeenTTL        = 10 * time.Minute
+)
+
+// EventSub transports
+const (
+	EventSubTransportWebSocket = "websocket"
+	EventSubTransportWebhook   = "webhook"
+)
+
+// EventSubConfig controls the EventSub client. URLs can be pointed at a local
+// server for testing.
+type EventSubConfig struct {
+	Transport         string // "websocket" (default) or "webhook"
+	WebSocketURL      string
+	APIBaseURL        string
+	ClientID          string
+	BroadcasterLogin  string
+	Token             func() (string, error) // user token, for the WebSocket transport
+	AppToken          func() (string, error) // app token, for the webhook transport
+	CallbackURL       string                 // public webhook URL
+	Secret            string                 // webhook HMAC secret, 10-100 characters
+	MinBackoff        time.Duration
+	MaxBackoff        time.Duration
+	KeepaliveGrace    time.Duration // extra time allowed past keepalive_timeout_seconds
+	TokenPollInterval time.Duration // how often to look for a token while none exists
+	ResyncInterval    time.Duration // how often the webhook transport re-checks its subscriptions
+}
+
+// EventSubSubscription is a subscription type the client creates on each new session
+type EventSubSubscription struct {
+	Type      string
+	Version   string
+	Condition func(broadcasterID string) map[string]interface{}
+}
+
+func broadcasterCondition(id string) map[string]interface{} {
+	return map[string]interface{}{"broadcaster_user_id": id}
+}
+
+// EventSubSubscriptions lists everything we subscribe to for the broadcaster
+var EventSubSubscriptions = []EventSubSubscription{
+	{"channel.follow", "2", func(id string) map[string]interface{} {
+		return map[string]interface{}{"broadcaster_user_id": id, "moderator_user_id": id}
+	}},
+	{"channel.raid", "1", func(id string) map[string]interface{} {
+		return map[string]interface{}{"to_broadcaster_user_id": id}
+	}},
+	{"channel.subscribe", "1", broadcasterCondition},
+	{"channel.subscription.gift", "1", broadcasterCondition},
+	{"channel.subscription.message", "1", broadcasterCondition},
+	{"channel.cheer", "1", broadcasterCondition},
+	{"channel.channel_points_custom_reward_redemption.add", "1", broadcasterCondition},
+	{"stream.online", "1", broadcasterCondition},
+	{"stream.offline", "1", broadcasterCondition},
+	{"channel.hype_train.begin", "1", broadcasterCondition},
+	{"channel.hype_train.progress", "1", broadcasterCondition},
+	{"channel.hype_train.end", "1", broadcasterCondition},
+	{"channel.poll.begin", "1", broadcasterCondition},
+	{"channel.poll.progress", "1", broadcasterCondition},
+	{"channel.poll.end", "1", broadcasterCondition},
+	{"channel.prediction.begin", "1", broadcasterCondition},
+	{"channel.prediction.progress", "1", broadcasterCondition},
+	{"channel.prediction.lock", "1", broadcasterCondition},
+	{"channel.prediction.end", "1", broadcasterCondition},
+}
+
+// EventSubClient keeps a supervised EventSub WebSocket session alive
 type EventSubClient struct {
+	cfg  EventSubConfig
+	http *http.Client
+
+	mu            sync.RWMutex
 	conn          *websocket.Conn
 	sessionID     string
 	isConnected   bool
-	mu            sync.RWMutex
-	reconnectChan chan bool
+	broadcasterID string
+
+	seen *eventSubDeduper
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 type EventSubMessage struct {
@@ -61,118 +143,462 @@ type SubscriptionPayload struct {
 	Cost      int       `json:"cost"`
 }
 
-var eventSubClient *EventSubClient
+var (
+	eventSubClients      []*EventSubClient // one per profile with a Twitch channel
+	twitchEventListeners []func(models.TwitchEvent)
+)
+
+// AddTwitchEventListener registers fn to receive every new EventSub notification.
+// Call it before StartTwitchEventSub.
+func AddTwitchEventListener(fn func(models.TwitchEvent)) {
+	twitchEventListeners = append(twitchEventListeners, fn)
+}
 
-// getTwitchUserToken - helper function to get current valid user token from database
+// getTwitchUserToken returns the default profile's user token, refreshing it if it's about to expire
 func getTwitchUserToken() (string, error) {
-	database := db.GetDB()
-	if database == nil {
-		return "", fmt.Errorf("database not available")
-	}
+	return twitchUserToken(DefaultProfile())
+}
 
-	accessToken, _, _, _, expiresAt, err := db.GetTwitchToken(database)
-	if err != nil {
-		return "", err
+// twitchUserToken returns the user token for a profile's channel. The token stored before
+// tokens were kept per account belongs to the default profile.
+func twitchUserToken(profile models.Profile) (string, error) {
+	token, err := Tokens().AccessToken(models.ProviderTwitch, profile.TwitchChannel)
+	if errors.Is(err, ErrNoToken) && profile.Default {
+		return Tokens().AccessToken(models.ProviderTwitch, "default")
 	}
+	return token, err
+}
 
-	// Check if token is expired
-	if time.Now().After(expiresAt) {
-		return "", fmt.Errorf("token expired at %v", expiresAt)
+// LoadEventSubConfig builds the production config for a profile's channel
+func LoadEventSubConfig(profile models.Profile) EventSubConfig {
+	twitchConf := conf().Twitch
+	return EventSubConfig{
+		Transport:         twitchConf.EventSubTransport,
+		WebSocketURL:      twitchConf.EventSubURL,
+		APIBaseURL:        helixDefaultURL,
+		ClientID:          twitchConf.ClientID,
+		BroadcasterLogin:  profile.TwitchChannel,
+		Token:             func() (string, error) { return twitchUserToken(profile) },
+		AppToken:          getTwitchToken,
+		CallbackURL:       twitchConf.EventSubCallbackURL,
+		Secret:            twitchConf.EventSubSecret,
+		MinBackoff:        time.Second,
+		MaxBackoff:        2 * time.Minute,
+		KeepaliveGrace:    5 * time.Second,
+		TokenPollInterval: time.Minute,
+		ResyncInterval:    10 * time.Minute,
 	}
+}
 
-	return accessToken, nil
+// NewEventSubClient creates a client; call Run to start it
+func NewEventSubClient(cfg EventSubConfig) *EventSubClient {
+	return &EventSubClient{
+		cfg:  cfg,
+		http: &http.Client{Timeout: 10 * time.Second},
+		seen: newEventSubDeduper(),
+		stop: make(chan struct{}),
+	}
 }
 
+// StartTwitchEventSub starts a supervised EventSub client for every profile with a Twitch
+// channel. Each waits for its broadcaster's user token if none has been stored yet.
 func StartTwitchEventSub() error {
-	eventSubClient = &EventSubClient{
-		reconnectChan: make(chan bool, 1),
+	for _, profile := range Profiles() {
+		if profile.TwitchChannel == "" {
+			continue
+		}
+		client := NewEventSubClient(LoadEventSubConfig(profile))
+		eventSubClients = append(eventSubClients, client)
+		go client.Run()
 	}
+	return nil
+}
 
-	go eventSubClient.connect()
-	go eventSubClient.handleReconnects()
+// IsConnected reports whether a session is currently open
+func (c *EventSubClient) IsConnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.isConnected
+}
 
-	return nil
+// Stop closes the session and ends Run
+func (c *EventSubClient) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+		c.mu.Lock()
+		if c.conn != nil {
+			c.conn.Close()
+		}
+		c.mu.Unlock()
+	})
 }
 
-func (c *EventSubClient) connect() error {
-	dialer := websocket.DefaultDialer
-	conn, _, err := dialer.Dial("wss://eventsub.wss.twitch.tv/ws", nil)
+func (c *EventSubClient) stopped() bool {
+	select {
+	case <-c.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep waits for d, returning false if the client was stopped meanwhile
+func (c *EventSubClient) sleep(d time.Duration) bool {
+	select {
+	case <-c.stop:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// Run supervises the session: it waits for a token, connects, and reconnects
+// with exponential backoff until Stop is called. With the webhook transport it
+// only keeps the subscriptions in place; events arrive on the callback route.
+func (c *EventSubClient) Run() {
+	if c.cfg.Transport == EventSubTransportWebhook {
+		c.runWebhook()
+		return
+	}
+
+	backoff := c.cfg.MinBackoff
+	var moved *eventSubSession
+	waitingForToken := false
+
+	for !c.stopped() {
+		// Subscriptions carry over to a reconnect URL, so only a fresh session subscribes
+		session, subscribe := moved, false
+		moved = nil
+		if session == nil {
+			if _, err := c.cfg.Token(); err != nil {
+				if !waitingForToken {
+					log.Printf("⏸️ EventSub waiting for a Twitch user token: %v", err)
+					log.Println("💡 Visit https://majesticcoding.com/api/twitch/oauth/start to authenticate")
+					waitingForToken = true
+				}
+				if !c.sleep(c.cfg.TokenPollInterval) {
+					return
+				}
+				continue
+			}
+			waitingForToken = false
+
+			var err error
+			if session, err = c.dialSession(c.cfg.WebSocketURL); err != nil {
+				if !c.backoff(&backoff, err) {
+					return
+				}
+				continue
+			}
+			subscribe = true
+		}
+
+		next, err := c.runSession(session, subscribe)
+		if c.stopped() {
+			if next != nil {
+				next.conn.Close()
+			}
+			return
+		}
+		backoff = c.cfg.MinBackoff
+		if next != nil {
+			moved = next
+			continue
+		}
+		if !c.backoff(&backoff, err) {
+			return
+		}
+	}
+}
+
+// backoff logs why the session ended and waits before the next attempt, doubling the wait
+// each time. It returns false if the client was stopped meanwhile.
+func (c *EventSubClient) backoff(backoff *time.Duration, err error) bool {
+	wait := *backoff + time.Duration(rand.Int63n(int64(*backoff)/2+1))
+	log.Printf("❌ EventSub session ended: %v (reconnecting in %s)", err, wait.Round(time.Millisecond))
+	if !c.sleep(wait) {
+		return false
+	}
+	*backoff *= 2
+	if *backoff > c.cfg.MaxBackoff {
+		*backoff = c.cfg.MaxBackoff
+	}
+	return true
+}
+
+// eventSubSession is a connection that has received its session_welcome
+type eventSubSession struct {
+	conn    *websocket.Conn
+	session *SessionPayload
+}
+
+// dialSession connects to url and waits for the session_welcome
+func (c *EventSubClient) dialSession(url string) (*eventSubSession, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
 	if err != nil {
-		log.Printf("❌ Failed to connect to Twitch EventSub: %v", err)
-		c.scheduleReconnect()
-		return err
+		return nil, fmt.Errorf("dial: %w", err)
 	}
+	log.Println("🔗 Connected to Twitch EventSub WebSocket")
+
+	conn.SetReadDeadline(time.Now().Add(eventSubWelcomeTimeout))
+	for {
+		var message EventSubMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			conn.Close()
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil, fmt.Errorf("no session_welcome within %s", eventSubWelcomeTimeout)
+			}
+			return nil, err
+		}
+		if message.Metadata.MessageType == "session_welcome" && message.Payload.Session != nil {
+			return &eventSubSession{conn: conn, session: message.Payload.Session}, nil
+		}
+	}
+}
+
+// runSession reads a welcomed session until it fails, times out or is moved. On
+// session_reconnect it keeps reading while it connects to the reconnect URL, and returns the
+// new session once that is welcomed, closing this one.
+func (c *EventSubClient) runSession(s *eventSubSession, subscribe bool) (*eventSubSession, error) {
+	conn, session := s.conn, s.session
 
 	c.mu.Lock()
 	c.conn = conn
+	c.sessionID = session.ID
 	c.isConnected = true
 	c.mu.Unlock()
-
-	log.Println("🔗 Connected to Twitch EventSub WebSocket")
+	log.Printf("📨 EventSub session %s (keepalive %ds)", session.ID, session.KeepaliveTimeoutSeconds)
 
 	defer func() {
 		c.mu.Lock()
 		c.isConnected = false
-		c.conn.Close()
+		c.conn = nil
 		c.mu.Unlock()
-		c.scheduleReconnect()
+		conn.Close()
 	}()
 
+	timeout := eventSubWelcomeTimeout
+	if session.KeepaliveTimeoutSeconds > 0 {
+		timeout = time.Duration(session.KeepaliveTimeoutSeconds)*time.Second + c.cfg.KeepaliveGrace
+	}
+	if subscribe {
+		go c.createSubscriptions(session.ID)
+	}
+
+	type handoff struct {
+		next *eventSubSession
+		err  error
+	}
+	var moving chan handoff // set while connecting to a reconnect URL
+
 	for {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+
 		var message EventSubMessage
-		err := conn.ReadJSON(&message)
-		if err != nil {
-			log.Printf("❌ Error reading EventSub message: %v", err)
-			break
+		if err := conn.ReadJSON(&message); err != nil {
+			if moving != nil {
+				// Twitch closes this connection once the new one is welcomed
+				h := <-moving
+				if h.err != nil {
+					return nil, fmt.Errorf("reconnect: %w", h.err)
+				}
+				return h.next, nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil, fmt.Errorf("no message within %s (keepalive timeout)", timeout)
+			}
+			return nil, err
+		}
+
+		if c.seen.isDuplicate(message.Metadata.MessageID) {
+			continue
 		}
 
-		c.handleMessage(message)
+		switch message.Metadata.MessageType {
+		case "session_keepalive":
+			// Any message resets the read deadline
+
+		case "notification":
+			log.Printf("🔔 Notification received: %s", message.Metadata.SubscriptionType)
+			c.handleNotification(message)
+
+		case "session_reconnect":
+			if message.Payload.Session == nil || message.Payload.Session.ReconnectURL == "" || moving != nil {
+				continue
+			}
+			url := message.Payload.Session.ReconnectURL
+			log.Printf("🔄 EventSub moving session to %s", url)
+			moving = make(chan handoff, 1)
+			go func() {
+				next, err := c.dialSession(url)
+				moving <- handoff{next, err}
+				if err == nil {
+					// Unblocks the read above; the old session has nothing more to send
+					conn.Close()
+				}
+			}()
+
+		case "revocation":
+			if sub := message.Payload.Subscription; sub != nil {
+				log.Printf("⚠️ Subscription revoked: %s (%s)", sub.Type, sub.Status)
+				go c.resubscribe(*sub)
+			}
+
+		default:
+			log.Printf("❓ Unknown message type: %s", message.Metadata.MessageType)
+		}
 	}
+}
 
-	return nil
+// eventSubDeduper remembers recent message ids; Twitch may deliver a message more than once
+type eventSubDeduper struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
 }
 
-func (c *EventSubClient) handleMessage(message EventSubMessage) {
-	switch message.Metadata.MessageType {
-	case "session_welcome":
-		log.Println("📨 Received session welcome")
-		if message.Payload.Session != nil {
-			c.mu.Lock()
-			c.sessionID = message.Payload.Session.ID
-			c.mu.Unlock()
-			log.Printf("🆔 Session ID: %s", c.sessionID)
+func newEventSubDeduper() *eventSubDeduper {
+	return &eventSubDeduper{seen: make(map[string]time.Time)}
+}
 
-			go c.createSubscriptions()
-		}
+// isDuplicate records a message id and reports whether it was seen recently
+func (d *eventSubDeduper) isDuplicate(messageID string) bool {
+	if messageID == "" {
+		return false
+	}
 
-	case "session_keepalive":
-		log.Println("💓 Keepalive received")
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	case "notification":
-		log.Printf("🔔 Notification received: %s", message.Metadata.SubscriptionType)
-		c.handleNotification(message)
+	now := time.Now()
+	if at, ok := d.seen[messageID]; ok && now.Sub(at) < eventSubSeenTTL {
+		return true
+	}
+	if len(d.seen) > 1000 {
+		for id, at := range d.seen {
+			if now.Sub(at) >= eventSubSeenTTL {
+				delete(d.seen, id)
+			}
+		}
+	}
+	d.seen[messageID] = now
+	return false
+}
+
+// resubscribe recreates a revoked subscription on the current session
+func (c *EventSubClient) resubscribe(sub SubscriptionPayload) {
+	if sub.Status == "version_removed" || sub.Status == "user_removed" {
+		log.Printf("❌ %s v%s can't be recreated (%s); not resubscribing", sub.Type, sub.Version, sub.Status)
+		return
+	}
 
-	case "session_reconnect":
-		log.Println("🔄 Session reconnect requested")
-		if message.Payload.Session != nil && message.Payload.Session.ReconnectURL != "" {
-			go c.reconnectTo(message.Payload.Session.ReconnectURL)
+	delay := c.cfg.MinBackoff
+	for attempt := 1; attempt <= 5; attempt++ {
+		if !c.sleep(delay) {
+			return
 		}
 
-	case "revocation":
-		log.Printf("❌ Subscription revoked: %s", message.Metadata.SubscriptionType)
+		token, err := c.subscriptionToken()
+		if err == nil {
+			err = c.createSubscription(sub.Type, sub.Version, sub.Condition, c.transport(), token)
+		}
+		if err == nil {
+			log.Printf("✅ Resubscribed to %s", sub.Type)
+			return
+		}
 
-	default:
-		log.Printf("❓ Unknown message type: %s", message.Metadata.MessageType)
+		log.Printf("⚠️ Resubscribe to %s failed (attempt %d): %v", sub.Type, attempt, err)
+		delay *= 2
+		if delay > c.cfg.MaxBackoff {
+			delay = c.cfg.MaxBackoff
+		}
 	}
 }
 
 func (c *EventSubClient) handleNotification(message EventSubMessage) {
-	database := db.GetDB()
-	if database == nil {
+	var common struct {
+		BroadcasterUserID string `json:"broadcaster_user_id"`
+		UserID            string `json:"user_id"`
+		UserLogin         string `json:"user_login"`
+		UserName          string `json:"user_name"`
+	}
+	json.Unmarshal(message.Payload.Event, &common)
+
+	event := models.TwitchEvent{
+		MessageID:         message.Metadata.MessageID,
+		Type:              message.Metadata.SubscriptionType,
+		Version:           message.Metadata.SubscriptionVersion,
+		Channel:           c.cfg.BroadcasterLogin,
+		BroadcasterUserID: common.BroadcasterUserID,
+		UserID:            common.UserID,
+		UserLogin:         common.UserLogin,
+		UserName:          common.UserName,
+		Event:             message.Payload.Event,
+		OccurredAt:        message.Metadata.MessageTimestamp,
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	if database := db.GetDB(); database != nil {
+		inserted, err := db.InsertTwitchEvent(database, event)
+		if err != nil {
+			log.Printf("❌ Failed to save %s event: %v", event.Type, err)
+		} else if !inserted {
+			log.Printf("🔁 Skipping redelivered %s event %s", event.Type, event.MessageID)
+			return
+		}
+		saveTwitchActivity(database, c.cfg.BroadcasterLogin, message)
+	} else {
 		log.Println("❌ Database not available for EventSub notification")
-		return
 	}
 
+	logTwitchEvent(event)
+	PublishEvent(TopicTwitchEvent, event)
+	for _, fn := range twitchEventListeners {
+		fn(event)
+	}
+}
+
+// logTwitchEvent prints a one-line summary of the event types without their own table
+func logTwitchEvent(event models.TwitchEvent) {
+	var e struct {
+		UserName        string `json:"user_name"`
+		Total           int    `json:"total"`
+		Tier            string `json:"tier"`
+		CumulativeMonth int    `json:"cumulative_months"`
+		Level           int    `json:"level"`
+		Title           string `json:"title"`
+		Type            string `json:"type"`
+		Reward          struct {
+			Title string `json:"title"`
+		} `json:"reward"`
+	}
+	json.Unmarshal(event.Event, &e)
+
+	switch {
+	case event.Type == "channel.subscription.gift":
+		log.Printf("🎁 %s gifted %d tier %s subs", e.UserName, e.Total, e.Tier)
+	case event.Type == "channel.subscription.message":
+		log.Printf("🔁 %s resubscribed (%d months)", e.UserName, e.CumulativeMonth)
+	case event.Type == "channel.channel_points_custom_reward_redemption.add":
+		log.Printf("🎟️ %s redeemed %s", e.UserName, e.Reward.Title)
+	case event.Type == "stream.online":
+		log.Printf("🟢 Stream online (%s)", e.Type)
+	case event.Type == "stream.offline":
+		log.Println("🔴 Stream offline")
+	case strings.HasPrefix(event.Type, "channel.hype_train."):
+		log.Printf("🚂 Hype train %s (level %d, total %d)", strings.TrimPrefix(event.Type, "channel.hype_train."), e.Level, e.Total)
+	case strings.HasPrefix(event.Type, "channel.poll."):
+		log.Printf("📊 Poll %s: %s", strings.TrimPrefix(event.Type, "channel.poll."), e.Title)
+	case strings.HasPrefix(event.Type, "channel.prediction."):
+		log.Printf("🔮 Prediction %s: %s", strings.TrimPrefix(event.Type, "channel.prediction."), e.Title)
+	}
+}
+
+// saveTwitchActivity writes follows, raids, subs and cheers to their own tables
+func saveTwitchActivity(database *sql.DB, channel string, message EventSubMessage) {
 	switch message.Metadata.SubscriptionType {
 	case "channel.follow":
 		var followEvent struct {
@@ -188,6 +614,7 @@ func (c *EventSubClient) handleNotification(message EventSubMessage) {
 		}
 
 		follower := models.TwitchFollower{
+			Channel:    channel,
 			UserID:     followEvent.UserID,
 			UserLogin:  followEvent.UserLogin,
 			UserName:   followEvent.UserName,
@@ -304,74 +731,90 @@ func (c *EventSubClient) handleNotification(message EventSubMessage) {
 	}
 }
 
-func (c *EventSubClient) createSubscriptions() {
-	c.mu.RLock()
-	sessionID := c.sessionID
-	c.mu.RUnlock()
-
-	if sessionID == "" {
-		log.Println("❌ No session ID available for creating subscriptions")
+func (c *EventSubClient) createSubscriptions(sessionID string) {
+	token, err := c.cfg.Token()
+	if err != nil {
+		log.Printf("❌ No valid user access token found: %v", err)
 		return
 	}
 
-	// Get broadcaster user ID using stored user token
-	log.Println("🔍 Getting broadcaster user ID for majesticcodingtwitch...")
-
-	// Try to get stored user access token (required for EventSub WebSocket)
-	userToken, err := getTwitchUserToken()
+	broadcasterID, err := c.lookupBroadcasterID(token)
 	if err != nil {
-		log.Printf("❌ No valid user access token found: %v", err)
-		log.Println("💡 Visit https://majesticcoding.com/api/twitch/oauth/start to authenticate")
+		log.Printf("❌ Failed to get broadcaster user ID: %v", err)
 		return
 	}
 
-	log.Println("✅ Using stored user access token for EventSub")
+	transport := map[string]interface{}{"method": "websocket", "session_id": sessionID}
+	created := 0
+	for _, sub := range EventSubSubscriptions {
+		if err := c.createSubscription(sub.Type, sub.Version, sub.Condition(broadcasterID), transport, token); err != nil {
+			log.Printf("❌ Failed to create %s subscription: %v", sub.Type, err)
+			continue
+		}
+		created++
+	}
+	log.Printf("✅ Created %d/%d EventSub subscriptions", created, len(EventSubSubscriptions))
+}
 
-	clientID := os.Getenv("TWITCH_CLIENT_ID")
-	url := "https://api.twitch.tv/helix/users?login=majesticcodingtwitch"
+// lookupBroadcasterID resolves and caches the broadcaster's user id
+func (c *EventSubClient) lookupBroadcasterID(token string) (string, error) {
+	c.mu.RLock()
+	id := c.broadcasterID
+	c.mu.RUnlock()
+	if id != "" {
+		return id, nil
+	}
 
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Client-ID", clientID)
-	req.Header.Set("Authorization", "Bearer "+userToken)
+	req, _ := http.NewRequest("GET", c.cfg.APIBaseURL+"/users?login="+c.cfg.BroadcasterLogin, nil)
+	req.Header.Set("Client-ID", c.cfg.ClientID)
+	req.Header.Set("Authorization", "Bearer "+token)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.http.Do(req)
 	if err != nil {
-		log.Printf("❌ Failed to get user info: %v", err)
-		return
+		return "", err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("users API returned status %d", resp.StatusCode)
+	}
+
 	var result struct {
 		Data []struct {
 			ID string `json:"id"`
 		} `json:"data"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || len(result.Data) == 0 {
-		log.Printf("❌ Failed to decode user data")
-		return
+		return "", fmt.Errorf("no user found for %s", c.cfg.BroadcasterLogin)
 	}
 
-	broadcasterUserID := result.Data[0].ID
-	log.Printf("✅ Found broadcaster user ID: %s", broadcasterUserID)
+	c.mu.Lock()
+	c.broadcasterID = result.Data[0].ID
+	c.mu.Unlock()
+	return result.Data[0].ID, nil
+}
 
-	// Start with RAIDS - easiest to test!
-	log.Println("🎯 Creating RAIDS subscription...")
-	condition := map[string]interface{}{
-		"to_broadcaster_user_id": broadcasterUserID,
+// subscriptionToken returns the token Helix expects for the configured transport
+func (c *EventSubClient) subscriptionToken() (string, error) {
+	if c.cfg.Transport == EventSubTransportWebhook {
+		return c.cfg.AppToken()
 	}
+	return c.cfg.Token()
+}
 
-	if err := c.createSubscription("channel.raid", "1", condition, sessionID, userToken); err != nil {
-		log.Printf("❌ Failed to create channel.raid subscription: %v", err)
-	} else {
-		log.Printf("✅ Created channel.raid subscription! 🎉")
-		log.Println("💡 Raid events will be captured when someone raids your channel")
+// transport describes where Twitch should deliver events for new subscriptions
+func (c *EventSubClient) transport() map[string]interface{} {
+	if c.cfg.Transport == EventSubTransportWebhook {
+		return map[string]interface{}{"method": "webhook", "callback": c.cfg.CallbackURL, "secret": c.cfg.Secret}
 	}
-}
 
-func (c *EventSubClient) createSubscription(subscriptionType, version string, condition map[string]interface{}, sessionID, accessToken string) error {
-	clientID := os.Getenv("TWITCH_CLIENT_ID")
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return map[string]interface{}{"method": "websocket", "session_id": c.sessionID}
+}
 
-	if clientID == "" {
+func (c *EventSubClient) createSubscription(subscriptionType, version string, condition map[string]interface{}, transport map[string]interface{}, accessToken string) error {
+	if c.cfg.ClientID == "" {
 		return fmt.Errorf("TWITCH_CLIENT_ID not set")
 	}
 
@@ -383,10 +826,7 @@ func (c *EventSubClient) createSubscription(subscriptionType, version string, co
 		"type":      subscriptionType,
 		"version":   version,
 		"condition": condition,
-		"transport": map[string]interface{}{
-			"method":     "websocket",
-			"session_id": sessionID,
-		},
+		"transport": transport,
 	}
 
 	jsonData, err := json.Marshal(payload)
@@ -394,17 +834,16 @@ func (c *EventSubClient) createSubscription(subscriptionType, version string, co
 		return err
 	}
 
-	req, err := http.NewRequest("POST", "https://api.twitch.tv/helix/eventsub/subscriptions", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", c.cfg.APIBaseURL+"/eventsub/subscriptions", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Client-ID", clientID)
+	req.Header.Set("Client-ID", c.cfg.ClientID)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return err
 	}
@@ -418,51 +857,3 @@ func (c *EventSubClient) createSubscription(subscriptionType, version string, co
 
 	return nil
 }
-
-func (c *EventSubClient) scheduleReconnect() {
-	select {
-	case c.reconnectChan <- true:
-	default:
-	}
-}
-
-func (c *EventSubClient) handleReconnects() {
-	for range c.reconnectChan {
-		log.Println("🔄 Attempting to reconnect in 5 seconds...")
-		time.Sleep(5 * time.Second)
-		go c.connect()
-	}
-}
-
-func (c *EventSubClient) reconnectTo(url string) {
-	log.Printf("🔄 Reconnecting to: %s", url)
-
-	dialer := websocket.DefaultDialer
-	conn, _, err := dialer.Dial(url, nil)
-	if err != nil {
-		log.Printf("❌ Failed to reconnect: %v", err)
-		c.scheduleReconnect()
-		return
-	}
-
-	c.mu.Lock()
-	if c.conn != nil {
-		c.conn.Close()
-	}
-	c.conn = conn
-	c.isConnected = true
-	c.mu.Unlock()
-
-	log.Println("✅ Successfully reconnected to Twitch EventSub")
-
-	for {
-		var message EventSubMessage
-		err := conn.ReadJSON(&message)
-		if err != nil {
-			log.Printf("❌ Error reading EventSub message after reconnect: %v", err)
-			break
-		}
-
-		c.handleMessage(message)
-	}
-}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"majesticcoding.com/api/models"
+)
+
+// fakeEventSub serves the EventSub WebSocket and the few Helix endpoints the client calls
+type fakeEventSub struct {
+	server   *httptest.Server
+	sessions chan *websocket.Conn
+	subs     chan string // subscription types created via Helix
+	paths    chan string // WebSocket paths that were dialed
+}
+
+func newFakeEventSub(t *testing.T) *fakeEventSub {
+	t.Helper()
+	f := &fakeEventSub{
+		sessions: make(chan *websocket.Conn, 4),
+		subs:     make(chan string, 64),
+		paths:    make(chan string, 4),
+	}
+
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", f.acceptSession(upgrader))
+	mux.HandleFunc("/ws/reconnect", f.acceptSession(upgrader))
+	mux.HandleFunc("/helix/users", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":[{"id":"1234"}]}`)
+	})
+	mux.HandleFunc("/helix/eventsub/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Type string `json:"type"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.subs <- body.Type
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeEventSub) acceptSession(upgrader websocket.Upgrader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		f.paths <- r.URL.Path
+		f.sessions <- conn
+	}
+}
+
+func (f *fakeEventSub) wsURL(path string) string {
+	return "ws" + strings.TrimPrefix(f.server.URL, "http") + path
+}
+
+func (f *fakeEventSub) nextSession(t *testing.T) (*websocket.Conn, string) {
+	t.Helper()
+	select {
+	case conn := <-f.sessions:
+		return conn, <-f.paths
+	case <-time.After(3 * time.Second):
+		t.Fatal("client did not connect")
+		return nil, ""
+	}
+}
+
+func sendEventSub(t *testing.T, conn *websocket.Conn, id, messageType, subType string, payload string) {
+	t.Helper()
+	frame := fmt.Sprintf(`{"metadata":{"message_id":%q,"message_type":%q,"message_timestamp":%q,"subscription_type":%q,"subscription_version":"1"},"payload":%s}`,
+		id, messageType, time.Now().UTC().Format(time.RFC3339Nano), subType, payload)
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(frame)); err != nil {
+		t.Fatalf("write %s: %v", messageType, err)
+	}
+}
+
+func welcomePayload(keepalive int) string {
+	return fmt.Sprintf(`{"session":{"id":"session-1","status":"connected","keepalive_timeout_seconds":%d}}`, keepalive)
+}
+
+func drainSubs(t *testing.T, subs chan string, n int) []string {
+	t.Helper()
+	var got []string
+	for len(got) < n {
+		select {
+		case s := <-subs:
+			got = append(got, s)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("got %d of %d subscriptions: %v", len(got), n, got)
+		}
+	}
+	return got
+}
+
+func TestEventSubClientLifecycle(t *testing.T) {
+	fake := newFakeEventSub(t)
+
+	var mu sync.Mutex
+	var received []models.TwitchEvent
+	twitchEventListeners = []func(models.TwitchEvent){func(e models.TwitchEvent) {
+		mu.Lock()
+		received = append(received, e)
+		mu.Unlock()
+	}}
+	t.Cleanup(func() { twitchEventListeners = nil })
+
+	client := NewEventSubClient(EventSubConfig{
+		WebSocketURL:      fake.wsURL("/ws"),
+		APIBaseURL:        fake.server.URL + "/helix",
+		ClientID:          "client",
+		BroadcasterLogin:  "majestic",
+		Token:             func() (string, error) { return "token", nil },
+		MinBackoff:        10 * time.Millisecond,
+		MaxBackoff:        50 * time.Millisecond,
+		KeepaliveGrace:    100 * time.Millisecond,
+		TokenPollInterval: 10 * time.Millisecond,
+	})
+	go client.Run()
+	t.Cleanup(client.Stop)
+
+	// A fresh session subscribes to every type
+	conn, _ := fake.nextSession(t)
+	sendEventSub(t, conn, "welcome-1", "session_welcome", "", welcomePayload(1))
+	if got := drainSubs(t, fake.subs, len(EventSubSubscriptions)); len(got) != len(EventSubSubscriptions) {
+		t.Fatalf("unexpected subscriptions %v", got)
+	}
+
+	// Redelivered notifications are handled once
+	gift := `{"subscription":{"type":"channel.subscription.gift","version":"1"},"event":{"user_name":"alice","total":5,"tier":"1000"}}`
+	sendEventSub(t, conn, "note-1", "notification", "channel.subscription.gift", gift)
+	sendEventSub(t, conn, "note-1", "notification", "channel.subscription.gift", gift)
+	sendEventSub(t, conn, "note-2", "notification", "stream.online", `{"event":{"broadcaster_user_id":"1234","type":"live"}}`)
+
+	// A revoked subscription is recreated
+	sendEventSub(t, conn, "revoke-1", "revocation", "channel.poll.begin",
+		`{"subscription":{"type":"channel.poll.begin","version":"1","status":"authorization_revoked","condition":{"broadcaster_user_id":"1234"}}}`)
+	if got := drainSubs(t, fake.subs, 1); got[0] != "channel.poll.begin" {
+		t.Fatalf("expected resubscribe to channel.poll.begin, got %v", got)
+	}
+
+	mu.Lock()
+	if len(received) != 2 || received[0].Type != "channel.subscription.gift" || received[1].Type != "stream.online" {
+		t.Fatalf("unexpected events delivered: %+v", received)
+	}
+	if received[0].UserName != "alice" {
+		t.Fatalf("expected user name from event, got %q", received[0].UserName)
+	}
+	mu.Unlock()
+
+	// session_reconnect moves to the new URL without resubscribing
+	sendEventSub(t, conn, "reconnect-1", "session_reconnect", "",
+		fmt.Sprintf(`{"session":{"id":"session-1","status":"reconnecting","reconnect_url":%q}}`, fake.wsURL("/ws/reconnect")))
+	moved, path := fake.nextSession(t)
+	if path != "/ws/reconnect" {
+		t.Fatalf("expected reconnect URL, got %s", path)
+	}
+
+	// The old session is still read until the new one is welcomed, then closed
+	sendEventSub(t, conn, "note-3", "notification", "channel.follow", `{"event":{"user_name":"bob"}}`)
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(received)
+		mu.Unlock()
+		if n == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("notification on the old session during the move wasn't delivered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	sendEventSub(t, moved, "welcome-2", "session_welcome", "", welcomePayload(1))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var netErr net.Error
+	if _, _, err := conn.ReadMessage(); err == nil || errors.As(err, &netErr) && netErr.Timeout() {
+		t.Fatalf("old session still open after the new one was welcomed: %v", err)
+	}
+	select {
+	case s := <-fake.subs:
+		t.Fatalf("reconnect should keep subscriptions, but %s was created", s)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// Silence past the keepalive timeout forces a fresh session
+	fresh, path := fake.nextSession(t)
+	if path != "/ws" {
+		t.Fatalf("expected a fresh session after keepalive timeout, got %s", path)
+	}
+	sendEventSub(t, fresh, "welcome-3", "session_welcome", "", welcomePayload(10))
+	drainSubs(t, fake.subs, len(EventSubSubscriptions))
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gempir/go-twitch-irc/v4"
+	"majesticcoding.com/api/models"
+)
+
+// TwitchIRCConfig describes how to reach Twitch chat with a logged-in identity
+type TwitchIRCConfig struct {
+	Login   string
+	Address string // host:port, empty for Twitch's own servers
+	TLS     bool
+}
+
+// LoadTwitchIRCConfig reads the chat identity from the config, defaulting the login to the channel
+func LoadTwitchIRCConfig(channel string) TwitchIRCConfig {
+	twitchConf := conf().Twitch
+	login := twitchConf.BotUsername
+	if login == "" {
+		login = channel
+	}
+
+	return TwitchIRCConfig{
+		Login:   strings.ToLower(login),
+		Address: twitchConf.IRCAddress,
+		TLS:     twitchConf.IRCTLS,
+	}
+}
+
+// Backoff between attempts to log into Twitch chat
+const (
+	twitchIRCMinBackoff = time.Second
+	twitchIRCMaxBackoff = 2 * time.Minute
+)
+
+// NewAuthenticatedTwitchClient creates an IRC client that logs in with a user access token.
+// The token can be empty when connectTwitchIRC will supply one.
+func NewAuthenticatedTwitchClient(cfg TwitchIRCConfig, accessToken string) *twitch.Client {
+	client := twitch.NewClient(cfg.Login, ircPassword(accessToken))
+	if cfg.Address != "" {
+		client.IrcAddress = cfg.Address
+	}
+	client.TLS = cfg.TLS
+	return client
+}
+
+func ircPassword(accessToken string) string {
+	return "oauth:" + strings.TrimPrefix(accessToken, "oauth:")
+}
+
+// connectTwitchIRC keeps client logged in until it's disconnected or done is closed. It asks
+// token for the access token before every login, so a token that expired since the last one
+// is refreshed rather than rejected.
+func connectTwitchIRC(client *twitch.Client, name string, token func() (string, error), done <-chan struct{}) {
+	// The client reconnects by itself when Twitch asks it to; use a fresh token for that too
+	client.OnReconnectMessage(func(twitch.ReconnectMessage) {
+		if t, err := token(); err == nil {
+			client.SetIRCToken(ircPassword(t))
+		}
+	})
+
+	backoff := twitchIRCMinBackoff
+	waitingForToken := false
+	for {
+		t, err := token()
+		if err != nil {
+			if !waitingForToken {
+				log.Printf("⏸️ %s waiting for a Twitch user token: %v", name, err)
+				waitingForToken = true
+			}
+		} else {
+			waitingForToken = false
+			client.SetIRCToken(ircPassword(t))
+			started := time.Now()
+			err = client.Connect()
+			if err == twitch.ErrClientDisconnected {
+				return
+			}
+			if time.Since(started) > twitchIRCMaxBackoff {
+				backoff = twitchIRCMinBackoff
+			}
+			log.Printf("❌ %s connection failed: %v (retrying in %s)", name, err, backoff)
+		}
+
+		select {
+		case <-done:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > twitchIRCMaxBackoff {
+			backoff = twitchIRCMaxBackoff
+		}
+	}
+}
+
+// toTwitchMessage converts an IRC PRIVMSG into the stored message model
+func toTwitchMessage(msg twitch.PrivateMessage) models.TwitchMessage {
+	return models.TwitchMessage{
+		MessageID:     msg.ID,
+		UserID:        msg.User.ID,
+		Channel:       strings.ToLower(msg.Channel),
+		Username:      msg.User.Name,
+		DisplayName:   msg.User.DisplayName,
+		Message:       msg.Message,
+		Color:         msg.User.Color,
+		Badges:        msg.User.Badges,
+		IsMod:         msg.User.IsMod,
+		IsVip:         msg.User.IsVip,
+		IsBroadcaster: msg.User.IsBroadcaster,
+		Emotes:        toTwitchEmotes(msg.Emotes),
+		Time:          msg.Time,
+	}
+}
+
+func toTwitchEmotes(emotes []*twitch.Emote) []models.TwitchEmote {
+	if len(emotes) == 0 {
+		return nil
+	}
+	list := make([]models.TwitchEmote, 0, len(emotes))
+	for _, e := range emotes {
+		list = append(list, models.TwitchEmote{ID: e.ID, Name: e.Name, Count: e.Count})
+	}
+	return list
+}
@@ -1,10 +1,7 @@
 package services
 
 import (
-	"fmt"
 	"log"
-	"net/http"
-	"net/url"
 	"strings"
 	"sync"
 
@@ -15,30 +12,72 @@ import (
 )
 
 var (
-	messages     []models.TwitchMessage
+	messages     = make(map[string][]models.TwitchMessage) // recent messages by channel
 	messagesLock sync.Mutex
 	maxMessages  = 50
+
+	twitchChatListeners []func(models.TwitchChatEvent)
+
+	chatFeedMu     sync.Mutex
+	chatFeedClient *twitch.Client
+	chatFeedJoined map[string]bool // channels the feed has joined
 )
 
-// StartTwitchChatFeed starts an anonymous Twitch client and stores messages
-func StartTwitchChatFeed(channel string) {
+// AddTwitchChatListener registers fn to receive live Twitch chat messages and
+// moderation events. Call it before StartTwitchChatFeed.
+func AddTwitchChatListener(fn func(models.TwitchChatEvent)) {
+	twitchChatListeners = append(twitchChatListeners, fn)
+}
+
+func emitTwitchChatEvent(event models.TwitchChatEvent) {
+	if event.Type == models.TwitchChatMessage && event.Message != nil {
+		PublishEvent(TopicTwitchChatMessage, *event.Message)
+	}
+	for _, fn := range twitchChatListeners {
+		fn(event)
+	}
+}
+
+// StartTwitchChatFeed starts one anonymous Twitch client that joins every profile's channel and
+// stores messages. It's safe to call again after a config reload: the running client joins any
+// channel added since.
+func StartTwitchChatFeed() {
+	chatFeedMu.Lock()
+	defer chatFeedMu.Unlock()
+
+	channels := TwitchChannels()
+	starting := chatFeedClient == nil
+	if starting {
+		if len(channels) == 0 {
+			return
+		}
+		chatFeedClient = newTwitchChatFeed()
+		chatFeedJoined = make(map[string]bool)
+	}
+
+	for _, channel := range channels {
+		if !chatFeedJoined[channel] {
+			chatFeedClient.Join(channel)
+			chatFeedJoined[channel] = true
+			log.Printf("💬 Twitch chat feed joined #%s", channel)
+		}
+	}
+
+	if starting {
+		client := chatFeedClient
+		go func() {
+			if err := client.Connect(); err != nil {
+				log.Printf("❌ Twitch connection failed: %v", err)
+			}
+		}()
+	}
+}
+
+func newTwitchChatFeed() *twitch.Client {
 	client := twitch.NewAnonymousClient()
 
 	client.OnPrivateMessage(func(msg twitch.PrivateMessage) {
-		messagesLock.Lock()
-		defer messagesLock.Unlock()
-
-		twitchMsg := models.TwitchMessage{
-			Username:      msg.User.Name,
-			DisplayName:   msg.User.DisplayName,
-			Message:       msg.Message,
-			Color:         msg.User.Color,
-			Badges:        msg.User.Badges,
-			IsMod:         msg.User.IsMod,
-			IsVip:         msg.User.IsVip,
-			IsBroadcaster: msg.User.IsBroadcaster,
-			Time:          msg.Time,
-		}
+		twitchMsg := toTwitchMessage(msg)
 
 		// Store in database
 		database := db.GetDB()
@@ -50,58 +89,89 @@ func StartTwitchChatFeed(channel string) {
 			}
 		}
 
-		// Check for !checkin command
-		if strings.HasPrefix(strings.ToLower(msg.Message), "!checkin ") {
-			location := strings.TrimSpace(msg.Message[9:]) // Remove "!checkin "
-			if location != "" {
-				log.Printf("🌍 Processing !checkin command from %s: %s", msg.User.DisplayName, location)
-				go handleCheckinCommand(location, msg.User.DisplayName)
-			}
+		// Without a logged-in bot, commands are dispatched from the read-only feed
+		if twitchBot != nil && twitchBot.client == nil && twitchMsg.Channel == twitchBot.channel {
+			go twitchBot.HandleMessage(msg)
 		}
 
 		// Keep in memory for quick access
-		messages = append(messages, twitchMsg)
-		if len(messages) > maxMessages {
-			messages = messages[len(messages)-maxMessages:]
+		messagesLock.Lock()
+		recent := append(messages[twitchMsg.Channel], twitchMsg)
+		if len(recent) > maxMessages {
+			recent = recent[len(recent)-maxMessages:]
 		}
+		messages[twitchMsg.Channel] = recent
+		messagesLock.Unlock()
+
+		emitTwitchChatEvent(models.TwitchChatEvent{Type: models.TwitchChatMessage, Channel: twitchMsg.Channel, Message: &twitchMsg})
 	})
 
-	client.OnConnect(func() {})
-	client.Join(channel)
+	// A moderator deleted a single message
+	client.OnClearMessage(func(msg twitch.ClearMessage) {
+		channel := strings.ToLower(msg.Channel)
+		removeRecentMessages(channel, func(m models.TwitchMessage) bool { return m.MessageID == msg.TargetMsgID })
+		log.Printf("🧹 Twitch message from %s deleted in #%s", msg.Login, channel)
+		emitTwitchChatEvent(models.TwitchChatEvent{
+			Type:      models.TwitchChatDelete,
+			Channel:   channel,
+			MessageID: msg.TargetMsgID,
+			Username:  msg.Login,
+		})
+	})
+
+	// A user was timed out or banned, or the whole chat was cleared
+	client.OnClearChatMessage(func(msg twitch.ClearChatMessage) {
+		channel := strings.ToLower(msg.Channel)
+		if msg.TargetUsername == "" {
+			removeRecentMessages(channel, func(models.TwitchMessage) bool { return true })
+			log.Printf("🧹 Twitch chat cleared in #%s", channel)
+			emitTwitchChatEvent(models.TwitchChatEvent{Type: models.TwitchChatClear, Channel: channel})
+			return
+		}
+
+		removeRecentMessages(channel, func(m models.TwitchMessage) bool {
+			return m.UserID == msg.TargetUserID || strings.EqualFold(m.Username, msg.TargetUsername)
+		})
 
-	go func() {
-		if err := client.Connect(); err != nil {
-			log.Printf("❌ Twitch connection failed: %v", err)
+		event := models.TwitchChatEvent{
+			Type:     models.TwitchChatBan,
+			Channel:  channel,
+			Username: msg.TargetUsername,
+			UserID:   msg.TargetUserID,
+		}
+		if msg.BanDuration > 0 {
+			event.Type = models.TwitchChatTimeout
+			event.Duration = msg.BanDuration
 		}
-	}()
+		log.Printf("🔨 Twitch user %s: %s", msg.TargetUsername, event.Type)
+		emitTwitchChatEvent(event)
+	})
+
+	client.OnConnect(func() {})
+	return client
 }
 
-// GetRecentMessages returns last Twitch messages
-func GetRecentMessages() []models.TwitchMessage {
+// removeRecentMessages drops moderated messages from a channel's in-memory history
+func removeRecentMessages(channel string, match func(models.TwitchMessage) bool) {
 	messagesLock.Lock()
 	defer messagesLock.Unlock()
 
-	copied := make([]models.TwitchMessage, len(messages))
-	copy(copied, messages)
-	return copied
+	kept := messages[channel][:0]
+	for _, m := range messages[channel] {
+		if !match(m) {
+			kept = append(kept, m)
+		}
+	}
+	messages[channel] = kept
 }
 
-// handleCheckinCommand processes !checkin commands from Twitch chat
-func handleCheckinCommand(location, username string) {
-	// Call the geocode API endpoint with username
-	geocodeURL := fmt.Sprintf("http://localhost:8080/api/geocode?city=%s&username=%s",
-		url.QueryEscape(location), url.QueryEscape(username))
-
-	resp, err := http.Get(geocodeURL)
-	if err != nil {
-		log.Printf("❌ Failed to call geocode API for %s: %v", location, err)
-		return
-	}
-	defer resp.Body.Close()
+// GetRecentMessages returns a channel's last Twitch messages
+func GetRecentMessages(channel string) []models.TwitchMessage {
+	messagesLock.Lock()
+	defer messagesLock.Unlock()
 
-	if resp.StatusCode == http.StatusOK {
-		log.Printf("✅ Successfully processed !checkin for %s from %s", location, username)
-	} else {
-		log.Printf("❌ Geocode API returned status %d for location: %s", resp.StatusCode, location)
-	}
+	recent := messages[strings.ToLower(channel)]
+	copied := make([]models.TwitchMessage, len(recent))
+	copy(copied, recent)
+	return copied
 }
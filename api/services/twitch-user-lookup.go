@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 )
 
@@ -18,8 +17,8 @@ type TwitchUsersResponse struct {
 
 // GetTwitchUserID fetches the numeric user ID for a given username
 func GetTwitchUserID(username string) (string, error) {
-	clientID := os.Getenv("TWITCH_CLIENT_ID")
-	accessToken := os.Getenv("TWITCH_ACCESS_TOKEN")
+	clientID := conf().Twitch.ClientID
+	accessToken := conf().Twitch.AccessToken
 
 	// If no access token is set, try to get one using client credentials
 	if accessToken == "" {
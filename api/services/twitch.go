@@ -1,17 +1,26 @@
 package services
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
+	"strings"
+	"time"
 
 	"majesticcoding.com/api/models"
+	"majesticcoding.com/db"
 )
 
 func getTwitchToken() (string, error) {
+	return twitchAppToken(context.Background())
+}
+
+// twitchAppToken returns the cached app access token, requesting a new one under ctx on a miss
+func twitchAppToken(ctx context.Context) (string, error) {
 	cacheKey := "twitch:token:oauth"
 
 	// Try to get from Redis cache first (1 hour TTL = 3600 seconds)
@@ -22,18 +31,24 @@ func getTwitchToken() (string, error) {
 	}
 	log.Printf("🔍 Twitch token cache MISS, fetching new token")
 
-	clientID := os.Getenv("TWITCH_CLIENT_ID")
-	clientSecret := os.Getenv("TWITCH_CLIENT_SECRET")
+	clientID := conf().Twitch.ClientID
+	clientSecret := conf().Twitch.ClientSecret
 
 	if clientID == "" || clientSecret == "" {
 		return "", fmt.Errorf("TWITCH_CLIENT_ID or TWITCH_CLIENT_SECRET not set")
 	}
 
-	resp, err := http.PostForm("https://id.twitch.tv/oauth2/token", url.Values{
+	form := url.Values{
 		"client_id":     {clientID},
 		"client_secret": {clientSecret},
 		"grant_type":    {"client_credentials"},
-	})
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://id.twitch.tv/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("request creation failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to get twitch token: %w", err)
 	}
@@ -70,80 +85,82 @@ func getTwitchToken() (string, error) {
 	return result.AccessToken, nil
 }
 
-func FetchTwitchStats(username string) (models.TwitchStats, error) {
-	token, err := getTwitchToken()
-	if err != nil {
-		return models.TwitchStats{}, err
-	}
+type twitchStatsProvider struct{}
 
-	clientID := os.Getenv("TWITCH_CLIENT_ID")
-	if clientID == "" {
-		return models.TwitchStats{}, fmt.Errorf("TWITCH_CLIENT_ID not set")
-	}
+func (twitchStatsProvider) Name() string                    { return "twitch" }
+func (twitchStatsProvider) Account(p models.Profile) string { return p.TwitchChannel }
+func (twitchStatsProvider) DefaultInterval() time.Duration  { return 10 * time.Minute }
+func (twitchStatsProvider) NewDetails() interface{}         { return &models.TwitchDetails{} }
+func (twitchStatsProvider) Feature() string                 { return "twitch" }
+func (twitchStatsProvider) Fetch(ctx context.Context, username string) (*models.StatsSnapshot, error) {
+	return FetchTwitchStats(ctx, username)
+}
 
-	url := fmt.Sprintf("https://api.twitch.tv/helix/users?login=%s", username)
+func (twitchStatsProvider) Record(database *sql.DB, s *models.StatsSnapshot) error {
+	return db.InsertTwitchStats(database, s.Account, int(s.Metrics["followers"]), 0, false)
+}
 
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Client-ID", clientID)
-	req.Header.Set("Authorization", "Bearer "+token)
+func (twitchStatsProvider) Latest(database *sql.DB, username string) (*models.StatsSnapshot, error) {
+	stats, err := db.GetLatestTwitchStats(database, username)
+	if err != nil {
+		return nil, err
+	}
+	return &models.StatsSnapshot{
+		DisplayName: stats.DisplayName,
+		URL:         "https://www.twitch.tv/" + username,
+		Metrics:     map[string]int64{"followers": int64(stats.Followers)},
+	}, nil
+}
 
-	resp, err := http.DefaultClient.Do(req)
+// FetchTwitchStats fetches a channel's profile and follower count
+func FetchTwitchStats(ctx context.Context, username string) (*models.StatsSnapshot, error) {
+	token, err := twitchAppToken(ctx)
 	if err != nil {
-		return models.TwitchStats{}, fmt.Errorf("http request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	// Check HTTP status code
-	if resp.StatusCode != http.StatusOK {
-		return models.TwitchStats{}, fmt.Errorf("twitch API returned status %d", resp.StatusCode)
+	clientID := conf().Twitch.ClientID
+	if clientID == "" {
+		return nil, fmt.Errorf("TWITCH_CLIENT_ID not set")
 	}
+	header := http.Header{}
+	header.Set("Client-ID", clientID)
+	header.Set("Authorization", "Bearer "+token)
 
 	var result struct {
 		Data []struct {
+			Login           string `json:"login"`
 			DisplayName     string `json:"display_name"`
 			Description     string `json:"description"`
 			BroadcasterType string `json:"broadcaster_type"`
+			ProfileImageURL string `json:"profile_image_url"`
 			ID              string `json:"id"`
 		} `json:"data"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return models.TwitchStats{}, fmt.Errorf("failed to decode response: %w", err)
+	if err := getStatsJSON(ctx, "twitch", "https://api.twitch.tv/helix/users?login="+url.QueryEscape(username), header, &result); err != nil {
+		return nil, err
 	}
-
 	if len(result.Data) == 0 {
-		return models.TwitchStats{}, fmt.Errorf("no user found for username: %s", username)
+		return nil, fmt.Errorf("no user found for username: %s", username)
 	}
-
 	user := result.Data[0]
 
-	// Fetch followers
-	followersURL := fmt.Sprintf("https://api.twitch.tv/helix/channels/followers?broadcaster_id=%s", user.ID)
-	req2, _ := http.NewRequest("GET", followersURL, nil)
-	req2.Header.Set("Client-ID", clientID)
-	req2.Header.Set("Authorization", "Bearer "+token)
-
-	resp2, err := http.DefaultClient.Do(req2)
-	if err != nil {
-		return models.TwitchStats{}, fmt.Errorf("followers request failed: %w", err)
-	}
-	defer resp2.Body.Close()
-
-	// Check HTTP status code for followers endpoint
-	if resp2.StatusCode != http.StatusOK {
-		return models.TwitchStats{}, fmt.Errorf("followers API returned status %d", resp2.StatusCode)
-	}
-
 	var followResult struct {
 		Total int `json:"total"`
 	}
-	if err := json.NewDecoder(resp2.Body).Decode(&followResult); err != nil {
-		return models.TwitchStats{}, fmt.Errorf("failed to decode followers: %w", err)
+	followersURL := "https://api.twitch.tv/helix/channels/followers?broadcaster_id=" + user.ID
+	if err := getStatsJSON(ctx, "twitch", followersURL, header, &followResult); err != nil {
+		return nil, fmt.Errorf("followers: %w", err)
 	}
 
-	return models.TwitchStats{
-		DisplayName:     user.DisplayName,
-		Description:     user.Description,
-		BroadcasterType: user.BroadcasterType,
-		Followers:       followResult.Total,
+	return &models.StatsSnapshot{
+		DisplayName: user.DisplayName,
+		URL:         "https://www.twitch.tv/" + user.Login,
+		Metrics:     map[string]int64{"followers": int64(followResult.Total)},
+		Details: &models.TwitchDetails{
+			Description:     user.Description,
+			BroadcasterType: user.BroadcasterType,
+			ProfileImageURL: user.ProfileImageURL,
+		},
 	}, nil
 }
@@ -1,52 +1,209 @@
 package services
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"majesticcoding.com/api/models"
+	"majesticcoding.com/db"
 )
 
-func FetchYouTubeStats() (map[string]interface{}, error) {
-	apiKey := os.Getenv("YT_API_KEY")
-	channelID := os.Getenv("YT_CHANNEL_ID")
+// youTubeRecentUploads is how many of the newest uploads a snapshot includes
+const youTubeRecentUploads = 5
+
+type youTubeStatsProvider struct{}
 
-	url := fmt.Sprintf("https://www.googleapis.com/youtube/v3/channels?part=snippet,statistics&id=%s&key=%s", channelID, apiKey)
+func (youTubeStatsProvider) Name() string                    { return "youtube" }
+func (youTubeStatsProvider) Account(p models.Profile) string { return p.YouTubeChannelID }
+func (youTubeStatsProvider) DefaultInterval() time.Duration  { return 30 * time.Minute } // the Data API has a daily quota
+func (youTubeStatsProvider) Feature() string                 { return "youtube_stats" }
+func (youTubeStatsProvider) NewDetails() interface{}         { return &models.YouTubeDetails{} }
+func (youTubeStatsProvider) Fetch(ctx context.Context, channelID string) (*models.StatsSnapshot, error) {
+	return FetchYouTubeStats(ctx, channelID)
+}
 
-	resp, err := http.Get(url)
+func (youTubeStatsProvider) Record(database *sql.DB, s *models.StatsSnapshot) error {
+	return db.InsertYouTubeStats(database, s.Account, s.DisplayName,
+		int(s.Metrics["subscribers"]), int(s.Metrics["videos"]), s.Metrics["views"])
+}
+
+func (youTubeStatsProvider) Latest(database *sql.DB, channelID string) (*models.StatsSnapshot, error) {
+	stats, err := db.GetLatestYouTubeStats(database, channelID)
 	if err != nil {
-		return nil, fmt.Errorf("http error: %w", err)
+		return nil, err
+	}
+	return &models.StatsSnapshot{
+		DisplayName: stats.ChannelName,
+		Metrics: map[string]int64{
+			"subscribers": int64(stats.Subscribers),
+			"views":       int64(stats.Views),
+			"videos":      int64(stats.Videos),
+		},
+	}, nil
+}
+
+// FetchYouTubeStats fetches a channel's statistics and its most recent uploads with their own
+// statistics. It costs three units of Data API quota.
+func FetchYouTubeStats(ctx context.Context, channelID string) (*models.StatsSnapshot, error) {
+	if channelID == "" {
+		return nil, fmt.Errorf("no YouTube channel configured")
 	}
-	defer resp.Body.Close()
 
-	var result struct {
+	var channels struct {
 		Items []struct {
 			Snippet struct {
-				Title string `json:"title"`
+				Title     string `json:"title"`
+				CustomURL string `json:"customUrl"`
 			} `json:"snippet"`
 			Statistics struct {
 				SubscriberCount string `json:"subscriberCount"`
 				ViewCount       string `json:"viewCount"`
 				VideoCount      string `json:"videoCount"`
 			} `json:"statistics"`
+			ContentDetails struct {
+				RelatedPlaylists struct {
+					Uploads string `json:"uploads"`
+				} `json:"relatedPlaylists"`
+			} `json:"contentDetails"`
 		} `json:"items"`
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("decode error: %w", err)
+	err := youTubeGet(ctx, "channels", url.Values{"part": {"snippet,statistics,contentDetails"}, "id": {channelID}}, &channels)
+	if err != nil {
+		return nil, err
 	}
-
-	if len(result.Items) == 0 {
+	if len(channels.Items) == 0 {
 		return nil, fmt.Errorf("no items returned")
 	}
+	channel := channels.Items[0]
 
-	item := result.Items[0]
+	channelURL := "https://www.youtube.com/channel/" + channelID
+	if channel.Snippet.CustomURL != "" {
+		channelURL = "https://www.youtube.com/" + channel.Snippet.CustomURL
+	}
 
-	// Return simplified map
-	return map[string]interface{}{
-		"channelTitle": item.Snippet.Title,
-		"subscribers":  item.Statistics.SubscriberCount,
-		"views":        item.Statistics.ViewCount,
-		"videos":       item.Statistics.VideoCount,
+	uploads, err := youTubeRecentVideos(ctx, channel.ContentDetails.RelatedPlaylists.Uploads)
+	if err != nil {
+		return nil, fmt.Errorf("recent uploads: %w", err)
+	}
+
+	return &models.StatsSnapshot{
+		DisplayName: channel.Snippet.Title,
+		URL:         channelURL,
+		Metrics: map[string]int64{
+			"subscribers": youTubeCount(channel.Statistics.SubscriberCount),
+			"views":       youTubeCount(channel.Statistics.ViewCount),
+			"videos":      youTubeCount(channel.Statistics.VideoCount),
+		},
+		Details: &models.YouTubeDetails{RecentUploads: uploads},
 	}, nil
 }
+
+// youTubeRecentVideos returns the newest videos in an uploads playlist with their statistics
+func youTubeRecentVideos(ctx context.Context, playlistID string) ([]models.YouTubeVideo, error) {
+	if playlistID == "" {
+		return []models.YouTubeVideo{}, nil
+	}
+
+	var items struct {
+		Items []struct {
+			ContentDetails struct {
+				VideoID string `json:"videoId"`
+			} `json:"contentDetails"`
+		} `json:"items"`
+	}
+	params := url.Values{
+		"part":       {"contentDetails"},
+		"playlistId": {playlistID},
+		"maxResults": {strconv.Itoa(youTubeRecentUploads)},
+	}
+	if err := youTubeGet(ctx, "playlistItems", params, &items); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(items.Items))
+	for _, item := range items.Items {
+		ids = append(ids, item.ContentDetails.VideoID)
+	}
+	if len(ids) == 0 {
+		return []models.YouTubeVideo{}, nil
+	}
+
+	var videos struct {
+		Items []struct {
+			ID      string `json:"id"`
+			Snippet struct {
+				Title       string    `json:"title"`
+				PublishedAt time.Time `json:"publishedAt"`
+				Thumbnails  map[string]struct {
+					URL string `json:"url"`
+				} `json:"thumbnails"`
+			} `json:"snippet"`
+			Statistics struct {
+				ViewCount    string `json:"viewCount"`
+				LikeCount    string `json:"likeCount"`
+				CommentCount string `json:"commentCount"`
+			} `json:"statistics"`
+		} `json:"items"`
+	}
+	if err := youTubeGet(ctx, "videos", url.Values{"part": {"snippet,statistics"}, "id": {strings.Join(ids, ",")}}, &videos); err != nil {
+		return nil, err
+	}
+
+	out := make([]models.YouTubeVideo, 0, len(videos.Items))
+	for _, v := range videos.Items {
+		out = append(out, models.YouTubeVideo{
+			ID:          v.ID,
+			Title:       v.Snippet.Title,
+			URL:         "https://www.youtube.com/watch?v=" + v.ID,
+			Thumbnail:   v.Snippet.Thumbnails["medium"].URL,
+			PublishedAt: v.Snippet.PublishedAt,
+			Views:       youTubeCount(v.Statistics.ViewCount),
+			Likes:       youTubeCount(v.Statistics.LikeCount),
+			Comments:    youTubeCount(v.Statistics.CommentCount),
+		})
+	}
+	return out, nil
+}
+
+// youTubeGet calls a Data API v3 resource and decodes the response into out
+func youTubeGet(ctx context.Context, resource string, params url.Values, out interface{}) error {
+	params.Set("key", conf().Google.YouTubeAPIKey)
+	endpoint := "https://www.googleapis.com/youtube/v3/" + resource + "?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("request creation failed: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		// Quota errors come back as 403 with the reason in the body
+		if resp.StatusCode == http.StatusTooManyRequests || strings.Contains(string(body), "quotaExceeded") || strings.Contains(string(body), "rateLimitExceeded") {
+			return &RateLimitError{Provider: "youtube"}
+		}
+		return fmt.Errorf("youtube API returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode error: %w", err)
+	}
+	return nil
+}
+
+// youTubeCount parses the API's string-encoded counts; hidden counts are 0
+func youTubeCount(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
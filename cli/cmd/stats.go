@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
@@ -12,19 +13,10 @@ import (
 
 var statsCmd = &cobra.Command{
 	Use:   "stats [provider]",
-	Short: "Fetch stats from /api/stats/:provider",
+	Short: "Fetch a provider's stats from /api/stats/:provider",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		provider := args[0]
-		valid := map[string]bool{
-			"youtube": true,
-			"github":  true,
-			"twitch":  true,
-		}
-		if !valid[provider] {
-			fmt.Println("Invalid provider. Use one of: youtube, github, twitch")
-			return
-		}
 
 		resp, err := http.Get(fmt.Sprintf("https://majesticcoding.com/api/stats/%s", provider))
 		if err != nil {
@@ -33,28 +25,41 @@ var statsCmd = &cobra.Command{
 		}
 		defer resp.Body.Close()
 
+		if resp.StatusCode == http.StatusNotFound {
+			fmt.Println("Unknown provider, or the profile has no account on it:", provider)
+			return
+		}
 		if resp.StatusCode != http.StatusOK {
 			fmt.Println("Failed to fetch stats:", resp.Status)
 			return
 		}
 
 		body, _ := ioutil.ReadAll(resp.Body)
-		var data map[string]interface{}
-		json.Unmarshal(body, &data)
+		var snapshot struct {
+			DisplayName string           `json:"displayName"`
+			URL         string           `json:"url"`
+			Metrics     map[string]int64 `json:"metrics"`
+		}
+		json.Unmarshal(body, &snapshot)
 
-		fmt.Println(renderTable(data))
+		fmt.Println(renderTable(snapshot.DisplayName, snapshot.URL, snapshot.Metrics))
 	},
 }
 
-func renderTable(data map[string]interface{}) string {
+func renderTable(name, url string, metrics map[string]int64) string {
 	keyStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("204"))
 	valStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
 
-	var out string
-	for k, v := range data {
+	out := fmt.Sprintf("%s %s\n", keyStyle.Render(name), valStyle.Render(url))
+	keys := make([]string, 0, len(metrics))
+	for k := range metrics {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
 		out += fmt.Sprintf("%s: %s\n",
 			keyStyle.Render(k),
-			valStyle.Render(fmt.Sprintf("%v", v)),
+			valStyle.Render(fmt.Sprintf("%d", metrics[k])),
 		)
 	}
 	return out
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"database/sql"
+
+	"majesticcoding.com/api/models"
+)
+
+// GetAlertTemplates returns the stored alert template overrides
+func GetAlertTemplates(db *sql.DB) ([]models.AlertTemplate, error) {
+	rows, err := db.Query(`
+		SELECT type, enabled, text, duration_seconds, priority, min_amount, image_url, sound_url
+		FROM bronze.alert_templates
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []models.AlertTemplate
+	for rows.Next() {
+		var t models.AlertTemplate
+		if err := rows.Scan(&t.Type, &t.Enabled, &t.Text, &t.DurationSeconds, &t.Priority,
+			&t.MinAmount, &t.ImageURL, &t.SoundURL); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// UpsertAlertTemplate saves the template for an alert type
+func UpsertAlertTemplate(db *sql.DB, t models.AlertTemplate) error {
+	_, err := db.Exec(`
+		INSERT INTO bronze.alert_templates (type, enabled, text, duration_seconds, priority, min_amount, image_url, sound_url)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (type) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			text = EXCLUDED.text,
+			duration_seconds = EXCLUDED.duration_seconds,
+			priority = EXCLUDED.priority,
+			min_amount = EXCLUDED.min_amount,
+			image_url = EXCLUDED.image_url,
+			sound_url = EXCLUDED.sound_url,
+			updated_at = CURRENT_TIMESTAMP
+	`, t.Type, t.Enabled, t.Text, t.DurationSeconds, t.Priority, t.MinAmount, t.ImageURL, t.SoundURL)
+	return err
+}
@@ -2,38 +2,40 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
-	"os"
 
 	_ "github.com/lib/pq"
 )
 
 var Database *sql.DB
 
-func Connect() {
-	connStr := os.Getenv("DATABASE_URL")
+// Connect opens the Postgres database at connStr, the config's DATABASE_URL. Without one, or
+// if it can't be reached, Database stays nil and the features needing it are off.
+func Connect(connStr string) error {
 	if connStr == "" {
-		log.Fatal("Error: DATABASE_URL not set")
+		return fmt.Errorf("DATABASE_URL not set")
 	}
 
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
-		log.Fatalf("Error: Unable to connect to database: %v", err)
+		return fmt.Errorf("unable to open database: %w", err)
 	}
 
 	// Verify connection
 	if err := db.Ping(); err != nil {
-		log.Fatalf("Error: Database ping failed: %v", err)
+		db.Close()
+		return fmt.Errorf("database ping failed: %w", err)
 	}
 
 	// Set default schema to bronze
 	_, err = db.Exec("SET search_path = bronze, public")
 	if err != nil {
 		log.Printf("Warning: Could not set schema to bronze: %v", err)
-	} else {
 	}
 
 	Database = db
+	return nil
 }
 
 func GetDB() *sql.DB {
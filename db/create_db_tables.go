@@ -69,6 +69,23 @@ func CreateCheckinsTable(db *sql.DB) error {
 			country TEXT,
 			checkin_time TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 		);
+
+		ALTER TABLE bronze.checkins ADD COLUMN IF NOT EXISTS username TEXT;
+		ALTER TABLE bronze.checkins ADD COLUMN IF NOT EXISTS platform TEXT;
+		ALTER TABLE bronze.checkins ADD COLUMN IF NOT EXISTS stream_key TEXT;
+
+		-- One checkin per user per stream. Duplicates from before the index keep their row but
+		-- lose the stream key, so the index can be built.
+		UPDATE bronze.checkins c SET stream_key = NULL
+		WHERE c.stream_key IS NOT NULL AND EXISTS (
+			SELECT 1 FROM bronze.checkins o
+			WHERE o.platform = c.platform AND LOWER(o.username) = LOWER(c.username)
+			  AND o.stream_key = c.stream_key AND o.id < c.id
+		);
+		DROP INDEX IF EXISTS bronze.idx_checkins_user_stream;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_checkins_user_stream_unique
+			ON bronze.checkins(platform, LOWER(username), stream_key)
+			WHERE username IS NOT NULL AND platform IS NOT NULL AND stream_key IS NOT NULL;
 	`)
 	if err != nil {
 		fmt.Printf("ERROR: Failed to create checkins table: %v\n", err)
@@ -146,8 +163,12 @@ func CreateTwitchMessagesTable(db *sql.DB) error {
 			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 		);
 		
+		ALTER TABLE bronze.twitch_messages ADD COLUMN IF NOT EXISTS emotes JSONB;
+		ALTER TABLE bronze.twitch_messages ADD COLUMN IF NOT EXISTS channel VARCHAR(25);
+
 		CREATE INDEX IF NOT EXISTS idx_twitch_messages_time ON bronze.twitch_messages(time);
 		CREATE INDEX IF NOT EXISTS idx_twitch_messages_username ON bronze.twitch_messages(username);
+		CREATE INDEX IF NOT EXISTS idx_twitch_messages_channel_time ON bronze.twitch_messages(channel, time);
 	`)
 	return err
 }
@@ -168,6 +189,8 @@ func CreateStatsHistoryTables(db *sql.DB) error {
 			recorded_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 		);
 
+		ALTER TABLE bronze.youtube_stats ADD COLUMN IF NOT EXISTS channel_title VARCHAR(255);
+
 		CREATE TABLE IF NOT EXISTS bronze.github_stats (
 			id SERIAL PRIMARY KEY,
 			username VARCHAR(255),
@@ -195,6 +218,12 @@ func CreateStatsHistoryTables(db *sql.DB) error {
 			main_language VARCHAR(100),
 			recorded_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 		);
+
+		-- History queries filter by account and time range
+		CREATE INDEX IF NOT EXISTS idx_youtube_stats_channel_time ON bronze.youtube_stats (LOWER(channel_id), recorded_at);
+		CREATE INDEX IF NOT EXISTS idx_github_stats_user_time ON bronze.github_stats (LOWER(username), recorded_at);
+		CREATE INDEX IF NOT EXISTS idx_twitch_stats_user_time ON bronze.twitch_stats (LOWER(username), recorded_at);
+		CREATE INDEX IF NOT EXISTS idx_leetcode_stats_user_time ON bronze.leetcode_stats (LOWER(username), recorded_at);
 	`)
 	return err
 }
@@ -257,6 +286,8 @@ func CreateTwitchActivitiesTables(db *sql.DB) error {
 			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 		);
 
+		ALTER TABLE bronze.twitch_followers ADD COLUMN IF NOT EXISTS channel VARCHAR(255);
+
 		CREATE INDEX IF NOT EXISTS idx_twitch_followers_user_id ON bronze.twitch_followers(user_id);
 		CREATE INDEX IF NOT EXISTS idx_twitch_followers_followed_at ON bronze.twitch_followers(followed_at);
 		CREATE INDEX IF NOT EXISTS idx_twitch_raids_created_at ON bronze.twitch_raids(created_at);
@@ -264,6 +295,24 @@ func CreateTwitchActivitiesTables(db *sql.DB) error {
 		CREATE INDEX IF NOT EXISTS idx_twitch_subs_created_at ON bronze.twitch_subs(created_at);
 		CREATE INDEX IF NOT EXISTS idx_twitch_bits_user_id ON bronze.twitch_bits(user_id);
 		CREATE INDEX IF NOT EXISTS idx_twitch_bits_created_at ON bronze.twitch_bits(created_at);
+
+		CREATE TABLE IF NOT EXISTS bronze.twitch_events (
+			id SERIAL PRIMARY KEY,
+			message_id VARCHAR(255) UNIQUE NOT NULL,
+			event_type VARCHAR(100) NOT NULL,
+			event_version VARCHAR(10) NOT NULL,
+			broadcaster_user_id VARCHAR(255),
+			user_id VARCHAR(255),
+			user_login VARCHAR(255),
+			user_name VARCHAR(255),
+			event JSONB NOT NULL,
+			occurred_at TIMESTAMPTZ NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+
+		ALTER TABLE bronze.twitch_events ADD COLUMN IF NOT EXISTS channel VARCHAR(255);
+
+		CREATE INDEX IF NOT EXISTS idx_twitch_events_type_time ON bronze.twitch_events(event_type, occurred_at);
 	`)
 	return err
 }
@@ -317,3 +366,180 @@ func CreateAuthSessionsTable(db *sql.DB) error {
 	`)
 	return err
 }
+
+func CreateTwitchBotCommandsTable(db *sql.DB) error {
+	// Ensure bronze schema exists
+	if err := CreateBronzeSchema(db); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS bronze.twitch_bot_commands (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(50) UNIQUE NOT NULL,
+			response TEXT NOT NULL,
+			aliases TEXT NOT NULL DEFAULT '',
+			permission VARCHAR(20) NOT NULL DEFAULT 'everyone',
+			cooldown_seconds INT NOT NULL DEFAULT 5,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	return err
+}
+
+func CreateAlertTemplatesTable(db *sql.DB) error {
+	// Ensure bronze schema exists
+	if err := CreateBronzeSchema(db); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS bronze.alert_templates (
+			type VARCHAR(50) PRIMARY KEY,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			text TEXT NOT NULL,
+			duration_seconds INT NOT NULL DEFAULT 6,
+			priority INT NOT NULL DEFAULT 0,
+			min_amount INT NOT NULL DEFAULT 0,
+			image_url TEXT NOT NULL DEFAULT '',
+			sound_url TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	return err
+}
+
+func CreateStreamSessionsTable(db *sql.DB) error {
+	// Ensure bronze schema exists
+	if err := CreateBronzeSchema(db); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS bronze.stream_sessions (
+			id SERIAL PRIMARY KEY,
+			channel VARCHAR(255) NOT NULL,
+			source VARCHAR(20) NOT NULL,
+			twitch_stream_id VARCHAR(255),
+			started_at TIMESTAMPTZ NOT NULL,
+			ended_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- At most one live session per channel
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_stream_sessions_open ON bronze.stream_sessions(channel) WHERE ended_at IS NULL;
+		CREATE INDEX IF NOT EXISTS idx_stream_sessions_started_at ON bronze.stream_sessions(started_at);
+	`)
+	return err
+}
+
+// CreateTwitchAnalyticsTables creates the chat rollup tables, keyed by channel. They are
+// filled incrementally from bronze.twitch_messages by the analytics rollup job.
+func CreateTwitchAnalyticsTables(db *sql.DB) error {
+	// Ensure bronze schema exists
+	if err := CreateBronzeSchema(db); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`
+		-- Rollups from before they were kept per channel are dropped; the job rebuilds them
+		-- from bronze.twitch_messages
+		DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM information_schema.tables
+					   WHERE table_schema = 'bronze' AND table_name = 'twitch_chatters')
+			   AND NOT EXISTS (SELECT 1 FROM information_schema.columns
+							   WHERE table_schema = 'bronze' AND table_name = 'twitch_chatters' AND column_name = 'channel') THEN
+				DROP TABLE IF EXISTS bronze.twitch_chatters, bronze.twitch_chatter_daily, bronze.twitch_chat_hourly,
+					bronze.twitch_word_daily, bronze.twitch_emote_daily, bronze.twitch_chat_rollup_state;
+			END IF;
+		END $$;
+
+		CREATE TABLE IF NOT EXISTS bronze.twitch_chatters (
+			channel VARCHAR(25) NOT NULL,
+			username VARCHAR(25) NOT NULL,
+			display_name VARCHAR(25),
+			messages BIGINT NOT NULL DEFAULT 0,
+			first_seen TIMESTAMPTZ NOT NULL,
+			last_seen TIMESTAMPTZ NOT NULL,
+			current_streak INT NOT NULL DEFAULT 0,
+			longest_streak INT NOT NULL DEFAULT 0,
+			last_streak_day DATE,
+			PRIMARY KEY (channel, username)
+		);
+
+		CREATE TABLE IF NOT EXISTS bronze.twitch_chatter_daily (
+			channel VARCHAR(25) NOT NULL,
+			day DATE NOT NULL,
+			username VARCHAR(25) NOT NULL,
+			messages INT NOT NULL,
+			PRIMARY KEY (channel, day, username)
+		);
+
+		CREATE TABLE IF NOT EXISTS bronze.twitch_chat_hourly (
+			channel VARCHAR(25) NOT NULL,
+			hour TIMESTAMPTZ NOT NULL,
+			messages INT NOT NULL,
+			PRIMARY KEY (channel, hour)
+		);
+
+		CREATE TABLE IF NOT EXISTS bronze.twitch_word_daily (
+			channel VARCHAR(25) NOT NULL,
+			day DATE NOT NULL,
+			word VARCHAR(50) NOT NULL,
+			count INT NOT NULL,
+			PRIMARY KEY (channel, day, word)
+		);
+
+		CREATE TABLE IF NOT EXISTS bronze.twitch_emote_daily (
+			channel VARCHAR(25) NOT NULL,
+			day DATE NOT NULL,
+			emote_id VARCHAR(100) NOT NULL,
+			emote_name VARCHAR(100) NOT NULL,
+			count INT NOT NULL,
+			PRIMARY KEY (channel, day, emote_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS bronze.twitch_chat_rollup_state (
+			name VARCHAR(50) NOT NULL,
+			channel VARCHAR(25) NOT NULL,
+			last_message_id BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (name, channel)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_twitch_chatter_daily_username ON bronze.twitch_chatter_daily(channel, username, day);
+	`)
+	return err
+}
+
+// CreateOAuthTokensTable creates the shared token store. Tokens are encrypted by the
+// services layer before they get here.
+func CreateOAuthTokensTable(db *sql.DB) error {
+	// Ensure bronze schema exists
+	if err := CreateBronzeSchema(db); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS bronze.oauth_tokens (
+			provider VARCHAR(50) NOT NULL,
+			account VARCHAR(255) NOT NULL,
+			access_token TEXT NOT NULL,
+			refresh_token TEXT NOT NULL DEFAULT '',
+			token_type VARCHAR(50) NOT NULL DEFAULT 'bearer',
+			scopes TEXT NOT NULL DEFAULT '',
+			expires_at TIMESTAMPTZ NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'ok',
+			last_error TEXT NOT NULL DEFAULT '',
+			last_refresh_at TIMESTAMPTZ,
+			failures INT NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (provider, account)
+		);
+	`)
+	return err
+}
@@ -9,11 +9,16 @@ func InitializeDatabaseTables(dbConn *sql.DB) {
 	CreateCheckinsTable(dbConn)
 	CreateSpotifyTokensTable(dbConn)
 	CreateTwitchTokensTable(dbConn)
+	CreateOAuthTokensTable(dbConn)
 	CreateTwitchMessagesTable(dbConn)
+	CreateTwitchAnalyticsTables(dbConn)
 	CreateStatsHistoryTables(dbConn)
 	CreateTwitchActivitiesTables(dbConn)
 	CreateUsersTable(dbConn)
 	CreateAuthSessionsTable(dbConn)
+	CreateTwitchBotCommandsTable(dbConn)
+	CreateAlertTemplatesTable(dbConn)
+	CreateStreamSessionsTable(dbConn)
 
 	// Vector tables for RAG
 	CreateVectorTables(dbConn)
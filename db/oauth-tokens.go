@@ -0,0 +1,108 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+const oauthTokenColumns = `provider, account, access_token, refresh_token, token_type, scopes, expires_at,
+	status, last_error, last_refresh_at, failures`
+
+func scanOAuthToken(row interface{ Scan(...interface{}) error }) (models.StoredOAuthToken, error) {
+	var t models.StoredOAuthToken
+	var lastRefresh sql.NullTime
+	err := row.Scan(&t.Provider, &t.Account, &t.AccessTokenEnc, &t.RefreshTokenEnc, &t.TokenType, &t.Scopes,
+		&t.ExpiresAt, &t.Status, &t.LastError, &lastRefresh, &t.Failures)
+	if lastRefresh.Valid {
+		t.LastRefreshAt = &lastRefresh.Time
+	}
+	return t, err
+}
+
+// SaveOAuthToken stores a token and resets its refresh status
+func SaveOAuthToken(db *sql.DB, t models.StoredOAuthToken) error {
+	_, err := db.Exec(`
+		INSERT INTO bronze.oauth_tokens (provider, account, access_token, refresh_token, token_type, scopes,
+										 expires_at, status, last_error, last_refresh_at, failures)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, '', $9, 0)
+		ON CONFLICT (provider, account) DO UPDATE SET
+			access_token = EXCLUDED.access_token,
+			refresh_token = EXCLUDED.refresh_token,
+			token_type = EXCLUDED.token_type,
+			scopes = EXCLUDED.scopes,
+			expires_at = EXCLUDED.expires_at,
+			status = EXCLUDED.status,
+			last_error = '',
+			last_refresh_at = COALESCE(EXCLUDED.last_refresh_at, oauth_tokens.last_refresh_at),
+			failures = 0,
+			updated_at = CURRENT_TIMESTAMP
+	`, t.Provider, t.Account, t.AccessTokenEnc, t.RefreshTokenEnc, t.TokenType, t.Scopes,
+		t.ExpiresAt, models.TokenOK, t.LastRefreshAt)
+	return err
+}
+
+// LockOAuthToken takes a Postgres advisory lock on one token, so only one process refreshes it
+// at a time. The lock is held until the returned unlock is called, and gives up after 30s.
+func LockOAuthToken(db *sql.DB, provider, account string) (func(), error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`SET LOCAL lock_timeout = '30s'`); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// Transaction-scoped, so the lock is released even if the connection is reused
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('oauth_token:' || $1::text || '|' || $2::text))`, provider, account); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return func() { tx.Rollback() }, nil
+}
+
+// RecordOAuthRefreshFailure marks a failed refresh so it shows up in the status endpoints
+func RecordOAuthRefreshFailure(db *sql.DB, provider, account, status, message string) error {
+	_, err := db.Exec(`
+		UPDATE bronze.oauth_tokens
+		SET status = $3, last_error = $4, failures = failures + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE provider = $1 AND account = $2
+	`, provider, account, status, message)
+	return err
+}
+
+// GetOAuthToken returns a stored token. An empty account picks the most recently saved one.
+// It returns sql.ErrNoRows if there is none.
+func GetOAuthToken(db *sql.DB, provider, account string) (models.StoredOAuthToken, error) {
+	return scanOAuthToken(db.QueryRow(`
+		SELECT `+oauthTokenColumns+`
+		FROM bronze.oauth_tokens
+		WHERE provider = $1 AND ($2 = '' OR account = $2)
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`, provider, account))
+}
+
+// GetExpiringOAuthTokens returns refreshable tokens that expire before the given time
+func GetExpiringOAuthTokens(db *sql.DB, before time.Time) ([]models.StoredOAuthToken, error) {
+	rows, err := db.Query(`
+		SELECT `+oauthTokenColumns+`
+		FROM bronze.oauth_tokens
+		WHERE expires_at < $1 AND refresh_token <> '' AND status <> $2
+	`, before, models.TokenReauthRequired)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.StoredOAuthToken
+	for rows.Next() {
+		t, err := scanOAuthToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
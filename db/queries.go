@@ -49,9 +49,29 @@ func GetRecentMessages(db *sql.DB, limit int) ([]models.Message, error) {
 	return messages, nil
 }
 
-func InsertCheckin(db *sql.DB, lat, lon float64, city, country string) error {
-	_, err := db.Exec(`INSERT INTO checkins (lat, lon, city, country) VALUES ($1, $2, $3, $4)`, lat, lon, city, country)
-	return err
+// InsertCheckin stores a checkin. It returns sql.ErrNoRows if the user already checked in
+// to the same stream.
+func InsertCheckin(db *sql.DB, c models.Checkin) (models.Checkin, error) {
+	err := db.QueryRow(`
+		INSERT INTO checkins (lat, lon, city, country, username, platform, stream_key)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''))
+		ON CONFLICT DO NOTHING
+		RETURNING id, checkin_time
+	`, c.Lat, c.Lon, c.City, c.Country, c.Username, c.Platform, c.StreamKey).Scan(&c.ID, &c.CheckinTime)
+	return c, err
+}
+
+// GetStreamCheckin returns a user's existing checkin for a stream, or sql.ErrNoRows if there is none
+func GetStreamCheckin(db *sql.DB, username, platform, streamKey string) (models.Checkin, error) {
+	var c models.Checkin
+	err := db.QueryRow(`
+		SELECT id, lat, lon, COALESCE(city, ''), COALESCE(country, ''), COALESCE(username, ''), COALESCE(platform, ''), checkin_time
+		FROM checkins
+		WHERE LOWER(username) = LOWER($1) AND platform = $2 AND stream_key = $3
+		ORDER BY checkin_time DESC
+		LIMIT 1
+	`, username, platform, streamKey).Scan(&c.ID, &c.Lat, &c.Lon, &c.City, &c.Country, &c.Username, &c.Platform, &c.CheckinTime)
+	return c, err
 }
 
 // CheckCityExists checks if a city already exists in the database
@@ -65,7 +85,7 @@ func CheckCityExists(db *sql.DB, city string) (bool, error) {
 }
 
 func GetCheckins(db *sql.DB) ([]models.Checkin, error) {
-	rows, err := db.Query(`SELECT id, lat, lon, COALESCE(city, ''), COALESCE(country, ''), checkin_time FROM checkins ORDER BY checkin_time DESC`)
+	rows, err := db.Query(`SELECT id, lat, lon, COALESCE(city, ''), COALESCE(country, ''), COALESCE(username, ''), COALESCE(platform, ''), checkin_time FROM checkins ORDER BY checkin_time DESC`)
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +94,7 @@ func GetCheckins(db *sql.DB) ([]models.Checkin, error) {
 	var checkins []models.Checkin
 	for rows.Next() {
 		var c models.Checkin
-		if err := rows.Scan(&c.ID, &c.Lat, &c.Lon, &c.City, &c.Country, &c.CheckinTime); err != nil {
+		if err := rows.Scan(&c.ID, &c.Lat, &c.Lon, &c.City, &c.Country, &c.Username, &c.Platform, &c.CheckinTime); err != nil {
 			return nil, err
 		}
 		checkins = append(checkins, c)
@@ -82,21 +102,7 @@ func GetCheckins(db *sql.DB) ([]models.Checkin, error) {
 	return checkins, nil
 }
 
-// Spotify token functions
-func SaveSpotifyToken(db *sql.DB, accessToken, refreshToken, tokenType string, expiresAt time.Time) error {
-	// First, clear any existing tokens (we only store one at a time)
-	_, err := db.Exec(`DELETE FROM spotify_tokens`)
-	if err != nil {
-		return err
-	}
-
-	// Insert new token
-	_, err = db.Exec(`
-		INSERT INTO spotify_tokens (access_token, refresh_token, token_type, expires_at) 
-		VALUES ($1, $2, $3, $4)
-	`, accessToken, refreshToken, tokenType, expiresAt)
-	return err
-}
+// Legacy single-row token tables, read once to migrate into bronze.oauth_tokens
 
 func GetSpotifyToken(db *sql.DB) (accessToken, refreshToken, tokenType string, expiresAt time.Time, err error) {
 	err = db.QueryRow(`
@@ -108,31 +114,6 @@ func GetSpotifyToken(db *sql.DB) (accessToken, refreshToken, tokenType string, e
 	return
 }
 
-func UpdateSpotifyToken(db *sql.DB, accessToken, refreshToken string, expiresAt time.Time) error {
-	_, err := db.Exec(`
-		UPDATE spotify_tokens
-		SET access_token = $1, refresh_token = $2, expires_at = $3, updated_at = CURRENT_TIMESTAMP
-		WHERE id = (SELECT id FROM spotify_tokens ORDER BY created_at DESC LIMIT 1)
-	`, accessToken, refreshToken, expiresAt)
-	return err
-}
-
-// Twitch token functions
-func SaveTwitchToken(db *sql.DB, accessToken, refreshToken, tokenType, scopes string, expiresAt time.Time) error {
-	// First, clear any existing tokens (we only store one at a time)
-	_, err := db.Exec(`DELETE FROM twitch_tokens`)
-	if err != nil {
-		return err
-	}
-
-	// Insert new token
-	_, err = db.Exec(`
-		INSERT INTO twitch_tokens (access_token, refresh_token, token_type, scopes, expires_at)
-		VALUES ($1, $2, $3, $4, $5)
-	`, accessToken, refreshToken, tokenType, scopes, expiresAt)
-	return err
-}
-
 func GetTwitchToken(db *sql.DB) (accessToken, refreshToken, tokenType, scopes string, expiresAt time.Time, err error) {
 	err = db.QueryRow(`
 		SELECT access_token, COALESCE(refresh_token, ''), token_type, COALESCE(scopes, ''), expires_at
@@ -143,18 +124,19 @@ func GetTwitchToken(db *sql.DB) (accessToken, refreshToken, tokenType, scopes st
 	return
 }
 
-func UpdateTwitchToken(db *sql.DB, accessToken, refreshToken string, expiresAt time.Time) error {
-	_, err := db.Exec(`
-		UPDATE twitch_tokens
-		SET access_token = $1, refresh_token = $2, expires_at = $3, updated_at = CURRENT_TIMESTAMP
-		WHERE id = (SELECT id FROM twitch_tokens ORDER BY created_at DESC LIMIT 1)
-	`, accessToken, refreshToken, expiresAt)
+// ClearLegacyTokens removes the plaintext rows once they've been migrated
+func ClearLegacyTokens(db *sql.DB, provider string) error {
+	table := "twitch_tokens"
+	if provider == models.ProviderSpotify {
+		table = "spotify_tokens"
+	}
+	_, err := db.Exec(`DELETE FROM ` + table)
 	return err
 }
 
 func GetRecentCheckins(db *sql.DB, hoursBack int) ([]models.Checkin, error) {
 	query := fmt.Sprintf(`
-		SELECT id, lat, lon, COALESCE(city, ''), COALESCE(country, ''), checkin_time 
+		SELECT id, lat, lon, COALESCE(city, ''), COALESCE(country, ''), COALESCE(username, ''), COALESCE(platform, ''), checkin_time 
 		FROM checkins 
 		WHERE checkin_time >= NOW() - INTERVAL '%d hours'
 		ORDER BY checkin_time DESC
@@ -169,7 +151,7 @@ func GetRecentCheckins(db *sql.DB, hoursBack int) ([]models.Checkin, error) {
 	var checkins []models.Checkin
 	for rows.Next() {
 		var c models.Checkin
-		if err := rows.Scan(&c.ID, &c.Lat, &c.Lon, &c.City, &c.Country, &c.CheckinTime); err != nil {
+		if err := rows.Scan(&c.ID, &c.Lat, &c.Lon, &c.City, &c.Country, &c.Username, &c.Platform, &c.CheckinTime); err != nil {
 			return nil, err
 		}
 		checkins = append(checkins, c)
@@ -188,14 +170,35 @@ func InsertTwitchMessage(db *sql.DB, message models.TwitchMessage) error {
 		}
 	}
 
+	var emotesJSON interface{}
+	if len(message.Emotes) > 0 {
+		if emotesBytes, err := json.Marshal(message.Emotes); err == nil {
+			emotesJSON = string(emotesBytes)
+		}
+	}
+
 	_, err := db.Exec(`
-		INSERT INTO twitch_messages (username, display_name, message, color, badges, is_mod, is_vip, is_broadcaster, time) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`, message.Username, message.DisplayName, message.Message, message.Color,
-		badgesJSON, message.IsMod, message.IsVip, message.IsBroadcaster, message.Time)
+		INSERT INTO twitch_messages (channel, username, display_name, message, color, badges, is_mod, is_vip, is_broadcaster, emotes, time) 
+		VALUES (NULLIF($1, ''), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, message.Channel, message.Username, message.DisplayName, message.Message, message.Color,
+		badgesJSON, message.IsMod, message.IsVip, message.IsBroadcaster, emotesJSON, message.Time)
 	return err
 }
 
+// AssignChannelToLegacyTwitchRows tags chat, follower and event rows recorded before
+// they carried a channel. They all came from the single channel tracked back then.
+func AssignChannelToLegacyTwitchRows(db *sql.DB, channel string) error {
+	if channel == "" {
+		return nil
+	}
+	for _, table := range []string{"twitch_messages", "twitch_followers", "twitch_events"} {
+		if _, err := db.Exec(`UPDATE bronze.`+table+` SET channel = $1 WHERE channel IS NULL`, channel); err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+	}
+	return nil
+}
+
 // GetRecentTwitchMessages fetches the most recent Twitch messages up to the given limit
 func GetRecentTwitchMessages(db *sql.DB, limit int) ([]models.TwitchMessage, error) {
 	rows, err := db.Query(`
@@ -222,11 +225,11 @@ func GetRecentTwitchMessages(db *sql.DB, limit int) ([]models.TwitchMessage, err
 }
 
 // Stats storage functions
-func InsertYouTubeStats(db *sql.DB, channelID string, subscribers, videos int, views int64) error {
+func InsertYouTubeStats(db *sql.DB, channelID, channelTitle string, subscribers, videos int, views int64) error {
 	_, err := db.Exec(`
-		INSERT INTO youtube_stats (channel_id, subscriber_count, video_count, view_count) 
-		VALUES ($1, $2, $3, $4)
-	`, channelID, subscribers, videos, views)
+		INSERT INTO youtube_stats (channel_id, channel_title, subscriber_count, video_count, view_count) 
+		VALUES ($1, $2, $3, $4, $5)
+	`, channelID, channelTitle, subscribers, videos, views)
 	return err
 }
 
@@ -270,42 +273,45 @@ func GetLatestLeetCodeStats(db *sql.DB, username string) (*models.LeetCodeStats,
 	return &stats, nil
 }
 
-func GetLatestYouTubeStats(db *sql.DB) (*models.YouTubeStats, error) {
+func GetLatestYouTubeStats(db *sql.DB, channelID string) (*models.YouTubeStats, error) {
 	var stats models.YouTubeStats
 	err := db.QueryRow(`
-		SELECT channel_id, subscriber_count, view_count, video_count
+		SELECT COALESCE(channel_title, channel_id), subscriber_count, view_count, video_count
 		FROM bronze.youtube_stats
+		WHERE channel_id = $1
 		ORDER BY recorded_at DESC
 		LIMIT 1
-	`).Scan(&stats.ChannelName, &stats.Subscribers, &stats.Views, &stats.Videos)
+	`, channelID).Scan(&stats.ChannelName, &stats.Subscribers, &stats.Views, &stats.Videos)
 	if err != nil {
 		return nil, err
 	}
 	return &stats, nil
 }
 
-func GetLatestGitHubStats(db *sql.DB) (*models.GitHubStats, error) {
+func GetLatestGitHubStats(db *sql.DB, username string) (*models.GitHubStats, error) {
 	var stats models.GitHubStats
 	err := db.QueryRow(`
 		SELECT username, public_repos, followers, total_stars
 		FROM bronze.github_stats
+		WHERE LOWER(username) = LOWER($1)
 		ORDER BY recorded_at DESC
 		LIMIT 1
-	`).Scan(&stats.Username, &stats.PublicRepos, &stats.Followers, &stats.StarsReceived)
+	`, username).Scan(&stats.Username, &stats.PublicRepos, &stats.Followers, &stats.StarsReceived)
 	if err != nil {
 		return nil, err
 	}
 	return &stats, nil
 }
 
-func GetLatestTwitchStats(db *sql.DB) (*models.TwitchStats, error) {
+func GetLatestTwitchStats(db *sql.DB, username string) (*models.TwitchStats, error) {
 	var stats models.TwitchStats
 	err := db.QueryRow(`
 		SELECT username, follower_count
 		FROM bronze.twitch_stats
+		WHERE LOWER(username) = LOWER($1)
 		ORDER BY recorded_at DESC
 		LIMIT 1
-	`).Scan(&stats.DisplayName, &stats.Followers)
+	`, username).Scan(&stats.DisplayName, &stats.Followers)
 	if err != nil {
 		return nil, err
 	}
@@ -315,12 +321,29 @@ func GetLatestTwitchStats(db *sql.DB) (*models.TwitchStats, error) {
 // Twitch Activities insert functions
 func InsertTwitchFollower(db *sql.DB, follower models.TwitchFollower) error {
 	_, err := db.Exec(`
-		INSERT INTO twitch_followers (user_id, user_login, user_name, followed_at)
-		VALUES ($1, $2, $3, $4)
-	`, follower.UserID, follower.UserLogin, follower.UserName, follower.FollowedAt)
+		INSERT INTO twitch_followers (channel, user_id, user_login, user_name, followed_at)
+		VALUES (NULLIF($1, ''), $2, $3, $4, $5)
+	`, follower.Channel, follower.UserID, follower.UserLogin, follower.UserName, follower.FollowedAt)
 	return err
 }
 
+// InsertTwitchEvent stores a raw EventSub notification. It returns false if the
+// message was already stored, so redelivered notifications can be skipped.
+func InsertTwitchEvent(db *sql.DB, event models.TwitchEvent) (bool, error) {
+	res, err := db.Exec(`
+		INSERT INTO twitch_events (message_id, event_type, event_version, channel, broadcaster_user_id,
+								   user_id, user_login, user_name, event, occurred_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9, $10)
+		ON CONFLICT (message_id) DO NOTHING
+	`, event.MessageID, event.Type, event.Version, event.Channel, event.BroadcasterUserID,
+		event.UserID, event.UserLogin, event.UserName, string(event.Event), event.OccurredAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
 func InsertTwitchRaid(db *sql.DB, raid models.TwitchRaid) error {
 	_, err := db.Exec(`
 		INSERT INTO twitch_raids (from_broadcaster_user_id, from_broadcaster_user_login, from_broadcaster_user_name,
@@ -352,13 +375,14 @@ func InsertTwitchBits(db *sql.DB, bits models.TwitchBits) error {
 }
 
 // Get functions for Twitch activities
-func GetRecentTwitchFollowers(db *sql.DB, limit int) ([]models.TwitchFollower, error) {
+func GetRecentTwitchFollowers(db *sql.DB, channel string, limit int) ([]models.TwitchFollower, error) {
 	rows, err := db.Query(`
-		SELECT id, user_id, user_login, user_name, followed_at, created_at
+		SELECT id, COALESCE(channel, ''), user_id, user_login, user_name, followed_at, created_at
 		FROM twitch_followers
+		WHERE channel = $1
 		ORDER BY followed_at DESC
-		LIMIT $1
-	`, limit)
+		LIMIT $2
+	`, channel, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -367,7 +391,7 @@ func GetRecentTwitchFollowers(db *sql.DB, limit int) ([]models.TwitchFollower, e
 	var followers []models.TwitchFollower
 	for rows.Next() {
 		var f models.TwitchFollower
-		if err := rows.Scan(&f.ID, &f.UserID, &f.UserLogin, &f.UserName, &f.FollowedAt, &f.CreatedAt); err != nil {
+		if err := rows.Scan(&f.ID, &f.Channel, &f.UserID, &f.UserLogin, &f.UserName, &f.FollowedAt, &f.CreatedAt); err != nil {
 			return nil, err
 		}
 		followers = append(followers, f)
@@ -375,14 +399,15 @@ func GetRecentTwitchFollowers(db *sql.DB, limit int) ([]models.TwitchFollower, e
 	return followers, nil
 }
 
-func GetRecentTwitchRaids(db *sql.DB, limit int) ([]models.TwitchRaid, error) {
+func GetRecentTwitchRaids(db *sql.DB, channel string, limit int) ([]models.TwitchRaid, error) {
 	rows, err := db.Query(`
 		SELECT id, from_broadcaster_user_id, from_broadcaster_user_login, from_broadcaster_user_name,
 			   to_broadcaster_user_id, to_broadcaster_user_login, to_broadcaster_user_name, viewers, created_at
 		FROM twitch_raids
+		WHERE to_broadcaster_user_login = $1
 		ORDER BY created_at DESC
-		LIMIT $1
-	`, limit)
+		LIMIT $2
+	`, channel, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -400,14 +425,15 @@ func GetRecentTwitchRaids(db *sql.DB, limit int) ([]models.TwitchRaid, error) {
 	return raids, nil
 }
 
-func GetRecentTwitchSubs(db *sql.DB, limit int) ([]models.TwitchSub, error) {
+func GetRecentTwitchSubs(db *sql.DB, channel string, limit int) ([]models.TwitchSub, error) {
 	rows, err := db.Query(`
 		SELECT id, user_id, user_login, user_name, broadcaster_user_id, broadcaster_user_login,
 			   broadcaster_user_name, tier, is_gift, gifter_user_id, gifter_user_login, gifter_user_name, created_at
 		FROM twitch_subs
+		WHERE broadcaster_user_login = $1
 		ORDER BY created_at DESC
-		LIMIT $1
-	`, limit)
+		LIMIT $2
+	`, channel, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -425,14 +451,15 @@ func GetRecentTwitchSubs(db *sql.DB, limit int) ([]models.TwitchSub, error) {
 	return subs, nil
 }
 
-func GetRecentTwitchBits(db *sql.DB, limit int) ([]models.TwitchBits, error) {
+func GetRecentTwitchBits(db *sql.DB, channel string, limit int) ([]models.TwitchBits, error) {
 	rows, err := db.Query(`
 		SELECT id, user_id, user_login, user_name, broadcaster_user_id, broadcaster_user_login,
 			   broadcaster_user_name, is_anonymous, message, bits, created_at
 		FROM twitch_bits
+		WHERE broadcaster_user_login = $1
 		ORDER BY created_at DESC
-		LIMIT $1
-	`, limit)
+		LIMIT $2
+	`, channel, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+// StatsHistoryColumn names a metric column in one of the stats history tables. The names are
+// interpolated into SQL, so they must only ever come from the services' metric catalog.
+type StatsHistoryColumn struct {
+	Table   string // e.g. github_stats
+	Account string // channel_id or username
+	Column  string // e.g. followers
+}
+
+// GetStatsHistoryBuckets groups a metric's samples for an account into buckets of bucketSeconds
+func GetStatsHistoryBuckets(db *sql.DB, col StatsHistoryColumn, account string, from, to time.Time, bucketSeconds int) ([]models.StatsBucket, error) {
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT to_timestamp(floor(extract(epoch FROM recorded_at) / $4) * $4) AS bucket,
+			MIN(%[3]s), MAX(%[3]s),
+			(array_agg(%[3]s ORDER BY recorded_at))[1],
+			(array_agg(%[3]s ORDER BY recorded_at DESC))[1],
+			COUNT(*), MIN(recorded_at), MAX(recorded_at)
+		FROM bronze.%[1]s
+		WHERE LOWER(%[2]s) = LOWER($1) AND recorded_at >= $2 AND recorded_at < $3 AND %[3]s IS NOT NULL
+		GROUP BY bucket
+		ORDER BY bucket
+	`, col.Table, col.Account, col.Column), account, from, to, bucketSeconds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []models.StatsBucket{}
+	for rows.Next() {
+		var b models.StatsBucket
+		if err := rows.Scan(&b.Start, &b.Min, &b.Max, &b.First, &b.Last, &b.Samples, &b.FirstAt, &b.LastAt); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// GetStatsHistoryChanges returns the samples in [from, to) whose value differs from the sample
+// before them, which may have been recorded before from
+func GetStatsHistoryChanges(db *sql.DB, col StatsHistoryColumn, account string, from, to time.Time) ([]models.StatsChange, error) {
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT recorded_at, prev, val FROM (
+			SELECT recorded_at, %[3]s AS val, LAG(%[3]s) OVER (ORDER BY recorded_at) AS prev
+			FROM bronze.%[1]s
+			WHERE LOWER(%[2]s) = LOWER($1) AND recorded_at < $3 AND %[3]s IS NOT NULL
+		) samples
+		WHERE recorded_at >= $2 AND prev IS DISTINCT FROM val
+		ORDER BY recorded_at
+	`, col.Table, col.Account, col.Column), account, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.StatsChange
+	for rows.Next() {
+		var c models.StatsChange
+		var prev sql.NullInt64
+		if err := rows.Scan(&c.At, &prev, &c.Value); err != nil {
+			return nil, err
+		}
+		if prev.Valid {
+			c.Previous = &prev.Int64
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,251 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"majesticcoding.com/api/models"
+)
+
+const streamSessionColumns = `id, channel, source, COALESCE(twitch_stream_id, ''), started_at, ended_at`
+
+func scanStreamSession(row interface{ Scan(...interface{}) error }) (models.StreamSession, error) {
+	var s models.StreamSession
+	var endedAt sql.NullTime
+	if err := row.Scan(&s.ID, &s.Channel, &s.Source, &s.TwitchID, &s.StartedAt, &endedAt); err != nil {
+		return s, err
+	}
+	if endedAt.Valid {
+		s.EndedAt = &endedAt.Time
+	}
+	return s, nil
+}
+
+// GetOpenStreamSession returns the live session for a channel, or sql.ErrNoRows
+func GetOpenStreamSession(db *sql.DB, channel string) (models.StreamSession, error) {
+	return scanStreamSession(db.QueryRow(`
+		SELECT `+streamSessionColumns+`
+		FROM bronze.stream_sessions
+		WHERE channel = $1 AND ended_at IS NULL
+	`, channel))
+}
+
+// StartStreamSession opens a session. If the channel already has one open, that session
+// is returned instead and started is false.
+func StartStreamSession(db *sql.DB, s models.StreamSession) (session models.StreamSession, started bool, err error) {
+	session, err = scanStreamSession(db.QueryRow(`
+		INSERT INTO bronze.stream_sessions (channel, source, twitch_stream_id, started_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		ON CONFLICT (channel) WHERE ended_at IS NULL DO NOTHING
+		RETURNING `+streamSessionColumns,
+		s.Channel, s.Source, s.TwitchID, s.StartedAt))
+	if err == sql.ErrNoRows {
+		session, err = GetOpenStreamSession(db, s.Channel)
+		return session, false, err
+	}
+	return session, err == nil, err
+}
+
+// SetStreamSessionTwitchID records Twitch's stream id and start time on a session
+// opened before the stream.online notification arrived
+func SetStreamSessionTwitchID(db *sql.DB, id int, twitchID string, startedAt time.Time) error {
+	_, err := db.Exec(`
+		UPDATE bronze.stream_sessions
+		SET twitch_stream_id = $2, started_at = LEAST(started_at, $3)
+		WHERE id = $1
+	`, id, twitchID, startedAt)
+	return err
+}
+
+// EndStreamSession closes a session
+func EndStreamSession(db *sql.DB, id int, endedAt time.Time) error {
+	_, err := db.Exec(`
+		UPDATE bronze.stream_sessions SET ended_at = $2 WHERE id = $1 AND ended_at IS NULL
+	`, id, endedAt)
+	return err
+}
+
+// GetStreamSession returns a session by id, or sql.ErrNoRows
+func GetStreamSession(db *sql.DB, id int) (models.StreamSession, error) {
+	return scanStreamSession(db.QueryRow(`
+		SELECT `+streamSessionColumns+`
+		FROM bronze.stream_sessions
+		WHERE id = $1
+	`, id))
+}
+
+// GetStreamSessions returns a channel's most recent sessions, newest first
+func GetStreamSessions(db *sql.DB, channel string, limit int) ([]models.StreamSession, error) {
+	rows, err := db.Query(`
+		SELECT `+streamSessionColumns+`
+		FROM bronze.stream_sessions
+		WHERE channel = $1
+		ORDER BY started_at DESC
+		LIMIT $2
+	`, channel, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.StreamSession{}
+	for rows.Next() {
+		s, err := scanStreamSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// GetStreamActivity fills the chat, follower, raid, sub, bit and checkin sections of a
+// report for everything on the channel between from and to. Chat is grouped into buckets
+// of bucketSeconds. Checkins are site-wide, so they aren't filtered by channel.
+func GetStreamActivity(db *sql.DB, report *models.StreamReport, channel string, from, to time.Time, bucketSeconds int) error {
+	err := db.QueryRow(`
+		SELECT COUNT(*), COUNT(DISTINCT LOWER(username))
+		FROM bronze.twitch_messages
+		WHERE time >= $1 AND time < $2 AND channel = $3
+	`, from, to, channel).Scan(&report.Messages, &report.UniqueChatters)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`
+		SELECT to_timestamp(floor(extract(epoch FROM time) / $3) * $3) AS bucket, COUNT(*)
+		FROM bronze.twitch_messages
+		WHERE time >= $1 AND time < $2 AND channel = $4
+		GROUP BY bucket
+		ORDER BY bucket
+	`, from, to, bucketSeconds, channel)
+	if err != nil {
+		return err
+	}
+	report.MessageRate = []models.StreamRateBucket{}
+	for rows.Next() {
+		var b models.StreamRateBucket
+		if err := rows.Scan(&b.Start, &b.Messages); err != nil {
+			rows.Close()
+			return err
+		}
+		report.MessageRate = append(report.MessageRate, b)
+	}
+	rows.Close()
+
+	if report.TopChatters, err = queryStreamCounts(db, `
+		SELECT MAX(COALESCE(NULLIF(display_name, ''), username)), COUNT(*) AS n
+		FROM bronze.twitch_messages
+		WHERE time >= $1 AND time < $2 AND channel = $3
+		GROUP BY LOWER(username)
+		ORDER BY n DESC
+		LIMIT 10
+	`, from, to, channel); err != nil {
+		return err
+	}
+
+	if report.CheckinCountries, err = queryStreamCounts(db, `
+		SELECT COALESCE(NULLIF(country, ''), 'Unknown'), COUNT(*) AS n
+		FROM bronze.checkins
+		WHERE checkin_time >= $1 AND checkin_time < $2
+		GROUP BY 1
+		ORDER BY n DESC
+	`, from, to); err != nil {
+		return err
+	}
+
+	rows, err = db.Query(`
+		SELECT user_name FROM bronze.twitch_followers
+		WHERE followed_at >= $1 AND followed_at < $2 AND channel = $3
+		ORDER BY followed_at
+	`, from, to, channel)
+	if err != nil {
+		return err
+	}
+	report.NewFollowers = []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		report.NewFollowers = append(report.NewFollowers, name)
+	}
+	rows.Close()
+
+	rows, err = db.Query(`
+		SELECT from_broadcaster_user_name, viewers, created_at FROM bronze.twitch_raids
+		WHERE created_at >= $1 AND created_at < $2 AND to_broadcaster_user_login = $3
+		ORDER BY created_at
+	`, from, to, channel)
+	if err != nil {
+		return err
+	}
+	report.Raids = []models.StreamRaidSource{}
+	for rows.Next() {
+		var r models.StreamRaidSource
+		if err := rows.Scan(&r.Channel, &r.Viewers, &r.At); err != nil {
+			rows.Close()
+			return err
+		}
+		report.Raids = append(report.Raids, r)
+	}
+	rows.Close()
+
+	// New and gifted subs come from channel.subscribe, renewals from channel.subscription.message
+	rows, err = db.Query(`
+		SELECT tier, is_gift, COUNT(*) FROM bronze.twitch_subs
+		WHERE created_at >= $1 AND created_at < $2 AND broadcaster_user_login = $3
+		GROUP BY tier, is_gift
+		UNION ALL
+		SELECT COALESCE(event->>'tier', '1000'), NULL, COUNT(*) FROM bronze.twitch_events
+		WHERE event_type = 'channel.subscription.message' AND occurred_at >= $1 AND occurred_at < $2 AND channel = $3
+		GROUP BY 1
+	`, from, to, channel)
+	if err != nil {
+		return err
+	}
+	report.Subs.ByTier = make(map[string]int)
+	for rows.Next() {
+		var tier string
+		var isGift sql.NullBool
+		var n int
+		if err := rows.Scan(&tier, &isGift, &n); err != nil {
+			rows.Close()
+			return err
+		}
+		switch {
+		case !isGift.Valid:
+			report.Subs.Resubs += n
+		case isGift.Bool:
+			report.Subs.Gifted += n
+		default:
+			report.Subs.New += n
+		}
+		report.Subs.ByTier[tier] += n
+	}
+	rows.Close()
+
+	return db.QueryRow(`
+		SELECT COALESCE(SUM(bits), 0) FROM bronze.twitch_bits
+		WHERE created_at >= $1 AND created_at < $2 AND broadcaster_user_login = $3
+	`, from, to, channel).Scan(&report.Bits)
+}
+
+func queryStreamCounts(db *sql.DB, query string, args ...interface{}) ([]models.StreamCount, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.StreamCount{}
+	for rows.Next() {
+		var c models.StreamCount
+		if err := rows.Scan(&c.Name, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
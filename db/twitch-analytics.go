@@ -0,0 +1,339 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"majesticcoding.com/api/models"
+)
+
+const chatRollupName = "twitch_chat"
+
+// GetChatRollupCursor returns the id of the last message included in a channel's chat rollups
+func GetChatRollupCursor(db *sql.DB, channel string) (int, error) {
+	var id int
+	err := db.QueryRow(`
+		SELECT last_message_id FROM bronze.twitch_chat_rollup_state WHERE name = $1 AND channel = $2
+	`, chatRollupName, channel).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// GetTwitchMessagesAfter returns up to limit of a channel's messages with an id greater than afterID,
+// oldest first. The newest few seconds are left out so an insert still in flight with a lower id isn't skipped.
+func GetTwitchMessagesAfter(db *sql.DB, channel string, afterID, limit int) ([]models.TwitchMessage, error) {
+	rows, err := db.Query(`
+		SELECT id, username, COALESCE(display_name, ''), message, emotes, time
+		FROM bronze.twitch_messages
+		WHERE id > $1 AND channel = $3 AND created_at < NOW() - INTERVAL '5 seconds'
+		ORDER BY id
+		LIMIT $2
+	`, afterID, limit, channel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []models.TwitchMessage
+	for rows.Next() {
+		var msg models.TwitchMessage
+		var emotes []byte
+		if err := rows.Scan(&msg.ID, &msg.Username, &msg.DisplayName, &msg.Message, &emotes, &msg.Time); err != nil {
+			return nil, err
+		}
+		if len(emotes) > 0 {
+			json.Unmarshal(emotes, &msg.Emotes)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// ApplyChatRollup adds a batch to a channel's rollups and advances its cursor in one transaction
+func ApplyChatRollup(db *sql.DB, channel string, r *models.ChatRollup) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for hour, n := range r.Hourly {
+		if _, err := tx.Exec(`
+			INSERT INTO bronze.twitch_chat_hourly (channel, hour, messages) VALUES ($1, $2, $3)
+			ON CONFLICT (channel, hour) DO UPDATE SET messages = twitch_chat_hourly.messages + EXCLUDED.messages
+		`, channel, hour, n); err != nil {
+			return err
+		}
+	}
+
+	for k, n := range r.Words {
+		if _, err := tx.Exec(`
+			INSERT INTO bronze.twitch_word_daily (channel, day, word, count) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (channel, day, word) DO UPDATE SET count = twitch_word_daily.count + EXCLUDED.count
+		`, channel, k.Day, k.Term, n); err != nil {
+			return err
+		}
+	}
+
+	for k, n := range r.Emotes {
+		if _, err := tx.Exec(`
+			INSERT INTO bronze.twitch_emote_daily (channel, day, emote_id, emote_name, count) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (channel, day, emote_id) DO UPDATE SET count = twitch_emote_daily.count + EXCLUDED.count,
+				emote_name = EXCLUDED.emote_name
+		`, channel, k.Day, k.Term, r.EmoteNames[k.Term], n); err != nil {
+			return err
+		}
+	}
+
+	if err := applyChatterRollup(tx, channel, r.Chatters); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO bronze.twitch_chat_rollup_state (name, channel, last_message_id, updated_at) VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (name, channel) DO UPDATE SET last_message_id = EXCLUDED.last_message_id, updated_at = NOW()
+	`, chatRollupName, channel, r.LastMessageID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type chatterStreak struct {
+	current, longest int
+	lastDay          string
+}
+
+func applyChatterRollup(tx *sql.Tx, channel string, chatters map[string]*models.ChatterRollup) error {
+	if len(chatters) == 0 {
+		return nil
+	}
+
+	usernames := make([]string, 0, len(chatters))
+	for username := range chatters {
+		usernames = append(usernames, username)
+	}
+
+	// Lock the existing rows so streaks are extended from their stored state
+	streaks := make(map[string]chatterStreak)
+	rows, err := tx.Query(`
+		SELECT username, current_streak, longest_streak, COALESCE(TO_CHAR(last_streak_day, 'YYYY-MM-DD'), '')
+		FROM bronze.twitch_chatters
+		WHERE channel = $1 AND username = ANY($2)
+		FOR UPDATE
+	`, channel, pq.Array(usernames))
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var username string
+		var s chatterStreak
+		if err := rows.Scan(&username, &s.current, &s.longest, &s.lastDay); err != nil {
+			rows.Close()
+			return err
+		}
+		streaks[username] = s
+	}
+	rows.Close()
+
+	for username, c := range chatters {
+		s := extendStreak(streaks[username], c.Days)
+
+		if _, err := tx.Exec(`
+			INSERT INTO bronze.twitch_chatters (channel, username, display_name, messages, first_seen, last_seen,
+												current_streak, longest_streak, last_streak_day)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (channel, username) DO UPDATE SET
+				display_name = COALESCE(NULLIF(EXCLUDED.display_name, ''), twitch_chatters.display_name),
+				messages = twitch_chatters.messages + EXCLUDED.messages,
+				first_seen = LEAST(twitch_chatters.first_seen, EXCLUDED.first_seen),
+				last_seen = GREATEST(twitch_chatters.last_seen, EXCLUDED.last_seen),
+				current_streak = EXCLUDED.current_streak,
+				longest_streak = EXCLUDED.longest_streak,
+				last_streak_day = EXCLUDED.last_streak_day
+		`, channel, username, c.DisplayName, c.Messages, c.FirstSeen, c.LastSeen, s.current, s.longest, s.lastDay); err != nil {
+			return err
+		}
+
+		for day, n := range c.Days {
+			if _, err := tx.Exec(`
+				INSERT INTO bronze.twitch_chatter_daily (channel, day, username, messages) VALUES ($1, $2, $3, $4)
+				ON CONFLICT (channel, day, username) DO UPDATE SET messages = twitch_chatter_daily.messages + EXCLUDED.messages
+			`, channel, day, username, n); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// extendStreak walks the new active days in order and extends or restarts the day streak
+func extendStreak(s chatterStreak, days map[string]int) chatterStreak {
+	ordered := make([]string, 0, len(days))
+	for day := range days {
+		ordered = append(ordered, day)
+	}
+	sort.Strings(ordered)
+
+	for _, day := range ordered {
+		if day <= s.lastDay {
+			continue
+		}
+		if s.lastDay != "" && day == nextDay(s.lastDay) {
+			s.current++
+		} else {
+			s.current = 1
+		}
+		s.lastDay = day
+		if s.current > s.longest {
+			s.longest = s.current
+		}
+	}
+	return s
+}
+
+func nextDay(day string) string {
+	t, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return ""
+	}
+	return t.AddDate(0, 0, 1).Format("2006-01-02")
+}
+
+// GetChatLeaderboard ranks a channel's chatters by messages sent on or after since
+func GetChatLeaderboard(db *sql.DB, channel string, since time.Time, limit int) ([]models.LeaderboardEntry, error) {
+	rows, err := db.Query(`
+		SELECT d.username, COALESCE(c.display_name, d.username), SUM(d.messages) AS total, COUNT(*)
+		FROM bronze.twitch_chatter_daily d
+		LEFT JOIN bronze.twitch_chatters c ON c.channel = d.channel AND c.username = d.username
+		WHERE d.channel = $1 AND d.day >= $2
+		GROUP BY d.username, c.display_name
+		ORDER BY total DESC, d.username
+		LIMIT $3
+	`, channel, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.LeaderboardEntry{}
+	for rows.Next() {
+		e := models.LeaderboardEntry{Rank: len(entries) + 1}
+		if err := rows.Scan(&e.Username, &e.DisplayName, &e.Messages, &e.ActiveDays); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetChatterStats returns a chatter's lifetime stats in a channel and their daily counts since the
+// given day, or sql.ErrNoRows if they've never chatted there
+func GetChatterStats(db *sql.DB, channel, username string, dailySince time.Time) (models.ChatterStats, error) {
+	var s models.ChatterStats
+	err := db.QueryRow(`
+		SELECT username, COALESCE(display_name, username), messages, first_seen, last_seen,
+			   current_streak, longest_streak, COALESCE(TO_CHAR(last_streak_day, 'YYYY-MM-DD'), '')
+		FROM bronze.twitch_chatters
+		WHERE channel = $1 AND username = $2
+	`, channel, username).Scan(&s.Username, &s.DisplayName, &s.Messages, &s.FirstSeen, &s.LastSeen,
+		&s.CurrentStreak, &s.LongestStreak, &s.LastStreakDay)
+	if err != nil {
+		return s, err
+	}
+
+	rows, err := db.Query(`
+		SELECT TO_CHAR(day, 'YYYY-MM-DD'), messages
+		FROM bronze.twitch_chatter_daily
+		WHERE channel = $1 AND username = $2 AND day >= $3
+		ORDER BY day
+	`, channel, username, dailySince)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+
+	s.Daily = []models.DailyCount{}
+	for rows.Next() {
+		var d models.DailyCount
+		if err := rows.Scan(&d.Day, &d.Messages); err != nil {
+			return s, err
+		}
+		s.Daily = append(s.Daily, d)
+	}
+	return s, rows.Err()
+}
+
+// GetTopChatWords returns the most used words in a channel on or after since
+func GetTopChatWords(db *sql.DB, channel string, since time.Time, limit int) ([]models.TermCount, error) {
+	return queryTermCounts(db, `
+		SELECT word, '', SUM(count) AS total
+		FROM bronze.twitch_word_daily
+		WHERE channel = $1 AND day >= $2
+		GROUP BY word
+		ORDER BY total DESC, word
+		LIMIT $3
+	`, channel, since, limit)
+}
+
+// GetTopChatEmotes returns the most used emotes in a channel on or after since
+func GetTopChatEmotes(db *sql.DB, channel string, since time.Time, limit int) ([]models.TermCount, error) {
+	return queryTermCounts(db, `
+		SELECT MAX(emote_name), emote_id, SUM(count) AS total
+		FROM bronze.twitch_emote_daily
+		WHERE channel = $1 AND day >= $2
+		GROUP BY emote_id
+		ORDER BY total DESC, emote_id
+		LIMIT $3
+	`, channel, since, limit)
+}
+
+func queryTermCounts(db *sql.DB, query string, channel string, since time.Time, limit int) ([]models.TermCount, error) {
+	rows, err := db.Query(query, channel, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terms := []models.TermCount{}
+	for rows.Next() {
+		var t models.TermCount
+		if err := rows.Scan(&t.Term, &t.EmoteID, &t.Count); err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+	}
+	return terms, rows.Err()
+}
+
+// GetChatHeatmap counts a channel's messages since the given time by weekday and hour in timeZone.
+// Hourly buckets are UTC, so zones with a half-hour offset are approximate.
+func GetChatHeatmap(db *sql.DB, channel string, since time.Time, timeZone string) ([7][24]int, error) {
+	var hours [7][24]int
+	rows, err := db.Query(`
+		SELECT EXTRACT(DOW FROM hour AT TIME ZONE $3)::int, EXTRACT(HOUR FROM hour AT TIME ZONE $3)::int, SUM(messages)
+		FROM bronze.twitch_chat_hourly
+		WHERE channel = $1 AND hour >= $2
+		GROUP BY 1, 2
+	`, channel, since, timeZone)
+	if err != nil {
+		return hours, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dow, hour, n int
+		if err := rows.Scan(&dow, &hour, &n); err != nil {
+			return hours, err
+		}
+		if dow >= 0 && dow < 7 && hour >= 0 && hour < 24 {
+			hours[dow][hour] = n
+		}
+	}
+	return hours, rows.Err()
+}
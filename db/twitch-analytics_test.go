@@ -0,0 +1,36 @@
+package db
+
+import "testing"
+
+func TestExtendStreak(t *testing.T) {
+	tests := []struct {
+		name  string
+		start chatterStreak
+		days  []string
+		want  chatterStreak
+	}{
+		{"first day", chatterStreak{}, []string{"2026-03-01"}, chatterStreak{1, 1, "2026-03-01"}},
+		{"consecutive days in one batch", chatterStreak{},
+			[]string{"2026-03-03", "2026-03-01", "2026-03-02"}, chatterStreak{3, 3, "2026-03-03"}},
+		{"continues a stored streak", chatterStreak{4, 6, "2026-03-01"},
+			[]string{"2026-03-02"}, chatterStreak{5, 6, "2026-03-02"}},
+		{"across a month end", chatterStreak{2, 2, "2026-02-28"},
+			[]string{"2026-03-01"}, chatterStreak{3, 3, "2026-03-01"}},
+		{"a missed day restarts it", chatterStreak{5, 5, "2026-03-01"},
+			[]string{"2026-03-03"}, chatterStreak{1, 5, "2026-03-03"}},
+		{"restart then a new best", chatterStreak{2, 2, "2026-03-01"},
+			[]string{"2026-03-05", "2026-03-06", "2026-03-07"}, chatterStreak{3, 3, "2026-03-07"}},
+		{"days already counted are skipped", chatterStreak{3, 3, "2026-03-05"},
+			[]string{"2026-03-04", "2026-03-05"}, chatterStreak{3, 3, "2026-03-05"}},
+	}
+
+	for _, tt := range tests {
+		days := make(map[string]int, len(tt.days))
+		for _, day := range tt.days {
+			days[day] = 1
+		}
+		if got := extendStreak(tt.start, days); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
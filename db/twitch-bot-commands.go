@@ -0,0 +1,62 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+
+	"majesticcoding.com/api/models"
+)
+
+// GetTwitchBotCommands returns all custom bot commands ordered by name
+func GetTwitchBotCommands(db *sql.DB) ([]models.TwitchBotCommand, error) {
+	rows, err := db.Query(`
+		SELECT id, name, response, aliases, permission, cooldown_seconds, enabled, created_at, updated_at
+		FROM bronze.twitch_bot_commands
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commands []models.TwitchBotCommand
+	for rows.Next() {
+		var cmd models.TwitchBotCommand
+		var aliases string
+		if err := rows.Scan(&cmd.ID, &cmd.Name, &cmd.Response, &aliases, &cmd.Permission,
+			&cmd.CooldownSeconds, &cmd.Enabled, &cmd.CreatedAt, &cmd.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if aliases != "" {
+			cmd.Aliases = strings.Split(aliases, ",")
+		}
+		commands = append(commands, cmd)
+	}
+	return commands, rows.Err()
+}
+
+// UpsertTwitchBotCommand creates a custom bot command or replaces the one with the same name
+func UpsertTwitchBotCommand(db *sql.DB, cmd models.TwitchBotCommand) error {
+	_, err := db.Exec(`
+		INSERT INTO bronze.twitch_bot_commands (name, response, aliases, permission, cooldown_seconds, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (name) DO UPDATE SET
+			response = EXCLUDED.response,
+			aliases = EXCLUDED.aliases,
+			permission = EXCLUDED.permission,
+			cooldown_seconds = EXCLUDED.cooldown_seconds,
+			enabled = EXCLUDED.enabled,
+			updated_at = CURRENT_TIMESTAMP
+	`, cmd.Name, cmd.Response, strings.Join(cmd.Aliases, ","), cmd.Permission, cmd.CooldownSeconds, cmd.Enabled)
+	return err
+}
+
+// DeleteTwitchBotCommand removes a custom bot command, returning false if it did not exist
+func DeleteTwitchBotCommand(db *sql.DB, name string) (bool, error) {
+	res, err := db.Exec(`DELETE FROM bronze.twitch_bot_commands WHERE name = $1`, name)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
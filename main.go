@@ -2,6 +2,8 @@ package main
 
 import (
 	"log"
+	"os"
+	"time"
 
 	"majesticcoding.com/api/config"
 	"majesticcoding.com/api/handlers"
@@ -17,25 +19,80 @@ import (
 
 func main() {
 	config.LoadEnv()
+	cfg, src, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error: Invalid config: %v", err)
+	}
+	cfg.LogSummary(src)
+	services.UseConfig(cfg)
+	handlers.UseConfig(cfg)
+	config.Watch(cfg, src, os.Args[1:], func(reloaded *config.Config) {
+		services.UseConfig(reloaded)
+		handlers.UseConfig(reloaded)
+		if services.FeatureEnabled("twitch_chat") {
+			services.StartTwitchChatFeed()
+		}
+	})
+
 	handlers.StartBroadcaster()
-	db.Connect()
+	if services.FeatureEnabled("database") {
+		if err := db.Connect(cfg.Database.URL); err != nil {
+			log.Printf("Warning: Database not connected: %v", err)
+			services.MarkFeatureUnavailable("database", "could not connect")
+		}
+	}
 
 	// Initialize Redis
 	if err := services.InitRedis(); err != nil {
 		log.Printf("Warning: Failed to initialize Redis: %v", err)
 	}
+	services.StartCacheInvalidation()
 
 	// Create database tables
 	database := db.GetDB()
 	if database != nil {
 		db.InitializeDatabaseTables(database)
 		services.StartSessionCleanup(database)
+		if err := db.AssignChannelToLegacyTwitchRows(database, services.DefaultProfile().TwitchChannel); err != nil {
+			log.Printf("Warning: Failed to assign legacy Twitch rows to the default channel: %v", err)
+		}
+		services.StartTokenRefresher()
+		services.StartChatAnalyticsRollup(time.Minute)
 	}
 
 	handlers.StartMessageCleanup()
-	services.StartTwitchChatFeed("majesticcodingtwitch")
-	handlers.InitSpotifyClient()
+	services.StartStatsCollector()
+	channel := services.DefaultProfile().TwitchChannel
+	if services.FeatureEnabled("twitch") {
+		services.StartTwitchBot(channel, services.TwitchBotDeps{
+			NowPlaying: handlers.CachedSpotifyTrack,
+		})
+	}
+	services.AddTwitchChatListener(handlers.BroadcastTwitchChatEvent)
+	if services.FeatureEnabled("twitch_chat") {
+		services.StartTwitchChatFeed()
+	}
+	if services.FeatureEnabled("chat_bridge") {
+		if err := services.StartChatBridge(channel, handlers.RelayTwitchToSiteChat); err != nil {
+			log.Printf("Warning: Chat bridge not started: %v", err)
+		}
+	}
+	services.SetGraphQLDeps(services.GraphQLDeps{
+		NowPlaying: handlers.CachedSpotifyTrack,
+	})
+	if services.FeatureEnabled("spotify") {
+		handlers.InitSpotifyClient()
+		services.StartNowPlayingWatcher(handlers.CurrentSpotifyTrack)
+	}
+	services.StartAlertEngine(handlers.DeliverAlertFrame)
+	services.StartStreamSessionTracking()
+	if services.FeatureEnabled("stream_status") {
+		services.StartIVSStatusPoller(time.Minute)
+	}
+	if services.FeatureEnabled("twitch_eventsub") {
+		services.StartTwitchEventSub()
+	}
 
 	router := handlers.InitializeRouter()
-	router.Run(":8080")
+	router.Run(":" + cfg.Server.Port)
 }
@@ -42,7 +42,8 @@ func TestMainDeployment(t *testing.T) {
 
 	defer func() {
 		fmt.Println("🛑 Cleaning up app process...")
-		_ = cmd.Process.Kill()
+		// go run starts the server as a child, so kill the whole process group
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
 		_ = cmd.Wait()
 	}()
 
@@ -0,0 +1,70 @@
+(function () {
+  const box = document.getElementById('alert');
+  const image = document.getElementById('alert-image');
+  const text = document.getElementById('alert-text');
+
+  // Supports ?ws=... to point the overlay at another server. ?key=... is the overlay secret
+  // (ALERT_OVERLAY_SECRET) the socket requires.
+  const q = new URLSearchParams(location.search);
+  const base = (location.protocol === 'https:' ? 'wss' : 'ws') + '://' + location.host;
+  const target = new URL(q.get('ws') || (base + '/ws/alerts'));
+  if (q.get('key')) target.searchParams.set('key', q.get('key'));
+  const wsURL = target.toString();
+
+  let ws = null;
+  let current = null; // { id, timer, audio }
+
+  function ack(id, status) {
+    if (ws && ws.readyState === WebSocket.OPEN) {
+      ws.send(JSON.stringify({ type: 'ack', id, status }));
+    }
+  }
+
+  function finish(id, report) {
+    if (!current || current.id !== id) return;
+    clearTimeout(current.timer);
+    if (current.audio) current.audio.pause();
+    current = null;
+    box.classList.add('opacity-0');
+    if (report) ack(id, 'done');
+  }
+
+  function play(alert) {
+    if (current) finish(current.id, true);
+
+    text.textContent = alert.text || '';
+    if (alert.image_url) {
+      image.src = alert.image_url;
+      image.classList.remove('hidden');
+    } else {
+      image.classList.add('hidden');
+    }
+
+    let audio = null;
+    if (alert.sound_url) {
+      audio = new Audio(alert.sound_url);
+      audio.play().catch(() => {}); // autoplay may be blocked outside OBS
+    }
+
+    box.classList.remove('opacity-0');
+    current = {
+      id: alert.id,
+      audio,
+      timer: setTimeout(() => finish(alert.id, true), alert.duration_ms || 5000),
+    };
+    ack(alert.id, 'started');
+  }
+
+  function connect() {
+    ws = new WebSocket(wsURL);
+    ws.onmessage = (e) => {
+      let frame;
+      try { frame = JSON.parse(e.data); } catch { return; }
+      if (frame.type === 'play' && frame.alert) play(frame.alert);
+      if (frame.type === 'skip') finish(frame.id, false);
+    };
+    ws.onclose = () => setTimeout(connect, 3000);
+  }
+
+  connect();
+})();
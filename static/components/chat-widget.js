@@ -2,10 +2,14 @@
   const log = document.getElementById('log');
 
   // Build WS URL (supports ?ws=... and optional ?room=...)
+  // The widget speaks chat protocol v2; a custom ?ws= endpoint may still send legacy frames.
   const q = new URLSearchParams(location.search);
   const base = (location.protocol === 'https:' ? 'wss' : 'ws') + '://' + location.host;
-  const roomPart = q.get('room') ? ('?room=' + encodeURIComponent(q.get('room'))) : '';
-  const wsURL = q.get('ws') || (base + '/ws/chat' + roomPart);
+  const params = new URLSearchParams({ v: '2' });
+  if (q.get('room')) params.set('room', q.get('room'));
+  const wsURL = q.get('ws') || (base + '/ws/chat?' + params.toString());
+
+  const rows = new Map(); // message id -> text element
 
   function getColorForUsername(u) {
     let h = 0;
@@ -14,14 +18,16 @@
     return `hsl(${hue},70%,60%)`;
   }
 
-  function append({ Username, Content } = {}) {
+  function append({ ID, Username, Content, Platform } = {}) {
+    if (ID && rows.has(ID)) return; // already rendered
+
     const row = document.createElement('div');
     row.className = 'cw-msg';
 
     if (Username) {
       const name = document.createElement('div');     // block element = its own line
       name.className = 'cw-name';
-      name.textContent = Username;
+      name.textContent = Platform === 'twitch' ? '🟣 ' + Username : Username;
       name.style.color = getColorForUsername(Username); // dynamic per-user color
       row.appendChild(name);
     }
@@ -31,13 +37,45 @@
     text.textContent = Content ?? '';
     row.appendChild(text);
 
+    if (ID) rows.set(ID, text);
+
     log.appendChild(row);
     log.scrollTop = log.scrollHeight; // stick to bottom
   }
 
+  // Compatibility shim: turn v2 envelopes and legacy bare messages into append() calls
+  function handle(frame) {
+    if (!frame || typeof frame !== 'object') return append({ Content: String(frame) });
+    if (!('v' in frame) || !frame.type) {
+      if (frame.type === 'error') return; // legacy rejection frame
+      return append(frame);
+    }
+
+    const p = frame.payload || {};
+    switch (frame.type) {
+      case 'history':
+        (Array.isArray(p) ? p : []).forEach(append);
+        break;
+      case 'message':
+        append(p);
+        break;
+      case 'edit': {
+        const text = rows.get(p.message_id);
+        if (text) text.textContent = p.content ?? '';
+        break;
+      }
+      case 'notice':
+        append({ Content: p.text });
+        break;
+      default:
+        // typing, join, leave, reaction, ack and error are not shown on the overlay
+        break;
+    }
+  }
+
   const ws = new WebSocket(wsURL);
   ws.onmessage = (e) => {
-    try { append(JSON.parse(e.data)); }
+    try { handle(JSON.parse(e.data)); }
     catch { append({ Content: String(e.data) }); }
   };
 })();
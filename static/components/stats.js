@@ -45,7 +45,26 @@ function showModalShell(title) {
   }, 100); // Small delay to prevent immediate closing
 }
 
-function populateModalContent(data) {
+// Flatten a stats snapshot into the label/value pairs the modal shows
+function snapshotEntries(data) {
+  if (!data.metrics) return data; // errors
+
+  const entries = { name: data.displayName || data.account, ...data.metrics };
+  const details = data.details || {};
+  if (details.languages && details.languages.length) {
+    entries.topLanguages = details.languages.slice(0, 3).map(l => l.name).join(" | ");
+  }
+  if (details.mainLanguages) {
+    entries.mainLanguages = details.mainLanguages;
+  }
+  if (details.recentUploads && details.recentUploads.length) {
+    entries.latestUpload = details.recentUploads[0].title;
+  }
+  return entries;
+}
+
+function populateModalContent(snapshot) {
+  const data = snapshotEntries(snapshot);
   const content = Object.entries(data)
     .map(([k, v]) => {
       let formattedValue = v;
@@ -55,8 +74,8 @@ function populateModalContent(data) {
         formattedValue = String(v);
       }
 
-      // Format camelCase labels to have spaces (e.g., MainLanguages -> Main Languages)
-      const formattedKey = k.replace(/([A-Z])/g, ' $1').trim();
+      // Format camelCase and snake_case labels to have spaces (e.g., MainLanguages -> Main Languages)
+      const formattedKey = k.replace(/_/g, ' ').replace(/([A-Z])/g, ' $1').trim();
 
       return `
         <div class="stat-box glowing-effect">
@@ -81,7 +100,7 @@ function adjustModalSize(data) {
   let hasLongWord = false;
 
   Object.entries(data).forEach(([k, v]) => {
-    const keyLength = k.replace(/([A-Z])/g, ' $1').trim().length;
+    const keyLength = k.replace(/_/g, ' ').replace(/([A-Z])/g, ' $1').trim().length;
     const valueStr = String(v);
     const valueLength = valueStr.length;
 
@@ -54,7 +54,20 @@
 
   ws.onmessage = (event) => {
     try {
-      const msg = JSON.parse(event.data);
+      const frame = JSON.parse(event.data);
+      if (frame.type === "ban" || frame.type === "timeout") {
+        const banned = Object.keys(users).find(
+          (u) => u.toLowerCase() === (frame.username || "").toLowerCase()
+        );
+        if (banned) {
+          overlay.removeChild(users[banned]);
+          delete users[banned];
+        }
+        return;
+      }
+      if (frame.type !== "message" || !frame.message) return;
+
+      const msg = frame.message;
       const username = msg.display_name || msg.username || "Unknown";
       const avatar = msg.avatar || `/static/img/default-avatar.png`;
       addUser(username, avatar);
@@ -37,9 +37,11 @@
     return table[k1] || table[k2] || '/static/img/arsenal-2.gif';
   }
 
-  function makeChip(displayName, message, username) {
+  function makeChip(displayName, message, username, messageId) {
     // chip wrapper
     const wrap = document.createElement('div');
+    wrap.dataset.messageId = messageId || '';
+    wrap.dataset.username = (username || '').toLowerCase();
     Object.assign(wrap.style, {
       display: 'flex',
       flexDirection: 'column',
@@ -101,29 +103,45 @@
     }, 8000);
   }
 
-  // WebSocket: /ws/twitch
+  // hide chips removed by moderators
+  function removeChips(match) {
+    Array.from(overlay.children).forEach((chip) => {
+      if (match(chip)) chip.remove();
+    });
+  }
+
+  // WebSocket: /ws/twitch (the server applies the allow-list via ?users=)
   const ws = new WebSocket(
-    (location.protocol === 'https:' ? 'wss' : 'ws') + '://' + location.host + '/ws/twitch'
+    (location.protocol === 'https:' ? 'wss' : 'ws') + '://' + location.host +
+    '/ws/twitch?users=' + encodeURIComponent(Array.from(allowed).join(','))
   );
 
   ws.onmessage = (ev) => {
     try {
-      const raw = JSON.parse(ev.data);
-      const items = Array.isArray(raw) ? raw : [raw];
-
-      items.forEach((msg) => {
-        const dn = (msg.display_name || '').toLowerCase();
-        const un = (msg.username || '').toLowerCase();
-
-        // only show if in allow-list
-        if (!allowed.has(un) && !allowed.has(dn)) return;
-
-        const displayName = msg.display_name || msg.username || '';
-        const username = msg.username || msg.display_name || '';
-        const message = msg.message || '';
-
-        makeChip(displayName, message, username);
-      });
+      const event = JSON.parse(ev.data);
+
+      switch (event.type) {
+        case 'message': {
+          const msg = event.message || {};
+          const displayName = msg.display_name || msg.username || '';
+          const username = msg.username || msg.display_name || '';
+          makeChip(displayName, msg.message || '', username, msg.message_id);
+          break;
+        }
+        case 'delete':
+          removeChips((chip) => chip.dataset.messageId === event.message_id);
+          break;
+        case 'timeout':
+        case 'ban':
+          removeChips((chip) => chip.dataset.username === (event.username || '').toLowerCase());
+          break;
+        case 'clear':
+          removeChips(() => true);
+          break;
+        default:
+          // history is not replayed on the overlay
+          break;
+      }
     } catch (e) {
       console.warn('bad message', e);
     }
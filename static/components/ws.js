@@ -69,6 +69,12 @@ function setupWebSocketHandlers() {
   ws.onmessage = (event) => {
     const msg = JSON.parse(event.data);
 
+    // Rejected message (rate limit, spam, mute)
+    if (msg.type === 'error') {
+      appendChatNotice(msg);
+      return;
+    }
+
     const container = document.createElement('div');
     container.className = "mb-2";
 
@@ -153,6 +159,19 @@ function appendMessage(msg) {
   chatMessages.scrollTop = chatMessages.scrollHeight;
 }
 
+// Show a server notice such as a rate limit or mute
+function appendChatNotice(notice) {
+  const container = document.createElement('div');
+  container.className = "mb-2 text-sm text-red-400 italic";
+  let text = notice.message || 'Message rejected';
+  if (notice.retry_after) {
+    text += ` (try again in ${notice.retry_after}s)`;
+  }
+  container.textContent = text;
+  chatMessages.appendChild(container);
+  chatMessages.scrollTop = chatMessages.scrollHeight;
+}
+
 // Simple function to check if user is authenticated
 function isUserAuthenticated() {
   return localStorage.getItem('supabase_token') !== null;